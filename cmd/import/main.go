@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/rasadov/subscription-manager/internal/config"
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/internal/service"
+	"github.com/rasadov/subscription-manager/pkg/database"
+	"github.com/rasadov/subscription-manager/pkg/tenant"
+)
+
+// import loads subscriptions from a CSV file using the same validation as the
+// POST /api/v1/subscriptions/import endpoint and prints the report as JSON.
+//
+// Usage: import -tenant default -file subscriptions.csv [-dry-run] [-upsert] [-mapping service_name=Service,price=Cost]
+func main() {
+	tenantID := flag.String("tenant", "", "tenant the subscriptions are imported into, default for a single-tenant deployment")
+	filePath := flag.String("file", "", "path to the CSV file, - reads from stdin")
+	dryRun := flag.Bool("dry-run", false, "validate rows without saving them")
+	upsert := flag.Bool("upsert", false, "update subscriptions matching user_id, service_name and start_date")
+	mapping := flag.String("mapping", "", "column mapping, e.g. service_name=Service,price=Cost")
+	flag.Parse()
+
+	if *tenantID == "" || *filePath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var input io.Reader = os.Stdin
+	if *filePath != "-" {
+		file, err := os.Open(*filePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to open CSV file: %v\n", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	db, err := database.NewPostgresDB(
+		cfg.Database.Host,
+		cfg.Database.User,
+		cfg.Database.Password,
+		cfg.Database.DBName,
+		cfg.Database.Port,
+		cfg.Database.SSLMode,
+	)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to database: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Imported rows are linked to the catalog and evaluated against budgets like the API does
+	subscriptionRepo := repository.NewSubscriptionRepositiry(db)
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	budgetService := service.NewBudgetService(repository.NewBudgetRepository(db), subscriptionRepo, logger,
+		service.WithDefaultThresholds(cfg.Budget.Thresholds))
+	importService := service.NewImportService(subscriptionRepo,
+		service.WithCatalog(repository.NewCatalogRepository(db)),
+		service.WithObserver(budgetService), service.WithDuplicateCheck())
+	report, httpErr := importService.ImportCSV(tenant.WithTenant(context.Background(), *tenantID), input, dto.ImportSubscriptionsQuery{
+		DryRun:  *dryRun,
+		Upsert:  *upsert,
+		Mapping: *mapping,
+	})
+	if httpErr != nil {
+		fmt.Fprintf(os.Stderr, "Import failed: %v\n", httpErr)
+		os.Exit(1)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write report: %v\n", err)
+		os.Exit(1)
+	}
+
+	if report.Rejected > 0 {
+		os.Exit(3)
+	}
+}
@@ -15,11 +15,17 @@ import (
 
 	"github.com/rasadov/subscription-manager/internal/config"
 	"github.com/rasadov/subscription-manager/internal/handlers"
+	"github.com/rasadov/subscription-manager/internal/middleware"
 	"github.com/rasadov/subscription-manager/internal/models"
 	"github.com/rasadov/subscription-manager/internal/repository"
 	"github.com/rasadov/subscription-manager/internal/service"
+	"github.com/rasadov/subscription-manager/pkg/auth"
 	"github.com/rasadov/subscription-manager/pkg/database"
+	"github.com/rasadov/subscription-manager/pkg/jwt"
 	"github.com/rasadov/subscription-manager/pkg/logger"
+	"github.com/rasadov/subscription-manager/pkg/oidc"
+	"github.com/rasadov/subscription-manager/pkg/ratelimit"
+	"github.com/rasadov/subscription-manager/pkg/schedule"
 
 	_ "github.com/rasadov/subscription-manager/docs"
 )
@@ -38,6 +44,16 @@ import (
 
 // @host localhost:8080
 // @BasePath /api/v1
+
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description JWT access token as "Bearer <token>"
+
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name Authorization
+// @description API key of a service account as "ApiKey <key>"
 func main() {
 	cfg, err := config.Load()
 	if err != nil {
@@ -64,7 +80,7 @@ func main() {
 	log.Info("Database connected successfully")
 
 	// Run migrations
-	err = db.AutoMigrate(&models.Subscription{})
+	err = db.AutoMigrate(&models.Subscription{}, &models.CalendarToken{}, &models.SubscriptionDraft{}, &models.ReceiptRule{}, &models.Service{}, &models.Plan{}, &models.Tag{}, &models.Budget{}, &models.BudgetAlert{}, &models.PriceChange{}, &models.RecommendationState{}, &models.SubscriptionMember{}, &models.Organization{}, &models.OrganizationMember{}, &models.APIKey{}, &models.RoleAssignment{}, &models.Session{}, &models.RateLimitBucket{}, &models.IdempotencyRecord{})
 	if err != nil {
 		log.Error("Failed to run migrations", "error", err)
 		os.Exit(1)
@@ -73,8 +89,68 @@ func main() {
 
 	// Initialize repository, service and handlers
 	subscriptionRepo := repository.NewSubscriptionRepositiry(db)
-	subscriptionService := service.NewSubscriptionService(subscriptionRepo)
+	catalogRepo := repository.NewCatalogRepository(db)
+	organizationRepo := repository.NewOrganizationRepository(db)
+	organizationService := service.NewOrganizationService(organizationRepo)
+	organizationHandler := handlers.NewOrganizationHandler(organizationService, log)
+	budgetRepo := repository.NewBudgetRepository(db)
+	budgetService := service.NewBudgetService(budgetRepo, subscriptionRepo, log, service.WithDefaultThresholds(cfg.Budget.Thresholds))
+	budgetHandler := handlers.NewBudgetHandler(budgetService, log)
+	subscriptionOptions := []service.SubscriptionServiceOption{
+		service.WithCatalog(catalogRepo), service.WithOrganizations(organizationRepo),
+		service.WithObserver(budgetService), service.WithDuplicateCheck(),
+	}
+	subscriptionService := service.NewSubscriptionService(subscriptionRepo, subscriptionOptions...)
 	subscriptionHandler := handlers.NewSubscriptionHandler(subscriptionService, log)
+	importService := service.NewImportService(subscriptionRepo, subscriptionOptions...)
+	importHandler := handlers.NewImportHandler(importService, log)
+	exportService := service.NewExportService(subscriptionRepo, subscriptionOptions...)
+	exportHandler := handlers.NewExportHandler(exportService, log)
+	calendarTokenRepo := repository.NewCalendarTokenRepository(db)
+	calendarService := service.NewCalendarService(calendarTokenRepo, subscriptionRepo)
+	calendarHandler := handlers.NewCalendarHandler(calendarService, log)
+	draftRepo := repository.NewDraftRepository(db)
+	statementService := service.NewStatementService(draftRepo, subscriptionRepo)
+	draftService := service.NewDraftService(draftRepo, subscriptionService)
+	draftHandler := handlers.NewDraftHandler(statementService, draftService, log)
+	receiptRuleRepo := repository.NewReceiptRuleRepository(db)
+	receiptService := service.NewReceiptService(receiptRuleRepo, draftRepo, subscriptionRepo)
+	receiptHandler := handlers.NewReceiptHandler(receiptService, log)
+	priceChangeRepo := repository.NewPriceChangeRepository(db)
+	forecastService := service.NewForecastService(subscriptionRepo, priceChangeRepo)
+	forecastHandler := handlers.NewForecastHandler(forecastService, log)
+	duplicateService := service.NewDuplicateService(subscriptionRepo)
+	insightsHandler := handlers.NewInsightsHandler(duplicateService, log)
+	recommendationRepo := repository.NewRecommendationRepository(db)
+	recommendationService := service.NewRecommendationService(subscriptionRepo, catalogRepo, recommendationRepo,
+		service.WithHouseholds(organizationService))
+	recommendationHandler := handlers.NewRecommendationHandler(recommendationService, log)
+	catalogService := service.NewCatalogService(catalogRepo)
+	catalogHandler := handlers.NewCatalogHandler(catalogService, log)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService, log)
+	roleRepo := repository.NewRoleRepository(db)
+	roleService := service.NewRoleService(roleRepo, cfg.Auth.DefaultRole)
+	roleHandler := handlers.NewRoleHandler(roleService, log)
+	sessionRepo := repository.NewSessionRepository(db)
+	sessionService := service.NewSessionService(sessionRepo, oidc.NewClient(oidc.Config{
+		Issuer:       cfg.OIDC.Issuer,
+		ClientID:     cfg.OIDC.ClientID,
+		ClientSecret: cfg.OIDC.ClientSecret,
+		RedirectURL:  cfg.OIDC.RedirectURL,
+		Scopes:       cfg.OIDC.Scopes,
+		Leeway:       cfg.Auth.Leeway,
+	}), service.SessionConfig{
+		TTL:                   cfg.OIDC.SessionTTL,
+		AdminRole:             cfg.Auth.AdminRole,
+		TenantClaim:           cfg.Auth.TenantClaim,
+		PostLogoutRedirectURL: cfg.OIDC.PostLogoutRedirectURL,
+	})
+	sessionHandler := handlers.NewSessionHandler(sessionService, log, cfg.OIDC.CookieSecure)
+	rateLimitRepo := repository.NewRateLimitRepository(db)
+	idempotencyRepo := repository.NewIdempotencyRepository(db)
+	idempotencyService := service.NewIdempotencyService(idempotencyRepo, cfg.IdempotencyTTL)
 
 	// Setup Gin router
 	if cfg.Server.Host == "release" {
@@ -84,18 +160,164 @@ func main() {
 	router := gin.New()
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Error("Invalid trusted proxies", "error", err)
+		os.Exit(1)
+	}
+
+	// Requests are limited per client IP before authentication, so that failing
+	// credentials are limited too, and per API key or user after it
+	var ipRateLimit, rateLimit []gin.HandlerFunc
+	if cfg.RateLimit.Enabled {
+		var store ratelimit.Store
+		switch cfg.RateLimit.Store {
+		case "memory":
+			store = ratelimit.NewMemoryStore()
+		case "postgres":
+			store = rateLimitRepo
+		default:
+			log.Error("Unknown rate limit store", "store", cfg.RateLimit.Store)
+			os.Exit(1)
+		}
+		ipRateLimit = append(ipRateLimit, middleware.RateLimitIP(store, cfg.RateLimit.IP, log))
+		rateLimit = append(rateLimit, middleware.RateLimit(store, middleware.RateLimitPolicy{
+			Default: cfg.RateLimit.Default,
+			Routes:  cfg.RateLimit.Routes,
+		}, log))
+	}
 
 	// Setup API routes
-	api := router.Group("/api/v1")
+	api := router.Group("/api/v1", ipRateLimit...)
 	{
+		// Calendar apps cannot send credentials, the feed is protected by its token
+		// and limited per client IP
+		api.GET("/users/:user_id/calendar.ics", append(rateLimit, calendarHandler.GetCalendarFeed)...)
+
+		if cfg.Auth.Enabled() || cfg.OIDC.Enabled() {
+			var authenticators []middleware.Authenticator
+			if cfg.Auth.Enabled() {
+				verifier, err := newJWTVerifier(cfg.Auth)
+				if err != nil {
+					log.Error("Failed to configure authentication", "error", err)
+					os.Exit(1)
+				}
+				authenticators = append(authenticators, middleware.NewJWTAuthenticator(verifier, cfg.Auth.AdminRole, cfg.Auth.TenantClaim))
+			}
+			authenticators = append(authenticators, middleware.NewAPIKeyAuthenticator(apiKeyService))
+			if cfg.OIDC.Enabled() {
+				authenticators = append(authenticators, middleware.NewSessionAuthenticator(sessionService, handlers.SessionCookie))
+			}
+			api.Use(middleware.Authenticate(log, authenticators...))
+			api.Use(middleware.ResolveRoles(roleService, log))
+		} else {
+			log.Warn("Authentication is disabled, set JWT_HS256_SECRET, JWT_JWKS_FILE, JWT_JWKS_URL or OIDC_ISSUER to enable it")
+		}
+
+		api.Use(rateLimit...)
+
+		// Permissions of every route. Which user's data a caller may access is
+		// checked by the services.
+		read := middleware.RequirePermission(auth.PermissionSubscriptionsRead)
+		write := middleware.RequirePermission(auth.PermissionSubscriptionsWrite)
+		costs := middleware.RequirePermission(auth.PermissionCostsRead)
+		admin := middleware.RequirePermission(auth.PermissionAdmin)
+		// Mutations may be retried with an Idempotency-Key, once permitted
+		idempotent := middleware.Idempotency(idempotencyService, log)
+
 		subscriptions := api.Group("/subscriptions")
 		{
-			subscriptions.POST("", subscriptionHandler.CreateSubscription)
-			subscriptions.GET("", subscriptionHandler.ListSubscriptions)
-			subscriptions.GET("/:id", subscriptionHandler.GetSubscription)
-			subscriptions.PUT("/:id", subscriptionHandler.UpdateSubscription)
-			subscriptions.DELETE("/:id", subscriptionHandler.DeleteSubscription)
-			subscriptions.GET("/total-cost", subscriptionHandler.CalculateTotalCost)
+			subscriptions.POST("", write, idempotent, subscriptionHandler.CreateSubscription)
+			subscriptions.GET("", read, subscriptionHandler.ListSubscriptions)
+			subscriptions.GET("/search", read, subscriptionHandler.SearchSubscriptions)
+			subscriptions.GET("/:id", read, subscriptionHandler.GetSubscription)
+			subscriptions.PUT("/:id", write, idempotent, subscriptionHandler.UpdateSubscription)
+			subscriptions.DELETE("/:id", write, idempotent, subscriptionHandler.DeleteSubscription)
+			subscriptions.GET("/total-cost", costs, subscriptionHandler.CalculateTotalCost)
+			subscriptions.GET("/forecast", costs, forecastHandler.Forecast)
+			subscriptions.POST("/:id/price-changes", write, idempotent, forecastHandler.SchedulePriceChange)
+			subscriptions.GET("/:id/price-changes", read, forecastHandler.ListPriceChanges)
+			subscriptions.DELETE("/:id/price-changes/:change_id", write, idempotent, forecastHandler.CancelPriceChange)
+			subscriptions.PUT("/:id/usage", write, idempotent, recommendationHandler.ReportUsage)
+			subscriptions.POST("/bulk", write, idempotent, subscriptionHandler.BulkSubscriptions)
+			subscriptions.POST("/import", write, idempotent, importHandler.ImportSubscriptions)
+			subscriptions.GET("/export", read, exportHandler.ExportSubscriptions)
+		}
+
+		users := api.Group("/users/:user_id")
+		{
+			users.POST("/calendar-token", write, idempotent, calendarHandler.IssueCalendarToken)
+			users.POST("/statements", write, idempotent, draftHandler.ImportStatement)
+			users.POST("/receipts", write, idempotent, receiptHandler.ImportReceipts)
+			users.GET("/drafts", read, draftHandler.ListDrafts)
+			users.POST("/drafts/:id/confirm", write, idempotent, draftHandler.ConfirmDraft)
+			users.POST("/drafts/:id/dismiss", write, idempotent, draftHandler.DismissDraft)
+			users.POST("/budgets", write, idempotent, budgetHandler.CreateBudget)
+			users.GET("/budgets", costs, budgetHandler.ListBudgets)
+			users.PUT("/budgets/:id", write, idempotent, budgetHandler.UpdateBudget)
+			users.DELETE("/budgets/:id", write, idempotent, budgetHandler.DeleteBudget)
+			users.GET("/budget-alerts", costs, budgetHandler.ListBudgetAlerts)
+			users.GET("/insights/duplicates", read, insightsHandler.FindDuplicates)
+			users.GET("/recommendations", costs, recommendationHandler.ListRecommendations)
+			users.POST("/recommendations/:key/dismiss", write, idempotent, recommendationHandler.DismissRecommendation)
+			users.POST("/recommendations/:key/snooze", write, idempotent, recommendationHandler.SnoozeRecommendation)
+		}
+
+		catalog := api.Group("/catalog/services")
+		{
+			catalog.POST("", admin, idempotent, catalogHandler.CreateService)
+			catalog.GET("", read, catalogHandler.ListServices)
+			catalog.GET("/match", read, catalogHandler.MatchService)
+			catalog.GET("/:id", read, catalogHandler.GetService)
+			catalog.PUT("/:id", admin, idempotent, catalogHandler.UpdateService)
+			catalog.DELETE("/:id", admin, idempotent, catalogHandler.DeleteService)
+			catalog.POST("/:id/plans", admin, idempotent, catalogHandler.CreatePlan)
+			catalog.PUT("/:id/plans/:plan_id", admin, idempotent, catalogHandler.UpdatePlan)
+			catalog.DELETE("/:id/plans/:plan_id", admin, idempotent, catalogHandler.DeletePlan)
+		}
+
+		organizations := api.Group("/organizations")
+		{
+			organizations.POST("", write, idempotent, organizationHandler.CreateOrganization)
+			organizations.GET("", read, organizationHandler.ListOrganizations)
+			organizations.GET("/:id", read, organizationHandler.GetOrganization)
+			organizations.PUT("/:id", write, idempotent, organizationHandler.UpdateOrganization)
+			organizations.DELETE("/:id", write, idempotent, organizationHandler.DeleteOrganization)
+			organizations.POST("/:id/members", write, idempotent, organizationHandler.AddMember)
+			organizations.PUT("/:id/members/:user_id", write, idempotent, organizationHandler.UpdateMember)
+			organizations.DELETE("/:id/members/:user_id", write, idempotent, organizationHandler.RemoveMember)
+			organizations.POST("/:id/members/:user_id/accept", write, idempotent, organizationHandler.AcceptMember)
+		}
+
+		receiptRules := api.Group("/receipt-rules")
+		{
+			receiptRules.POST("", admin, idempotent, receiptHandler.CreateReceiptRule)
+			receiptRules.GET("", read, receiptHandler.ListReceiptRules)
+			receiptRules.DELETE("/:id", admin, idempotent, receiptHandler.DeleteReceiptRule)
+		}
+
+		apiKeys := api.Group("/api-keys", admin)
+		{
+			apiKeys.POST("", idempotent, apiKeyHandler.CreateAPIKey)
+			apiKeys.GET("", apiKeyHandler.ListAPIKeys)
+			apiKeys.DELETE("/:id", idempotent, apiKeyHandler.RevokeAPIKey)
+		}
+
+		roleAssignments := api.Group("/role-assignments", admin)
+		{
+			roleAssignments.POST("", idempotent, roleHandler.AssignRole)
+			roleAssignments.GET("", roleHandler.ListRoleAssignments)
+			roleAssignments.DELETE("/:id", idempotent, roleHandler.RevokeRole)
+		}
+	}
+
+	// Browser login of the web dashboard
+	if cfg.OIDC.Enabled() {
+		login := router.Group("/auth")
+		{
+			login.GET("/login", sessionHandler.Login)
+			login.GET("/callback", sessionHandler.Callback)
+			login.GET("/logout", sessionHandler.Logout)
+			login.POST("/logout", sessionHandler.Logout)
 		}
 	}
 
@@ -123,11 +345,46 @@ func main() {
 		}
 	}()
 
+	// Evaluate budgets on a schedule, so alerts are raised when a new month starts
+	// even if no subscription changes
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go schedule.Every(jobsCtx, cfg.Budget.CheckInterval, func(ctx context.Context) {
+		if err := budgetService.EvaluateAll(ctx, time.Now()); err != nil {
+			log.Error("Failed to evaluate budgets", "error", err)
+		}
+	})
+	if cfg.RateLimit.Enabled && cfg.RateLimit.Store == "postgres" {
+		// Buckets idle for longer than the longest period are full again
+		idle := cfg.RateLimit.Default.Per
+		for _, limit := range cfg.RateLimit.Routes {
+			idle = max(idle, limit.Per)
+		}
+		go schedule.Every(jobsCtx, time.Hour, func(ctx context.Context) {
+			if _, err := rateLimitRepo.DeleteIdleBuckets(ctx, time.Now().Add(-idle)); err != nil {
+				log.Error("Failed to delete idle rate limit buckets", "error", err)
+			}
+		})
+	}
+	go schedule.Every(jobsCtx, time.Hour, func(ctx context.Context) {
+		if _, err := idempotencyService.DeleteExpired(ctx, time.Now()); err != nil {
+			log.Error("Failed to delete expired idempotency records", "error", err)
+		}
+	})
+	if cfg.OIDC.Enabled() {
+		go schedule.Every(jobsCtx, time.Hour, func(ctx context.Context) {
+			if _, err := sessionService.DeleteExpiredSessions(ctx, time.Now()); err != nil {
+				log.Error("Failed to delete expired sessions", "error", err)
+			}
+		})
+	}
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
 	log.Info("Shutting down server...")
+	stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -150,3 +407,26 @@ func main() {
 
 	log.Info("Server exited gracefully")
 }
+
+// newJWTVerifier builds a verifier accepting tokens signed with any configured key.
+func newJWTVerifier(cfg config.AuthConfig) (*jwt.Verifier, error) {
+	verifier := &jwt.Verifier{
+		Issuer:   cfg.Issuer,
+		Audience: cfg.Audience,
+		Leeway:   cfg.Leeway,
+	}
+	if cfg.HS256Secret != "" {
+		verifier.Keys = append(verifier.Keys, jwt.Secret(cfg.HS256Secret))
+	}
+	if cfg.JWKSFile != "" {
+		keys, err := jwt.LoadJWKSFile(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		verifier.Keys = append(verifier.Keys, keys)
+	}
+	if cfg.JWKSURL != "" {
+		verifier.Keys = append(verifier.Keys, jwt.NewRemoteJWKS(cfg.JWKSURL, cfg.JWKSRefresh, nil))
+	}
+	return verifier, nil
+}
@@ -3,17 +3,31 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rasadov/subscription-manager/pkg/ratelimit"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Log      LogConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	Log       LogConfig
+	Budget    BudgetConfig
+	Auth      AuthConfig
+	OIDC      OIDCConfig
+	RateLimit RateLimitConfig
+	// IdempotencyTTL is how long responses to requests with an Idempotency-Key
+	// are kept for retries.
+	IdempotencyTTL time.Duration
 }
 
 type ServerConfig struct {
 	Port int
 	Host string
+	// TrustedProxies are the proxies whose X-Forwarded-For header is trusted to
+	// carry the client IP. No proxy is trusted by default.
+	TrustedProxies []string
 }
 
 type DatabaseConfig struct {
@@ -29,11 +43,87 @@ type LogConfig struct {
 	Level string
 }
 
+type BudgetConfig struct {
+	// CheckInterval is how often every budget is evaluated.
+	CheckInterval time.Duration
+	// Thresholds are the default alert percentages of new budgets.
+	Thresholds []int
+}
+
+// AuthConfig configures JWT authentication. It is enabled when at least one key
+// source is set.
+type AuthConfig struct {
+	// HS256Secret is a shared secret for HS256 tokens.
+	HS256Secret string
+	// JWKSFile and JWKSURL point to key sets with RS256 and ES256 public keys.
+	JWKSFile string
+	JWKSURL  string
+	// JWKSRefresh is how often the key set at JWKSURL is fetched again.
+	JWKSRefresh time.Duration
+	Issuer      string
+	Audience    string
+	// Leeway is the tolerated clock skew when checking token lifetimes.
+	Leeway time.Duration
+	// AdminRole is the role claim value that stands for the admin role.
+	AdminRole string
+	// DefaultRole is the role of users without a role in their token or a stored
+	// role assignment.
+	DefaultRole string
+	// TenantClaim is the claim holding the tenant of the caller.
+	TenantClaim string
+}
+
+// Enabled reports whether a key source is configured.
+func (c AuthConfig) Enabled() bool {
+	return c.HS256Secret != "" || c.JWKSFile != "" || c.JWKSURL != ""
+}
+
+// OIDCConfig configures the browser login against an OpenID provider. It is
+// enabled when the issuer and the client ID are set.
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the URL of /auth/callback registered at the provider.
+	RedirectURL string
+	// Scopes are requested in addition to "openid".
+	Scopes []string
+	// PostLogoutRedirectURL is where browsers are sent after logging out.
+	PostLogoutRedirectURL string
+	// SessionTTL is how long a login session lasts.
+	SessionTTL time.Duration
+	// CookieSecure marks the session cookie as HTTPS only. It is only disabled
+	// for local development over plain HTTP.
+	CookieSecure bool
+}
+
+// Enabled reports whether a provider is configured.
+func (c OIDCConfig) Enabled() bool {
+	return c.Issuer != "" && c.ClientID != ""
+}
+
+// RateLimitConfig configures the rate limiting of API clients.
+type RateLimitConfig struct {
+	Enabled bool
+	// Store is where buckets are kept: "memory" for a single replica or
+	// "postgres" to share them between replicas.
+	Store string
+	// IP limits every client IP address before authentication, including the
+	// requests that fail it.
+	IP ratelimit.Limit
+	// Default is the limit of routes without a limit of their own.
+	Default ratelimit.Limit
+	// Routes are the limits of individual routes, keyed by their full path.
+	Routes map[string]ratelimit.Limit
+}
+
 func Load() (*Config, error) {
 	config := &Config{
 		Server: ServerConfig{
 			Port: getEnvInt("SERVER_PORT", 8080),
 			Host: getEnvString("SERVER_HOST", "localhost"),
+			// An empty list trusts no proxy
+			TrustedProxies: getEnvStringList("TRUSTED_PROXIES", []string{}),
 		},
 		Database: DatabaseConfig{
 			Host:     getEnvString("POSTGRES_HOST", "localhost"),
@@ -46,6 +136,44 @@ func Load() (*Config, error) {
 		Log: LogConfig{
 			Level: getEnvString("LOG_LEVEL", "info"),
 		},
+		Budget: BudgetConfig{
+			CheckInterval: getEnvDuration("BUDGET_CHECK_INTERVAL", 24*time.Hour),
+			Thresholds:    getEnvIntList("BUDGET_ALERT_THRESHOLDS", []int{80, 100}),
+		},
+		Auth: AuthConfig{
+			HS256Secret: getEnvString("JWT_HS256_SECRET", ""),
+			JWKSFile:    getEnvString("JWT_JWKS_FILE", ""),
+			JWKSURL:     getEnvString("JWT_JWKS_URL", ""),
+			JWKSRefresh: getEnvDuration("JWT_JWKS_REFRESH", time.Hour),
+			Issuer:      getEnvString("JWT_ISSUER", ""),
+			Audience:    getEnvString("JWT_AUDIENCE", ""),
+			Leeway:      getEnvDuration("JWT_LEEWAY", 30*time.Second),
+			AdminRole:   getEnvString("JWT_ADMIN_ROLE", "admin"),
+			TenantClaim: getEnvString("JWT_TENANT_CLAIM", "tenant"),
+			DefaultRole: getEnvString("RBAC_DEFAULT_ROLE", "editor"),
+		},
+		OIDC: OIDCConfig{
+			Issuer:                getEnvString("OIDC_ISSUER", ""),
+			ClientID:              getEnvString("OIDC_CLIENT_ID", ""),
+			ClientSecret:          getEnvString("OIDC_CLIENT_SECRET", ""),
+			RedirectURL:           getEnvString("OIDC_REDIRECT_URL", "http://localhost:8080/auth/callback"),
+			Scopes:                getEnvStringList("OIDC_SCOPES", []string{"profile", "email"}),
+			PostLogoutRedirectURL: getEnvString("OIDC_POST_LOGOUT_REDIRECT_URL", ""),
+			SessionTTL:            getEnvDuration("SESSION_TTL", 12*time.Hour),
+			CookieSecure:          getEnvBool("SESSION_COOKIE_SECURE", true),
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: getEnvBool("RATE_LIMIT_ENABLED", true),
+			Store:   getEnvString("RATE_LIMIT_STORE", "memory"),
+			IP:      getEnvLimit("RATE_LIMIT_IP", ratelimit.Limit{Requests: 300, Per: time.Minute}),
+			Default: getEnvLimit("RATE_LIMIT_DEFAULT", ratelimit.Limit{Requests: 120, Per: time.Minute}),
+			Routes: getEnvLimits("RATE_LIMIT_ROUTES", map[string]ratelimit.Limit{
+				"/api/v1/subscriptions/total-cost":    {Requests: 20, Per: time.Minute},
+				"/api/v1/subscriptions/export":        {Requests: 5, Per: time.Minute},
+				"/api/v1/users/:user_id/calendar.ics": {Requests: 30, Per: time.Minute},
+			}),
+		},
+		IdempotencyTTL: getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour),
 	}
 
 	return config, nil
@@ -66,3 +194,89 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil && duration > 0 {
+			return duration
+		}
+	}
+	return defaultValue
+}
+
+// getEnvIntList parses a comma separated list of integers, falling back to the
+// default when any element is invalid.
+func getEnvIntList(key string, defaultValue []int) []int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var values []int
+	for _, item := range strings.Split(value, ",") {
+		intValue, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil {
+			return defaultValue
+		}
+		values = append(values, intValue)
+	}
+	return values
+}
+
+// getEnvStringList parses a comma separated list, skipping blank elements.
+func getEnvStringList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var values []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	return values
+}
+
+// getEnvLimit parses a rate limit such as "100/1m".
+func getEnvLimit(key string, defaultValue ratelimit.Limit) ratelimit.Limit {
+	if value := os.Getenv(key); value != "" {
+		if limit, err := ratelimit.ParseLimit(value); err == nil {
+			return limit
+		}
+	}
+	return defaultValue
+}
+
+// getEnvLimits parses a comma separated list of <route>=<limit> pairs, falling
+// back to the default when any element is invalid.
+func getEnvLimits(key string, defaultValue map[string]ratelimit.Limit) map[string]ratelimit.Limit {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	limits := map[string]ratelimit.Limit{}
+	for _, item := range strings.Split(value, ",") {
+		route, raw, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok || route == "" {
+			return defaultValue
+		}
+		limit, err := ratelimit.ParseLimit(raw)
+		if err != nil {
+			return defaultValue
+		}
+		limits[route] = limit
+	}
+	return limits
+}
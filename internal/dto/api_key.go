@@ -0,0 +1,56 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/rasadov/subscription-manager/internal/models"
+)
+
+type APIKeyPathParams struct {
+	ID int `uri:"id" binding:"required"`
+}
+
+// CreateAPIKeyRequest creates a key limited to Scopes. Keys without ExpiresAt do
+// not expire.
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=255"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1,dive,oneof=subscriptions:read subscriptions:write costs:read admin:*"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type APIKeyResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  string     `json:"created_by,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreatedAPIKeyResponse is returned once on creation. Key is the only copy of the
+// secret, it cannot be retrieved later.
+type CreatedAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+type ListAPIKeysResponse struct {
+	Data []*APIKeyResponse `json:"data"`
+}
+
+func NewAPIKeyResponse(key *models.APIKey) *APIKeyResponse {
+	return &APIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.Scopes,
+		CreatedBy:  key.CreatedBy,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+		CreatedAt:  key.CreatedAt,
+	}
+}
@@ -17,6 +17,16 @@ type Pagination struct {
 	TotalPages int `json:"total_pages"`
 }
 
+// CursorPagination describes a cursor paginated page. NextCursor and PrevCursor
+// are passed as after and before to fetch the adjacent pages; they are empty when
+// there is none. Total is left out when the count was skipped.
+type CursorPagination struct {
+	Limit      int    `json:"limit"`
+	Total      *int   `json:"total,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
 type MonthYear time.Time
 
 func (m MonthYear) MarshalJSON() ([]byte, error) {
@@ -0,0 +1,97 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/rasadov/subscription-manager/internal/models"
+)
+
+type BudgetPathParams struct {
+	UserID string `uri:"user_id" binding:"required,uuid"`
+	ID     int    `uri:"id" binding:"required"`
+}
+
+// CreateBudgetRequest limits the monthly spend on all subscriptions, on a category
+// or on a service. Thresholds are percentages of the limit, 80 and 100 by default.
+type CreateBudgetRequest struct {
+	Scope        string `json:"scope" binding:"required,oneof=all category service"`
+	Category     string `json:"category,omitempty" binding:"required_if=Scope category,max=64"`
+	ServiceName  string `json:"service_name,omitempty" binding:"required_if=Scope service"`
+	MonthlyLimit int64  `json:"monthly_limit" binding:"required,gt=0"`
+	Currency     string `json:"currency,omitempty" binding:"omitempty,iso4217"`
+	Thresholds   []int  `json:"thresholds,omitempty" binding:"omitempty,dive,gt=0,lte=1000"`
+}
+
+type UpdateBudgetRequest struct {
+	MonthlyLimit *int64  `json:"monthly_limit,omitempty" binding:"omitempty,gt=0"`
+	Currency     *string `json:"currency,omitempty" binding:"omitempty,iso4217"`
+	Thresholds   *[]int  `json:"thresholds,omitempty" binding:"omitempty,min=1,dive,gt=0,lte=1000"`
+}
+
+// BudgetSpend is the projected spend of the current month against a budget.
+type BudgetSpend struct {
+	Month   MonthYear `json:"month"`
+	Spend   int64     `json:"spend"`
+	Percent float64   `json:"percent"`
+}
+
+type BudgetResponse struct {
+	ID           uint         `json:"id"`
+	UserID       string       `json:"user_id"`
+	Scope        string       `json:"scope"`
+	Category     string       `json:"category,omitempty"`
+	ServiceName  string       `json:"service_name,omitempty"`
+	MonthlyLimit int64        `json:"monthly_limit"`
+	Currency     string       `json:"currency"`
+	Thresholds   []int        `json:"thresholds"`
+	Current      *BudgetSpend `json:"current,omitempty"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+}
+
+type ListBudgetsResponse struct {
+	Data []*BudgetResponse `json:"data"`
+}
+
+type BudgetAlertResponse struct {
+	ID           uint      `json:"id"`
+	BudgetID     uint      `json:"budget_id"`
+	Month        MonthYear `json:"month"`
+	Threshold    int       `json:"threshold"`
+	Spend        int64     `json:"spend"`
+	MonthlyLimit int64     `json:"monthly_limit"`
+	Currency     string    `json:"currency"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type ListBudgetAlertsResponse struct {
+	Data []*BudgetAlertResponse `json:"data"`
+}
+
+func NewBudgetResponse(budget *models.Budget) *BudgetResponse {
+	return &BudgetResponse{
+		ID:           budget.ID,
+		UserID:       budget.UserID,
+		Scope:        budget.Scope,
+		Category:     budget.Category,
+		ServiceName:  budget.ServiceName,
+		MonthlyLimit: budget.MonthlyLimit,
+		Currency:     budget.Currency,
+		Thresholds:   budget.Thresholds,
+		CreatedAt:    budget.CreatedAt,
+		UpdatedAt:    budget.UpdatedAt,
+	}
+}
+
+func NewBudgetAlertResponse(alert *models.BudgetAlert) *BudgetAlertResponse {
+	return &BudgetAlertResponse{
+		ID:           alert.ID,
+		BudgetID:     alert.BudgetID,
+		Month:        MonthYear(alert.Month),
+		Threshold:    alert.Threshold,
+		Spend:        alert.Spend,
+		MonthlyLimit: alert.MonthlyLimit,
+		Currency:     alert.Currency,
+		CreatedAt:    alert.CreatedAt,
+	}
+}
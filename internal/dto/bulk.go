@@ -0,0 +1,52 @@
+package dto
+
+import "encoding/json"
+
+// MaxBulkOperations is the largest number of operations accepted in one bulk request.
+const MaxBulkOperations = 1000
+
+const (
+	BulkOpCreate = "create"
+	BulkOpUpdate = "update"
+	BulkOpDelete = "delete"
+
+	BulkStatusSucceeded  = "succeeded"
+	BulkStatusFailed     = "failed"
+	BulkStatusRolledBack = "rolled_back"
+)
+
+type BulkSubscriptionsQuery struct {
+	// Atomic applies all operations in one transaction, or none of them when one fails.
+	Atomic bool `form:"atomic"`
+}
+
+// BulkOperation is one item of a bulk request. Data is a CreateSubscriptionRequest
+// for create and an UpdateSubscriptionRequest for update; ID selects the
+// subscription to update or delete.
+type BulkOperation struct {
+	Op   string          `json:"op" enums:"create,update,delete"`
+	ID   uint            `json:"id,omitempty"`
+	Data json.RawMessage `json:"data,omitempty" swaggertype:"object"`
+}
+
+// BulkOperationResult reports the outcome of the operation at Index. Code is the
+// status the single-item endpoint would have answered with.
+type BulkOperationResult struct {
+	Index        int                   `json:"index"`
+	Op           string                `json:"op"`
+	ID           uint                  `json:"id,omitempty"`
+	Status       string                `json:"status"`
+	Code         int                   `json:"code,omitempty"`
+	Error        string                `json:"error,omitempty"`
+	Subscription *SubscriptionResponse `json:"subscription,omitempty"`
+}
+
+type BulkSubscriptionsResponse struct {
+	Atomic    bool `json:"atomic"`
+	Total     int  `json:"total"`
+	Succeeded int  `json:"succeeded"`
+	Failed    int  `json:"failed"`
+	// RolledBack is set when an atomic request failed and none of its operations were applied.
+	RolledBack bool                   `json:"rolled_back"`
+	Results    []*BulkOperationResult `json:"results"`
+}
@@ -0,0 +1,14 @@
+package dto
+
+type UserPathParams struct {
+	UserID string `uri:"user_id" binding:"required,uuid"`
+}
+
+type CalendarFeedQuery struct {
+	Token string `form:"token" binding:"required"`
+}
+
+type CalendarTokenResponse struct {
+	Token   string `json:"token"`
+	FeedURL string `json:"feed_url"`
+}
@@ -0,0 +1,117 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/rasadov/subscription-manager/internal/models"
+)
+
+type CreatePlanRequest struct {
+	Name         string `json:"name" binding:"required"`
+	Price        int64  `json:"price" binding:"gte=0"`
+	Currency     string `json:"currency,omitempty" binding:"omitempty,iso4217"`
+	BillingCycle string `json:"billing_cycle,omitempty" binding:"omitempty,oneof=monthly quarterly yearly"`
+	Seats        int    `json:"seats,omitempty" binding:"omitempty,gte=1"`
+}
+
+type UpdatePlanRequest struct {
+	Name         *string `json:"name,omitempty"`
+	Price        *int64  `json:"price,omitempty" binding:"omitempty,gte=0"`
+	Currency     *string `json:"currency,omitempty" binding:"omitempty,iso4217"`
+	BillingCycle *string `json:"billing_cycle,omitempty" binding:"omitempty,oneof=monthly quarterly yearly"`
+	Seats        *int    `json:"seats,omitempty" binding:"omitempty,gte=1"`
+}
+
+type CreateServiceRequest struct {
+	Name     string              `json:"name" binding:"required"`
+	Aliases  []string            `json:"aliases,omitempty"`
+	Category string              `json:"category,omitempty"`
+	Website  string              `json:"website,omitempty" binding:"omitempty,url"`
+	LogoURL  string              `json:"logo_url,omitempty" binding:"omitempty,url"`
+	Plans    []CreatePlanRequest `json:"plans,omitempty" binding:"dive"`
+}
+
+type UpdateServiceRequest struct {
+	Name     *string   `json:"name,omitempty"`
+	Aliases  *[]string `json:"aliases,omitempty"`
+	Category *string   `json:"category,omitempty"`
+	Website  *string   `json:"website,omitempty" binding:"omitempty,url"`
+	LogoURL  *string   `json:"logo_url,omitempty" binding:"omitempty,url"`
+}
+
+type ListServicesQuery struct {
+	Category *string `form:"category"`
+}
+
+type MatchServiceQuery struct {
+	Name string `form:"name" binding:"required"`
+}
+
+type PlanResponse struct {
+	ID           uint   `json:"id"`
+	ServiceID    uint   `json:"service_id"`
+	Name         string `json:"name"`
+	Price        int64  `json:"price"`
+	Currency     string `json:"currency"`
+	BillingCycle string `json:"billing_cycle"`
+	Seats        int    `json:"seats"`
+}
+
+type ServiceResponse struct {
+	ID        uint            `json:"id"`
+	Name      string          `json:"name"`
+	Aliases   []string        `json:"aliases"`
+	Category  string          `json:"category,omitempty"`
+	Website   string          `json:"website,omitempty"`
+	LogoURL   string          `json:"logo_url,omitempty"`
+	Plans     []*PlanResponse `json:"plans"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+type ListServicesResponse struct {
+	Data []*ServiceResponse `json:"data"`
+}
+
+// ServiceMatchResponse is the catalog entry a free-text service name resolves to.
+type ServiceMatchResponse struct {
+	Service *ServiceResponse `json:"service"`
+	Plan    *PlanResponse    `json:"plan,omitempty"`
+	Score   float64          `json:"score"`
+}
+
+func NewPlanResponse(plan *models.Plan) *PlanResponse {
+	return &PlanResponse{
+		ID:           plan.ID,
+		ServiceID:    plan.ServiceID,
+		Name:         plan.Name,
+		Price:        plan.Price,
+		Currency:     plan.Currency,
+		BillingCycle: plan.BillingCycle,
+		Seats:        plan.Seats,
+	}
+}
+
+func NewServiceResponse(service *models.Service) *ServiceResponse {
+	aliases := service.Aliases
+	if aliases == nil {
+		aliases = []string{}
+	}
+
+	plans := make([]*PlanResponse, 0, len(service.Plans))
+	for idx := range service.Plans {
+		plans = append(plans, NewPlanResponse(&service.Plans[idx]))
+	}
+
+	return &ServiceResponse{
+		ID:        service.ID,
+		Name:      service.Name,
+		Aliases:   aliases,
+		Category:  service.Category,
+		Website:   service.Website,
+		LogoURL:   service.LogoURL,
+		Plans:     plans,
+		CreatedAt: service.CreatedAt,
+		UpdatedAt: service.UpdatedAt,
+	}
+}
@@ -0,0 +1,76 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/rasadov/subscription-manager/internal/models"
+)
+
+type DraftPathParams struct {
+	UserID string `uri:"user_id" binding:"required,uuid"`
+	ID     int    `uri:"id" binding:"required"`
+}
+
+type ListDraftsQuery struct {
+	Status *string `form:"status" binding:"omitempty,oneof=pending confirmed dismissed"`
+}
+
+// ConfirmDraftRequest optionally overrides the proposed values before the draft becomes a subscription.
+type ConfirmDraftRequest struct {
+	ServiceName  *string `json:"service_name,omitempty"`
+	Price        *int64  `json:"price,omitempty"`
+	Currency     *string `json:"currency,omitempty" binding:"omitempty,iso4217"`
+	BillingCycle *string `json:"billing_cycle,omitempty" binding:"omitempty,oneof=monthly quarterly yearly"`
+	StartDate    *string `json:"start_date,omitempty"`
+	EndDate      *string `json:"end_date,omitempty"`
+}
+
+type DraftResponse struct {
+	ID             uint      `json:"id"`
+	UserID         string    `json:"user_id"`
+	Source         string    `json:"source"`
+	ServiceName    string    `json:"service_name"`
+	Price          int64     `json:"price"`
+	Currency       string    `json:"currency"`
+	BillingCycle   string    `json:"billing_cycle"`
+	StartDate      MonthYear `json:"start_date"`
+	Confidence     float64   `json:"confidence"`
+	Details        string    `json:"details,omitempty"`
+	Status         string    `json:"status"`
+	SubscriptionID *uint     `json:"subscription_id,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type ListDraftsResponse struct {
+	Data []*DraftResponse `json:"data"`
+}
+
+type ImportStatementQuery struct {
+	Format     string `form:"format" binding:"omitempty,oneof=csv ofx qfx"`
+	Mapping    string `form:"mapping"`
+	DateFormat string `form:"date_format"`
+	Currency   string `form:"currency" binding:"omitempty,iso4217"`
+}
+
+type ImportStatementResponse struct {
+	Transactions int              `json:"transactions"`
+	Drafts       []*DraftResponse `json:"drafts"`
+}
+
+func NewDraftResponse(draft *models.SubscriptionDraft) *DraftResponse {
+	return &DraftResponse{
+		ID:             draft.ID,
+		UserID:         draft.UserID,
+		Source:         draft.Source,
+		ServiceName:    draft.ServiceName,
+		Price:          draft.Price,
+		Currency:       draft.Currency,
+		BillingCycle:   draft.BillingCycle,
+		StartDate:      MonthYear(draft.StartDate),
+		Confidence:     draft.Confidence,
+		Details:        draft.Details,
+		Status:         draft.Status,
+		SubscriptionID: draft.SubscriptionID,
+		CreatedAt:      draft.CreatedAt,
+	}
+}
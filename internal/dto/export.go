@@ -0,0 +1,12 @@
+package dto
+
+const (
+	ExportFormatCSV   = "csv"
+	ExportFormatJSONL = "jsonl"
+	ExportFormatXLSX  = "xlsx"
+)
+
+type ExportSubscriptionsQuery struct {
+	ListSubscriptionsQuery
+	Format string `form:"format,default=csv" binding:"omitempty,oneof=csv jsonl xlsx"`
+}
@@ -0,0 +1,78 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/rasadov/subscription-manager/internal/models"
+)
+
+// ForecastQuery selects the subscriptions to project and the window of the
+// forecast, which starts at the current month unless Start (MM-YYYY) is given.
+type ForecastQuery struct {
+	UserID   *string `form:"user_id"`
+	Months   int     `form:"months,default=12" binding:"min=1,max=60"`
+	Start    *string `form:"start"`
+	Currency string  `form:"currency" binding:"omitempty,iso4217"`
+}
+
+// ForecastCharge is one projected charge of a subscription.
+type ForecastCharge struct {
+	SubscriptionID uint   `json:"subscription_id"`
+	ServiceName    string `json:"service_name"`
+	Amount         int64  `json:"amount"`
+	// TrialEnded marks the first paid charge after a free trial.
+	TrialEnded bool `json:"trial_ended,omitempty"`
+	// PriceChanged marks a charge at a scheduled price.
+	PriceChanged bool `json:"price_changed,omitempty"`
+}
+
+type ForecastMonth struct {
+	Month   MonthYear         `json:"month"`
+	Total   int64             `json:"total"`
+	Charges []*ForecastCharge `json:"charges"`
+}
+
+// ForecastResponse is the projected spend per month. Total covers the requested
+// months, AnnualTotal the first twelve months of the window whatever its length.
+type ForecastResponse struct {
+	Currency    string           `json:"currency"`
+	Months      []*ForecastMonth `json:"months"`
+	Total       int64            `json:"total"`
+	AnnualTotal int64            `json:"annual_total"`
+}
+
+type SubscriptionPathParams struct {
+	ID int `uri:"id" binding:"required"`
+}
+
+type PriceChangePathParams struct {
+	ID       int `uri:"id" binding:"required"`
+	ChangeID int `uri:"change_id" binding:"required"`
+}
+
+type CreatePriceChangeRequest struct {
+	EffectiveDate string `json:"effective_date" binding:"required"`
+	Price         *int64 `json:"price" binding:"required,gte=0"`
+}
+
+type PriceChangeResponse struct {
+	ID             uint      `json:"id"`
+	SubscriptionID uint      `json:"subscription_id"`
+	EffectiveDate  MonthYear `json:"effective_date"`
+	Price          int64     `json:"price"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type ListPriceChangesResponse struct {
+	Data []*PriceChangeResponse `json:"data"`
+}
+
+func NewPriceChangeResponse(change *models.PriceChange) *PriceChangeResponse {
+	return &PriceChangeResponse{
+		ID:             change.ID,
+		SubscriptionID: change.SubscriptionID,
+		EffectiveDate:  MonthYear(change.EffectiveDate),
+		Price:          change.Price,
+		CreatedAt:      change.CreatedAt,
+	}
+}
@@ -0,0 +1,33 @@
+package dto
+
+type ImportSubscriptionsQuery struct {
+	DryRun  bool   `form:"dry_run"`
+	Upsert  bool   `form:"upsert"`
+	Mapping string `form:"mapping"`
+}
+
+type ImportRowResult struct {
+	Row    int      `json:"row"`
+	Status string   `json:"status"`
+	Action string   `json:"action,omitempty"`
+	ID     uint     `json:"id,omitempty"`
+	Errors []string `json:"errors,omitempty"`
+	// Warnings are those of the create endpoint, e.g. about a duplicate subscription.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+type ImportSubscriptionsResponse struct {
+	DryRun   bool               `json:"dry_run"`
+	Total    int                `json:"total"`
+	Accepted int                `json:"accepted"`
+	Rejected int                `json:"rejected"`
+	Rows     []*ImportRowResult `json:"rows"`
+}
+
+const (
+	ImportStatusAccepted = "accepted"
+	ImportStatusRejected = "rejected"
+
+	ImportActionCreate = "create"
+	ImportActionUpdate = "update"
+)
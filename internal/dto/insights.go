@@ -0,0 +1,26 @@
+package dto
+
+const (
+	DuplicateKindOverlap       = "overlap"
+	DuplicateKindMultiplePlans = "multiple_plans"
+)
+
+// DuplicateGroup is a set of subscriptions of the same service whose periods
+// overlap. While they overlap, every subscription but the most expensive one is
+// counted as waste.
+type DuplicateGroup struct {
+	Kind          string                  `json:"kind"`
+	ServiceName   string                  `json:"service_name"`
+	Subscriptions []*SubscriptionResponse `json:"subscriptions"`
+	OverlapStart  MonthYear               `json:"overlap_start"`
+	// OverlapEnd is null while the overlap has no end date.
+	OverlapEnd   *MonthYear `json:"overlap_end,omitempty"`
+	MonthlyWaste int64      `json:"monthly_waste"`
+	WastedSpend  int64      `json:"wasted_spend"`
+}
+
+type DuplicatesResponse struct {
+	Groups       []*DuplicateGroup `json:"groups"`
+	MonthlyWaste int64             `json:"monthly_waste"`
+	WastedSpend  int64             `json:"wasted_spend"`
+}
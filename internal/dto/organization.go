@@ -0,0 +1,91 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/rasadov/subscription-manager/internal/models"
+)
+
+type OrganizationPathParams struct {
+	ID int `uri:"id" binding:"required"`
+}
+
+type OrganizationMemberPathParams struct {
+	ID     int    `uri:"id" binding:"required"`
+	UserID string `uri:"user_id" binding:"required,uuid"`
+}
+
+// CreateOrganizationRequest creates a household or a company owned by OwnerID.
+// Members are invited and join once they accept.
+type CreateOrganizationRequest struct {
+	Name    string                         `json:"name" binding:"required,max=255"`
+	Kind    string                         `json:"kind,omitempty" binding:"omitempty,oneof=household company"`
+	OwnerID string                         `json:"owner_id" binding:"required,uuid"`
+	Members []AddOrganizationMemberRequest `json:"members,omitempty" binding:"omitempty,dive"`
+}
+
+type UpdateOrganizationRequest struct {
+	Name *string `json:"name,omitempty" binding:"omitempty,max=255"`
+	Kind *string `json:"kind,omitempty" binding:"omitempty,oneof=household company"`
+}
+
+type ListOrganizationsQuery struct {
+	UserID *string `form:"user_id" binding:"omitempty,uuid"`
+	Kind   *string `form:"kind" binding:"omitempty,oneof=household company"`
+}
+
+type AddOrganizationMemberRequest struct {
+	UserID string `json:"user_id" binding:"required,uuid"`
+	Role   string `json:"role,omitempty" binding:"omitempty,oneof=owner admin member"`
+}
+
+type UpdateOrganizationMemberRequest struct {
+	Role string `json:"role" binding:"required,oneof=owner admin member"`
+}
+
+// OrganizationMemberResponse is a member of an organization; AcceptedAt is
+// missing while the invitation is pending.
+type OrganizationMemberResponse struct {
+	UserID     string     `json:"user_id"`
+	Role       string     `json:"role"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type OrganizationResponse struct {
+	ID        uint                          `json:"id"`
+	Name      string                        `json:"name"`
+	Kind      string                        `json:"kind"`
+	Members   []*OrganizationMemberResponse `json:"members"`
+	CreatedAt time.Time                     `json:"created_at"`
+	UpdatedAt time.Time                     `json:"updated_at"`
+}
+
+type ListOrganizationsResponse struct {
+	Data []*OrganizationResponse `json:"data"`
+}
+
+func NewOrganizationMemberResponse(member *models.OrganizationMember) *OrganizationMemberResponse {
+	return &OrganizationMemberResponse{
+		UserID:     member.UserID,
+		Role:       member.Role,
+		AcceptedAt: member.AcceptedAt,
+		CreatedAt:  member.CreatedAt,
+	}
+}
+
+func NewOrganizationResponse(organization *models.Organization) *OrganizationResponse {
+	members := make([]*OrganizationMemberResponse, 0, len(organization.Members))
+	for idx := range organization.Members {
+		members = append(members, NewOrganizationMemberResponse(&organization.Members[idx]))
+	}
+
+	return &OrganizationResponse{
+		ID:        organization.ID,
+		Name:      organization.Name,
+		Kind:      organization.Kind,
+		Members:   members,
+		CreatedAt: organization.CreatedAt,
+		UpdatedAt: organization.UpdatedAt,
+	}
+}
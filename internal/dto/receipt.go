@@ -0,0 +1,70 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/rasadov/subscription-manager/internal/models"
+)
+
+type ImportReceiptsQuery struct {
+	Format string `form:"format" binding:"omitempty,oneof=eml mbox"`
+}
+
+// SkippedReceipt is a message that did not produce a draft.
+type SkippedReceipt struct {
+	From    string `json:"from"`
+	Subject string `json:"subject"`
+	Reason  string `json:"reason"`
+}
+
+type ImportReceiptsResponse struct {
+	Messages int               `json:"messages"`
+	Drafts   []*DraftResponse  `json:"drafts"`
+	Skipped  []*SkippedReceipt `json:"skipped"`
+}
+
+type CreateReceiptRuleRequest struct {
+	SenderDomain   string `json:"sender_domain" binding:"required,fqdn"`
+	ServiceName    string `json:"service_name,omitempty"`
+	SubjectPattern string `json:"subject_pattern,omitempty"`
+	PricePattern   string `json:"price_pattern" binding:"required"`
+	Currency       string `json:"currency,omitempty" binding:"omitempty,iso4217"`
+	DatePattern    string `json:"date_pattern,omitempty"`
+	DateLayout     string `json:"date_layout,omitempty"`
+	BillingCycle   string `json:"billing_cycle,omitempty" binding:"omitempty,oneof=monthly quarterly yearly"`
+	Priority       int    `json:"priority,omitempty"`
+}
+
+type ReceiptRuleResponse struct {
+	ID             uint      `json:"id"`
+	SenderDomain   string    `json:"sender_domain"`
+	ServiceName    string    `json:"service_name,omitempty"`
+	SubjectPattern string    `json:"subject_pattern,omitempty"`
+	PricePattern   string    `json:"price_pattern"`
+	Currency       string    `json:"currency,omitempty"`
+	DatePattern    string    `json:"date_pattern,omitempty"`
+	DateLayout     string    `json:"date_layout,omitempty"`
+	BillingCycle   string    `json:"billing_cycle,omitempty"`
+	Priority       int       `json:"priority"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type ListReceiptRulesResponse struct {
+	Data []*ReceiptRuleResponse `json:"data"`
+}
+
+func NewReceiptRuleResponse(rule *models.ReceiptRule) *ReceiptRuleResponse {
+	return &ReceiptRuleResponse{
+		ID:             rule.ID,
+		SenderDomain:   rule.SenderDomain,
+		ServiceName:    rule.ServiceName,
+		SubjectPattern: rule.SubjectPattern,
+		PricePattern:   rule.PricePattern,
+		Currency:       rule.Currency,
+		DatePattern:    rule.DatePattern,
+		DateLayout:     rule.DateLayout,
+		BillingCycle:   rule.BillingCycle,
+		Priority:       rule.Priority,
+		CreatedAt:      rule.CreatedAt,
+	}
+}
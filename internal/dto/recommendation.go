@@ -0,0 +1,41 @@
+package dto
+
+import "time"
+
+type RecommendationPathParams struct {
+	UserID string `uri:"user_id" binding:"required,uuid"`
+	Key    string `uri:"key" binding:"required"`
+}
+
+type ListRecommendationsQuery struct {
+	// IncludeHidden also returns dismissed and snoozed recommendations.
+	IncludeHidden bool `form:"include_hidden"`
+}
+
+type SnoozeRecommendationRequest struct {
+	// Days to hide the recommendation for, 30 by default.
+	Days int `json:"days,omitempty" binding:"omitempty,min=1,max=365"`
+}
+
+type ReportUsageRequest struct {
+	Usage string `json:"usage" binding:"required,oneof=daily weekly monthly rarely never"`
+}
+
+// RecommendationResponse is a suggestion to save money. EstimatedSavings is per
+// year in Currency.
+type RecommendationResponse struct {
+	Key              string     `json:"key"`
+	Type             string     `json:"type"`
+	Title            string     `json:"title"`
+	ServiceName      string     `json:"service_name"`
+	SubscriptionIDs  []uint     `json:"subscription_ids"`
+	PlanID           *uint      `json:"plan_id,omitempty"`
+	Currency         string     `json:"currency"`
+	EstimatedSavings int64      `json:"estimated_savings"`
+	Status           string     `json:"status,omitempty"`
+	SnoozedUntil     *time.Time `json:"snoozed_until,omitempty"`
+}
+
+type ListRecommendationsResponse struct {
+	Data []*RecommendationResponse `json:"data"`
+}
@@ -0,0 +1,42 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/rasadov/subscription-manager/internal/models"
+)
+
+type RoleAssignmentPathParams struct {
+	ID int `uri:"id" binding:"required"`
+}
+
+type CreateRoleAssignmentRequest struct {
+	UserID string `json:"user_id" binding:"required,uuid"`
+	Role   string `json:"role" binding:"required,oneof=viewer editor auditor admin"`
+}
+
+type ListRoleAssignmentsQuery struct {
+	UserID *string `form:"user_id" binding:"omitempty,uuid"`
+}
+
+type RoleAssignmentResponse struct {
+	ID        uint      `json:"id"`
+	UserID    string    `json:"user_id"`
+	Role      string    `json:"role"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ListRoleAssignmentsResponse struct {
+	Data []*RoleAssignmentResponse `json:"data"`
+}
+
+func NewRoleAssignmentResponse(assignment *models.RoleAssignment) *RoleAssignmentResponse {
+	return &RoleAssignmentResponse{
+		ID:        assignment.ID,
+		UserID:    assignment.UserID,
+		Role:      assignment.Role,
+		CreatedBy: assignment.CreatedBy,
+		CreatedAt: assignment.CreatedAt,
+	}
+}
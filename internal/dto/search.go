@@ -0,0 +1,21 @@
+package dto
+
+// SearchSubscriptionsQuery searches the subscriptions of a user, or of the
+// members of an organization, for Q.
+type SearchSubscriptionsQuery struct {
+	Q              string  `form:"q" binding:"required,max=200"`
+	UserID         *string `form:"user_id"`
+	OrganizationID *uint   `form:"organization_id"`
+	Limit          int     `form:"limit,default=20" binding:"min=1,max=100"`
+}
+
+// SubscriptionSearchResult is a subscription with its relevance, from 0 to 1
+// for an exact match.
+type SubscriptionSearchResult struct {
+	*SubscriptionResponse
+	Score float64 `json:"score"`
+}
+
+type SearchSubscriptionsResponse struct {
+	Data []*SubscriptionSearchResult `json:"data"`
+}
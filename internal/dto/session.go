@@ -0,0 +1,30 @@
+package dto
+
+import "time"
+
+type LoginQuery struct {
+	// ReturnTo is the path of the dashboard the browser returns to after login.
+	ReturnTo string `form:"return_to"`
+}
+
+type LoginCallbackQuery struct {
+	Code             string `form:"code"`
+	State            string `form:"state"`
+	Error            string `form:"error"`
+	ErrorDescription string `form:"error_description"`
+}
+
+// LoginRedirect starts a login attempt. Attempt is kept in a cookie until the
+// provider redirects back to the callback.
+type LoginRedirect struct {
+	URL     string
+	Attempt string
+}
+
+// LoginSession is a session created by a completed login.
+type LoginSession struct {
+	UserID    string
+	Token     string
+	ExpiresAt time.Time
+	ReturnTo  string
+}
@@ -6,60 +6,155 @@ import (
 	"github.com/rasadov/subscription-manager/internal/models"
 )
 
+// CreateSubscriptionRequest creates a custom subscription or one of a catalog
+// service (ServiceID) or plan (PlanID). Values omitted for a plan are taken from it.
 type CreateSubscriptionRequest struct {
-	ServiceName string `json:"service_name" binding:"required"`
-	Price       int64  `json:"price" binding:"required"`
-	UserID      string `json:"user_id" binding:"required,uuid"`
-	StartDate   string `json:"start_date" binding:"required"`
-	EndDate     string `json:"end_date,omitempty"`
+	ServiceName  string `json:"service_name" binding:"required_without_all=ServiceID PlanID"`
+	ServiceID    *uint  `json:"service_id,omitempty"`
+	PlanID       *uint  `json:"plan_id,omitempty"`
+	Price        int64  `json:"price" binding:"required_without=PlanID"`
+	Currency     string `json:"currency,omitempty" binding:"omitempty,iso4217"`
+	UserID       string `json:"user_id" binding:"required,uuid"`
+	BillingCycle string `json:"billing_cycle,omitempty" binding:"omitempty,oneof=monthly quarterly yearly"`
+	StartDate    string `json:"start_date" binding:"required"`
+	EndDate      string `json:"end_date,omitempty"`
+	// TrialEndDate is the month (MM-YYYY) in which a free trial ends and billing starts.
+	TrialEndDate string `json:"trial_end_date,omitempty"`
+	// Category defaults to the category of the linked catalog service.
+	Category string   `json:"category,omitempty" binding:"omitempty,max=64"`
+	Tags     []string `json:"tags,omitempty" binding:"omitempty,dive,max=64"`
+	Notes    string   `json:"notes,omitempty" binding:"omitempty,max=2000"`
+	// Members share the cost with the owner (UserID) according to SplitRule,
+	// which defaults to an equal split. The owner pays what is left.
+	SplitRule string                      `json:"split_rule,omitempty" binding:"omitempty,oneof=equal percentage fixed"`
+	Members   []SubscriptionMemberRequest `json:"members,omitempty" binding:"omitempty,dive"`
+}
+
+// SubscriptionMemberRequest adds a user to a shared subscription. Share is the
+// member's percentage with the percentage split rule and their amount with the
+// fixed one; it is ignored when the price is split equally.
+type SubscriptionMemberRequest struct {
+	UserID string `json:"user_id" binding:"required,uuid"`
+	Share  int64  `json:"share,omitempty" binding:"min=0"`
 }
 
 type UpdateSubscriptionRequest struct {
-	ServiceName *string `json:"service_name,omitempty"`
-	Price       *int64  `json:"price,omitempty"`
-	StartDate   *string `json:"start_date,omitempty"`
-	EndDate     *string `json:"end_date,omitempty"`
+	ServiceName  *string `json:"service_name,omitempty"`
+	Price        *int64  `json:"price,omitempty"`
+	Currency     *string `json:"currency,omitempty" binding:"omitempty,iso4217"`
+	BillingCycle *string `json:"billing_cycle,omitempty" binding:"omitempty,oneof=monthly quarterly yearly"`
+	StartDate    *string `json:"start_date,omitempty"`
+	// EndDate and TrialEndDate are removed by an empty string.
+	EndDate      *string `json:"end_date,omitempty"`
+	TrialEndDate *string `json:"trial_end_date,omitempty"`
+	Category     *string `json:"category,omitempty" binding:"omitempty,max=64"`
+	// Tags replaces all tags of the subscription; an empty list removes them.
+	Tags      *[]string `json:"tags,omitempty" binding:"omitempty,dive,max=64"`
+	Notes     *string   `json:"notes,omitempty" binding:"omitempty,max=2000"`
+	SplitRule *string   `json:"split_rule,omitempty" binding:"omitempty,oneof=equal percentage fixed"`
+	// Members replaces all members of the subscription; an empty list stops sharing it.
+	Members *[]SubscriptionMemberRequest `json:"members,omitempty" binding:"omitempty,dive"`
 }
 
 type ListSubscriptionsQuery struct {
-	UserID        *string `form:"user_id"`
-	ServiceName   *string `form:"service_name"`
-	Page          int     `form:"page,default=1"`
-	Limit         int     `form:"limit,default=10"`
-	StartDateFrom *string `form:"start_date_from"`
-	StartDateTo   *string `form:"start_date_to"`
-	EndDateFrom   *string `form:"end_date_from"`
-	EndDateTo     *string `form:"end_date_to"`
-	SortBy        *string `form:"sort_by"`
-	SortOrder     *string `form:"sort_order"`
+	UserID *string `form:"user_id"`
+	// OrganizationID selects the subscriptions of all members of an organization.
+	OrganizationID *uint   `form:"organization_id"`
+	ServiceName    *string `form:"service_name"`
+	Page           int     `form:"page,default=1"`
+	Limit          int     `form:"limit,default=10"`
+	StartDateFrom  *string `form:"start_date_from"`
+	StartDateTo    *string `form:"start_date_to"`
+	EndDateFrom    *string `form:"end_date_from"`
+	EndDateTo      *string `form:"end_date_to"`
+	// Sort is a comma separated list of fields, each prefixed with - for descending
+	// order, e.g. -price,service_name. It replaces SortBy and SortOrder.
+	Sort      *string `form:"sort"`
+	SortBy    *string `form:"sort_by"`
+	SortOrder *string `form:"sort_order"`
+	Category  *string `form:"category"`
+	// Tags is a comma separated list; TagMatch selects whether any or all of them must be present.
+	Tags     *string `form:"tags"`
+	TagMatch string  `form:"tag_match,default=any" binding:"omitempty,oneof=any all"`
+	// IncludeShared also lists subscriptions shared with the selected users.
+	IncludeShared bool `form:"include_shared"`
+	// After and Before switch from page numbers to cursor pagination and select the
+	// subscriptions following or preceding a cursor; an empty After is the first page.
+	After  *string `form:"after"`
+	Before *string `form:"before"`
+	// SkipTotal leaves out the total count of cursor paginated listings.
+	SkipTotal bool `form:"skip_total"`
+	// Filter is an expression such as price > 1000 and end_date is null, see pkg/filter.
+	Filter *string `form:"filter"`
 }
 
 type SubscriptionResponse struct {
-	ID          uint       `json:"id"`
-	ServiceName string     `json:"service_name"`
-	Price       int64      `json:"price"`
-	UserID      string     `json:"user_id"`
-	StartDate   MonthYear  `json:"start_date"`
-	EndDate     *MonthYear `json:"end_date,omitempty"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
+	ID           uint       `json:"id"`
+	ServiceName  string     `json:"service_name"`
+	ServiceID    *uint      `json:"service_id,omitempty"`
+	PlanID       *uint      `json:"plan_id,omitempty"`
+	Price        int64      `json:"price"`
+	Currency     string     `json:"currency"`
+	UserID       string     `json:"user_id"`
+	BillingCycle string     `json:"billing_cycle"`
+	StartDate    MonthYear  `json:"start_date"`
+	EndDate      *MonthYear `json:"end_date,omitempty"`
+	TrialEndDate *MonthYear `json:"trial_end_date,omitempty"`
+	Category     string     `json:"category,omitempty"`
+	Tags         []string   `json:"tags"`
+	Notes        string     `json:"notes,omitempty"`
+	Usage        string     `json:"usage,omitempty"`
+	SplitRule    string     `json:"split_rule,omitempty"`
+	// Members lists what every user pays for a shared subscription, the owner included.
+	Members   []*SubscriptionMemberResponse `json:"members,omitempty"`
+	CreatedAt time.Time                     `json:"created_at"`
+	UpdatedAt time.Time                     `json:"updated_at"`
+	// Warnings are returned on creation, e.g. when the subscription duplicates an existing one.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+type SubscriptionMemberResponse struct {
+	UserID string `json:"user_id"`
+	Share  int64  `json:"share"`
+	Amount int64  `json:"amount"`
 }
 
+// TotalCostQuery sums subscription prices; with UserID or OrganizationID shared
+// subscriptions count with the amount the selected users pay.
 type TotalCostQuery struct {
-	UserID      *string `form:"user_id"`
-	ServiceName *string `form:"service_name"`
-	StartDate   *string `form:"start_date" binding:"required"`
-	EndDate     *string `form:"end_date" binding:"required"`
+	UserID         *string `form:"user_id"`
+	OrganizationID *uint   `form:"organization_id"`
+	ServiceName    *string `form:"service_name"`
+	StartDate      *string `form:"start_date" binding:"required"`
+	EndDate        *string `form:"end_date" binding:"required"`
+	Category       *string `form:"category"`
+	Tags           *string `form:"tags"`
+	TagMatch       string  `form:"tag_match,default=any" binding:"omitempty,oneof=any all"`
+	GroupBy        string  `form:"group_by" binding:"omitempty,oneof=category tag"`
 }
 
 type TotalCostResponse struct {
 	TotalCost int64 `json:"total_cost"`
 	Period    *Period
+	// Groups breaks the total down by category or tag when group_by is set. With
+	// tags a subscription counts towards each of its tags, so groups may add up to
+	// more than the total.
+	Groups []*CostGroup `json:"groups,omitempty"`
+}
+
+// CostGroup is the cost of one category or tag; Key is null for subscriptions
+// without a category or tags.
+type CostGroup struct {
+	Key       *string `json:"key"`
+	TotalCost int64   `json:"total_cost"`
 }
 
+// ListSubscriptionsResponse has Pagination for listings by page number and Cursors
+// for cursor paginated ones.
 type ListSubscriptionsResponse struct {
 	Data       []*SubscriptionResponse `json:"data"`
-	Pagination *Pagination             `json:"pagination"`
+	Pagination *Pagination             `json:"pagination,omitempty"`
+	Cursors    *CursorPagination       `json:"cursors,omitempty"`
 }
 
 func NewSubscriptionResponse(subscription *models.Subscription) *SubscriptionResponse {
@@ -69,14 +164,45 @@ func NewSubscriptionResponse(subscription *models.Subscription) *SubscriptionRes
 		endDate = &endDateVal
 	}
 
+	var trialEndDate *MonthYear
+	if subscription.TrialEndDate != nil {
+		trialEndDateVal := MonthYear(*subscription.TrialEndDate)
+		trialEndDate = &trialEndDateVal
+	}
+
+	tags := make([]string, 0, len(subscription.Tags))
+	for _, tag := range subscription.Tags {
+		tags = append(tags, tag.Name)
+	}
+
+	var members []*SubscriptionMemberResponse
+	for _, member := range subscription.Members {
+		members = append(members, &SubscriptionMemberResponse{
+			UserID: member.UserID,
+			Share:  member.Share,
+			Amount: member.Amount,
+		})
+	}
+
 	return &SubscriptionResponse{
-		ID:          subscription.ID,
-		ServiceName: subscription.ServiceName,
-		Price:       subscription.Price,
-		UserID:      subscription.UserID,
-		StartDate:   MonthYear(subscription.StartDate),
-		EndDate:     endDate,
-		CreatedAt:   subscription.CreatedAt,
-		UpdatedAt:   subscription.UpdatedAt,
+		ID:           subscription.ID,
+		ServiceName:  subscription.ServiceName,
+		ServiceID:    subscription.ServiceID,
+		PlanID:       subscription.PlanID,
+		Price:        subscription.Price,
+		Currency:     subscription.Currency,
+		UserID:       subscription.UserID,
+		BillingCycle: subscription.BillingCycle,
+		StartDate:    MonthYear(subscription.StartDate),
+		EndDate:      endDate,
+		TrialEndDate: trialEndDate,
+		Category:     subscription.Category,
+		Tags:         tags,
+		Notes:        subscription.Notes,
+		Usage:        subscription.Usage,
+		SplitRule:    subscription.SplitRule,
+		Members:      members,
+		CreatedAt:    subscription.CreatedAt,
+		UpdatedAt:    subscription.UpdatedAt,
 	}
 }
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/service"
+)
+
+type APIKeyHandler struct {
+	service service.APIKeyService
+	logger  *slog.Logger
+}
+
+func NewAPIKeyHandler(service service.APIKeyService, logger *slog.Logger) *APIKeyHandler {
+	return &APIKeyHandler{service: service, logger: logger}
+}
+
+// CreateAPIKey godoc
+// @Summary Create an API key
+// @Description Create a key for service-to-service access limited to the given scopes. The key is only returned once.
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Param key body dto.CreateAPIKeyRequest true "API key"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
+// @Success 201 {object} dto.CreatedAPIKeyResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var req dto.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, httpErr := h.service.CreateAPIKey(c.Request.Context(), req)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "name", req.Name, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("API key created", "id", response.ID, "prefix", response.Prefix, "scopes", response.Scopes)
+	c.JSON(http.StatusCreated, response)
+}
+
+// ListAPIKeys godoc
+// @Summary List API keys
+// @Description List the API keys of the tenant, including revoked ones, without their secrets
+// @Tags api-keys
+// @Produce json
+// @Success 200 {object} dto.ListAPIKeysResponse
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /api-keys [get]
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	response, httpErr := h.service.ListAPIKeys(c.Request.Context())
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke an API key
+// @Description Revoke an API key; requests using it are rejected from now on
+// @Tags api-keys
+// @Param id path int true "API key ID"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	var params dto.APIKeyPathParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		h.logger.Error("Invalid API key ID", "id", c.Param("id"))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if httpErr := h.service.RevokeAPIKey(c.Request.Context(), params.ID); httpErr != nil {
+		h.logger.Error(httpErr.Error(), "id", params.ID, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("API key revoked", "id", params.ID)
+	c.JSON(http.StatusNoContent, nil)
+}
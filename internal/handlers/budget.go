@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/service"
+)
+
+type BudgetHandler struct {
+	service service.BudgetService
+	logger  *slog.Logger
+}
+
+func NewBudgetHandler(service service.BudgetService, logger *slog.Logger) *BudgetHandler {
+	return &BudgetHandler{service: service, logger: logger}
+}
+
+// CreateBudget godoc
+// @Summary Create a budget
+// @Description Limit the projected monthly spend of a user on all subscriptions, a category or a service
+// @Tags budgets
+// @Accept json
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Param budget body dto.CreateBudgetRequest true "Budget"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
+// @Success 201 {object} dto.BudgetResponse
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /users/{user_id}/budgets [post]
+func (h *BudgetHandler) CreateBudget(c *gin.Context) {
+	var params dto.UserPathParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		h.logger.Error("Invalid user ID", "user_id", c.Param("user_id"))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req dto.CreateBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, httpErr := h.service.CreateBudget(c.Request.Context(), params.UserID, req)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "user_id", params.UserID, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Budget created", "user_id", params.UserID, "id", response.ID)
+	c.JSON(http.StatusCreated, response)
+}
+
+// ListBudgets godoc
+// @Summary List budgets
+// @Description List the budgets of a user with the projected spend of the current month
+// @Tags budgets
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Success 200 {object} dto.ListBudgetsResponse
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /users/{user_id}/budgets [get]
+func (h *BudgetHandler) ListBudgets(c *gin.Context) {
+	var params dto.UserPathParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		h.logger.Error("Invalid user ID", "user_id", c.Param("user_id"))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, httpErr := h.service.ListBudgets(c.Request.Context(), params.UserID)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "user_id", params.UserID, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Budgets listed successfully", "user_id", params.UserID, "count", len(response.Data))
+	c.JSON(http.StatusOK, response)
+}
+
+// UpdateBudget godoc
+// @Summary Update a budget
+// @Description Update the limit, currency or alert thresholds of a budget
+// @Tags budgets
+// @Accept json
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Param id path int true "Budget ID"
+// @Param budget body dto.UpdateBudgetRequest true "Budget update"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
+// @Success 200 {object} dto.BudgetResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /users/{user_id}/budgets/{id} [put]
+func (h *BudgetHandler) UpdateBudget(c *gin.Context) {
+	var params dto.BudgetPathParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		h.logger.Error("Invalid path parameters", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req dto.UpdateBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, httpErr := h.service.UpdateBudget(c.Request.Context(), params.UserID, params.ID, req)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "id", params.ID, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Budget updated", "id", params.ID)
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteBudget godoc
+// @Summary Delete a budget
+// @Description Delete a budget and its alerts
+// @Tags budgets
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Param id path int true "Budget ID"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /users/{user_id}/budgets/{id} [delete]
+func (h *BudgetHandler) DeleteBudget(c *gin.Context) {
+	var params dto.BudgetPathParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		h.logger.Error("Invalid path parameters", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if httpErr := h.service.DeleteBudget(c.Request.Context(), params.UserID, params.ID); httpErr != nil {
+		h.logger.Error(httpErr.Error(), "id", params.ID, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Budget deleted", "id", params.ID)
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// ListBudgetAlerts godoc
+// @Summary List budget alerts
+// @Description List the alerts raised when the projected spend of a month reached a budget threshold, newest first
+// @Tags budgets
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Success 200 {object} dto.ListBudgetAlertsResponse
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /users/{user_id}/budget-alerts [get]
+func (h *BudgetHandler) ListBudgetAlerts(c *gin.Context) {
+	var params dto.UserPathParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		h.logger.Error("Invalid user ID", "user_id", c.Param("user_id"))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, httpErr := h.service.ListAlerts(c.Request.Context(), params.UserID)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "user_id", params.UserID, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Budget alerts listed successfully", "user_id", params.UserID, "count", len(response.Data))
+	c.JSON(http.StatusOK, response)
+}
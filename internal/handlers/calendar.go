@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/service"
+)
+
+type CalendarHandler struct {
+	service service.CalendarService
+	logger  *slog.Logger
+}
+
+func NewCalendarHandler(service service.CalendarService, logger *slog.Logger) *CalendarHandler {
+	return &CalendarHandler{service: service, logger: logger}
+}
+
+// IssueCalendarToken godoc
+// @Summary Issue a calendar feed token
+// @Description Create or rotate the secret token of the user's iCalendar feed. Rotating invalidates previously shared feed URLs.
+// @Tags calendar
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
+// @Success 200 {object} dto.CalendarTokenResponse
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /users/{user_id}/calendar-token [post]
+func (h *CalendarHandler) IssueCalendarToken(c *gin.Context) {
+	var params dto.UserPathParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		h.logger.Error("Invalid user ID", "user_id", c.Param("user_id"))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, httpErr := h.service.IssueToken(c.Request.Context(), params.UserID)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "user_id", params.UserID, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	response.FeedURL = fmt.Sprintf("%s://%s/api/v1/users/%s/calendar.ics?token=%s",
+		scheme, c.Request.Host, params.UserID, url.QueryEscape(response.Token))
+
+	h.logger.Info("Calendar token issued", "user_id", params.UserID)
+	c.JSON(http.StatusOK, response)
+}
+
+// GetCalendarFeed godoc
+// @Summary Get the iCalendar feed of renewals
+// @Description RFC 5545 feed with an event per upcoming renewal and subscription end date
+// @Tags calendar
+// @Produce text/calendar
+// @Param user_id path string true "User ID"
+// @Param token query string true "Calendar feed token"
+// @Success 200 {string} string
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/{user_id}/calendar.ics [get]
+func (h *CalendarHandler) GetCalendarFeed(c *gin.Context) {
+	var params dto.UserPathParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		h.logger.Error("Invalid user ID", "user_id", c.Param("user_id"))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var query dto.CalendarFeedQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		h.logger.Error("Invalid query parameters", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var feed bytes.Buffer
+	httpErr := h.service.WriteFeed(c.Request.Context(), &feed, params.UserID, query.Token)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "user_id", params.UserID, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Calendar feed served", "user_id", params.UserID)
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", feed.Bytes())
+}
@@ -0,0 +1,352 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/service"
+)
+
+type CatalogHandler struct {
+	service service.CatalogService
+	logger  *slog.Logger
+}
+
+func NewCatalogHandler(service service.CatalogService, logger *slog.Logger) *CatalogHandler {
+	return &CatalogHandler{service: service, logger: logger}
+}
+
+// CreateService godoc
+// @Summary Create a catalog service
+// @Description Add a canonical service with its aliases and optional plans to the catalog
+// @Tags catalog
+// @Accept json
+// @Produce json
+// @Param service body dto.CreateServiceRequest true "Service"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
+// @Success 201 {object} dto.ServiceResponse
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /catalog/services [post]
+func (h *CatalogHandler) CreateService(c *gin.Context) {
+	var req dto.CreateServiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, httpErr := h.service.CreateService(c.Request.Context(), req)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "name", req.Name, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Catalog service created", "id", response.ID, "name", response.Name)
+	c.JSON(http.StatusCreated, response)
+}
+
+// ListServices godoc
+// @Summary List catalog services
+// @Description List catalog services with their plans
+// @Tags catalog
+// @Produce json
+// @Param category query string false "Category filter"
+// @Success 200 {object} dto.ListServicesResponse
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /catalog/services [get]
+func (h *CatalogHandler) ListServices(c *gin.Context) {
+	var query dto.ListServicesQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		h.logger.Error("Invalid query parameters", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, httpErr := h.service.ListServices(c.Request.Context(), query)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Catalog services listed successfully", "count", len(response.Data))
+	c.JSON(http.StatusOK, response)
+}
+
+// MatchService godoc
+// @Summary Match a service name
+// @Description Resolve a free-text service name to a catalog service (and plan) using names and aliases
+// @Tags catalog
+// @Produce json
+// @Param name query string true "Service name as typed by the user"
+// @Success 200 {object} dto.ServiceMatchResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /catalog/services/match [get]
+func (h *CatalogHandler) MatchService(c *gin.Context) {
+	var query dto.MatchServiceQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		h.logger.Error("Invalid query parameters", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, httpErr := h.service.MatchService(c.Request.Context(), query.Name)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "name", query.Name, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Catalog service matched", "name", query.Name, "id", response.Service.ID)
+	c.JSON(http.StatusOK, response)
+}
+
+// GetService godoc
+// @Summary Get a catalog service
+// @Description Get a catalog service with its plans by ID
+// @Tags catalog
+// @Produce json
+// @Param id path int true "Service ID"
+// @Success 200 {object} dto.ServiceResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /catalog/services/{id} [get]
+func (h *CatalogHandler) GetService(c *gin.Context) {
+	id, ok := h.pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	response, httpErr := h.service.GetService(c.Request.Context(), id)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "id", id, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Catalog service retrieved successfully", "id", id)
+	c.JSON(http.StatusOK, response)
+}
+
+// UpdateService godoc
+// @Summary Update a catalog service
+// @Description Update the name, aliases, category, website or logo of a catalog service
+// @Tags catalog
+// @Accept json
+// @Produce json
+// @Param id path int true "Service ID"
+// @Param service body dto.UpdateServiceRequest true "Service update"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
+// @Success 200 {object} dto.ServiceResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /catalog/services/{id} [put]
+func (h *CatalogHandler) UpdateService(c *gin.Context) {
+	id, ok := h.pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req dto.UpdateServiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, httpErr := h.service.UpdateService(c.Request.Context(), id, req)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "id", id, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Catalog service updated successfully", "id", id)
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteService godoc
+// @Summary Delete a catalog service
+// @Description Delete a catalog service and its plans; linked subscriptions keep their name
+// @Tags catalog
+// @Produce json
+// @Param id path int true "Service ID"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /catalog/services/{id} [delete]
+func (h *CatalogHandler) DeleteService(c *gin.Context) {
+	id, ok := h.pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	if httpErr := h.service.DeleteService(c.Request.Context(), id); httpErr != nil {
+		h.logger.Error(httpErr.Error(), "id", id, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Catalog service deleted successfully", "id", id)
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// CreatePlan godoc
+// @Summary Add a plan
+// @Description Add a plan with its default price, currency and billing cycle to a catalog service
+// @Tags catalog
+// @Accept json
+// @Produce json
+// @Param id path int true "Service ID"
+// @Param plan body dto.CreatePlanRequest true "Plan"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
+// @Success 201 {object} dto.PlanResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /catalog/services/{id}/plans [post]
+func (h *CatalogHandler) CreatePlan(c *gin.Context) {
+	serviceID, ok := h.pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req dto.CreatePlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, httpErr := h.service.CreatePlan(c.Request.Context(), serviceID, req)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "service_id", serviceID, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Plan created", "service_id", serviceID, "id", response.ID)
+	c.JSON(http.StatusCreated, response)
+}
+
+// UpdatePlan godoc
+// @Summary Update a plan
+// @Description Update a plan of a catalog service
+// @Tags catalog
+// @Accept json
+// @Produce json
+// @Param id path int true "Service ID"
+// @Param plan_id path int true "Plan ID"
+// @Param plan body dto.UpdatePlanRequest true "Plan update"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
+// @Success 200 {object} dto.PlanResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /catalog/services/{id}/plans/{plan_id} [put]
+func (h *CatalogHandler) UpdatePlan(c *gin.Context) {
+	serviceID, ok := h.pathID(c, "id")
+	if !ok {
+		return
+	}
+	planID, ok := h.pathID(c, "plan_id")
+	if !ok {
+		return
+	}
+
+	var req dto.UpdatePlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, httpErr := h.service.UpdatePlan(c.Request.Context(), serviceID, planID, req)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "service_id", serviceID, "id", planID, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Plan updated", "service_id", serviceID, "id", planID)
+	c.JSON(http.StatusOK, response)
+}
+
+// DeletePlan godoc
+// @Summary Delete a plan
+// @Description Delete a plan of a catalog service; subscriptions on it stay linked to the service
+// @Tags catalog
+// @Produce json
+// @Param id path int true "Service ID"
+// @Param plan_id path int true "Plan ID"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /catalog/services/{id}/plans/{plan_id} [delete]
+func (h *CatalogHandler) DeletePlan(c *gin.Context) {
+	serviceID, ok := h.pathID(c, "id")
+	if !ok {
+		return
+	}
+	planID, ok := h.pathID(c, "plan_id")
+	if !ok {
+		return
+	}
+
+	if httpErr := h.service.DeletePlan(c.Request.Context(), serviceID, planID); httpErr != nil {
+		h.logger.Error(httpErr.Error(), "service_id", serviceID, "id", planID, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Plan deleted", "service_id", serviceID, "id", planID)
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// pathID parses a numeric path parameter and answers 400 when it is invalid.
+func (h *CatalogHandler) pathID(c *gin.Context, name string) (int, bool) {
+	raw := c.Param(name)
+	id, err := strconv.Atoi(raw)
+	if err != nil {
+		h.logger.Error("Invalid path parameter", name, raw)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/service"
+)
+
+type DraftHandler struct {
+	statements service.StatementService
+	drafts     service.DraftService
+	logger     *slog.Logger
+}
+
+func NewDraftHandler(statements service.StatementService, drafts service.DraftService, logger *slog.Logger) *DraftHandler {
+	return &DraftHandler{statements: statements, drafts: drafts, logger: logger}
+}
+
+// ImportStatement godoc
+// @Summary Import a bank statement
+// @Description Upload a bank or card statement (CSV or OFX/QFX), detect recurring charges and store them as subscription drafts
+// @Tags drafts
+// @Accept multipart/form-data
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Param file formData file true "Statement file"
+// @Param format query string false "Statement format (csv/ofx/qfx), detected from the file extension by default"
+// @Param mapping query string false "CSV column mapping, e.g. date=Booking Date,description=Payee,amount=Amount"
+// @Param date_format query string false "Go time layout of the CSV date column, e.g. 02.01.2006"
+// @Param currency query string false "ISO 4217 currency of the statement, RUB by default"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
+// @Success 200 {object} dto.ImportStatementResponse
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /users/{user_id}/statements [post]
+func (h *DraftHandler) ImportStatement(c *gin.Context) {
+	var params dto.UserPathParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		h.logger.Error("Invalid user ID", "user_id", c.Param("user_id"))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var query dto.ImportStatementQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		h.logger.Error("Invalid query parameters", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var body io.Reader = c.Request.Body
+	filename := ""
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			h.logger.Error("Missing statement file", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			h.logger.Error("Failed to open statement file", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer file.Close()
+		body = file
+		filename = fileHeader.Filename
+	}
+
+	response, httpErr := h.statements.ImportStatement(c.Request.Context(), params.UserID, filename, body, query)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "user_id", params.UserID, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Statement imported", "user_id", params.UserID,
+		"transactions", response.Transactions, "drafts", len(response.Drafts))
+	c.JSON(http.StatusOK, response)
+}
+
+// ListDrafts godoc
+// @Summary List subscription drafts
+// @Description List subscriptions proposed from imported data
+// @Tags drafts
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Param status query string false "Status filter (pending/confirmed/dismissed)"
+// @Success 200 {object} dto.ListDraftsResponse
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /users/{user_id}/drafts [get]
+func (h *DraftHandler) ListDrafts(c *gin.Context) {
+	var params dto.UserPathParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		h.logger.Error("Invalid user ID", "user_id", c.Param("user_id"))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var query dto.ListDraftsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		h.logger.Error("Invalid query parameters", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, httpErr := h.drafts.ListDrafts(c.Request.Context(), params.UserID, query)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "user_id", params.UserID, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Drafts listed successfully", "user_id", params.UserID, "count", len(response.Data))
+	c.JSON(http.StatusOK, response)
+}
+
+// ConfirmDraft godoc
+// @Summary Confirm a subscription draft
+// @Description Create a subscription from a pending draft, optionally overriding the proposed values
+// @Tags drafts
+// @Accept json
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Param id path int true "Draft ID"
+// @Param overrides body dto.ConfirmDraftRequest false "Values overriding the proposal"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
+// @Success 201 {object} dto.SubscriptionResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /users/{user_id}/drafts/{id}/confirm [post]
+func (h *DraftHandler) ConfirmDraft(c *gin.Context) {
+	var params dto.DraftPathParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		h.logger.Error("Invalid path parameters", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req dto.ConfirmDraftRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.logger.Error("Invalid request body", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	response, httpErr := h.drafts.ConfirmDraft(c.Request.Context(), params.UserID, params.ID, req)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "id", params.ID, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Draft confirmed", "id", params.ID, "subscription_id", response.ID)
+	c.JSON(http.StatusCreated, response)
+}
+
+// DismissDraft godoc
+// @Summary Dismiss a subscription draft
+// @Description Mark a pending draft as not being a subscription
+// @Tags drafts
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Param id path int true "Draft ID"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /users/{user_id}/drafts/{id}/dismiss [post]
+func (h *DraftHandler) DismissDraft(c *gin.Context) {
+	var params dto.DraftPathParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		h.logger.Error("Invalid path parameters", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	httpErr := h.drafts.DismissDraft(c.Request.Context(), params.UserID, params.ID)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "id", params.ID, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Draft dismissed", "id", params.ID)
+	c.JSON(http.StatusNoContent, nil)
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/service"
+)
+
+var exportContentTypes = map[string]string{
+	dto.ExportFormatCSV:   "text/csv; charset=utf-8",
+	dto.ExportFormatJSONL: "application/x-ndjson",
+	dto.ExportFormatXLSX:  "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+type ExportHandler struct {
+	service service.ExportService
+	logger  *slog.Logger
+}
+
+func NewExportHandler(service service.ExportService, logger *slog.Logger) *ExportHandler {
+	return &ExportHandler{service: service, logger: logger}
+}
+
+// ExportSubscriptions godoc
+// @Summary Export subscriptions
+// @Description Stream all subscriptions matching the list filters as CSV, JSON Lines or XLSX
+// @Tags subscriptions
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param format query string false "Export format (csv/jsonl/xlsx)" default(csv)
+// @Param user_id query string false "User ID filter"
+// @Param organization_id query int false "Organization filter, exports the subscriptions of its members"
+// @Param service_name query string false "Service name filter"
+// @Param start_date_from query string false "Start date from filter (MM-YYYY)"
+// @Param start_date_to query string false "Start date to filter (MM-YYYY)"
+// @Param end_date_from query string false "End date from filter (MM-YYYY)"
+// @Param end_date_to query string false "End date to filter (MM-YYYY)"
+// @Param filter query string false "Filter expression, e.g. price > 1000 and service_name ilike 'net%' and end_date is null"
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /subscriptions/export [get]
+func (h *ExportHandler) ExportSubscriptions(c *gin.Context) {
+	var query dto.ExportSubscriptionsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		h.logger.Error("Invalid query parameters", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename := fmt.Sprintf("subscriptions-%s.%s", time.Now().UTC().Format("20060102-150405"), query.Format)
+	writer := &lazyHeaderWriter{
+		ResponseWriter: c.Writer,
+		headers: map[string]string{
+			"Content-Type":        exportContentTypes[query.Format],
+			"Content-Disposition": fmt.Sprintf("attachment; filename=%q", filename),
+		},
+	}
+
+	httpErr := h.service.ExportSubscriptions(c.Request.Context(), writer, query)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "error", httpErr)
+		if !c.Writer.Written() {
+			c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		}
+		return
+	}
+
+	if !c.Writer.Written() {
+		writer.setHeaders()
+		c.Status(http.StatusOK)
+	}
+	h.logger.Info("Subscriptions exported successfully", "format", query.Format)
+}
+
+// lazyHeaderWriter sets the download headers only once the body starts, so
+// errors detected before streaming can still be reported as JSON.
+type lazyHeaderWriter struct {
+	gin.ResponseWriter
+	headers map[string]string
+	started bool
+}
+
+func (w *lazyHeaderWriter) Write(data []byte) (int, error) {
+	w.setHeaders()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *lazyHeaderWriter) setHeaders() {
+	if w.started {
+		return
+	}
+	w.started = true
+	for key, value := range w.headers {
+		w.Header().Set(key, value)
+	}
+}
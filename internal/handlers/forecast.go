@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/service"
+)
+
+type ForecastHandler struct {
+	service service.ForecastService
+	logger  *slog.Logger
+}
+
+func NewForecastHandler(service service.ForecastService, logger *slog.Logger) *ForecastHandler {
+	return &ForecastHandler{service: service, logger: logger}
+}
+
+// Forecast godoc
+// @Summary Forecast subscription spend
+// @Description Project the spend of the coming months from billing cycles, end dates, scheduled price changes and trials ending
+// @Tags subscriptions
+// @Produce json
+// @Param user_id query string false "User ID filter"
+// @Param months query int false "Number of months to project (1-60)" default(12)
+// @Param start query string false "First month of the forecast (MM-YYYY), the current month by default"
+// @Param currency query string false "ISO 4217 currency of the forecast, RUB by default"
+// @Success 200 {object} dto.ForecastResponse
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /subscriptions/forecast [get]
+func (h *ForecastHandler) Forecast(c *gin.Context) {
+	var query dto.ForecastQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		h.logger.Error("Invalid query parameters", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, httpErr := h.service.Forecast(c.Request.Context(), query)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Forecast calculated successfully", "months", len(response.Months), "total", response.Total)
+	c.JSON(http.StatusOK, response)
+}
+
+// SchedulePriceChange godoc
+// @Summary Schedule a price change
+// @Description Record a future price of a subscription, charged from the effective month on
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Param change body dto.CreatePriceChangeRequest true "Price change"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
+// @Success 201 {object} dto.PriceChangeResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /subscriptions/{id}/price-changes [post]
+func (h *ForecastHandler) SchedulePriceChange(c *gin.Context) {
+	var params dto.SubscriptionPathParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		h.logger.Error("Invalid subscription ID", "id", c.Param("id"))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subscription ID"})
+		return
+	}
+
+	var req dto.CreatePriceChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, httpErr := h.service.SchedulePriceChange(c.Request.Context(), params.ID, req)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "subscription_id", params.ID, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Price change scheduled", "subscription_id", params.ID, "id", response.ID)
+	c.JSON(http.StatusCreated, response)
+}
+
+// ListPriceChanges godoc
+// @Summary List price changes
+// @Description List the scheduled price changes of a subscription ordered by effective date
+// @Tags subscriptions
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Success 200 {object} dto.ListPriceChangesResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /subscriptions/{id}/price-changes [get]
+func (h *ForecastHandler) ListPriceChanges(c *gin.Context) {
+	var params dto.SubscriptionPathParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		h.logger.Error("Invalid subscription ID", "id", c.Param("id"))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subscription ID"})
+		return
+	}
+
+	response, httpErr := h.service.ListPriceChanges(c.Request.Context(), params.ID)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "subscription_id", params.ID, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Price changes listed successfully", "subscription_id", params.ID, "count", len(response.Data))
+	c.JSON(http.StatusOK, response)
+}
+
+// CancelPriceChange godoc
+// @Summary Cancel a price change
+// @Description Delete a scheduled price change of a subscription
+// @Tags subscriptions
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Param change_id path int true "Price change ID"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /subscriptions/{id}/price-changes/{change_id} [delete]
+func (h *ForecastHandler) CancelPriceChange(c *gin.Context) {
+	var params dto.PriceChangePathParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		h.logger.Error("Invalid path parameters", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if httpErr := h.service.CancelPriceChange(c.Request.Context(), params.ID, params.ChangeID); httpErr != nil {
+		h.logger.Error(httpErr.Error(), "subscription_id", params.ID, "id", params.ChangeID, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Price change cancelled", "subscription_id", params.ID, "id", params.ChangeID)
+	c.JSON(http.StatusNoContent, nil)
+}
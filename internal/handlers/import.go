@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/service"
+)
+
+type ImportHandler struct {
+	service service.ImportService
+	logger  *slog.Logger
+}
+
+func NewImportHandler(service service.ImportService, logger *slog.Logger) *ImportHandler {
+	return &ImportHandler{service: service, logger: logger}
+}
+
+// ImportSubscriptions godoc
+// @Summary Import subscriptions from CSV
+// @Description Import subscriptions from a CSV file uploaded as multipart "file" field or sent as a text/csv body.
+// @Description Every row is validated with the same rules as subscription creation and reported as accepted or rejected.
+// @Tags subscriptions
+// @Accept multipart/form-data
+// @Accept text/csv
+// @Produce json
+// @Param file formData file false "CSV file"
+// @Param dry_run query bool false "Validate rows without saving them"
+// @Param upsert query bool false "Update subscriptions matching user_id, service_name and start_date instead of creating duplicates"
+// @Param mapping query string false "Column mapping, e.g. service_name=Service,price=Cost"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
+// @Success 200 {object} dto.ImportSubscriptionsResponse
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /subscriptions/import [post]
+func (h *ImportHandler) ImportSubscriptions(c *gin.Context) {
+	var query dto.ImportSubscriptionsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		h.logger.Error("Invalid query parameters", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var body io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			h.logger.Error("Missing CSV file", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			h.logger.Error("Failed to open CSV file", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	response, httpErr := h.service.ImportCSV(c.Request.Context(), body, query)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Subscriptions imported", "dry_run", response.DryRun,
+		"accepted", response.Accepted, "rejected", response.Rejected)
+	c.JSON(http.StatusOK, response)
+}
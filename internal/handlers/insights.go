@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/service"
+)
+
+type InsightsHandler struct {
+	duplicates service.DuplicateService
+	logger     *slog.Logger
+}
+
+func NewInsightsHandler(duplicates service.DuplicateService, logger *slog.Logger) *InsightsHandler {
+	return &InsightsHandler{duplicates: duplicates, logger: logger}
+}
+
+// FindDuplicates godoc
+// @Summary Find duplicate subscriptions
+// @Description Flag subscriptions of a user to the same service with overlapping periods or several plans, and estimate the wasted spend
+// @Tags insights
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Success 200 {object} dto.DuplicatesResponse
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /users/{user_id}/insights/duplicates [get]
+func (h *InsightsHandler) FindDuplicates(c *gin.Context) {
+	var params dto.UserPathParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		h.logger.Error("Invalid user ID", "user_id", c.Param("user_id"))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, httpErr := h.duplicates.FindDuplicates(c.Request.Context(), params.UserID)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "user_id", params.UserID, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Duplicates found", "user_id", params.UserID, "groups", len(response.Groups))
+	c.JSON(http.StatusOK, response)
+}
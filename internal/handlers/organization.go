@@ -0,0 +1,341 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/service"
+)
+
+type OrganizationHandler struct {
+	service service.OrganizationService
+	logger  *slog.Logger
+}
+
+func NewOrganizationHandler(service service.OrganizationService, logger *slog.Logger) *OrganizationHandler {
+	return &OrganizationHandler{service: service, logger: logger}
+}
+
+// CreateOrganization godoc
+// @Summary Create an organization
+// @Description Create a household or a company grouping users, owned by owner_id; the listed members are invited
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Param organization body dto.CreateOrganizationRequest true "Organization"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
+// @Success 201 {object} dto.OrganizationResponse
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /organizations [post]
+func (h *OrganizationHandler) CreateOrganization(c *gin.Context) {
+	var req dto.CreateOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, httpErr := h.service.CreateOrganization(c.Request.Context(), req)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "name", req.Name, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Organization created", "id", response.ID, "name", response.Name)
+	c.JSON(http.StatusCreated, response)
+}
+
+// ListOrganizations godoc
+// @Summary List organizations
+// @Description List organizations with their members, optionally only the ones a user belongs to
+// @Tags organizations
+// @Produce json
+// @Param user_id query string false "Member user ID"
+// @Param kind query string false "Organization kind" Enums(household, company)
+// @Success 200 {object} dto.ListOrganizationsResponse
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /organizations [get]
+func (h *OrganizationHandler) ListOrganizations(c *gin.Context) {
+	var query dto.ListOrganizationsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		h.logger.Error("Invalid query parameters", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, httpErr := h.service.ListOrganizations(c.Request.Context(), query)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Organizations listed successfully", "count", len(response.Data))
+	c.JSON(http.StatusOK, response)
+}
+
+// GetOrganization godoc
+// @Summary Get an organization
+// @Description Get an organization with its members
+// @Tags organizations
+// @Produce json
+// @Param id path int true "Organization ID"
+// @Success 200 {object} dto.OrganizationResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /organizations/{id} [get]
+func (h *OrganizationHandler) GetOrganization(c *gin.Context) {
+	var params dto.OrganizationPathParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		h.logger.Error("Invalid organization ID", "id", c.Param("id"))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, httpErr := h.service.GetOrganization(c.Request.Context(), params.ID)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "id", params.ID, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// UpdateOrganization godoc
+// @Summary Update an organization
+// @Description Rename an organization or change its kind
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Param id path int true "Organization ID"
+// @Param organization body dto.UpdateOrganizationRequest true "Organization update"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
+// @Success 200 {object} dto.OrganizationResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /organizations/{id} [put]
+func (h *OrganizationHandler) UpdateOrganization(c *gin.Context) {
+	var params dto.OrganizationPathParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		h.logger.Error("Invalid organization ID", "id", c.Param("id"))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req dto.UpdateOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, httpErr := h.service.UpdateOrganization(c.Request.Context(), params.ID, req)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "id", params.ID, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Organization updated", "id", params.ID)
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteOrganization godoc
+// @Summary Delete an organization
+// @Description Delete an organization and its memberships; subscriptions of the members are kept
+// @Tags organizations
+// @Param id path int true "Organization ID"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /organizations/{id} [delete]
+func (h *OrganizationHandler) DeleteOrganization(c *gin.Context) {
+	var params dto.OrganizationPathParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		h.logger.Error("Invalid organization ID", "id", c.Param("id"))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if httpErr := h.service.DeleteOrganization(c.Request.Context(), params.ID); httpErr != nil {
+		h.logger.Error(httpErr.Error(), "id", params.ID, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Organization deleted", "id", params.ID)
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// AddMember godoc
+// @Summary Invite an organization member
+// @Description Invite a user to an organization with the owner, admin or member role; the membership takes effect once the user accepts it
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Param id path int true "Organization ID"
+// @Param member body dto.AddOrganizationMemberRequest true "Member"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
+// @Success 201 {object} dto.OrganizationMemberResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /organizations/{id}/members [post]
+func (h *OrganizationHandler) AddMember(c *gin.Context) {
+	var params dto.OrganizationPathParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		h.logger.Error("Invalid organization ID", "id", c.Param("id"))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req dto.AddOrganizationMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, httpErr := h.service.AddMember(c.Request.Context(), params.ID, req)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "id", params.ID, "user_id", req.UserID, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Organization member invited", "id", params.ID, "user_id", req.UserID)
+	c.JSON(http.StatusCreated, response)
+}
+
+// UpdateMember godoc
+// @Summary Change the role of an organization member
+// @Description Change the role of a member; the last owner cannot be demoted
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Param id path int true "Organization ID"
+// @Param user_id path string true "Member user ID"
+// @Param member body dto.UpdateOrganizationMemberRequest true "Role"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
+// @Success 200 {object} dto.OrganizationMemberResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /organizations/{id}/members/{user_id} [put]
+func (h *OrganizationHandler) UpdateMember(c *gin.Context) {
+	var params dto.OrganizationMemberPathParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		h.logger.Error("Invalid path parameters", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req dto.UpdateOrganizationMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, httpErr := h.service.UpdateMember(c.Request.Context(), params.ID, params.UserID, req)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "id", params.ID, "user_id", params.UserID, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Organization member updated", "id", params.ID, "user_id", params.UserID, "role", response.Role)
+	c.JSON(http.StatusOK, response)
+}
+
+// RemoveMember godoc
+// @Summary Remove an organization member
+// @Description Remove a user from an organization; the last owner cannot be removed
+// @Tags organizations
+// @Param id path int true "Organization ID"
+// @Param user_id path string true "Member user ID"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /organizations/{id}/members/{user_id} [delete]
+func (h *OrganizationHandler) RemoveMember(c *gin.Context) {
+	var params dto.OrganizationMemberPathParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		h.logger.Error("Invalid path parameters", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if httpErr := h.service.RemoveMember(c.Request.Context(), params.ID, params.UserID); httpErr != nil {
+		h.logger.Error(httpErr.Error(), "id", params.ID, "user_id", params.UserID, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Organization member removed", "id", params.ID, "user_id", params.UserID)
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// AcceptMember godoc
+// @Summary Accept an invitation to an organization
+// @Description Accept the invitation of the user, who only then shares their subscriptions with the organization
+// @Tags organizations
+// @Produce json
+// @Param id path int true "Organization ID"
+// @Param user_id path string true "Invited user ID"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
+// @Success 200 {object} dto.OrganizationMemberResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /organizations/{id}/members/{user_id}/accept [post]
+func (h *OrganizationHandler) AcceptMember(c *gin.Context) {
+	var params dto.OrganizationMemberPathParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		h.logger.Error("Invalid path parameters", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, httpErr := h.service.AcceptMember(c.Request.Context(), params.ID, params.UserID)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "id", params.ID, "user_id", params.UserID, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Organization invitation accepted", "id", params.ID, "user_id", params.UserID)
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/service"
+)
+
+type ReceiptHandler struct {
+	service service.ReceiptService
+	logger  *slog.Logger
+}
+
+func NewReceiptHandler(service service.ReceiptService, logger *slog.Logger) *ReceiptHandler {
+	return &ReceiptHandler{service: service, logger: logger}
+}
+
+// ImportReceipts godoc
+// @Summary Import email receipts
+// @Description Upload forwarded receipts as an .eml or mbox file, extract service name, price, currency and billing date and store them as subscription drafts
+// @Tags drafts
+// @Accept multipart/form-data
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Param file formData file true "Receipt file (.eml or mbox)"
+// @Param format query string false "File format (eml/mbox), detected from the file by default"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
+// @Success 200 {object} dto.ImportReceiptsResponse
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /users/{user_id}/receipts [post]
+func (h *ReceiptHandler) ImportReceipts(c *gin.Context) {
+	var params dto.UserPathParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		h.logger.Error("Invalid user ID", "user_id", c.Param("user_id"))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var query dto.ImportReceiptsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		h.logger.Error("Invalid query parameters", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var body io.Reader = c.Request.Body
+	filename := ""
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			h.logger.Error("Missing receipt file", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			h.logger.Error("Failed to open receipt file", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer file.Close()
+		body = file
+		filename = fileHeader.Filename
+	}
+
+	response, httpErr := h.service.ImportReceipts(c.Request.Context(), params.UserID, filename, body, query)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "user_id", params.UserID, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Receipts imported", "user_id", params.UserID,
+		"messages", response.Messages, "drafts", len(response.Drafts), "skipped", len(response.Skipped))
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateReceiptRule godoc
+// @Summary Create a receipt rule
+// @Description Add a regex rule that extracts subscription data from receipts sent by a domain and its subdomains
+// @Tags receipt-rules
+// @Accept json
+// @Produce json
+// @Param rule body dto.CreateReceiptRuleRequest true "Rule"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
+// @Success 201 {object} dto.ReceiptRuleResponse
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /receipt-rules [post]
+func (h *ReceiptHandler) CreateReceiptRule(c *gin.Context) {
+	var req dto.CreateReceiptRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, httpErr := h.service.CreateRule(c.Request.Context(), req)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "sender_domain", req.SenderDomain, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Receipt rule created", "id", response.ID, "sender_domain", response.SenderDomain)
+	c.JSON(http.StatusCreated, response)
+}
+
+// ListReceiptRules godoc
+// @Summary List receipt rules
+// @Description List the receipt rules grouped by sender domain in the order they are tried
+// @Tags receipt-rules
+// @Produce json
+// @Success 200 {object} dto.ListReceiptRulesResponse
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /receipt-rules [get]
+func (h *ReceiptHandler) ListReceiptRules(c *gin.Context) {
+	response, httpErr := h.service.ListRules(c.Request.Context())
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Receipt rules listed successfully", "count", len(response.Data))
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteReceiptRule godoc
+// @Summary Delete a receipt rule
+// @Description Delete a receipt rule by ID
+// @Tags receipt-rules
+// @Produce json
+// @Param id path int true "Rule ID"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /receipt-rules/{id} [delete]
+func (h *ReceiptHandler) DeleteReceiptRule(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		h.logger.Error("Invalid receipt rule ID", "id", idParam)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid receipt rule ID"})
+		return
+	}
+
+	if httpErr := h.service.DeleteRule(c.Request.Context(), id); httpErr != nil {
+		h.logger.Error(httpErr.Error(), "id", id, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Receipt rule deleted", "id", id)
+	c.JSON(http.StatusNoContent, nil)
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/service"
+)
+
+type RecommendationHandler struct {
+	service service.RecommendationService
+	logger  *slog.Logger
+}
+
+func NewRecommendationHandler(service service.RecommendationService, logger *slog.Logger) *RecommendationHandler {
+	return &RecommendationHandler{service: service, logger: logger}
+}
+
+// ListRecommendations godoc
+// @Summary List savings recommendations
+// @Description Suggest savings: yearly billing, cancelling unused and duplicate subscriptions, family plans in the household
+// @Tags recommendations
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Param include_hidden query bool false "Include dismissed and snoozed recommendations"
+// @Success 200 {object} dto.ListRecommendationsResponse
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /users/{user_id}/recommendations [get]
+func (h *RecommendationHandler) ListRecommendations(c *gin.Context) {
+	var params dto.UserPathParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		h.logger.Error("Invalid user ID", "user_id", c.Param("user_id"))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var query dto.ListRecommendationsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		h.logger.Error("Invalid query parameters", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, httpErr := h.service.ListRecommendations(c.Request.Context(), params.UserID, query)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "user_id", params.UserID, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Recommendations listed successfully", "user_id", params.UserID, "count", len(response.Data))
+	c.JSON(http.StatusOK, response)
+}
+
+// DismissRecommendation godoc
+// @Summary Dismiss a recommendation
+// @Description Hide a recommendation for good
+// @Tags recommendations
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Param key path string true "Recommendation key"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /users/{user_id}/recommendations/{key}/dismiss [post]
+func (h *RecommendationHandler) DismissRecommendation(c *gin.Context) {
+	var params dto.RecommendationPathParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		h.logger.Error("Invalid path parameters", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if httpErr := h.service.DismissRecommendation(c.Request.Context(), params.UserID, params.Key); httpErr != nil {
+		h.logger.Error(httpErr.Error(), "user_id", params.UserID, "key", params.Key, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Recommendation dismissed", "user_id", params.UserID, "key", params.Key)
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// SnoozeRecommendation godoc
+// @Summary Snooze a recommendation
+// @Description Hide a recommendation for a number of days, 30 by default
+// @Tags recommendations
+// @Accept json
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Param key path string true "Recommendation key"
+// @Param snooze body dto.SnoozeRecommendationRequest false "Snooze period"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /users/{user_id}/recommendations/{key}/snooze [post]
+func (h *RecommendationHandler) SnoozeRecommendation(c *gin.Context) {
+	var params dto.RecommendationPathParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		h.logger.Error("Invalid path parameters", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req dto.SnoozeRecommendationRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.logger.Error("Invalid request body", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if httpErr := h.service.SnoozeRecommendation(c.Request.Context(), params.UserID, params.Key, req); httpErr != nil {
+		h.logger.Error(httpErr.Error(), "user_id", params.UserID, "key", params.Key, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Recommendation snoozed", "user_id", params.UserID, "key", params.Key)
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// ReportUsage godoc
+// @Summary Report subscription usage
+// @Description Record how often the subscription is used; rarely and never used subscriptions are recommended for cancellation
+// @Tags recommendations
+// @Accept json
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Param usage body dto.ReportUsageRequest true "Usage"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
+// @Success 200 {object} dto.SubscriptionResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /subscriptions/{id}/usage [put]
+func (h *RecommendationHandler) ReportUsage(c *gin.Context) {
+	var params dto.SubscriptionPathParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		h.logger.Error("Invalid subscription ID", "id", c.Param("id"))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subscription ID"})
+		return
+	}
+
+	var req dto.ReportUsageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, httpErr := h.service.ReportUsage(c.Request.Context(), params.ID, req)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "id", params.ID, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Subscription usage reported", "id", params.ID, "usage", req.Usage)
+	c.JSON(http.StatusOK, response)
+}
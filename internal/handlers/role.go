@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/service"
+)
+
+type RoleHandler struct {
+	service service.RoleService
+	logger  *slog.Logger
+}
+
+func NewRoleHandler(service service.RoleService, logger *slog.Logger) *RoleHandler {
+	return &RoleHandler{service: service, logger: logger}
+}
+
+// AssignRole godoc
+// @Summary Assign a role
+// @Description Grant a user the viewer, editor, auditor or admin role
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Param assignment body dto.CreateRoleAssignmentRequest true "Role assignment"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
+// @Success 201 {object} dto.RoleAssignmentResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /role-assignments [post]
+func (h *RoleHandler) AssignRole(c *gin.Context) {
+	var req dto.CreateRoleAssignmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, httpErr := h.service.AssignRole(c.Request.Context(), req)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "user_id", req.UserID, "role", req.Role, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Role assigned", "id", response.ID, "user_id", response.UserID, "role", response.Role)
+	c.JSON(http.StatusCreated, response)
+}
+
+// ListRoleAssignments godoc
+// @Summary List role assignments
+// @Description List the role assignments of the tenant, optionally of one user
+// @Tags roles
+// @Produce json
+// @Param user_id query string false "User ID"
+// @Success 200 {object} dto.ListRoleAssignmentsResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /role-assignments [get]
+func (h *RoleHandler) ListRoleAssignments(c *gin.Context) {
+	var query dto.ListRoleAssignmentsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		h.logger.Error("Invalid query parameters", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, httpErr := h.service.ListRoleAssignments(c.Request.Context(), query)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// RevokeRole godoc
+// @Summary Revoke a role
+// @Description Delete a role assignment
+// @Tags roles
+// @Param id path int true "Role assignment ID"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /role-assignments/{id} [delete]
+func (h *RoleHandler) RevokeRole(c *gin.Context) {
+	var params dto.RoleAssignmentPathParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		h.logger.Error("Invalid role assignment ID", "id", c.Param("id"))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if httpErr := h.service.RevokeRole(c.Request.Context(), params.ID); httpErr != nil {
+		h.logger.Error(httpErr.Error(), "id", params.ID, "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Role revoked", "id", params.ID)
+	c.JSON(http.StatusNoContent, nil)
+}
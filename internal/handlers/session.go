@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/service"
+)
+
+const (
+	// SessionCookie holds the session token of a logged in browser.
+	SessionCookie = "sm_session"
+	// loginCookie holds the login attempt until the provider redirects back.
+	loginCookie = "sm_login"
+	// loginCookiePath limits the login cookie to the login endpoints.
+	loginCookiePath = "/auth"
+)
+
+// SessionHandler serves the browser login against an OpenID provider. Its routes
+// live outside /api/v1 and are not part of the API documentation, since they
+// answer with redirects instead of JSON.
+type SessionHandler struct {
+	service service.SessionService
+	logger  *slog.Logger
+	// secure marks the cookies as HTTPS only.
+	secure bool
+}
+
+func NewSessionHandler(service service.SessionService, logger *slog.Logger, secure bool) *SessionHandler {
+	return &SessionHandler{service: service, logger: logger, secure: secure}
+}
+
+// Login sends the browser to the provider. The return_to query parameter is the
+// dashboard path the browser comes back to.
+func (h *SessionHandler) Login(c *gin.Context) {
+	var query dto.LoginQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		h.logger.Error("Invalid query parameters", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	redirect, httpErr := h.service.BeginLogin(c.Request.Context(), query.ReturnTo)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.setCookie(c, loginCookie, redirect.Attempt, loginCookiePath, time.Now().Add(service.LoginAttemptTTL))
+	c.Redirect(http.StatusFound, redirect.URL)
+}
+
+// Callback completes the login the provider redirects back with and sets the
+// session cookie.
+func (h *SessionHandler) Callback(c *gin.Context) {
+	var query dto.LoginCallbackQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		h.logger.Error("Invalid query parameters", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The attempt is single use, whatever the outcome
+	attempt, _ := c.Cookie(loginCookie)
+	h.clearCookie(c, loginCookie, loginCookiePath)
+
+	session, httpErr := h.service.CompleteLogin(c.Request.Context(), attempt, query)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("User logged in", "user_id", session.UserID)
+	h.setCookie(c, SessionCookie, session.Token, "/", session.ExpiresAt)
+	c.Redirect(http.StatusFound, session.ReturnTo)
+}
+
+// Logout ends the session and sends the browser to the provider's logout.
+func (h *SessionHandler) Logout(c *gin.Context) {
+	token, _ := c.Cookie(SessionCookie)
+	h.clearCookie(c, SessionCookie, "/")
+
+	url, httpErr := h.service.Logout(c.Request.Context(), token)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("User logged out")
+	c.Redirect(http.StatusFound, url)
+}
+
+// setCookie sets an HTTP-only cookie. SameSite=Lax keeps browsers from sending it
+// with cross-site requests other than top level navigation, which protects the
+// API from cross-site request forgery.
+func (h *SessionHandler) setCookie(c *gin.Context, name, value, path string, expires time.Time) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Expires:  expires,
+		MaxAge:   int(time.Until(expires).Seconds()),
+		Secure:   h.secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (h *SessionHandler) clearCookie(c *gin.Context, name, path string) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Path:     path,
+		MaxAge:   -1,
+		Secure:   h.secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -27,9 +27,12 @@ func NewSubscriptionHandler(service service.SubscriptionService, logger *slog.Lo
 // @Accept json
 // @Produce json
 // @Param subscription body dto.CreateSubscriptionRequest true "Subscription details"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
 // @Success 201 {object} dto.SubscriptionResponse
 // @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /subscriptions [post]
 func (h *SubscriptionHandler) CreateSubscription(c *gin.Context) {
 	var req dto.CreateSubscriptionRequest
@@ -61,6 +64,8 @@ func (h *SubscriptionHandler) CreateSubscription(c *gin.Context) {
 // @Success 200 {object} dto.SubscriptionResponse
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /subscriptions/{id} [get]
 func (h *SubscriptionHandler) GetSubscription(c *gin.Context) {
 	idParam := c.Param("id")
@@ -90,9 +95,12 @@ func (h *SubscriptionHandler) GetSubscription(c *gin.Context) {
 // @Produce json
 // @Param id path int true "Subscription ID"
 // @Param subscription body dto.UpdateSubscriptionRequest true "Updated subscription details"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
 // @Success 200 {object} dto.SubscriptionResponse
 // @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /subscriptions/{id} [put]
 func (h *SubscriptionHandler) UpdateSubscription(c *gin.Context) {
 	idParam := c.Param("id")
@@ -128,9 +136,12 @@ func (h *SubscriptionHandler) UpdateSubscription(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path int true "Subscription ID"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
 // @Success 204
 // @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /subscriptions/{id} [delete]
 func (h *SubscriptionHandler) DeleteSubscription(c *gin.Context) {
 	idParam := c.Param("id")
@@ -152,6 +163,50 @@ func (h *SubscriptionHandler) DeleteSubscription(c *gin.Context) {
 	c.JSON(http.StatusNoContent, nil)
 }
 
+// BulkSubscriptions godoc
+// @Summary Create, update and delete subscriptions in bulk
+// @Description Apply up to 1000 create, update and delete operations in order. The data of an operation is the body of the
+// @Description matching single-item endpoint, and every operation is reported with the status that endpoint would answer with.
+// @Description With atomic=true all operations run in one transaction and none is applied when one of them fails.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param operations body []dto.BulkOperation true "Operations to apply"
+// @Param atomic query bool false "Apply all operations or none of them"
+// @Param Idempotency-Key header string false "Key making the request safe to retry"
+// @Success 200 {object} dto.BulkSubscriptionsResponse
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /subscriptions/bulk [post]
+func (h *SubscriptionHandler) BulkSubscriptions(c *gin.Context) {
+	var query dto.BulkSubscriptionsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		h.logger.Error("Invalid query parameters", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var operations []dto.BulkOperation
+	if err := c.ShouldBindJSON(&operations); err != nil {
+		h.logger.Error("Invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, httpErr := h.service.BulkSubscriptions(c.Request.Context(), operations, query.Atomic)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Bulk subscription operations applied", "atomic", response.Atomic,
+		"succeeded", response.Succeeded, "failed", response.Failed, "rolled_back", response.RolledBack)
+	c.JSON(http.StatusOK, response)
+}
+
 // ListSubscriptions godoc
 // @Summary List subscriptions
 // @Description Get a list of subscriptions with optional filtering and pagination
@@ -159,17 +214,30 @@ func (h *SubscriptionHandler) DeleteSubscription(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param user_id query string false "User ID filter"
+// @Param organization_id query int false "Organization filter, selects the subscriptions of its members"
 // @Param service_name query string false "Service name filter"
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(10)
 // @Param start_date_from query string false "Start date from filter (MM-YYYY)"
 // @Param end_date_from query string false "End date from filter (MM-YYYY)"
 // @Param end_date_to query string false "End date to filter (MM-YYYY)"
-// @Param sort_by query string false "Sort field"
-// @Param sort_order query string false "Sort order (asc/desc)"
+// @Param sort query string false "Comma separated sort fields, prefixed with - for descending order, e.g. -price,service_name"
+// @Param sort_by query string false "Sort field, used without sort"
+// @Param sort_order query string false "Sort order (asc/desc), used without sort"
+// @Param category query string false "Category filter"
+// @Param tags query string false "Comma separated tags filter"
+// @Param tag_match query string false "Whether any or all of the tags must be present" Enums(any, all) default(any)
+// @Param include_shared query bool false "Also list subscriptions shared with the selected users"
+// @Param after query string false "Cursor pagination: list the subscriptions after this cursor, empty for the first page"
+// @Param before query string false "Cursor pagination: list the subscriptions before this cursor"
+// @Param skip_total query bool false "Leave out the total count of a cursor paginated listing"
+// @Param filter query string false "Filter expression, e.g. price > 1000 and service_name ilike 'net%' and end_date is null"
 // @Success 200 {object} dto.ListSubscriptionsResponse
 // @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /subscriptions [get]
 func (h *SubscriptionHandler) ListSubscriptions(c *gin.Context) {
 	var query dto.ListSubscriptionsQuery
@@ -186,7 +254,44 @@ func (h *SubscriptionHandler) ListSubscriptions(c *gin.Context) {
 		return
 	}
 
-	h.logger.Info("Subscriptions listed successfully", "count", response.Pagination.Total)
+	h.logger.Info("Subscriptions listed successfully", "count", len(response.Data))
+	c.JSON(http.StatusOK, response)
+}
+
+// SearchSubscriptions godoc
+// @Summary Search subscriptions
+// @Description Find subscriptions whose service name, tags or notes resemble the query, tolerating typos.
+// @Description Results are ordered by relevance, a score from 0 to 1 for an exact match.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param q query string true "Search query, e.g. spotfy"
+// @Param user_id query string false "User ID filter"
+// @Param organization_id query int false "Organization filter, searches the subscriptions of its members"
+// @Param limit query int false "Maximum number of results" default(20)
+// @Success 200 {object} dto.SearchSubscriptionsResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /subscriptions/search [get]
+func (h *SubscriptionHandler) SearchSubscriptions(c *gin.Context) {
+	var query dto.SearchSubscriptionsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		h.logger.Error("Invalid query parameters", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, httpErr := h.service.SearchSubscriptions(c.Request.Context(), query)
+	if httpErr != nil {
+		h.logger.Error(httpErr.Error(), "error", httpErr)
+		c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+		return
+	}
+
+	h.logger.Info("Subscriptions searched successfully", "count", len(response.Data))
 	c.JSON(http.StatusOK, response)
 }
 
@@ -197,12 +302,20 @@ func (h *SubscriptionHandler) ListSubscriptions(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param user_id query string false "User ID filter"
+// @Param organization_id query int false "Organization filter, sums the spend of its members"
 // @Param service_name query string false "Service name filter"
 // @Param start_date query string true "Start date (MM-YYYY)"
 // @Param end_date query string true "End date (MM-YYYY)"
+// @Param category query string false "Category filter"
+// @Param tags query string false "Comma separated tags filter"
+// @Param tag_match query string false "Whether any or all of the tags must be present" Enums(any, all) default(any)
+// @Param group_by query string false "Break the total down by category or tag" Enums(category, tag)
 // @Success 200 {object} dto.TotalCostResponse
 // @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /subscriptions/total-cost [get]
 func (h *SubscriptionHandler) CalculateTotalCost(c *gin.Context) {
 	var query dto.TotalCostQuery
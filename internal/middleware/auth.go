@@ -0,0 +1,187 @@
+// Package middleware contains the Gin middleware of the API.
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasadov/subscription-manager/pkg/auth"
+	"github.com/rasadov/subscription-manager/pkg/jwt"
+	"github.com/rasadov/subscription-manager/pkg/tenant"
+)
+
+// ErrInvalidCredentials is returned by authenticators for credentials that are
+// not accepted.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Authenticator turns the credentials of an Authorization header into a
+// principal.
+type Authenticator interface {
+	// Scheme is the authorization scheme the authenticator handles, e.g. Bearer.
+	// Authenticators with an empty scheme only accept cookies.
+	Scheme() string
+	Authenticate(ctx context.Context, credentials string) (*auth.Principal, error)
+}
+
+// CookieAuthenticator is an Authenticator that also reads credentials from a
+// cookie, for browsers that cannot set the Authorization header.
+type CookieAuthenticator interface {
+	Authenticator
+	Cookie() string
+}
+
+// Authenticate rejects requests without valid credentials with 401 and stores the
+// principal and its tenant in the request context. The scheme of the
+// Authorization header selects the authenticator. Requests without the header
+// may authenticate with the cookie of a CookieAuthenticator.
+func Authenticate(logger *slog.Logger, authenticators ...Authenticator) gin.HandlerFunc {
+	schemes := make([]string, 0, len(authenticators))
+	for _, authenticator := range authenticators {
+		if authenticator.Scheme() != "" {
+			schemes = append(schemes, authenticator.Scheme())
+		}
+	}
+	challenge := strings.Join(schemes, ", ")
+
+	return func(c *gin.Context) {
+		authenticator, credentials := selectAuthenticator(c, authenticators)
+		if authenticator == nil || credentials == "" {
+			c.Header("WWW-Authenticate", challenge)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			return
+		}
+
+		principal, err := authenticator.Authenticate(c.Request.Context(), credentials)
+		if err != nil {
+			logger.Warn("Authentication failed", "scheme", authenticator.Scheme(), "error", err)
+			if authenticator.Scheme() != "" {
+				c.Header("WWW-Authenticate", authenticator.Scheme()+` error="invalid_token"`)
+			} else {
+				c.Header("WWW-Authenticate", challenge)
+			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+			return
+		}
+
+		ctx := auth.WithPrincipal(c.Request.Context(), principal)
+		ctx = tenant.WithTenant(ctx, principal.TenantID)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// selectAuthenticator returns the authenticator of the request and its
+// credentials. The Authorization header takes precedence over cookies.
+func selectAuthenticator(c *gin.Context, authenticators []Authenticator) (Authenticator, string) {
+	if header := c.GetHeader("Authorization"); header != "" {
+		scheme, credentials, _ := strings.Cut(header, " ")
+		for _, candidate := range authenticators {
+			if candidate.Scheme() != "" && strings.EqualFold(candidate.Scheme(), scheme) {
+				return candidate, strings.TrimSpace(credentials)
+			}
+		}
+		return nil, ""
+	}
+
+	for _, candidate := range authenticators {
+		if cookieAuthenticator, ok := candidate.(CookieAuthenticator); ok {
+			if value, err := c.Cookie(cookieAuthenticator.Cookie()); err == nil && value != "" {
+				return candidate, value
+			}
+		}
+	}
+	return nil, ""
+}
+
+// JWTAuthenticator accepts bearer tokens signed by a configured key.
+type JWTAuthenticator struct {
+	verifier    *jwt.Verifier
+	adminRole   string
+	tenantClaim string
+}
+
+// NewJWTAuthenticator returns an authenticator using the verifier. The "roles"
+// claim may carry roles, where adminRole stands for the admin role, and the
+// tenant is read from the tenantClaim claim. Permissions are granted once the
+// roles are resolved by ResolveRoles.
+func NewJWTAuthenticator(verifier *jwt.Verifier, adminRole, tenantClaim string) *JWTAuthenticator {
+	return &JWTAuthenticator{verifier: verifier, adminRole: adminRole, tenantClaim: tenantClaim}
+}
+
+func (a *JWTAuthenticator) Scheme() string {
+	return "Bearer"
+}
+
+func (a *JWTAuthenticator) Authenticate(ctx context.Context, token string) (*auth.Principal, error) {
+	claims, err := a.verifier.Verify(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	principal := &auth.Principal{
+		Subject:  claims.Subject,
+		TenantID: claims.String(a.tenantClaim),
+	}
+	if principal.TenantID == "" {
+		principal.TenantID = tenant.Default
+	}
+	principal.Roles = auth.RolesFromClaim(claims.Strings("roles"), a.adminRole)
+	return principal, nil
+}
+
+// APIKeyVerifier resolves API keys to principals.
+type APIKeyVerifier interface {
+	Authenticate(ctx context.Context, key string) (*auth.Principal, error)
+}
+
+// APIKeyAuthenticator accepts "Authorization: ApiKey <key>" headers of service
+// accounts.
+type APIKeyAuthenticator struct {
+	keys APIKeyVerifier
+}
+
+func NewAPIKeyAuthenticator(keys APIKeyVerifier) *APIKeyAuthenticator {
+	return &APIKeyAuthenticator{keys: keys}
+}
+
+func (a *APIKeyAuthenticator) Scheme() string {
+	return "ApiKey"
+}
+
+func (a *APIKeyAuthenticator) Authenticate(ctx context.Context, key string) (*auth.Principal, error) {
+	return a.keys.Authenticate(ctx, key)
+}
+
+// SessionVerifier resolves login sessions to principals.
+type SessionVerifier interface {
+	Authenticate(ctx context.Context, token string) (*auth.Principal, error)
+}
+
+// SessionAuthenticator accepts the session cookie set by the browser login.
+type SessionAuthenticator struct {
+	sessions SessionVerifier
+	cookie   string
+}
+
+func NewSessionAuthenticator(sessions SessionVerifier, cookie string) *SessionAuthenticator {
+	return &SessionAuthenticator{sessions: sessions, cookie: cookie}
+}
+
+func (a *SessionAuthenticator) Scheme() string {
+	return ""
+}
+
+func (a *SessionAuthenticator) Cookie() string {
+	return a.cookie
+}
+
+func (a *SessionAuthenticator) Authenticate(ctx context.Context, token string) (*auth.Principal, error) {
+	return a.sessions.Authenticate(ctx, token)
+}
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/pkg/exceptions"
+)
+
+// IdempotencyKeyHeader carries the key clients send to make a request safe to
+// retry.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// MaxIdempotentBodySize is the largest request body read into memory for a
+// request with an idempotency key.
+const MaxIdempotentBodySize = 32 << 20
+
+// replayedHeaders are the response headers stored for replays besides the
+// content type.
+var replayedHeaders = []string{"Location", "Content-Location", "Content-Disposition"}
+
+// IdempotencyStore remembers the responses to requests with an idempotency key.
+type IdempotencyStore interface {
+	Reserve(ctx context.Context, key, fingerprint string) (*models.IdempotencyRecord, exceptions.HTTPError)
+	// Complete stores the response set on the record.
+	Complete(ctx context.Context, record *models.IdempotencyRecord) error
+	Release(ctx context.Context, record *models.IdempotencyRecord) error
+}
+
+// Idempotency makes requests with an Idempotency-Key header safe to retry. The
+// response to the first request is stored and replayed to retries with the same
+// method, URL and body, marked with an Idempotent-Replayed header. Reusing a key
+// for another request, or while its first request is in progress, is answered
+// with 409. Server errors are not stored, so the request can be retried, and
+// neither are responses that fail to be stored. Bodies of requests with the
+// header are limited to MaxIdempotentBodySize. Requests without it are handled
+// as usual.
+func Idempotency(store IdempotencyStore, logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, MaxIdempotentBodySize))
+		if err != nil {
+			logger.Error("Failed to read request body", "error", err)
+			status := http.StatusBadRequest
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		record, httpErr := store.Reserve(c.Request.Context(), key, requestFingerprint(c.Request, body))
+		if httpErr != nil {
+			logger.Warn(httpErr.Error(), "idempotency_key", key)
+			c.AbortWithStatusJSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+			return
+		}
+
+		if record.Completed() {
+			for name, value := range record.Headers {
+				c.Header(name, value)
+			}
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(record.StatusCode, record.ContentType, record.Body)
+			c.Abort()
+			return
+		}
+
+		// The request context may be cancelled once the response is written
+		ctx := context.WithoutCancel(c.Request.Context())
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				if err := store.Release(ctx, record); err != nil {
+					logger.Error("Failed to release idempotency key", "idempotency_key", key, "error", err)
+				}
+				panic(recovered)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		release := func() {
+			if err := store.Release(ctx, record); err != nil {
+				logger.Error("Failed to release idempotency key", "idempotency_key", key, "error", err)
+			}
+		}
+
+		record.StatusCode = recorder.Status()
+		if record.StatusCode >= http.StatusInternalServerError {
+			release()
+			return
+		}
+		record.ContentType = recorder.Header().Get("Content-Type")
+		record.Headers = map[string]string{}
+		for _, name := range replayedHeaders {
+			if value := recorder.Header().Get(name); value != "" {
+				record.Headers[name] = value
+			}
+		}
+		record.Body = recorder.body.Bytes()
+		if err := store.Complete(ctx, record); err != nil {
+			// Otherwise retries would be rejected as in progress until the key expires
+			logger.Error("Failed to store idempotent response", "idempotency_key", key, "error", err)
+			release()
+		}
+	}
+}
+
+// requestFingerprint identifies a request by its method, URL and body.
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	io.WriteString(hash, r.Method+" "+r.URL.RequestURI()+"\n")
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// responseRecorder keeps a copy of the response body it writes.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasadov/subscription-manager/pkg/auth"
+)
+
+// RoleResolver grants a principal the permissions of its roles.
+type RoleResolver interface {
+	ResolveRoles(ctx context.Context, principal *auth.Principal) error
+}
+
+// ResolveRoles grants the authenticated principal the permissions of its roles.
+// Principals that already have permissions, such as API keys, are left as is.
+func ResolveRoles(resolver RoleResolver, logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := auth.FromContext(c.Request.Context())
+		if ok && principal.Permissions == nil {
+			if err := resolver.ResolveRoles(c.Request.Context(), principal); err != nil {
+				logger.Error("Failed to resolve roles", "subject", principal.Subject, "error", err)
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to resolve roles"})
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// RequirePermission rejects principals without the permission with 403. Requests
+// without a principal pass: they only reach handlers when authentication is
+// disabled.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := auth.FromContext(c.Request.Context())
+		if ok && !principal.Allows(permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "missing permission " + permission})
+			return
+		}
+		c.Next()
+	}
+}
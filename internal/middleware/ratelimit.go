@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasadov/subscription-manager/pkg/auth"
+	"github.com/rasadov/subscription-manager/pkg/ratelimit"
+)
+
+// RateLimitPolicy assigns limits to routes.
+type RateLimitPolicy struct {
+	// Default limits every route without its own limit. Those routes share one
+	// bucket per client.
+	Default ratelimit.Limit
+	// Routes are the limits of routes with a bucket of their own, keyed by the full
+	// route path such as /api/v1/subscriptions/export.
+	Routes map[string]ratelimit.Limit
+}
+
+// RateLimit limits the requests of every client, which is the API key or user of
+// authenticated requests and the client IP otherwise, so it runs after
+// Authenticate. Responses carry RateLimit-* headers, and rejected requests get
+// 429 with Retry-After. Requests are let through when the store fails, so an
+// unavailable store does not take the API down with it.
+func RateLimit(store ratelimit.Store, policy RateLimitPolicy, logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bucket := "default"
+		limit, ok := policy.Routes[c.FullPath()]
+		if ok {
+			bucket = c.FullPath()
+		} else {
+			limit = policy.Default
+		}
+		takeToken(c, store, bucket, rateLimitClient(c), limit, logger)
+	}
+}
+
+// RateLimitIP limits the requests of every client IP address with one bucket for
+// all routes. It runs before Authenticate, so requests failing authentication,
+// such as guessed API keys, are limited too.
+func RateLimitIP(store ratelimit.Store, limit ratelimit.Limit, logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		takeToken(c, store, "ip", "ip:"+c.ClientIP(), limit, logger)
+	}
+}
+
+// takeToken takes a token from the bucket of the client and continues with the
+// request, or rejects it when the bucket is empty.
+func takeToken(c *gin.Context, store ratelimit.Store, bucket, client string, limit ratelimit.Limit, logger *slog.Logger) {
+	result, err := store.Take(c.Request.Context(), bucket+"|"+client, limit, time.Now())
+	if err != nil {
+		logger.Error("Rate limit check failed", "client", client, "error", err)
+		c.Next()
+		return
+	}
+
+	c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, ceilSeconds(limit.Per)))
+	c.Header("RateLimit-Limit", strconv.Itoa(limit.Requests))
+	c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+	if !result.Allowed {
+		logger.Warn("Rate limit exceeded", "client", client, "route", bucket)
+		c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Rate limit exceeded"})
+		return
+	}
+	c.Next()
+}
+
+// rateLimitClient identifies the client of a request. Subjects are only unique
+// within their tenant.
+func rateLimitClient(c *gin.Context) string {
+	if principal, ok := auth.FromContext(c.Request.Context()); ok {
+		return "principal:" + principal.TenantID + ":" + principal.Subject
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
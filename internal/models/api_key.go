@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// APIKey is a credential of a service account, such as a billing job, that calls
+// the API without a user token. Only the SHA-256 hash of the secret is stored;
+// Prefix is the first characters of the key, kept to tell keys apart.
+type APIKey struct {
+	ID         uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	TenantID   string     `json:"-" gorm:"type:varchar(64);not null;default:default;index"`
+	Name       string     `json:"name" gorm:"type:varchar(255);not null"`
+	Prefix     string     `json:"prefix" gorm:"type:varchar(16);not null"`
+	SecretHash string     `json:"-" gorm:"type:varchar(64);not null;uniqueIndex"`
+	Scopes     []string   `json:"scopes" gorm:"type:text;serializer:json"`
+	CreatedBy  string     `json:"created_by,omitempty" gorm:"type:varchar(255)"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// Active reports whether the key is neither revoked nor expired at now.
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+const (
+	BudgetScopeAll      = "all"
+	BudgetScopeCategory = "category"
+	BudgetScopeService  = "service"
+)
+
+// Budget caps the monthly spend of a user on all subscriptions, on one category
+// or on one service. Thresholds are the percentages of MonthlyLimit at which an
+// alert is raised.
+type Budget struct {
+	ID           uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	TenantID     string    `json:"-" gorm:"type:varchar(64);not null;default:default;index"`
+	UserID       string    `json:"user_id" gorm:"type:uuid;not null;index"`
+	Scope        string    `json:"scope" gorm:"type:varchar(16);not null;default:all"`
+	Category     string    `json:"category,omitempty" gorm:"type:varchar(64)"`
+	ServiceName  string    `json:"service_name,omitempty" gorm:"type:varchar(255)"`
+	MonthlyLimit int64     `json:"monthly_limit" gorm:"not null"`
+	Currency     string    `json:"currency" gorm:"type:varchar(3);not null;default:RUB"`
+	Thresholds   []int     `json:"thresholds" gorm:"serializer:json"`
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// BudgetAlert records that the projected spend of a month reached a threshold of
+// a budget. Each threshold alerts at most once per budget and month.
+type BudgetAlert struct {
+	ID           uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	TenantID     string    `json:"-" gorm:"type:varchar(64);not null;default:default;uniqueIndex:idx_budget_alerts_tenant_id_budget_id_month_threshold"`
+	BudgetID     uint      `json:"budget_id" gorm:"not null;uniqueIndex:idx_budget_alerts_tenant_id_budget_id_month_threshold"`
+	UserID       string    `json:"user_id" gorm:"type:uuid;not null;index"`
+	Month        time.Time `json:"month" gorm:"not null;uniqueIndex:idx_budget_alerts_tenant_id_budget_id_month_threshold"`
+	Threshold    int       `json:"threshold" gorm:"not null;uniqueIndex:idx_budget_alerts_tenant_id_budget_id_month_threshold"`
+	Spend        int64     `json:"spend" gorm:"not null"`
+	MonthlyLimit int64     `json:"monthly_limit" gorm:"not null"`
+	Currency     string    `json:"currency" gorm:"type:varchar(3);not null"`
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// CalendarToken is the per-user secret that authorizes access to the iCalendar
+// feed. Only the SHA-256 hash of the token is stored.
+type CalendarToken struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	TenantID  string    `json:"-" gorm:"type:varchar(64);not null;default:default;uniqueIndex:idx_calendar_tokens_tenant_id_user_id"`
+	UserID    string    `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_calendar_tokens_tenant_id_user_id"`
+	TokenHash string    `json:"-" gorm:"type:varchar(64);not null;uniqueIndex"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Service is a canonical entry of the service catalog of a tenant. Aliases are
+// the other names users type for it, e.g. "netflix.com" or "Нетфликс".
+type Service struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	TenantID  string    `json:"-" gorm:"type:varchar(64);not null;default:default;uniqueIndex:idx_services_tenant_id_name"`
+	Name      string    `json:"name" gorm:"type:varchar(255);not null;uniqueIndex:idx_services_tenant_id_name"`
+	Aliases   []string  `json:"aliases" gorm:"type:text;serializer:json"`
+	Category  string    `json:"category" gorm:"type:varchar(64);index"`
+	Website   string    `json:"website" gorm:"type:varchar(255)"`
+	LogoURL   string    `json:"logo_url" gorm:"type:varchar(512)"`
+	Plans     []Plan    `json:"plans" gorm:"foreignKey:ServiceID;constraint:OnDelete:CASCADE"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// Plan is a tier of a catalog service with its list price.
+type Plan struct {
+	ID           uint   `json:"id" gorm:"primaryKey;autoIncrement"`
+	TenantID     string `json:"-" gorm:"type:varchar(64);not null;default:default;uniqueIndex:idx_plans_tenant_id_service_id_name"`
+	ServiceID    uint   `json:"service_id" gorm:"not null;uniqueIndex:idx_plans_tenant_id_service_id_name"`
+	Name         string `json:"name" gorm:"type:varchar(255);not null;uniqueIndex:idx_plans_tenant_id_service_id_name"`
+	Price        int64  `json:"price" gorm:"not null"`
+	Currency     string `json:"currency" gorm:"type:varchar(3);not null;default:RUB"`
+	BillingCycle string `json:"billing_cycle" gorm:"type:varchar(16);not null;default:monthly"`
+	// Seats is the number of people the plan can be shared with, above one for family plans.
+	Seats     int       `json:"seats" gorm:"not null;default:1"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
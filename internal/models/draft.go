@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+const (
+	DraftSourceStatement = "statement"
+	DraftSourceReceipt   = "receipt"
+
+	DraftStatusPending   = "pending"
+	DraftStatusConfirmed = "confirmed"
+	DraftStatusDismissed = "dismissed"
+)
+
+// SubscriptionDraft is a subscription proposed from imported data that the
+// user still has to confirm (turning it into a Subscription) or dismiss.
+type SubscriptionDraft struct {
+	ID             uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	TenantID       string    `json:"-" gorm:"type:varchar(64);not null;default:default;index"`
+	UserID         string    `json:"user_id" gorm:"type:uuid;not null;index"`
+	Source         string    `json:"source" gorm:"type:varchar(32);not null"`
+	ServiceName    string    `json:"service_name" gorm:"type:varchar(255);not null"`
+	Price          int64     `json:"price" gorm:"not null"`
+	Currency       string    `json:"currency" gorm:"type:varchar(3);not null;default:RUB"`
+	BillingCycle   string    `json:"billing_cycle" gorm:"type:varchar(16);not null;default:monthly"`
+	StartDate      time.Time `json:"start_date" gorm:"type:timestamp;not null"`
+	Confidence     float64   `json:"confidence" gorm:"not null;default:0"`
+	Details        string    `json:"details" gorm:"type:text"`
+	Status         string    `json:"status" gorm:"type:varchar(16);not null;default:pending;index"`
+	SubscriptionID *uint     `json:"subscription_id,omitempty"`
+	CreatedAt      time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// IdempotencyRecord remembers the response to a request sent with an
+// Idempotency-Key header, so retries of the request get the same response
+// instead of repeating its effects. Keys belong to the caller that sent them.
+// StatusCode is 0 while the first request is still being handled. Headers are
+// the response headers replayed besides the content type, such as Location.
+type IdempotencyRecord struct {
+	ID          uint              `json:"id" gorm:"primaryKey;autoIncrement"`
+	TenantID    string            `json:"-" gorm:"type:varchar(64);not null;default:default;uniqueIndex:idx_idempotency_records_tenant_id_subject_key"`
+	Subject     string            `json:"subject" gorm:"type:varchar(255);not null;uniqueIndex:idx_idempotency_records_tenant_id_subject_key"`
+	Key         string            `json:"key" gorm:"type:varchar(255);not null;uniqueIndex:idx_idempotency_records_tenant_id_subject_key"`
+	Fingerprint string            `json:"fingerprint" gorm:"type:varchar(64);not null"`
+	StatusCode  int               `json:"status_code" gorm:"not null;default:0"`
+	ContentType string            `json:"content_type" gorm:"type:varchar(255)"`
+	Headers     map[string]string `json:"headers,omitempty" gorm:"type:text;serializer:json"`
+	Body        []byte            `json:"-"`
+	CreatedAt   time.Time         `json:"created_at" gorm:"autoCreateTime"`
+	ExpiresAt   time.Time         `json:"expires_at" gorm:"not null;index"`
+}
+
+// Completed reports whether the response of the request is stored.
+func (r *IdempotencyRecord) Completed() bool {
+	return r.StatusCode != 0
+}
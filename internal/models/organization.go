@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+const (
+	OrganizationKindHousehold = "household"
+	OrganizationKindCompany   = "company"
+)
+
+const (
+	OrganizationRoleOwner  = "owner"
+	OrganizationRoleAdmin  = "admin"
+	OrganizationRoleMember = "member"
+)
+
+// Organization groups users whose subscriptions are viewed together: a household
+// sharing family plans or a company tracking the SaaS spend of its employees.
+type Organization struct {
+	ID        uint                 `json:"id" gorm:"primaryKey;autoIncrement"`
+	TenantID  string               `json:"-" gorm:"type:varchar(64);not null;default:default;index"`
+	Name      string               `json:"name" gorm:"type:varchar(255);not null"`
+	Kind      string               `json:"kind" gorm:"type:varchar(16);not null;default:company"`
+	Members   []OrganizationMember `json:"members,omitempty" gorm:"constraint:OnDelete:CASCADE"`
+	CreatedAt time.Time            `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time            `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// OrganizationMember is the membership of a user in an organization with one of
+// the OrganizationRole constants. Members added by someone else are invitations
+// until they accept them; only accepted members share their data.
+type OrganizationMember struct {
+	ID             uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	TenantID       string     `json:"-" gorm:"type:varchar(64);not null;default:default;uniqueIndex:idx_organization_members_tenant_id_organization_id_user_id"`
+	OrganizationID uint       `json:"organization_id" gorm:"not null;uniqueIndex:idx_organization_members_tenant_id_organization_id_user_id"`
+	UserID         string     `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_organization_members_tenant_id_organization_id_user_id;index"`
+	Role           string     `json:"role" gorm:"type:varchar(16);not null;default:member"`
+	AcceptedAt     *time.Time `json:"accepted_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// Accepted reports whether the user has accepted the membership.
+func (m *OrganizationMember) Accepted() bool {
+	return m.AcceptedAt != nil
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// PriceChange schedules a new price of a subscription, charged from the month of
+// EffectiveDate on. Announced price increases are recorded this way so forecasts
+// include them before they happen.
+type PriceChange struct {
+	ID             uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	TenantID       string    `json:"-" gorm:"type:varchar(64);not null;default:default;index"`
+	SubscriptionID uint      `json:"subscription_id" gorm:"not null;index"`
+	EffectiveDate  time.Time `json:"effective_date" gorm:"type:timestamp;not null"`
+	Price          int64     `json:"price" gorm:"not null"`
+	CreatedAt      time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
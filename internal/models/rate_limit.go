@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// RateLimitBucket is the token bucket of a rate limited client, shared by every
+// replica of the service. Version is incremented by every update, so concurrent
+// requests of a client cannot spend the same token twice.
+type RateLimitBucket struct {
+	Key        string    `json:"key" gorm:"type:varchar(255);primaryKey"`
+	Tokens     float64   `json:"tokens" gorm:"not null"`
+	RefilledAt time.Time `json:"refilled_at" gorm:"not null;index"`
+	Version    int64     `json:"version" gorm:"not null;default:0"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// ReceiptRule describes how to read receipts sent from one domain. Rules for a
+// domain are tried in descending priority before the generic extractor.
+type ReceiptRule struct {
+	ID             uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	TenantID       string    `json:"-" gorm:"type:varchar(64);not null;default:default;index"`
+	SenderDomain   string    `json:"sender_domain" gorm:"type:varchar(255);not null;index"`
+	ServiceName    string    `json:"service_name" gorm:"type:varchar(255)"`
+	SubjectPattern string    `json:"subject_pattern" gorm:"type:text"`
+	PricePattern   string    `json:"price_pattern" gorm:"type:text;not null"`
+	Currency       string    `json:"currency" gorm:"type:varchar(3)"`
+	DatePattern    string    `json:"date_pattern" gorm:"type:text"`
+	DateLayout     string    `json:"date_layout" gorm:"type:varchar(64)"`
+	BillingCycle   string    `json:"billing_cycle" gorm:"type:varchar(16)"`
+	Priority       int       `json:"priority" gorm:"not null;default:0"`
+	CreatedAt      time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
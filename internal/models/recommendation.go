@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+const (
+	RecommendationAnnualBilling = "annual_billing"
+	RecommendationCancelUnused  = "cancel_unused"
+	RecommendationConsolidate   = "consolidate_duplicates"
+	RecommendationFamilyPlan    = "family_plan"
+
+	RecommendationStatusDismissed = "dismissed"
+	RecommendationStatusSnoozed   = "snoozed"
+)
+
+// RecommendationState remembers that a user dismissed a recommendation or
+// snoozed it until a date. Recommendations are computed on request and
+// identified by a key derived from the subscriptions they are about.
+type RecommendationState struct {
+	ID           uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	TenantID     string     `json:"-" gorm:"type:varchar(64);not null;default:default;uniqueIndex:idx_recommendation_states_tenant_id_user_id_key"`
+	UserID       string     `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_recommendation_states_tenant_id_user_id_key"`
+	Key          string     `json:"key" gorm:"type:varchar(128);not null;uniqueIndex:idx_recommendation_states_tenant_id_user_id_key"`
+	Status       string     `json:"status" gorm:"type:varchar(16);not null"`
+	SnoozedUntil *time.Time `json:"snoozed_until,omitempty" gorm:"type:timestamp;default:null"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// RoleAssignment grants a user one of the roles defined in pkg/auth within a
+// tenant. Users hold the roles of their assignments in addition to the roles of
+// their token.
+type RoleAssignment struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	TenantID  string    `json:"-" gorm:"type:varchar(64);not null;default:default;uniqueIndex:idx_role_assignments_tenant_id_user_id_role"`
+	UserID    string    `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_role_assignments_tenant_id_user_id_role"`
+	Role      string    `json:"role" gorm:"type:varchar(16);not null;uniqueIndex:idx_role_assignments_tenant_id_user_id_role"`
+	CreatedBy string    `json:"created_by,omitempty" gorm:"type:varchar(255)"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Session is a browser login created by the OpenID Connect flow. The session
+// cookie holds a random token of which only the SHA-256 hash is stored. UserID is
+// the "sub" claim of the ID token and Roles are the known roles of its "roles"
+// claim.
+type Session struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	TenantID  string    `json:"-" gorm:"type:varchar(64);not null;default:default;index"`
+	TokenHash string    `json:"-" gorm:"type:varchar(64);not null;uniqueIndex"`
+	UserID    string    `json:"user_id" gorm:"type:varchar(255);not null"`
+	Roles     []string  `json:"roles" gorm:"type:text;serializer:json"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+const (
+	SplitEqual      = "equal"
+	SplitPercentage = "percentage"
+	SplitFixed      = "fixed"
+)
+
+// SubscriptionMember is a user sharing the cost of a subscription. Share is the
+// percentage or the fixed amount requested for the member, depending on the split
+// rule of the subscription; Amount is the resulting part of Price the member pays.
+// The owner of a shared subscription is a member too and pays the remainder.
+type SubscriptionMember struct {
+	ID             uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	TenantID       string    `json:"-" gorm:"type:varchar(64);not null;default:default;uniqueIndex:idx_subscription_members_tenant_id_subscription_id_user_id"`
+	SubscriptionID uint      `json:"subscription_id" gorm:"not null;uniqueIndex:idx_subscription_members_tenant_id_subscription_id_user_id"`
+	UserID         string    `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_subscription_members_tenant_id_subscription_id_user_id;index"`
+	Share          int64     `json:"share" gorm:"not null;default:0"`
+	Amount         int64     `json:"amount" gorm:"not null"`
+	CreatedAt      time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
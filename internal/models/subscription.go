@@ -1,16 +1,133 @@
 package models
 
 import (
+	"math"
 	"time"
 )
 
+const (
+	BillingCycleMonthly   = "monthly"
+	BillingCycleQuarterly = "quarterly"
+	BillingCycleYearly    = "yearly"
+
+	// DefaultCurrency is assumed for subscriptions created without an explicit currency.
+	DefaultCurrency = "RUB"
+)
+
+const (
+	UsageDaily   = "daily"
+	UsageWeekly  = "weekly"
+	UsageMonthly = "monthly"
+	UsageRarely  = "rarely"
+	UsageNever   = "never"
+)
+
 type Subscription struct {
-	ID          uint       `json:"id" gorm:"primaryKey;autoIncrement"`
-	ServiceName string     `json:"service_name" gorm:"type:varchar(255);not null;index"`
-	Price       int64      `json:"price" gorm:"not null"`
-	UserID      string     `json:"user_id" gorm:"type:uuid;not null;index"`
-	StartDate   time.Time  `json:"start_date" gorm:"type:timestamp;not null;index"`
-	EndDate     *time.Time `json:"end_date,omitempty" gorm:"type:timestamp;default:null"`
-	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt   time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+	ID           uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	TenantID     string     `json:"-" gorm:"type:varchar(64);not null;default:default;index"`
+	ServiceName  string     `json:"service_name" gorm:"type:varchar(255);not null;index"`
+	ServiceID    *uint      `json:"service_id,omitempty" gorm:"index"`
+	PlanID       *uint      `json:"plan_id,omitempty" gorm:"index"`
+	Price        int64      `json:"price" gorm:"not null"`
+	Currency     string     `json:"currency" gorm:"type:varchar(3);not null;default:RUB"`
+	UserID       string     `json:"user_id" gorm:"type:uuid;not null;index"`
+	BillingCycle string     `json:"billing_cycle" gorm:"type:varchar(16);not null;default:monthly"`
+	StartDate    time.Time  `json:"start_date" gorm:"type:timestamp;not null;index"`
+	EndDate      *time.Time `json:"end_date,omitempty" gorm:"type:timestamp;default:null"`
+	// TrialEndDate is the month of the first paid charge of a subscription that
+	// starts with a free trial; earlier charges cost nothing.
+	TrialEndDate *time.Time `json:"trial_end_date,omitempty" gorm:"type:timestamp;default:null"`
+	Category     string     `json:"category,omitempty" gorm:"type:varchar(64);index"`
+	Notes        string     `json:"notes,omitempty" gorm:"type:text"`
+	Tags         []Tag      `json:"tags,omitempty" gorm:"many2many:subscription_tags;constraint:OnDelete:CASCADE"`
+	// SplitRule is set for subscriptions shared between Members, see the Split constants.
+	SplitRule string               `json:"split_rule,omitempty" gorm:"type:varchar(16)"`
+	Members   []SubscriptionMember `json:"members,omitempty" gorm:"constraint:OnDelete:CASCADE"`
+	// Usage is how often the user reports using the subscription, see the Usage constants.
+	Usage           string     `json:"usage,omitempty" gorm:"type:varchar(16)"`
+	UsageReportedAt *time.Time `json:"usage_reported_at,omitempty" gorm:"type:timestamp;default:null"`
+	CreatedAt       time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt       time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// CycleMonths returns the number of months between two charges of the subscription.
+func (s *Subscription) CycleMonths() int {
+	switch s.BillingCycle {
+	case BillingCycleQuarterly:
+		return 3
+	case BillingCycleYearly:
+		return 12
+	default:
+		return 1
+	}
+}
+
+// BillingDates returns the dates in [from, to) on which the subscription is charged.
+// The first charge happens on StartDate and repeats every billing cycle up to and
+// including the EndDate month.
+func (s *Subscription) BillingDates(from, to time.Time) []time.Time {
+	var dates []time.Time
+	cycle := s.CycleMonths()
+
+	// Skip the cycles that end before the window starts
+	first := 0
+	if elapsed := monthsBetween(s.StartDate, from); elapsed > 0 {
+		first = elapsed / cycle
+	}
+
+	for k := first; ; k++ {
+		date := s.StartDate.AddDate(0, k*cycle, 0)
+		if !date.Before(to) || (s.EndDate != nil && date.After(*s.EndDate)) {
+			break
+		}
+		if !date.Before(from) {
+			dates = append(dates, date)
+		}
+	}
+
+	return dates
+}
+
+// InTrial reports whether a charge on the given date falls into the free trial.
+func (s *Subscription) InTrial(date time.Time) bool {
+	return s.TrialEndDate != nil && monthsBetween(date, *s.TrialEndDate) > 0
+}
+
+// MonthlyPrice is the price spread evenly over the months of a billing cycle.
+func (s *Subscription) MonthlyPrice() int64 {
+	return int64(math.Round(float64(s.Price) / float64(s.CycleMonths())))
+}
+
+// AmountFor is the part of the price the user pays: their member amount when the
+// subscription is shared with them and the full price otherwise, like the total cost.
+func (s *Subscription) AmountFor(userID string) int64 {
+	var amount int64
+	shared := false
+	for _, member := range s.Members {
+		if member.UserID == userID {
+			amount += member.Amount
+			shared = true
+		}
+	}
+	if !shared {
+		return s.Price
+	}
+	return amount
+}
+
+// MonthlyAmountFor is AmountFor spread evenly over the months of a billing cycle.
+func (s *Subscription) MonthlyAmountFor(userID string) int64 {
+	return int64(math.Round(float64(s.AmountFor(userID)) / float64(s.CycleMonths())))
+}
+
+// ActiveIn reports whether the subscription runs during the month of the given date.
+func (s *Subscription) ActiveIn(month time.Time) bool {
+	if monthsBetween(s.StartDate, month) < 0 {
+		return false
+	}
+	return s.EndDate == nil || monthsBetween(month, *s.EndDate) >= 0
+}
+
+func monthsBetween(from, to time.Time) int {
+	return (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())
 }
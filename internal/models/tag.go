@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// Tag is a free-form label a user puts on subscriptions, e.g. a project or a
+// household member. Tag names are unique per user and stored lowercase.
+type Tag struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	TenantID  string    `json:"-" gorm:"type:varchar(64);not null;default:default;uniqueIndex:idx_tags_tenant_id_user_id_name"`
+	UserID    string    `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_tags_tenant_id_user_id_name"`
+	Name      string    `json:"name" gorm:"type:varchar(64);not null;uniqueIndex:idx_tags_tenant_id_user_id_name"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/rasadov/subscription-manager/internal/models"
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key *models.APIKey) error
+	GetAPIKey(ctx context.Context, id int) (*models.APIKey, error)
+	// GetAPIKeyBySecretHash finds the key with the secret hash. It is scoped to
+	// the tenant of ctx like every query.
+	GetAPIKeyBySecretHash(ctx context.Context, hash string) (*models.APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]*models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id int, at time.Time) error
+	TouchAPIKey(ctx context.Context, id uint, at time.Time) error
+}
+
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+func (r *apiKeyRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	return r.db.WithContext(ctx).Create(key).Error
+}
+
+func (r *apiKeyRepository) GetAPIKey(ctx context.Context, id int) (*models.APIKey, error) {
+	return r.findAPIKey(ctx, "id = ?", id)
+}
+
+func (r *apiKeyRepository) GetAPIKeyBySecretHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	return r.findAPIKey(ctx, "secret_hash = ?", hash)
+}
+
+func (r *apiKeyRepository) findAPIKey(ctx context.Context, query string, args ...any) (*models.APIKey, error) {
+	var key models.APIKey
+
+	res := r.db.WithContext(ctx).Where(query, args...).Limit(1).Find(&key)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &key, nil
+}
+
+func (r *apiKeyRepository) ListAPIKeys(ctx context.Context) ([]*models.APIKey, error) {
+	var keys []*models.APIKey
+	if err := r.db.WithContext(ctx).Order("id").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey marks the key as revoked. Revoking a revoked key keeps the time it
+// was first revoked.
+func (r *apiKeyRepository) RevokeAPIKey(ctx context.Context, id int, at time.Time) error {
+	if _, err := r.GetAPIKey(ctx, id); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Model(&models.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", at).Error
+}
+
+func (r *apiKeyRepository) TouchAPIKey(ctx context.Context, id uint, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.APIKey{}).
+		Where("id = ?", id).
+		UpdateColumn("last_used_at", at).Error
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/rasadov/subscription-manager/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BudgetRepository interface {
+	CreateBudget(ctx context.Context, budget *models.Budget) error
+	GetBudget(ctx context.Context, id int) (*models.Budget, error)
+	UpdateBudget(ctx context.Context, budget *models.Budget) error
+	DeleteBudget(ctx context.Context, id int) error
+	ListBudgets(ctx context.Context, userID *string) ([]*models.Budget, error)
+	CreateAlert(ctx context.Context, alert *models.BudgetAlert) (bool, error)
+	ListAlerts(ctx context.Context, userID string) ([]*models.BudgetAlert, error)
+}
+
+type budgetRepository struct {
+	db *gorm.DB
+}
+
+func NewBudgetRepository(db *gorm.DB) BudgetRepository {
+	return &budgetRepository{db: db}
+}
+
+func (r *budgetRepository) CreateBudget(ctx context.Context, budget *models.Budget) error {
+	return r.db.WithContext(ctx).Create(budget).Error
+}
+
+func (r *budgetRepository) GetBudget(ctx context.Context, id int) (*models.Budget, error) {
+	var budget models.Budget
+
+	res := r.db.WithContext(ctx).Find(&budget, id)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &budget, nil
+}
+
+func (r *budgetRepository) UpdateBudget(ctx context.Context, budget *models.Budget) error {
+	return r.db.WithContext(ctx).Save(budget).Error
+}
+
+// DeleteBudget removes the budget together with its alerts.
+func (r *budgetRepository) DeleteBudget(ctx context.Context, id int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("budget_id = ?", id).Delete(&models.BudgetAlert{}).Error; err != nil {
+			return err
+		}
+
+		res := tx.Delete(&models.Budget{}, id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return nil
+	})
+}
+
+// ListBudgets returns the budgets of a user, or of every user when userID is nil.
+func (r *budgetRepository) ListBudgets(ctx context.Context, userID *string) ([]*models.Budget, error) {
+	var budgets []*models.Budget
+
+	db := r.db.WithContext(ctx)
+	if userID != nil {
+		db = db.Where("user_id = ?", *userID)
+	}
+
+	if err := db.Order("tenant_id, user_id, id").Find(&budgets).Error; err != nil {
+		return nil, err
+	}
+
+	return budgets, nil
+}
+
+// CreateAlert stores the alert unless the threshold already alerted for the budget
+// and month, and reports whether it was created.
+func (r *budgetRepository) CreateAlert(ctx context.Context, alert *models.BudgetAlert) (bool, error) {
+	res := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(alert)
+	if res.Error != nil {
+		return false, res.Error
+	}
+
+	return res.RowsAffected > 0, nil
+}
+
+func (r *budgetRepository) ListAlerts(ctx context.Context, userID string) ([]*models.BudgetAlert, error) {
+	var alerts []*models.BudgetAlert
+
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at desc, id desc").
+		Find(&alerts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return alerts, nil
+}
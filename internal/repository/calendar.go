@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/rasadov/subscription-manager/internal/models"
+	"gorm.io/gorm"
+)
+
+type CalendarTokenRepository interface {
+	GetCalendarToken(ctx context.Context, userID string) (*models.CalendarToken, error)
+	// GetCalendarTokenByHash looks a token up by the hash of its secret, which is
+	// unique across tenants unlike the user ID.
+	GetCalendarTokenByHash(ctx context.Context, tokenHash string) (*models.CalendarToken, error)
+	SaveCalendarToken(ctx context.Context, token *models.CalendarToken) error
+}
+
+type calendarTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewCalendarTokenRepository(db *gorm.DB) CalendarTokenRepository {
+	return &calendarTokenRepository{db: db}
+}
+
+func (r *calendarTokenRepository) GetCalendarToken(ctx context.Context, userID string) (*models.CalendarToken, error) {
+	var token models.CalendarToken
+
+	res := r.db.WithContext(ctx).Where("user_id = ?", userID).Limit(1).Find(&token)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &token, nil
+}
+
+func (r *calendarTokenRepository) GetCalendarTokenByHash(ctx context.Context, tokenHash string) (*models.CalendarToken, error) {
+	var stored models.CalendarToken
+
+	res := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).Limit(1).Find(&stored)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &stored, nil
+}
+
+func (r *calendarTokenRepository) SaveCalendarToken(ctx context.Context, token *models.CalendarToken) error {
+	return r.db.WithContext(ctx).Save(token).Error
+}
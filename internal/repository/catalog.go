@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/rasadov/subscription-manager/internal/models"
+	"gorm.io/gorm"
+)
+
+type CatalogRepository interface {
+	CreateService(ctx context.Context, service *models.Service) error
+	GetService(ctx context.Context, id int) (*models.Service, error)
+	UpdateService(ctx context.Context, service *models.Service) error
+	DeleteService(ctx context.Context, id int) error
+	ListServices(ctx context.Context, category *string) ([]*models.Service, error)
+	CreatePlan(ctx context.Context, plan *models.Plan) error
+	GetPlan(ctx context.Context, id int) (*models.Plan, error)
+	UpdatePlan(ctx context.Context, plan *models.Plan) error
+	DeletePlan(ctx context.Context, id int) error
+}
+
+type catalogRepository struct {
+	db *gorm.DB
+}
+
+func NewCatalogRepository(db *gorm.DB) CatalogRepository {
+	return &catalogRepository{db: db}
+}
+
+// CreateService stores the service together with its plans.
+func (r *catalogRepository) CreateService(ctx context.Context, service *models.Service) error {
+	return r.db.WithContext(ctx).Create(service).Error
+}
+
+func (r *catalogRepository) GetService(ctx context.Context, id int) (*models.Service, error) {
+	var service models.Service
+
+	res := r.db.WithContext(ctx).Preload("Plans", orderPlans).Find(&service, id)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &service, nil
+}
+
+// UpdateService saves the service fields only, plans are managed separately.
+func (r *catalogRepository) UpdateService(ctx context.Context, service *models.Service) error {
+	return r.db.WithContext(ctx).Omit("Plans").Save(service).Error
+}
+
+// DeleteService removes the service and its plans. Subscriptions that referenced
+// them keep their name and become custom subscriptions.
+func (r *catalogRepository) DeleteService(ctx context.Context, id int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.Subscription{}).
+			Where("service_id = ?", id).
+			Updates(map[string]any{"service_id": nil, "plan_id": nil}).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("service_id = ?", id).Delete(&models.Plan{}).Error; err != nil {
+			return err
+		}
+
+		res := tx.Delete(&models.Service{}, id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return nil
+	})
+}
+
+func (r *catalogRepository) ListServices(ctx context.Context, category *string) ([]*models.Service, error) {
+	var services []*models.Service
+
+	db := r.db.WithContext(ctx).Preload("Plans", orderPlans)
+	if category != nil {
+		db = db.Where("category = ?", *category)
+	}
+
+	if err := db.Order("name").Find(&services).Error; err != nil {
+		return nil, err
+	}
+
+	return services, nil
+}
+
+func (r *catalogRepository) CreatePlan(ctx context.Context, plan *models.Plan) error {
+	return r.db.WithContext(ctx).Create(plan).Error
+}
+
+func (r *catalogRepository) GetPlan(ctx context.Context, id int) (*models.Plan, error) {
+	var plan models.Plan
+
+	res := r.db.WithContext(ctx).Find(&plan, id)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &plan, nil
+}
+
+func (r *catalogRepository) UpdatePlan(ctx context.Context, plan *models.Plan) error {
+	return r.db.WithContext(ctx).Save(plan).Error
+}
+
+// DeletePlan removes the plan; subscriptions on it stay linked to the service.
+func (r *catalogRepository) DeletePlan(ctx context.Context, id int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.Subscription{}).Where("plan_id = ?", id).Update("plan_id", nil).Error
+		if err != nil {
+			return err
+		}
+
+		res := tx.Delete(&models.Plan{}, id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return nil
+	})
+}
+
+func orderPlans(db *gorm.DB) *gorm.DB {
+	return db.Order("price, id")
+}
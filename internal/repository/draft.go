@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/rasadov/subscription-manager/internal/models"
+	"gorm.io/gorm"
+)
+
+type DraftRepository interface {
+	SaveDraft(ctx context.Context, draft *models.SubscriptionDraft) error
+	GetDraft(ctx context.Context, id int) (*models.SubscriptionDraft, error)
+	FindPendingDraft(ctx context.Context, userID, source, serviceName string) (*models.SubscriptionDraft, error)
+	ListDrafts(ctx context.Context, userID string, status *string) ([]*models.SubscriptionDraft, error)
+}
+
+type draftRepository struct {
+	db *gorm.DB
+}
+
+func NewDraftRepository(db *gorm.DB) DraftRepository {
+	return &draftRepository{db: db}
+}
+
+func (r *draftRepository) SaveDraft(ctx context.Context, draft *models.SubscriptionDraft) error {
+	return r.db.WithContext(ctx).Save(draft).Error
+}
+
+func (r *draftRepository) GetDraft(ctx context.Context, id int) (*models.SubscriptionDraft, error) {
+	var draft models.SubscriptionDraft
+
+	res := r.db.WithContext(ctx).Find(&draft, id)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &draft, nil
+}
+
+func (r *draftRepository) FindPendingDraft(ctx context.Context, userID, source, serviceName string) (*models.SubscriptionDraft, error) {
+	var draft models.SubscriptionDraft
+
+	res := r.db.WithContext(ctx).
+		Where("user_id = ? AND source = ? AND service_name = ? AND status = ?", userID, source, serviceName, models.DraftStatusPending).
+		Limit(1).
+		Find(&draft)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &draft, nil
+}
+
+func (r *draftRepository) ListDrafts(ctx context.Context, userID string, status *string) ([]*models.SubscriptionDraft, error) {
+	var drafts []*models.SubscriptionDraft
+
+	db := r.db.WithContext(ctx).Where("user_id = ?", userID)
+	if status != nil {
+		db = db.Where("status = ?", *status)
+	}
+
+	if err := db.Order("confidence desc, id").Find(&drafts).Error; err != nil {
+		return nil, err
+	}
+
+	return drafts, nil
+}
@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rasadov/subscription-manager/pkg/filter"
+)
+
+var (
+	ErrUnknownFilterField = errors.New("unknown filter field")
+	ErrInvalidFilter      = errors.New("invalid filter")
+)
+
+type filterKind int
+
+const (
+	filterInteger filterKind = iota
+	filterDecimal
+	filterString
+	filterDate
+)
+
+func (k filterKind) String() string {
+	switch k {
+	case filterInteger:
+		return "an integer"
+	case filterDecimal:
+		return "a number"
+	case filterDate:
+		return "a date"
+	}
+	return "a string"
+}
+
+// filterColumn maps a filter field to the SQL expression it compares.
+type filterColumn struct {
+	column   string
+	kind     filterKind
+	nullable bool
+}
+
+// subscriptionFilterColumns is the whitelist of fields filter expressions can
+// refer to. Only these expressions ever reach WHERE, values are always bound.
+// Optional strings are stored empty when missing and compared as NULL.
+var subscriptionFilterColumns = map[string]filterColumn{
+	"id":             {column: "id", kind: filterInteger},
+	"service_name":   {column: "service_name", kind: filterString},
+	"service_id":     {column: "service_id", kind: filterInteger, nullable: true},
+	"plan_id":        {column: "plan_id", kind: filterInteger, nullable: true},
+	"price":          {column: "price", kind: filterInteger},
+	"monthly_cost":   {column: monthlyCostColumn, kind: filterDecimal},
+	"currency":       {column: "currency", kind: filterString},
+	"user_id":        {column: "user_id", kind: filterString},
+	"billing_cycle":  {column: "billing_cycle", kind: filterString},
+	"start_date":     {column: "start_date", kind: filterDate},
+	"end_date":       {column: "end_date", kind: filterDate, nullable: true},
+	"trial_end_date": {column: "trial_end_date", kind: filterDate, nullable: true},
+	"category":       {column: "NULLIF(category, '')", kind: filterString, nullable: true},
+	"usage":          {column: "NULLIF(usage, '')", kind: filterString, nullable: true},
+	"split_rule":     {column: "NULLIF(split_rule, '')", kind: filterString, nullable: true},
+	"created_at":     {column: "created_at", kind: filterDate},
+	"updated_at":     {column: "updated_at", kind: filterDate},
+}
+
+// FilterableSubscriptionFields lists the fields filter expressions can refer to.
+func FilterableSubscriptionFields() []string {
+	return slices.Sorted(maps.Keys(subscriptionFilterColumns))
+}
+
+// FilterExpression is a filter expression translated to a parameterized SQL condition.
+type FilterExpression struct {
+	sql  string
+	args []interface{}
+}
+
+// NewFilterExpression translates a parsed filter expression, checking its fields
+// against the whitelist and its values against the types of the fields. Dates are
+// written as MM-YYYY or YYYY-MM-DD.
+func NewFilterExpression(node filter.Node) (*FilterExpression, error) {
+	expression := &FilterExpression{}
+	sql, err := expression.translate(node)
+	if err != nil {
+		return nil, err
+	}
+	expression.sql = sql
+	return expression, nil
+}
+
+func (e *FilterExpression) translate(node filter.Node) (string, error) {
+	switch node := node.(type) {
+	case filter.And:
+		return e.binary(node.Left, node.Right, " AND ")
+	case filter.Or:
+		return e.binary(node.Left, node.Right, " OR ")
+	case filter.Not:
+		operand, err := e.translate(node.Operand)
+		if err != nil {
+			return "", err
+		}
+		return "NOT " + operand, nil
+	case filter.Condition:
+		return e.condition(node)
+	}
+	return "", fmt.Errorf("%w: unsupported node %T", ErrInvalidFilter, node)
+}
+
+func (e *FilterExpression) binary(left, right filter.Node, operator string) (string, error) {
+	leftSQL, err := e.translate(left)
+	if err != nil {
+		return "", err
+	}
+	rightSQL, err := e.translate(right)
+	if err != nil {
+		return "", err
+	}
+	return "(" + leftSQL + operator + rightSQL + ")", nil
+}
+
+func (e *FilterExpression) condition(condition filter.Condition) (string, error) {
+	column, ok := subscriptionFilterColumns[condition.Field]
+	if !ok {
+		return "", fmt.Errorf("%w %q, expected one of %s",
+			ErrUnknownFilterField, condition.Field, strings.Join(FilterableSubscriptionFields(), ", "))
+	}
+
+	switch condition.Op {
+	case filter.OpIsNull, filter.OpIsNotNull:
+		if !column.nullable {
+			return "", fmt.Errorf("%w: %s is never null", ErrInvalidFilter, condition.Field)
+		}
+		if condition.Op == filter.OpIsNotNull {
+			return column.column + " IS NOT NULL", nil
+		}
+		return column.column + " IS NULL", nil
+
+	case filter.OpLike, filter.OpNotLike, filter.OpILike, filter.OpNotILike:
+		if column.kind != filterString {
+			return "", fmt.Errorf("%w: %s cannot be matched with %s", ErrInvalidFilter, condition.Field, condition.Op)
+		}
+		sql := column.column + " LIKE ?"
+		if condition.Op == filter.OpILike || condition.Op == filter.OpNotILike {
+			sql = "LOWER(" + column.column + ") LIKE LOWER(?)"
+		}
+		if condition.Op == filter.OpNotLike || condition.Op == filter.OpNotILike {
+			sql = "NOT " + sql
+		}
+		e.args = append(e.args, condition.Values[0].Text)
+		return "(" + sql + ")", nil
+
+	case filter.OpIn, filter.OpNotIn:
+		values := make([]interface{}, 0, len(condition.Values))
+		for _, value := range condition.Values {
+			parsed, err := filterValue(condition.Field, column.kind, value)
+			if err != nil {
+				return "", err
+			}
+			values = append(values, parsed)
+		}
+		e.args = append(e.args, values)
+		if condition.Op == filter.OpNotIn {
+			return column.column + " NOT IN ?", nil
+		}
+		return column.column + " IN ?", nil
+	}
+
+	value, err := filterValue(condition.Field, column.kind, condition.Values[0])
+	if err != nil {
+		return "", err
+	}
+	operator := condition.Op
+	if operator == filter.OpNotEqual {
+		operator = "<>"
+	}
+	e.args = append(e.args, value)
+	return column.column + " " + operator + " ?", nil
+}
+
+// filterValue parses a literal into the Go value bound for a column of the kind.
+func filterValue(field string, kind filterKind, value filter.Value) (interface{}, error) {
+	invalid := fmt.Errorf("%w: %s expects %s, got %q", ErrInvalidFilter, field, kind, value.Text)
+	switch kind {
+	case filterInteger:
+		if value.String {
+			return nil, invalid
+		}
+		parsed, err := strconv.ParseInt(value.Text, 10, 64)
+		if err != nil {
+			return nil, invalid
+		}
+		return parsed, nil
+	case filterDecimal:
+		if value.String {
+			return nil, invalid
+		}
+		parsed, err := strconv.ParseFloat(value.Text, 64)
+		if err != nil {
+			return nil, invalid
+		}
+		return parsed, nil
+	case filterDate:
+		if !value.String {
+			return nil, invalid
+		}
+		for _, layout := range []string{"01-2006", time.DateOnly} {
+			if parsed, err := time.Parse(layout, value.Text); err == nil {
+				return parsed, nil
+			}
+		}
+		return nil, invalid
+	}
+	if !value.String {
+		return nil, invalid
+	}
+	return value.Text, nil
+}
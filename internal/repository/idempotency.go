@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/rasadov/subscription-manager/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyRepository interface {
+	// CreateIdempotencyRecord stores the record unless the caller already has a
+	// record with its key, and reports whether it was stored.
+	CreateIdempotencyRecord(ctx context.Context, record *models.IdempotencyRecord) (bool, error)
+	GetIdempotencyRecord(ctx context.Context, subject, key string) (*models.IdempotencyRecord, error)
+	// CompleteIdempotencyRecord stores the response set on the record.
+	CompleteIdempotencyRecord(ctx context.Context, record *models.IdempotencyRecord) error
+	DeleteIdempotencyRecord(ctx context.Context, id uint) error
+	// DeleteExpiredIdempotencyRecords removes the records that expired before the
+	// time and returns how many were removed.
+	DeleteExpiredIdempotencyRecords(ctx context.Context, before time.Time) (int64, error)
+}
+
+type idempotencyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &idempotencyRepository{db: db}
+}
+
+func (r *idempotencyRepository) CreateIdempotencyRecord(ctx context.Context, record *models.IdempotencyRecord) (bool, error) {
+	res := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *idempotencyRepository) GetIdempotencyRecord(ctx context.Context, subject, key string) (*models.IdempotencyRecord, error) {
+	var record models.IdempotencyRecord
+
+	res := r.db.WithContext(ctx).Where("subject = ? AND key = ?", subject, key).Limit(1).Find(&record)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &record, nil
+}
+
+func (r *idempotencyRepository) CompleteIdempotencyRecord(ctx context.Context, record *models.IdempotencyRecord) error {
+	return r.db.WithContext(ctx).Model(record).
+		Select("status_code", "content_type", "headers", "body").
+		Updates(record).Error
+}
+
+func (r *idempotencyRepository) DeleteIdempotencyRecord(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&models.IdempotencyRecord{}).Error
+}
+
+func (r *idempotencyRepository) DeleteExpiredIdempotencyRecords(ctx context.Context, before time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).Where("expires_at < ?", before).Delete(&models.IdempotencyRecord{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/rasadov/subscription-manager/internal/models"
+	"gorm.io/gorm"
+)
+
+type OrganizationRepository interface {
+	CreateOrganization(ctx context.Context, organization *models.Organization) error
+	GetOrganization(ctx context.Context, id int) (*models.Organization, error)
+	UpdateOrganization(ctx context.Context, organization *models.Organization) error
+	DeleteOrganization(ctx context.Context, id int) error
+	// ListOrganizations returns every organization, or the ones userID is a member of.
+	ListOrganizations(ctx context.Context, userID *string, kind *string) ([]*models.Organization, error)
+	AddMember(ctx context.Context, member *models.OrganizationMember) error
+	GetMember(ctx context.Context, organizationID int, userID string) (*models.OrganizationMember, error)
+	UpdateMember(ctx context.Context, member *models.OrganizationMember) error
+	RemoveMember(ctx context.Context, organizationID int, userID string) error
+}
+
+type organizationRepository struct {
+	db *gorm.DB
+}
+
+func NewOrganizationRepository(db *gorm.DB) OrganizationRepository {
+	return &organizationRepository{db: db}
+}
+
+// CreateOrganization stores the organization together with its members.
+func (r *organizationRepository) CreateOrganization(ctx context.Context, organization *models.Organization) error {
+	return r.db.WithContext(ctx).Create(organization).Error
+}
+
+func (r *organizationRepository) GetOrganization(ctx context.Context, id int) (*models.Organization, error) {
+	var organization models.Organization
+
+	res := r.db.WithContext(ctx).Preload("Members", orderMembers).Find(&organization, id)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &organization, nil
+}
+
+// UpdateOrganization saves the organization fields only, members are managed separately.
+func (r *organizationRepository) UpdateOrganization(ctx context.Context, organization *models.Organization) error {
+	return r.db.WithContext(ctx).Omit("Members").Save(organization).Error
+}
+
+// DeleteOrganization removes the organization and its memberships; the
+// subscriptions of its members are not affected.
+func (r *organizationRepository) DeleteOrganization(ctx context.Context, id int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("organization_id = ?", id).Delete(&models.OrganizationMember{}).Error; err != nil {
+			return err
+		}
+
+		res := tx.Delete(&models.Organization{}, id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return nil
+	})
+}
+
+func (r *organizationRepository) ListOrganizations(ctx context.Context, userID *string, kind *string) ([]*models.Organization, error) {
+	var organizations []*models.Organization
+
+	db := r.db.WithContext(ctx).Preload("Members", orderMembers)
+	if userID != nil {
+		db = db.Where("id IN (?)", r.db.WithContext(ctx).Model(&models.OrganizationMember{}).Select("organization_id").Where("user_id = ?", *userID))
+	}
+	if kind != nil {
+		db = db.Where("kind = ?", *kind)
+	}
+
+	if err := db.Order("name, id").Find(&organizations).Error; err != nil {
+		return nil, err
+	}
+
+	return organizations, nil
+}
+
+func (r *organizationRepository) AddMember(ctx context.Context, member *models.OrganizationMember) error {
+	return r.db.WithContext(ctx).Create(member).Error
+}
+
+func (r *organizationRepository) GetMember(ctx context.Context, organizationID int, userID string) (*models.OrganizationMember, error) {
+	var member models.OrganizationMember
+
+	res := r.db.WithContext(ctx).
+		Where("organization_id = ? AND user_id = ?", organizationID, userID).
+		Limit(1).
+		Find(&member)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &member, nil
+}
+
+func (r *organizationRepository) UpdateMember(ctx context.Context, member *models.OrganizationMember) error {
+	return r.db.WithContext(ctx).Save(member).Error
+}
+
+func (r *organizationRepository) RemoveMember(ctx context.Context, organizationID int, userID string) error {
+	res := r.db.WithContext(ctx).
+		Where("organization_id = ? AND user_id = ?", organizationID, userID).
+		Delete(&models.OrganizationMember{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func orderMembers(db *gorm.DB) *gorm.DB {
+	return db.Order("created_at, id")
+}
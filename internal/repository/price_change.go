@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/rasadov/subscription-manager/internal/models"
+	"gorm.io/gorm"
+)
+
+type PriceChangeRepository interface {
+	CreatePriceChange(ctx context.Context, change *models.PriceChange) error
+	ListPriceChanges(ctx context.Context, subscriptionIDs []uint) ([]*models.PriceChange, error)
+	DeletePriceChange(ctx context.Context, subscriptionID, id int) error
+}
+
+type priceChangeRepository struct {
+	db *gorm.DB
+}
+
+func NewPriceChangeRepository(db *gorm.DB) PriceChangeRepository {
+	return &priceChangeRepository{db: db}
+}
+
+func (r *priceChangeRepository) CreatePriceChange(ctx context.Context, change *models.PriceChange) error {
+	return r.db.WithContext(ctx).Create(change).Error
+}
+
+// ListPriceChanges returns the price changes of the subscriptions ordered by
+// subscription and effective date.
+func (r *priceChangeRepository) ListPriceChanges(ctx context.Context, subscriptionIDs []uint) ([]*models.PriceChange, error) {
+	var changes []*models.PriceChange
+	if len(subscriptionIDs) == 0 {
+		return changes, nil
+	}
+
+	err := r.db.WithContext(ctx).
+		Where("subscription_id IN ?", subscriptionIDs).
+		Order("subscription_id, effective_date, id").
+		Find(&changes).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+func (r *priceChangeRepository) DeletePriceChange(ctx context.Context, subscriptionID, id int) error {
+	res := r.db.WithContext(ctx).Where("subscription_id = ?", subscriptionID).Delete(&models.PriceChange{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/pkg/ratelimit"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// rateLimitAttempts is how often taking a token is retried when other requests
+// of the client update its bucket at the same time.
+const rateLimitAttempts = 5
+
+// ErrRateLimitContention is returned when a bucket kept changing during every
+// attempt to take a token.
+var ErrRateLimitContention = errors.New("rate limit bucket is contended")
+
+// RateLimitRepository is a ratelimit.Store keeping buckets in the database, so
+// every replica of the service shares them.
+type RateLimitRepository interface {
+	ratelimit.Store
+	// DeleteIdleBuckets removes the buckets last used before the time. Buckets
+	// idle for longer than the longest limit period are full and can be dropped.
+	DeleteIdleBuckets(ctx context.Context, before time.Time) (int64, error)
+}
+
+type rateLimitRepository struct {
+	db *gorm.DB
+}
+
+func NewRateLimitRepository(db *gorm.DB) RateLimitRepository {
+	return &rateLimitRepository{db: db}
+}
+
+// Take updates the bucket with a compare-and-swap on its version instead of
+// locking it, and retries when another request updated it first.
+func (r *rateLimitRepository) Take(ctx context.Context, key string, limit ratelimit.Limit, now time.Time) (ratelimit.Result, error) {
+	db := r.db.WithContext(ctx)
+
+	for range rateLimitAttempts {
+		var stored models.RateLimitBucket
+		res := db.Where("key = ?", key).Limit(1).Find(&stored)
+		if res.Error != nil {
+			return ratelimit.Result{}, res.Error
+		}
+
+		if res.RowsAffected == 0 {
+			var bucket ratelimit.Bucket
+			result := bucket.Take(limit, now)
+			res = db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.RateLimitBucket{
+				Key:        key,
+				Tokens:     bucket.Tokens,
+				RefilledAt: now,
+			})
+			if res.Error != nil {
+				return ratelimit.Result{}, res.Error
+			}
+			if res.RowsAffected == 1 {
+				return result, nil
+			}
+			continue
+		}
+
+		bucket := ratelimit.Bucket{Tokens: stored.Tokens, Updated: stored.RefilledAt}
+		result := bucket.Take(limit, now)
+		res = db.Model(&models.RateLimitBucket{}).
+			Where("key = ? AND version = ?", key, stored.Version).
+			Updates(map[string]any{"tokens": bucket.Tokens, "refilled_at": bucket.Updated, "version": stored.Version + 1})
+		if res.Error != nil {
+			return ratelimit.Result{}, res.Error
+		}
+		if res.RowsAffected == 1 {
+			return result, nil
+		}
+	}
+
+	return ratelimit.Result{}, ErrRateLimitContention
+}
+
+func (r *rateLimitRepository) DeleteIdleBuckets(ctx context.Context, before time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).Where("refilled_at < ?", before).Delete(&models.RateLimitBucket{})
+	return res.RowsAffected, res.Error
+}
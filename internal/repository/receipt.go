@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/rasadov/subscription-manager/internal/models"
+	"gorm.io/gorm"
+)
+
+type ReceiptRuleRepository interface {
+	CreateReceiptRule(ctx context.Context, rule *models.ReceiptRule) error
+	DeleteReceiptRule(ctx context.Context, id int) error
+	ListReceiptRules(ctx context.Context) ([]*models.ReceiptRule, error)
+}
+
+type receiptRuleRepository struct {
+	db *gorm.DB
+}
+
+func NewReceiptRuleRepository(db *gorm.DB) ReceiptRuleRepository {
+	return &receiptRuleRepository{db: db}
+}
+
+func (r *receiptRuleRepository) CreateReceiptRule(ctx context.Context, rule *models.ReceiptRule) error {
+	return r.db.WithContext(ctx).Create(rule).Error
+}
+
+func (r *receiptRuleRepository) DeleteReceiptRule(ctx context.Context, id int) error {
+	res := r.db.WithContext(ctx).Delete(&models.ReceiptRule{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *receiptRuleRepository) ListReceiptRules(ctx context.Context) ([]*models.ReceiptRule, error) {
+	var rules []*models.ReceiptRule
+
+	err := r.db.WithContext(ctx).Order("sender_domain, priority desc, id").Find(&rules).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
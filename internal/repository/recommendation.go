@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/rasadov/subscription-manager/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RecommendationRepository interface {
+	ListStates(ctx context.Context, userID string) ([]*models.RecommendationState, error)
+	SaveState(ctx context.Context, state *models.RecommendationState) error
+}
+
+type recommendationRepository struct {
+	db *gorm.DB
+}
+
+func NewRecommendationRepository(db *gorm.DB) RecommendationRepository {
+	return &recommendationRepository{db: db}
+}
+
+func (r *recommendationRepository) ListStates(ctx context.Context, userID string) ([]*models.RecommendationState, error) {
+	var states []*models.RecommendationState
+
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&states).Error; err != nil {
+		return nil, err
+	}
+
+	return states, nil
+}
+
+// SaveState stores the state of a recommendation, replacing the previous one.
+func (r *recommendationRepository) SaveState(ctx context.Context, state *models.RecommendationState) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant_id"}, {Name: "user_id"}, {Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"status", "snoozed_until", "updated_at"}),
+	}).Create(state).Error
+}
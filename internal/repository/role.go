@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/rasadov/subscription-manager/internal/models"
+	"gorm.io/gorm"
+)
+
+type RoleRepository interface {
+	CreateRoleAssignment(ctx context.Context, assignment *models.RoleAssignment) error
+	GetRoleAssignment(ctx context.Context, id int) (*models.RoleAssignment, error)
+	// ListRoleAssignments returns the assignments of the user, or of every user
+	// when userID is nil.
+	ListRoleAssignments(ctx context.Context, userID *string) ([]*models.RoleAssignment, error)
+	DeleteRoleAssignment(ctx context.Context, id int) error
+}
+
+type roleRepository struct {
+	db *gorm.DB
+}
+
+func NewRoleRepository(db *gorm.DB) RoleRepository {
+	return &roleRepository{db: db}
+}
+
+func (r *roleRepository) CreateRoleAssignment(ctx context.Context, assignment *models.RoleAssignment) error {
+	return r.db.WithContext(ctx).Create(assignment).Error
+}
+
+func (r *roleRepository) GetRoleAssignment(ctx context.Context, id int) (*models.RoleAssignment, error) {
+	var assignment models.RoleAssignment
+
+	res := r.db.WithContext(ctx).Where("id = ?", id).Limit(1).Find(&assignment)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &assignment, nil
+}
+
+func (r *roleRepository) ListRoleAssignments(ctx context.Context, userID *string) ([]*models.RoleAssignment, error) {
+	var assignments []*models.RoleAssignment
+
+	db := r.db.WithContext(ctx)
+	if userID != nil {
+		db = db.Where("user_id = ?", *userID)
+	}
+	if err := db.Order("user_id, role").Find(&assignments).Error; err != nil {
+		return nil, err
+	}
+	return assignments, nil
+}
+
+func (r *roleRepository) DeleteRoleAssignment(ctx context.Context, id int) error {
+	res := r.db.WithContext(ctx).Where("id = ?", id).Delete(&models.RoleAssignment{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/pkg/textmatch"
+	"gorm.io/gorm"
+)
+
+// SubscriptionMatch is a subscription found by a search with its relevance, a
+// score in (0, 1] where 1 is an exact match.
+type SubscriptionMatch struct {
+	Subscription *models.Subscription
+	Score        float64
+}
+
+// Matches in the tags and notes rank below equally good ones in the service name.
+const (
+	searchTagWeight   = 0.9
+	searchNotesWeight = 0.8
+)
+
+const (
+	// searchThreshold is the lowest score of a match when scoring in Go, where an
+	// edit distance of one in a seven letter word scores 0.86.
+	searchThreshold = 0.6
+	// searchTrigramThreshold is the lowest score of a match with pg_trgm, whose
+	// trigram similarities are lower than edit distance based ones.
+	searchTrigramThreshold = 0.3
+)
+
+// searchTrigramScore scores a subscription with pg_trgm word similarity against
+// its service name, tags and notes; notes matching as full text score 1. Its
+// arguments are listed by searchTrigramArgs.
+const searchTrigramScore = `GREATEST(
+	word_similarity(?, service_name),
+	? * COALESCE((SELECT MAX(word_similarity(?, tags.name)) FROM subscription_tags
+		JOIN tags ON tags.id = subscription_tags.tag_id WHERE subscription_tags.subscription_id = subscriptions.id), 0),
+	? * CASE WHEN to_tsvector('simple', COALESCE(notes, '')) @@ plainto_tsquery('simple', ?)
+		THEN 1 ELSE word_similarity(?, COALESCE(notes, '')) END
+)`
+
+func searchTrigramArgs(query string) []interface{} {
+	return []interface{}{query, searchTagWeight, query, searchNotesWeight, query, query}
+}
+
+// SearchSubscriptions returns up to limit subscriptions matching the filter whose
+// service name, tags or notes resemble query, the most relevant first. Postgres
+// with the pg_trgm extension scores with trigrams and full text search, other
+// databases in Go.
+func (s *subscriptionRepository) SearchSubscriptions(ctx context.Context, filter SubscriptionFilter, query string, limit int) ([]*SubscriptionMatch, error) {
+	trigrams, err := s.hasTrigrams(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if trigrams {
+		return s.searchSubscriptionsByTrigrams(ctx, filter, query, limit)
+	}
+	return s.searchSubscriptionsInGo(ctx, filter, query, limit)
+}
+
+// hasTrigrams reports whether the database has the pg_trgm extension, which the
+// migrations create. A missing extension is looked up again on the next search,
+// so it is used as soon as the migration has run.
+func (s *subscriptionRepository) hasTrigrams(ctx context.Context) (bool, error) {
+	if s.db.Dialector.Name() != "postgres" {
+		return false, nil
+	}
+	if s.trigrams.Load() {
+		return true, nil
+	}
+	var installed bool
+	err := s.db.WithContext(ctx).
+		Raw("SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm')").
+		Scan(&installed).Error
+	if err != nil {
+		return false, err
+	}
+	if installed {
+		s.trigrams.Store(true)
+	}
+	return installed, nil
+}
+
+func (s *subscriptionRepository) searchSubscriptionsByTrigrams(ctx context.Context, filter SubscriptionFilter, query string, limit int) ([]*SubscriptionMatch, error) {
+	var scores []struct {
+		ID    uint
+		Score float64
+	}
+	err := applySubscriptionFilter(s.db.WithContext(ctx).Model(&models.Subscription{}), filter).
+		Select("id, "+searchTrigramScore+" AS score", searchTrigramArgs(query)...).
+		Where(searchTrigramScore+" >= ?", append(searchTrigramArgs(query), searchTrigramThreshold)...).
+		Order("score desc, id").
+		Limit(limit).
+		Scan(&scores).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(scores) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint, 0, len(scores))
+	for _, score := range scores {
+		ids = append(ids, score.ID)
+	}
+	var subscriptions []*models.Subscription
+	if err := s.db.WithContext(ctx).Preload("Tags").Preload("Members").Find(&subscriptions, ids).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*models.Subscription, len(subscriptions))
+	for _, subscription := range subscriptions {
+		byID[subscription.ID] = subscription
+	}
+
+	matches := make([]*SubscriptionMatch, 0, len(scores))
+	for _, score := range scores {
+		if subscription, ok := byID[score.ID]; ok {
+			matches = append(matches, &SubscriptionMatch{Subscription: subscription, Score: score.Score})
+		}
+	}
+	return matches, nil
+}
+
+// searchSubscriptionsInGo scores every subscription matching the filter with
+// textmatch, reading them in batches.
+func (s *subscriptionRepository) searchSubscriptionsInGo(ctx context.Context, filter SubscriptionFilter, query string, limit int) ([]*SubscriptionMatch, error) {
+	var matches []*SubscriptionMatch
+	var batch []*models.Subscription
+	err := applySubscriptionFilter(s.db.WithContext(ctx), filter).
+		Preload("Tags").Preload("Members").
+		FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+			for _, subscription := range batch {
+				if score := searchScore(subscription, query); score >= searchThreshold {
+					matches = append(matches, &SubscriptionMatch{Subscription: subscription, Score: score})
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(matches, func(a, b *SubscriptionMatch) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Subscription.ID, b.Subscription.ID)
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+func searchScore(subscription *models.Subscription, query string) float64 {
+	score := textmatch.WordSimilarity(query, subscription.ServiceName)
+	for _, tag := range subscription.Tags {
+		score = max(score, searchTagWeight*textmatch.WordSimilarity(query, tag.Name))
+	}
+	return max(score, searchNotesWeight*textmatch.WordSimilarity(query, subscription.Notes))
+}
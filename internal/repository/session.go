@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/rasadov/subscription-manager/internal/models"
+	"gorm.io/gorm"
+)
+
+type SessionRepository interface {
+	CreateSession(ctx context.Context, session *models.Session) error
+	// GetSessionByTokenHash finds the session with the token hash. It is scoped to
+	// the tenant of ctx like every query.
+	GetSessionByTokenHash(ctx context.Context, hash string) (*models.Session, error)
+	DeleteSession(ctx context.Context, id uint) error
+	// DeleteExpiredSessions removes the sessions that expired before the time and
+	// returns how many were removed.
+	DeleteExpiredSessions(ctx context.Context, before time.Time) (int64, error)
+}
+
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+func (r *sessionRepository) CreateSession(ctx context.Context, session *models.Session) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+func (r *sessionRepository) GetSessionByTokenHash(ctx context.Context, hash string) (*models.Session, error) {
+	var session models.Session
+
+	res := r.db.WithContext(ctx).Where("token_hash = ?", hash).Limit(1).Find(&session)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &session, nil
+}
+
+func (r *sessionRepository) DeleteSession(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&models.Session{}).Error
+}
+
+func (r *sessionRepository) DeleteExpiredSessions(ctx context.Context, before time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).Where("expires_at < ?", before).Delete(&models.Session{})
+	return res.RowsAffected, res.Error
+}
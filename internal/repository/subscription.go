@@ -3,28 +3,177 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/pkg/tenant"
 	"gorm.io/gorm"
 )
 
 type SubscriptionRepository interface {
 	CreateSubscription(ctx context.Context, subscription *models.Subscription) error
 	GetSubscription(ctx context.Context, id int) (*models.Subscription, error)
+	GetSubscriptionByNaturalKey(ctx context.Context, userID, serviceName string, startDate time.Time) (*models.Subscription, error)
 	UpdateSubscription(ctx context.Context, id int, subscription *models.Subscription) error
 	DeleteSubscription(ctx context.Context, id int) error
 	ListSubscriptions(ctx context.Context,
 		page, elements int,
-		userID *string, serviceName *string,
-		startDateFrom *time.Time, startDateTo *time.Time,
-		endDateFrom *time.Time, endDateTo *time.Time,
-		sortBy *string, sortOrder *string) (subscriptions []*models.Subscription, total int64, err error)
-	CalculateTotalCost(ctx context.Context, userID, serviceName string, startDate, endDate *time.Time) (int64, error)
+		filter SubscriptionFilter,
+		sort []SortField) (subscriptions []*models.Subscription, total int64, err error)
+	// ListSubscriptionsPage lists subscriptions with keyset pagination. next and
+	// prev are the cursors of the adjacent pages, nil when there is none.
+	ListSubscriptionsPage(ctx context.Context, filter SubscriptionFilter, page SubscriptionPage) (subscriptions []*models.Subscription, next, prev *Cursor, err error)
+	CountSubscriptions(ctx context.Context, filter SubscriptionFilter) (int64, error)
+	SearchSubscriptions(ctx context.Context, filter SubscriptionFilter, query string, limit int) ([]*SubscriptionMatch, error)
+	StreamSubscriptions(ctx context.Context, filter SubscriptionFilter, fn func(subscription *models.Subscription) error) error
+	CalculateTotalCost(ctx context.Context, filter SubscriptionFilter) (int64, error)
+	CalculateCostByGroup(ctx context.Context, filter SubscriptionFilter, groupBy string) ([]*CostGroup, error)
+	// Transaction runs fn with a repository whose operations share one database
+	// transaction. It is committed when fn returns nil and rolled back otherwise.
+	Transaction(ctx context.Context, fn func(repo SubscriptionRepository) error) error
+}
+
+const (
+	GroupByCategory = "category"
+	GroupByTag      = "tag"
+)
+
+// CostGroup is the cost of the subscriptions sharing a category or tag. Key is
+// nil for subscriptions without category or tags.
+type CostGroup struct {
+	Key       *string
+	TotalCost int64
+}
+
+// SubscriptionFilter holds the optional conditions shared by listing, exporting and cost aggregation.
+type SubscriptionFilter struct {
+	UserID *string
+	// UserIDs selects the subscriptions of several users, e.g. the members of an
+	// organization. A non-nil empty list selects nothing.
+	UserIDs     []string
+	ServiceName *string
+	// ServiceID (or PlanID) replaces the exact ServiceName match with the catalog entry:
+	// it selects subscriptions linked to it and unlinked ones named like one of ServiceNames.
+	ServiceID    *uint
+	PlanID       *uint
+	ServiceNames []string
+	Category     *string
+	// Tags selects subscriptions with any of the tags, or with all of them when MatchAllTags is set.
+	Tags         []string
+	MatchAllTags bool
+	// IncludeShared extends the UserID and UserIDs conditions to subscriptions
+	// shared with the users, not only the ones they own.
+	IncludeShared bool
+	StartDateFrom *time.Time
+	StartDateTo   *time.Time
+	EndDateFrom   *time.Time
+	EndDateTo     *time.Time
+	// Expression is a filter expression combined with the other conditions.
+	Expression *FilterExpression
+}
+
+var (
+	ErrUnknownSortField = errors.New("unknown sort field")
+	ErrCursorSortField  = errors.New("cursor pagination cannot sort by field")
+	ErrInvalidCursor    = errors.New("invalid cursor")
+)
+
+// SortField orders a listing by one of the sortable subscription fields. Listings
+// are sorted by created_at descending without sort fields, and rows with equal
+// values are ordered by id in the direction of the last field.
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// Cursor is the position of a row in a sorted listing: the values of its sort
+// fields and its id, which breaks ties between rows with equal values.
+type Cursor struct {
+	Values []json.RawMessage `json:"v"`
+	ID     uint              `json:"id"`
+}
+
+// SubscriptionPage selects up to Limit subscriptions following After, or
+// preceding Before, in Sort order.
+type SubscriptionPage struct {
+	Sort   []SortField
+	Limit  int
+	After  *Cursor
+	Before *Cursor
+}
+
+// sortColumn maps a sort field to the SQL expression it orders by. Rows are only
+// compared to cursors by columns that are not nullable, which have value and
+// decode to read and parse their cursor values.
+type sortColumn struct {
+	column   string
+	nullable bool
+	value    func(subscription *models.Subscription) interface{}
+	decode   func(raw json.RawMessage) (interface{}, error)
+}
+
+// monthlyCostColumn is the price spread evenly over the months of the billing
+// cycle, which sorting and filter expressions share. It is computed in floating
+// point like monthlyCost, so that rows compare to cursors exactly.
+const monthlyCostColumn = "CASE billing_cycle WHEN 'yearly' THEN CAST(price AS DOUBLE PRECISION) / 12 " +
+	"WHEN 'quarterly' THEN CAST(price AS DOUBLE PRECISION) / 3 ELSE CAST(price AS DOUBLE PRECISION) END"
+
+func monthlyCost(subscription *models.Subscription) float64 {
+	return float64(subscription.Price) / float64(subscription.CycleMonths())
+}
+
+// subscriptionSortColumns is the whitelist of fields listings can be sorted by.
+// Only these expressions ever reach ORDER BY.
+var subscriptionSortColumns = map[string]sortColumn{
+	"id": {column: "id",
+		value: func(s *models.Subscription) interface{} { return s.ID }, decode: decodeCursorValue[uint]},
+	"created_at": {column: "created_at",
+		value: func(s *models.Subscription) interface{} { return s.CreatedAt }, decode: decodeCursorValue[time.Time]},
+	"updated_at": {column: "updated_at",
+		value: func(s *models.Subscription) interface{} { return s.UpdatedAt }, decode: decodeCursorValue[time.Time]},
+	"start_date": {column: "start_date",
+		value: func(s *models.Subscription) interface{} { return s.StartDate }, decode: decodeCursorValue[time.Time]},
+	"end_date":       {column: "end_date", nullable: true},
+	"trial_end_date": {column: "trial_end_date", nullable: true},
+	"price": {column: "price",
+		value: func(s *models.Subscription) interface{} { return s.Price }, decode: decodeCursorValue[int64]},
+	"monthly_cost": {column: monthlyCostColumn,
+		value: func(s *models.Subscription) interface{} { return monthlyCost(s) }, decode: decodeCursorValue[float64]},
+	"service_name": {column: "service_name",
+		value: func(s *models.Subscription) interface{} { return s.ServiceName }, decode: decodeCursorValue[string]},
+	"currency": {column: "currency",
+		value: func(s *models.Subscription) interface{} { return s.Currency }, decode: decodeCursorValue[string]},
+	"billing_cycle": {column: "billing_cycle",
+		value: func(s *models.Subscription) interface{} { return s.BillingCycle }, decode: decodeCursorValue[string]},
+	"category": {column: "category", nullable: true},
+	"user_id": {column: "user_id",
+		value: func(s *models.Subscription) interface{} { return s.UserID }, decode: decodeCursorValue[string]},
+}
+
+// SortableSubscriptionFields lists the fields subscriptions can be sorted by.
+func SortableSubscriptionFields() []string {
+	return slices.Sorted(maps.Keys(subscriptionSortColumns))
+}
+
+func decodeCursorValue[T any](raw json.RawMessage) (interface{}, error) {
+	var value T
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
 }
 
 type subscriptionRepository struct {
 	db *gorm.DB
+	// trigrams is set once the pg_trgm extension is found, see hasTrigrams.
+	trigrams atomic.Bool
 }
 
 func NewSubscriptionRepositiry(db *gorm.DB) SubscriptionRepository {
@@ -32,13 +181,18 @@ func NewSubscriptionRepositiry(db *gorm.DB) SubscriptionRepository {
 }
 
 func (s *subscriptionRepository) CreateSubscription(ctx context.Context, subscription *models.Subscription) error {
-	return s.db.WithContext(ctx).Create(subscription).Error
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := resolveTags(tx, subscription); err != nil {
+			return err
+		}
+		return tx.Create(subscription).Error
+	})
 }
 
 func (s *subscriptionRepository) GetSubscription(ctx context.Context, id int) (*models.Subscription, error) {
 	var subscription models.Subscription
 
-	res := s.db.WithContext(ctx).Find(&subscription, id)
+	res := s.db.WithContext(ctx).Preload("Tags").Preload("Members").Find(&subscription, id)
 	if res.Error != nil {
 		return nil, res.Error
 	}
@@ -50,56 +204,107 @@ func (s *subscriptionRepository) GetSubscription(ctx context.Context, id int) (*
 	return &subscription, nil
 }
 
-func (s *subscriptionRepository) UpdateSubscription(ctx context.Context, id int, subscription *models.Subscription) error {
-	return s.db.WithContext(ctx).Save(subscription).Error
-}
-
-func (s *subscriptionRepository) DeleteSubscription(ctx context.Context, id int) error {
-	return s.db.WithContext(ctx).Delete(&models.Subscription{}, id).Error
-}
-
-func (s *subscriptionRepository) ListSubscriptions(ctx context.Context,
-	page, elements int, userID *string, serviceName *string,
-	startDateFrom *time.Time, startDateTo *time.Time,
-	endDateFrom *time.Time, endDateTo *time.Time,
-	sortBy *string, sortOrder *string) (subscriptions []*models.Subscription, total int64, err error) {
-	db := s.db.WithContext(ctx)
+// GetSubscriptionByNaturalKey finds a subscription by the (user, service, start date)
+// triple that identifies it outside of this system, e.g. in imported spreadsheets.
+func (s *subscriptionRepository) GetSubscriptionByNaturalKey(ctx context.Context, userID, serviceName string, startDate time.Time) (*models.Subscription, error) {
+	var subscription models.Subscription
 
-	if userID != nil {
-		db = db.Where("user_id = ?", *userID)
+	res := s.db.WithContext(ctx).
+		Preload("Tags").
+		Preload("Members").
+		Where("user_id = ? AND service_name = ? AND start_date = ?", userID, serviceName, startDate).
+		Order("id").
+		Limit(1).
+		Find(&subscription)
+	if res.Error != nil {
+		return nil, res.Error
 	}
 
-	if startDateFrom != nil {
-		db = db.Where("start_date >= ?", startDateFrom)
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
 	}
 
-	if startDateTo != nil {
-		db = db.Where("start_date <= ?", startDateTo)
-	}
+	return &subscription, nil
+}
 
-	if endDateFrom != nil {
-		db = db.Where("end_date >= ?", endDateFrom)
-	}
+// UpdateSubscription saves the subscription and replaces its tags and members with
+// subscription.Tags and subscription.Members. It fails with gorm.ErrRecordNotFound
+// when the subscription belongs to another tenant than the one of ctx.
+func (s *subscriptionRepository) UpdateSubscription(ctx context.Context, id int, subscription *models.Subscription) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Tag links are not tenant scoped, so refuse to touch the subscription of
+		// another tenant; a subscription that does not exist at all is created
+		var owner models.Subscription
+		res := tx.WithContext(tenant.WithAllTenants(ctx)).Select("id", "tenant_id").Find(&owner, subscription.ID)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected > 0 && owner.TenantID != tenant.FromContext(ctx) {
+			return gorm.ErrRecordNotFound
+		}
+		if err := resolveTags(tx, subscription); err != nil {
+			return err
+		}
+		if err := tx.Omit("Tags", "Members").Save(subscription).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(subscription).Association("Tags").Replace(subscription.Tags); err != nil {
+			return err
+		}
+		if err := tx.Where("subscription_id = ?", subscription.ID).Delete(&models.SubscriptionMember{}).Error; err != nil {
+			return err
+		}
+		for idx := range subscription.Members {
+			subscription.Members[idx].ID = 0
+			subscription.Members[idx].SubscriptionID = subscription.ID
+		}
+		if len(subscription.Members) == 0 {
+			return nil
+		}
+		return tx.Create(&subscription.Members).Error
+	})
+}
 
-	if endDateTo != nil {
-		db = db.Where("end_date <= ?", endDateTo)
-	}
+// DeleteSubscription removes the subscription with its tag links, members and scheduled price changes.
+func (s *subscriptionRepository) DeleteSubscription(ctx context.Context, id int) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Tag links are not tenant scoped, so make sure the subscription is ours first
+		res := tx.Select("id").Find(&models.Subscription{}, id)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		if err := tx.Where("subscription_id = ?", id).Delete(&models.PriceChange{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("subscription_id = ?", id).Delete(&models.SubscriptionMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Select("Tags").Delete(&models.Subscription{ID: uint(id)}).Error
+	})
+}
+
+func (s *subscriptionRepository) Transaction(ctx context.Context, fn func(repo SubscriptionRepository) error) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&subscriptionRepository{db: tx})
+	})
+}
 
-	if serviceName != nil {
-		db = db.Where("service_name = ?", *serviceName)
+func (s *subscriptionRepository) ListSubscriptions(ctx context.Context,
+	page, elements int, filter SubscriptionFilter,
+	sort []SortField) (subscriptions []*models.Subscription, total int64, err error) {
+	sort, columns, err := sortColumns(sort, false)
+	if err != nil {
+		return nil, 0, err
 	}
 
+	db := applySubscriptionFilter(s.db.WithContext(ctx), filter)
+
 	// Count total records
 	if err := db.Model(&models.Subscription{}).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
-	// Sorting
-	if sortBy != nil && sortOrder != nil {
-		db = db.Order(*sortBy + " " + *sortOrder)
-	} else {
-		db = db.Order("created_at desc")
-	}
+	db = orderBy(db, columns, sort, false)
 
 	// Pagination
 	limit := 10
@@ -112,33 +317,194 @@ func (s *subscriptionRepository) ListSubscriptions(ctx context.Context,
 	offset := (page - 1) * limit
 	db = db.Limit(limit).Offset(offset)
 
-	if err := db.Find(&subscriptions).Error; err != nil {
+	if err := db.Preload("Tags").Preload("Members").Find(&subscriptions).Error; err != nil {
 		return nil, 0, err
 	}
 
 	return subscriptions, total, nil
 }
 
-func (s *subscriptionRepository) CalculateTotalCost(ctx context.Context, userID, serviceName string, startDate, endDate *time.Time) (int64, error) {
-	var totalCost int64
+func (s *subscriptionRepository) ListSubscriptionsPage(ctx context.Context, filter SubscriptionFilter, page SubscriptionPage) (subscriptions []*models.Subscription, next, prev *Cursor, err error) {
+	sort, columns, err := sortColumns(page.Sort, true)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	idDesc := sort[len(sort)-1].Desc
+
+	// Pages before a cursor are read in reverse order and flipped afterwards
+	backward := page.Before != nil
+	cursor := page.After
+	if backward {
+		cursor = page.Before
+	}
 
-	db := s.db.WithContext(ctx).Model(&models.Subscription{})
+	db := applySubscriptionFilter(s.db.WithContext(ctx), filter)
+	if cursor != nil {
+		condition, args, err := keysetCondition(columns, sort, idDesc, cursor, backward)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		db = db.Where(condition, args...)
+	}
+	db = orderBy(db, columns, sort, backward)
+
+	limit := 10
+	if page.Limit > 0 {
+		limit = page.Limit
+	}
+	if err := db.Limit(limit + 1).Preload("Tags").Preload("Members").Find(&subscriptions).Error; err != nil {
+		return nil, nil, nil, err
+	}
 
-	if userID != "" {
-		db = db.Where("user_id = ?", userID)
+	more := len(subscriptions) > limit
+	if more {
+		subscriptions = subscriptions[:limit]
 	}
-	if serviceName != "" {
-		db = db.Where("service_name = ?", serviceName)
+	if backward {
+		slices.Reverse(subscriptions)
 	}
-	if startDate != nil {
-		db = db.Where("start_date >= ?", *startDate)
+	if len(subscriptions) == 0 {
+		return subscriptions, nil, nil, nil
 	}
-	if endDate != nil {
-		db = db.Where("end_date <= ?", *endDate)
+
+	first := newCursor(columns, subscriptions[0])
+	last := newCursor(columns, subscriptions[len(subscriptions)-1])
+	if backward {
+		next = last
+		if more {
+			prev = first
+		}
+	} else {
+		if more {
+			next = last
+		}
+		if page.After != nil {
+			prev = first
+		}
 	}
+	return subscriptions, next, prev, nil
+}
 
+func (s *subscriptionRepository) CountSubscriptions(ctx context.Context, filter SubscriptionFilter) (int64, error) {
+	var total int64
+	err := applySubscriptionFilter(s.db.WithContext(ctx).Model(&models.Subscription{}), filter).Count(&total).Error
+	return total, err
+}
+
+// sortColumns looks the sort fields up in the whitelist, defaulting to the newest
+// subscriptions first. With cursors only columns that are not nullable are allowed.
+func sortColumns(sort []SortField, cursors bool) ([]SortField, []sortColumn, error) {
+	if len(sort) == 0 {
+		sort = []SortField{{Field: "created_at", Desc: true}}
+	}
+	columns := make([]sortColumn, 0, len(sort))
+	for _, field := range sort {
+		column, ok := subscriptionSortColumns[field.Field]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w %q, expected one of %s",
+				ErrUnknownSortField, field.Field, strings.Join(SortableSubscriptionFields(), ", "))
+		}
+		if cursors && column.nullable {
+			return nil, nil, fmt.Errorf("%w %q", ErrCursorSortField, field.Field)
+		}
+		columns = append(columns, column)
+	}
+	return sort, columns, nil
+}
+
+// orderBy sorts by the columns and breaks ties by id, reversing every direction
+// for backward. Rows without a value come last, the same with every database.
+func orderBy(db *gorm.DB, columns []sortColumn, sort []SortField, backward bool) *gorm.DB {
+	for idx, column := range columns {
+		if column.nullable {
+			db = db.Order(column.column + " IS NULL")
+		}
+		db = db.Order(column.column + sortDirection(sort[idx].Desc != backward))
+	}
+	return db.Order("id" + sortDirection(sort[len(sort)-1].Desc != backward))
+}
+
+// keysetCondition selects the rows following the cursor in the order of the sort
+// columns and id, or preceding it when backward is set.
+func keysetCondition(columns []sortColumn, sort []SortField, idDesc bool, cursor *Cursor, backward bool) (string, []interface{}, error) {
+	if len(cursor.Values) != len(columns) {
+		return "", nil, ErrInvalidCursor
+	}
+
+	var clauses, equal []string
+	var args, equalArgs []interface{}
+	for idx, column := range columns {
+		value, err := column.decode(cursor.Values[idx])
+		if err != nil {
+			return "", nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+		}
+		clause := append(slices.Clone(equal), column.column+keysetOperator(sort[idx].Desc != backward))
+		clauses = append(clauses, "("+strings.Join(clause, " AND ")+")")
+		args = append(append(args, equalArgs...), value)
+		equal = append(equal, column.column+" = ?")
+		equalArgs = append(equalArgs, value)
+	}
+	clause := append(equal, "id"+keysetOperator(idDesc != backward))
+	clauses = append(clauses, "("+strings.Join(clause, " AND ")+")")
+	args = append(append(args, equalArgs...), cursor.ID)
+
+	return "(" + strings.Join(clauses, " OR ") + ")", args, nil
+}
+
+func keysetOperator(desc bool) string {
+	if desc {
+		return " < ?"
+	}
+	return " > ?"
+}
+
+func sortDirection(desc bool) string {
+	if desc {
+		return " desc"
+	}
+	return " asc"
+}
+
+func newCursor(columns []sortColumn, subscription *models.Subscription) *Cursor {
+	cursor := &Cursor{ID: subscription.ID, Values: make([]json.RawMessage, 0, len(columns))}
+	for _, column := range columns {
+		// Times, integers and strings always marshal
+		raw, _ := json.Marshal(column.value(subscription))
+		cursor.Values = append(cursor.Values, raw)
+	}
+	return cursor
+}
+
+// streamBatchSize is the number of subscriptions StreamSubscriptions reads at once.
+const streamBatchSize = 500
+
+// StreamSubscriptions iterates over every subscription matching the filter in id
+// order, reading them in batches with their tags and members, so callers can
+// process arbitrarily large result sets row by row.
+func (s *subscriptionRepository) StreamSubscriptions(ctx context.Context, filter SubscriptionFilter, fn func(subscription *models.Subscription) error) error {
+	var batch []*models.Subscription
+	return applySubscriptionFilter(s.db.WithContext(ctx), filter).
+		Preload("Tags").Preload("Members").
+		FindInBatches(&batch, streamBatchSize, func(tx *gorm.DB, _ int) error {
+			for _, subscription := range batch {
+				if err := fn(subscription); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+// CalculateTotalCost sums the price of the matching subscriptions. With a UserID
+// filter shared subscriptions count with the amount the user pays.
+func (s *subscriptionRepository) CalculateTotalCost(ctx context.Context, filter SubscriptionFilter) (int64, error) {
+	var totalCost int64
+
+	db := applySubscriptionFilter(s.db.WithContext(ctx).Model(&models.Subscription{}), filter)
+
+	price, args := priceColumn(filter)
 	var totalCostNull sql.NullInt64
-	if err := db.Select("SUM(price) as total_cost").Scan(&totalCostNull).Error; err != nil {
+	if err := db.Select("SUM("+price+") as total_cost", args...).Scan(&totalCostNull).Error; err != nil {
 		return 0, err
 	}
 	totalCost = int64(0)
@@ -148,3 +514,140 @@ func (s *subscriptionRepository) CalculateTotalCost(ctx context.Context, userID,
 
 	return totalCost, nil
 }
+
+// CalculateCostByGroup sums the price of the matching subscriptions per category or
+// per tag. A subscription with several tags counts towards each of them.
+func (s *subscriptionRepository) CalculateCostByGroup(ctx context.Context, filter SubscriptionFilter, groupBy string) ([]*CostGroup, error) {
+	filtered := applySubscriptionFilter(s.db.WithContext(ctx).Model(&models.Subscription{}), filter)
+	price, args := priceColumn(filter)
+
+	var db *gorm.DB
+	switch groupBy {
+	case GroupByCategory:
+		db = filtered.Select("NULLIF(category, '') AS key, SUM("+price+") AS total_cost", args...).Group("NULLIF(category, '')")
+	case GroupByTag:
+		db = s.db.WithContext(ctx).
+			Table("(?) AS s", filtered.Select("id, "+price+" AS price", args...)).
+			Joins("LEFT JOIN subscription_tags st ON st.subscription_id = s.id").
+			Joins("LEFT JOIN tags t ON t.id = st.tag_id").
+			Select("t.name AS key, SUM(s.price) AS total_cost").
+			Group("t.name")
+	default:
+		return nil, fmt.Errorf("unknown cost grouping %q", groupBy)
+	}
+
+	var groups []*CostGroup
+	if err := db.Order("total_cost desc").Scan(&groups).Error; err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+// priceColumn is the SQL expression for the cost of a subscription. With a user
+// filter it is the amount the filtered users pay for a shared subscription and the
+// full price of an unshared one.
+func priceColumn(filter SubscriptionFilter) (string, []interface{}) {
+	var users []string
+	switch {
+	case filter.UserID != nil:
+		users = []string{*filter.UserID}
+	case filter.UserIDs != nil:
+		users = filter.UserIDs
+	default:
+		return "price", nil
+	}
+	return "COALESCE((SELECT SUM(m.amount) FROM subscription_members m WHERE m.subscription_id = subscriptions.id AND m.user_id IN ?), price)",
+		[]interface{}{users}
+}
+
+// resolveTags replaces new tags of the subscription with the stored tags of its
+// user, creating the ones that do not exist yet.
+func resolveTags(tx *gorm.DB, subscription *models.Subscription) error {
+	for idx := range subscription.Tags {
+		tag := &subscription.Tags[idx]
+		if tag.ID != 0 {
+			continue
+		}
+		tag.UserID = subscription.UserID
+		err := tx.Where(models.Tag{UserID: tag.UserID, Name: tag.Name}).FirstOrCreate(tag).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applySubscriptionFilter(db *gorm.DB, filter SubscriptionFilter) *gorm.DB {
+	if filter.UserID != nil {
+		if filter.IncludeShared {
+			db = db.Where("(user_id = ? OR id IN (SELECT subscription_id FROM subscription_members WHERE user_id = ?))",
+				*filter.UserID, *filter.UserID)
+		} else {
+			db = db.Where("user_id = ?", *filter.UserID)
+		}
+	}
+
+	if filter.UserIDs != nil {
+		if filter.IncludeShared {
+			db = db.Where("(user_id IN ? OR id IN (SELECT subscription_id FROM subscription_members WHERE user_id IN ?))",
+				filter.UserIDs, filter.UserIDs)
+		} else {
+			db = db.Where("user_id IN ?", filter.UserIDs)
+		}
+	}
+
+	if filter.StartDateFrom != nil {
+		db = db.Where("start_date >= ?", filter.StartDateFrom)
+	}
+
+	if filter.StartDateTo != nil {
+		db = db.Where("start_date <= ?", filter.StartDateTo)
+	}
+
+	if filter.EndDateFrom != nil {
+		db = db.Where("end_date >= ?", filter.EndDateFrom)
+	}
+
+	if filter.EndDateTo != nil {
+		db = db.Where("end_date <= ?", filter.EndDateTo)
+	}
+
+	if filter.ServiceID != nil {
+		names := make([]string, 0, len(filter.ServiceNames))
+		for _, name := range filter.ServiceNames {
+			names = append(names, strings.ToLower(name))
+		}
+		if filter.PlanID != nil {
+			db = db.Where("(plan_id = ? OR (service_id IS NULL AND LOWER(service_name) IN ?))", *filter.PlanID, names)
+		} else {
+			db = db.Where("(service_id = ? OR (service_id IS NULL AND LOWER(service_name) IN ?))", *filter.ServiceID, names)
+		}
+	} else if filter.ServiceName != nil {
+		db = db.Where("service_name = ?", *filter.ServiceName)
+	}
+
+	if filter.Category != nil {
+		db = db.Where("category = ?", *filter.Category)
+	}
+
+	if len(filter.Tags) > 0 {
+		tagged := db.Session(&gorm.Session{NewDB: true}).
+			Table("subscription_tags").
+			Select("subscription_tags.subscription_id").
+			Joins("JOIN tags ON tags.id = subscription_tags.tag_id").
+			Where("tags.name IN ?", filter.Tags)
+		if filter.MatchAllTags {
+			tagged = tagged.
+				Group("subscription_tags.subscription_id").
+				Having("COUNT(DISTINCT tags.name) = ?", len(filter.Tags))
+		}
+		db = db.Where("id IN (?)", tagged)
+	}
+
+	if filter.Expression != nil {
+		db = db.Where("("+filter.Expression.sql+")", filter.Expression.args...)
+	}
+
+	return db
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/pkg/auth"
+	"github.com/rasadov/subscription-manager/pkg/exceptions"
+	"github.com/rasadov/subscription-manager/pkg/tenant"
+	"gorm.io/gorm"
+)
+
+const (
+	// apiKeyPrefix starts every key, so leaked keys are easy to recognize.
+	apiKeyPrefix = "sm_"
+	// apiKeyDisplayLength is how many characters of a key are kept for display.
+	apiKeyDisplayLength = len(apiKeyPrefix) + 8
+	// apiKeyTouchInterval limits how often the last use of a key is written.
+	apiKeyTouchInterval = time.Minute
+)
+
+// ErrInvalidAPIKey is returned for keys that are unknown, revoked or expired.
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+type APIKeyService interface {
+	CreateAPIKey(ctx context.Context, req dto.CreateAPIKeyRequest) (*dto.CreatedAPIKeyResponse, exceptions.HTTPError)
+	ListAPIKeys(ctx context.Context) (*dto.ListAPIKeysResponse, exceptions.HTTPError)
+	RevokeAPIKey(ctx context.Context, id int) exceptions.HTTPError
+	// Authenticate returns the principal of an active key.
+	Authenticate(ctx context.Context, key string) (*auth.Principal, error)
+}
+
+type apiKeyService struct {
+	repo repository.APIKeyRepository
+}
+
+func NewAPIKeyService(repo repository.APIKeyRepository) APIKeyService {
+	return &apiKeyService{repo: repo}
+}
+
+// CreateAPIKey creates a key in the tenant of the caller. Callers may only grant
+// scopes they have themselves.
+func (s *apiKeyService) CreateAPIKey(ctx context.Context, req dto.CreateAPIKeyRequest) (*dto.CreatedAPIKeyResponse, exceptions.HTTPError) {
+	if httpErr := requireAdmin(ctx); httpErr != nil {
+		return nil, httpErr
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, exceptions.NewBadRequest("expires_at must be in the future")
+	}
+
+	principal, authenticated := auth.FromContext(ctx)
+	scopes := make([]string, 0, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		if authenticated && !principal.Allows(scope) {
+			return nil, exceptions.NewForbidden(fmt.Sprintf("scope %s exceeds the permissions of the caller", scope))
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+	plain := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	key := &models.APIKey{
+		Name:       strings.TrimSpace(req.Name),
+		Prefix:     plain[:apiKeyDisplayLength],
+		SecretHash: hashSecret(plain),
+		Scopes:     scopes,
+		ExpiresAt:  req.ExpiresAt,
+	}
+	if key.Name == "" {
+		return nil, exceptions.NewBadRequest("name must not be blank")
+	}
+	if authenticated {
+		key.CreatedBy = principal.Subject
+	}
+
+	if err := s.repo.CreateAPIKey(ctx, key); err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	return &dto.CreatedAPIKeyResponse{APIKeyResponse: *dto.NewAPIKeyResponse(key), Key: plain}, nil
+}
+
+func (s *apiKeyService) ListAPIKeys(ctx context.Context) (*dto.ListAPIKeysResponse, exceptions.HTTPError) {
+	if httpErr := requireAdmin(ctx); httpErr != nil {
+		return nil, httpErr
+	}
+
+	keys, err := s.repo.ListAPIKeys(ctx)
+	if err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	response := &dto.ListAPIKeysResponse{Data: make([]*dto.APIKeyResponse, 0, len(keys))}
+	for _, key := range keys {
+		response.Data = append(response.Data, dto.NewAPIKeyResponse(key))
+	}
+	return response, nil
+}
+
+// RevokeAPIKey disables the key immediately. Revoked keys stay listed so their
+// last use remains visible.
+func (s *apiKeyService) RevokeAPIKey(ctx context.Context, id int) exceptions.HTTPError {
+	if httpErr := requireAdmin(ctx); httpErr != nil {
+		return httpErr
+	}
+
+	if err := s.repo.RevokeAPIKey(ctx, id, time.Now()); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return exceptions.NewNotFound(err.Error())
+		}
+		return exceptions.NewInternalServerError(err.Error())
+	}
+	return nil
+}
+
+// Authenticate looks the key up in every tenant, since the tenant of a request is
+// only known once its credentials are. Keys act for every user of their tenant
+// with their scopes as permissions.
+func (s *apiKeyService) Authenticate(ctx context.Context, plain string) (*auth.Principal, error) {
+	if !strings.HasPrefix(plain, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.repo.GetAPIKeyBySecretHash(tenant.WithAllTenants(ctx), hashSecret(plain))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if !key.Active(now) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		if err := s.repo.TouchAPIKey(tenant.WithTenant(ctx, key.TenantID), key.ID, now); err != nil {
+			return nil, err
+		}
+	}
+
+	return &auth.Principal{
+		Subject:     fmt.Sprintf("api-key:%d", key.ID),
+		TenantID:    key.TenantID,
+		AllUsers:    true,
+		Permissions: append([]string{}, key.Scopes...),
+	}, nil
+}
+
+// hashSecret returns the hex SHA-256 of an API key, session or calendar feed
+// token, which is what gets stored and looked up. All of them are long random
+// strings, so a fast unsalted hash is enough to make a leaked table useless.
+func hashSecret(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/pkg/auth"
+	"github.com/rasadov/subscription-manager/pkg/exceptions"
+)
+
+// The checks below apply to authenticated callers only. Calls without a principal
+// come from background jobs or from deployments with authentication disabled and
+// are allowed everything.
+
+// restricted returns the principal of the context when it may only access its own
+// data, that is when it is authenticated without access to every user.
+func restricted(ctx context.Context) (*auth.Principal, bool) {
+	principal, ok := auth.FromContext(ctx)
+	if !ok || principal.AllUsers {
+		return nil, false
+	}
+	return principal, true
+}
+
+// authorizeUser checks that the caller may act on behalf of the user.
+func authorizeUser(ctx context.Context, userID string) exceptions.HTTPError {
+	principal, ok := restricted(ctx)
+	if !ok || principal.Subject == userID {
+		return nil
+	}
+	return exceptions.NewForbidden("access to the data of another user is not allowed")
+}
+
+// authorizeSubscription checks that the caller may access the subscription. Its
+// owner may read and change it and the members sharing its cost may read it.
+// Other callers get a not found error, so the IDs of subscriptions they cannot
+// see are not revealed.
+func authorizeSubscription(ctx context.Context, subscription *models.Subscription, write bool) exceptions.HTTPError {
+	principal, ok := restricted(ctx)
+	if !ok || subscription.UserID == principal.Subject {
+		return nil
+	}
+	if !write {
+		for _, member := range subscription.Members {
+			if member.UserID == principal.Subject {
+				return nil
+			}
+		}
+	}
+	return exceptions.NewNotFound("record not found")
+}
+
+// scopeUserFilter restricts a user_id filter to the caller. Queries without one
+// are limited to the caller's subscriptions, unless they select an organization,
+// whose access is checked separately by authorizeOrganization.
+func scopeUserFilter(ctx context.Context, userID *string, organizationID *uint) (*string, exceptions.HTTPError) {
+	principal, ok := restricted(ctx)
+	if !ok || organizationID != nil {
+		return userID, nil
+	}
+	if userID == nil {
+		return &principal.Subject, nil
+	}
+	if httpErr := authorizeUser(ctx, *userID); httpErr != nil {
+		return nil, httpErr
+	}
+	return userID, nil
+}
+
+// authorizeOrganization checks that the caller is a member of the organization,
+// and an owner or admin who accepted the membership when manage is set. Managing
+// includes seeing the subscriptions of every member. Invited users can see the
+// organization they are invited to; non-members get a not found error.
+func authorizeOrganization(ctx context.Context, organization *models.Organization, manage bool) exceptions.HTTPError {
+	principal, ok := restricted(ctx)
+	if !ok {
+		return nil
+	}
+	for _, member := range organization.Members {
+		if member.UserID != principal.Subject {
+			continue
+		}
+		if !manage {
+			return nil
+		}
+		if !member.Accepted() {
+			return exceptions.NewForbidden("the invitation to the organization has not been accepted")
+		}
+		if member.Role == models.OrganizationRoleOwner || member.Role == models.OrganizationRoleAdmin {
+			return nil
+		}
+		return exceptions.NewForbidden("this requires the owner or admin role in the organization")
+	}
+	return exceptions.NewNotFound("record not found")
+}
+
+// authorizeOwner checks that the caller is an owner of the organization who
+// accepted the membership. Only owners may make others owners or change the role
+// of an owner, so admins cannot take over an organization.
+func authorizeOwner(ctx context.Context, organization *models.Organization) exceptions.HTTPError {
+	principal, ok := restricted(ctx)
+	if !ok {
+		return nil
+	}
+	for _, member := range organization.Members {
+		if member.UserID == principal.Subject && member.Role == models.OrganizationRoleOwner && member.Accepted() {
+			return nil
+		}
+	}
+	return exceptions.NewForbidden("this requires the owner role in the organization")
+}
+
+// requireAdmin checks that the caller has the admin permission.
+func requireAdmin(ctx context.Context) exceptions.HTTPError {
+	principal, ok := auth.FromContext(ctx)
+	if ok && (!principal.AllUsers || !principal.Allows(auth.PermissionAdmin)) {
+		return exceptions.NewForbidden("this requires the admin role")
+	}
+	return nil
+}
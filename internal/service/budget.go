@@ -0,0 +1,369 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/pkg/exceptions"
+	"github.com/rasadov/subscription-manager/pkg/tenant"
+	"gorm.io/gorm"
+)
+
+// DefaultBudgetThresholds are the percentages of the limit at which a budget
+// alerts when it does not set its own.
+var DefaultBudgetThresholds = []int{80, 100}
+
+type BudgetService interface {
+	SubscriptionObserver
+	CreateBudget(ctx context.Context, userID string, req dto.CreateBudgetRequest) (*dto.BudgetResponse, exceptions.HTTPError)
+	UpdateBudget(ctx context.Context, userID string, id int, req dto.UpdateBudgetRequest) (*dto.BudgetResponse, exceptions.HTTPError)
+	DeleteBudget(ctx context.Context, userID string, id int) exceptions.HTTPError
+	ListBudgets(ctx context.Context, userID string) (*dto.ListBudgetsResponse, exceptions.HTTPError)
+	ListAlerts(ctx context.Context, userID string) (*dto.ListBudgetAlertsResponse, exceptions.HTTPError)
+	// EvaluateUser checks the budgets of a user against the projected spend of the
+	// month of now and returns the alerts raised for thresholds reached for the first time.
+	EvaluateUser(ctx context.Context, userID string, now time.Time) ([]*models.BudgetAlert, error)
+	// EvaluateAll evaluates the budgets of every user of every tenant; it runs on a schedule.
+	EvaluateAll(ctx context.Context, now time.Time) error
+}
+
+type budgetService struct {
+	budgets       repository.BudgetRepository
+	subscriptions repository.SubscriptionRepository
+	logger        *slog.Logger
+	thresholds    []int
+}
+
+type BudgetServiceOption func(*budgetService)
+
+// WithDefaultThresholds replaces DefaultBudgetThresholds for budgets created
+// without thresholds.
+func WithDefaultThresholds(thresholds []int) BudgetServiceOption {
+	return func(s *budgetService) {
+		if len(thresholds) > 0 {
+			s.thresholds = normalizeThresholds(thresholds)
+		}
+	}
+}
+
+// NewBudgetService creates the budget service. Alerts are logged to logger as
+// they are raised, and evaluation failures triggered by subscription changes are
+// logged instead of failing the change.
+func NewBudgetService(budgets repository.BudgetRepository, subscriptions repository.SubscriptionRepository,
+	logger *slog.Logger, opts ...BudgetServiceOption) BudgetService {
+	s := &budgetService{
+		budgets:       budgets,
+		subscriptions: subscriptions,
+		logger:        logger,
+		thresholds:    DefaultBudgetThresholds,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *budgetService) CreateBudget(ctx context.Context, userID string, req dto.CreateBudgetRequest) (*dto.BudgetResponse, exceptions.HTTPError) {
+	if httpErr := authorizeUser(ctx, userID); httpErr != nil {
+		return nil, httpErr
+	}
+
+	budget := &models.Budget{
+		UserID:       userID,
+		Scope:        req.Scope,
+		MonthlyLimit: req.MonthlyLimit,
+		Currency:     req.Currency,
+		Thresholds:   normalizeThresholds(req.Thresholds),
+	}
+	switch req.Scope {
+	case models.BudgetScopeCategory:
+		budget.Category = normalizeCategory(req.Category)
+		if budget.Category == "" {
+			return nil, exceptions.NewBadRequest("category must not be blank")
+		}
+	case models.BudgetScopeService:
+		budget.ServiceName = strings.TrimSpace(req.ServiceName)
+		if budget.ServiceName == "" {
+			return nil, exceptions.NewBadRequest("service_name must not be blank")
+		}
+	}
+	if budget.Currency == "" {
+		budget.Currency = models.DefaultCurrency
+	}
+	if len(budget.Thresholds) == 0 {
+		budget.Thresholds = s.thresholds
+	}
+
+	if err := s.budgets.CreateBudget(ctx, budget); err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	s.evaluate(ctx, userID)
+
+	return dto.NewBudgetResponse(budget), nil
+}
+
+func (s *budgetService) UpdateBudget(ctx context.Context, userID string, id int, req dto.UpdateBudgetRequest) (*dto.BudgetResponse, exceptions.HTTPError) {
+	if httpErr := authorizeUser(ctx, userID); httpErr != nil {
+		return nil, httpErr
+	}
+
+	budget, httpErr := s.getBudget(ctx, userID, id)
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
+	if req.MonthlyLimit != nil {
+		budget.MonthlyLimit = *req.MonthlyLimit
+	}
+	if req.Currency != nil {
+		budget.Currency = *req.Currency
+	}
+	if req.Thresholds != nil {
+		budget.Thresholds = normalizeThresholds(*req.Thresholds)
+	}
+
+	if err := s.budgets.UpdateBudget(ctx, budget); err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	s.evaluate(ctx, userID)
+
+	return dto.NewBudgetResponse(budget), nil
+}
+
+func (s *budgetService) DeleteBudget(ctx context.Context, userID string, id int) exceptions.HTTPError {
+	if httpErr := authorizeUser(ctx, userID); httpErr != nil {
+		return httpErr
+	}
+
+	if _, httpErr := s.getBudget(ctx, userID, id); httpErr != nil {
+		return httpErr
+	}
+
+	if err := s.budgets.DeleteBudget(ctx, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return exceptions.NewNotFound(err.Error())
+		}
+		return exceptions.NewInternalServerError(err.Error())
+	}
+
+	return nil
+}
+
+// ListBudgets returns the budgets of a user with the projected spend of the current month.
+func (s *budgetService) ListBudgets(ctx context.Context, userID string) (*dto.ListBudgetsResponse, exceptions.HTTPError) {
+	if httpErr := authorizeUser(ctx, userID); httpErr != nil {
+		return nil, httpErr
+	}
+
+	budgets, err := s.budgets.ListBudgets(ctx, &userID)
+	if err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	subscriptions, err := s.userSubscriptions(ctx, userID)
+	if err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	month := startOfMonth(time.Now())
+	response := &dto.ListBudgetsResponse{Data: []*dto.BudgetResponse{}}
+	for _, budget := range budgets {
+		spend := budgetSpend(budget, userID, subscriptions, month)
+		item := dto.NewBudgetResponse(budget)
+		item.Current = &dto.BudgetSpend{
+			Month:   dto.MonthYear(month),
+			Spend:   spend,
+			Percent: float64(spend) * 100 / float64(budget.MonthlyLimit),
+		}
+		response.Data = append(response.Data, item)
+	}
+
+	return response, nil
+}
+
+func (s *budgetService) ListAlerts(ctx context.Context, userID string) (*dto.ListBudgetAlertsResponse, exceptions.HTTPError) {
+	if httpErr := authorizeUser(ctx, userID); httpErr != nil {
+		return nil, httpErr
+	}
+
+	alerts, err := s.budgets.ListAlerts(ctx, userID)
+	if err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	response := &dto.ListBudgetAlertsResponse{Data: []*dto.BudgetAlertResponse{}}
+	for _, alert := range alerts {
+		response.Data = append(response.Data, dto.NewBudgetAlertResponse(alert))
+	}
+
+	return response, nil
+}
+
+// SubscriptionChanged re-evaluates the budgets of the owner and the members of a
+// created or updated subscription.
+func (s *budgetService) SubscriptionChanged(ctx context.Context, subscription *models.Subscription) {
+	s.evaluate(ctx, subscription.UserID)
+	for _, member := range subscription.Members {
+		if member.UserID != subscription.UserID {
+			s.evaluate(ctx, member.UserID)
+		}
+	}
+}
+
+func (s *budgetService) EvaluateUser(ctx context.Context, userID string, now time.Time) ([]*models.BudgetAlert, error) {
+	budgets, err := s.budgets.ListBudgets(ctx, &userID)
+	if err != nil || len(budgets) == 0 {
+		return nil, err
+	}
+
+	return s.evaluateBudgets(ctx, userID, budgets, startOfMonth(now))
+}
+
+func (s *budgetService) EvaluateAll(ctx context.Context, now time.Time) error {
+	budgets, err := s.budgets.ListBudgets(tenant.WithAllTenants(ctx), nil)
+	if err != nil {
+		return err
+	}
+
+	// Budgets are ordered by tenant and user, so each run of equal tenant and user
+	// IDs is one user, evaluated within their tenant
+	month := startOfMonth(now)
+	for start := 0; start < len(budgets); {
+		end := start + 1
+		for end < len(budgets) && budgets[end].TenantID == budgets[start].TenantID && budgets[end].UserID == budgets[start].UserID {
+			end++
+		}
+		tenantCtx := tenant.WithTenant(ctx, budgets[start].TenantID)
+		if _, err := s.evaluateBudgets(tenantCtx, budgets[start].UserID, budgets[start:end], month); err != nil {
+			return err
+		}
+		start = end
+	}
+
+	return nil
+}
+
+// evaluate runs EvaluateUser for the current month, logging failures since the
+// change that triggered it has already been stored.
+func (s *budgetService) evaluate(ctx context.Context, userID string) {
+	if _, err := s.EvaluateUser(ctx, userID, time.Now()); err != nil {
+		s.logger.Error("Failed to evaluate budgets", "user_id", userID, "error", err)
+	}
+}
+
+func (s *budgetService) evaluateBudgets(ctx context.Context, userID string, budgets []*models.Budget, month time.Time) ([]*models.BudgetAlert, error) {
+	subscriptions, err := s.userSubscriptions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var alerts []*models.BudgetAlert
+	for _, budget := range budgets {
+		spend := budgetSpend(budget, userID, subscriptions, month)
+		for _, threshold := range budget.Thresholds {
+			if spend*100 < budget.MonthlyLimit*int64(threshold) {
+				break
+			}
+
+			alert := &models.BudgetAlert{
+				BudgetID:     budget.ID,
+				UserID:       budget.UserID,
+				Month:        month,
+				Threshold:    threshold,
+				Spend:        spend,
+				MonthlyLimit: budget.MonthlyLimit,
+				Currency:     budget.Currency,
+			}
+			created, err := s.budgets.CreateAlert(ctx, alert)
+			if err != nil {
+				return nil, err
+			}
+			if !created {
+				continue
+			}
+
+			s.logger.Warn("Budget threshold reached", "user_id", budget.UserID, "budget_id", budget.ID,
+				"scope", budget.Scope, "threshold", threshold, "spend", spend,
+				"monthly_limit", budget.MonthlyLimit, "currency", budget.Currency)
+			alerts = append(alerts, alert)
+		}
+	}
+
+	return alerts, nil
+}
+
+// userSubscriptions returns the subscriptions a user owns or shares, with their
+// members so that the user's part of the price is known.
+func (s *budgetService) userSubscriptions(ctx context.Context, userID string) ([]*models.Subscription, error) {
+	var subscriptions []*models.Subscription
+	filter := repository.SubscriptionFilter{UserID: &userID, IncludeShared: true}
+	err := s.subscriptions.StreamSubscriptions(ctx, filter, func(subscription *models.Subscription) error {
+		subscriptions = append(subscriptions, subscription)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func (s *budgetService) getBudget(ctx context.Context, userID string, id int) (*models.Budget, exceptions.HTTPError) {
+	budget, err := s.budgets.GetBudget(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, exceptions.NewNotFound(err.Error())
+		}
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	if budget.UserID != userID {
+		return nil, exceptions.NewNotFound(gorm.ErrRecordNotFound.Error())
+	}
+
+	return budget, nil
+}
+
+// budgetSpend projects the spend of a month within the scope of a budget as the
+// monthly share of every subscription running that month. Of shared subscriptions
+// only the part userID pays is counted, and subscriptions in another currency
+// than the budget are not counted at all.
+func budgetSpend(budget *models.Budget, userID string, subscriptions []*models.Subscription, month time.Time) int64 {
+	var spend int64
+	for _, subscription := range subscriptions {
+		if !subscription.ActiveIn(month) || subscription.Currency != budget.Currency {
+			continue
+		}
+		switch budget.Scope {
+		case models.BudgetScopeCategory:
+			if subscription.Category != budget.Category {
+				continue
+			}
+		case models.BudgetScopeService:
+			if !strings.EqualFold(subscription.ServiceName, budget.ServiceName) {
+				continue
+			}
+		}
+		spend += subscription.MonthlyAmountFor(userID)
+	}
+	return spend
+}
+
+// normalizeThresholds sorts thresholds and drops repeated ones.
+func normalizeThresholds(thresholds []int) []int {
+	normalized := slices.Clone(thresholds)
+	slices.Sort(normalized)
+	return slices.Compact(normalized)
+}
+
+func startOfMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
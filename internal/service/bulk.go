@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/pkg/exceptions"
+)
+
+// errBulkFailed rolls back the transaction of an atomic bulk request in which an
+// operation failed.
+var errBulkFailed = errors.New("bulk operation failed")
+
+// BulkSubscriptions applies create, update and delete operations in order and
+// reports the outcome of each one. Without atomic every operation stands on its
+// own; with atomic they share one transaction that is rolled back when any of them
+// fails, so either all or none are applied. Observers are notified once the
+// changes are saved.
+func (s *subscriptionService) BulkSubscriptions(ctx context.Context, operations []dto.BulkOperation, atomic bool) (*dto.BulkSubscriptionsResponse, exceptions.HTTPError) {
+	if len(operations) == 0 {
+		return nil, exceptions.NewBadRequest("at least one operation is required")
+	}
+	if len(operations) > dto.MaxBulkOperations {
+		return nil, exceptions.NewBadRequest(fmt.Sprintf("at most %d operations are allowed per request", dto.MaxBulkOperations))
+	}
+
+	response := &dto.BulkSubscriptionsResponse{
+		Atomic:  atomic,
+		Total:   len(operations),
+		Results: make([]*dto.BulkOperationResult, 0, len(operations)),
+	}
+	var changed []*models.Subscription
+	apply := func(repo repository.SubscriptionRepository) {
+		for idx, operation := range operations {
+			result, subscription := s.applyBulkOperation(ctx, repo, idx, operation)
+			if result.Status == dto.BulkStatusSucceeded {
+				response.Succeeded++
+			} else {
+				response.Failed++
+			}
+			if subscription != nil {
+				changed = append(changed, subscription)
+			}
+			response.Results = append(response.Results, result)
+		}
+	}
+
+	if atomic {
+		// Failed operations are still reported after the first one, so every
+		// problem of the request can be fixed at once
+		err := s.repo.Transaction(ctx, func(repo repository.SubscriptionRepository) error {
+			apply(repo)
+			if response.Failed > 0 {
+				return errBulkFailed
+			}
+			return nil
+		})
+		if errors.Is(err, errBulkFailed) {
+			rollBackResults(response, operations)
+			return response, nil
+		}
+		if err != nil {
+			return nil, exceptions.NewInternalServerError(err.Error())
+		}
+	} else {
+		apply(s.repo)
+	}
+
+	for _, subscription := range changed {
+		s.notify(ctx, subscription)
+	}
+	return response, nil
+}
+
+func (s *subscriptionService) applyBulkOperation(ctx context.Context, repo repository.SubscriptionRepository, index int, operation dto.BulkOperation) (*dto.BulkOperationResult, *models.Subscription) {
+	result := &dto.BulkOperationResult{Index: index, Op: operation.Op, ID: operation.ID}
+
+	var subscription *models.Subscription
+	var httpErr exceptions.HTTPError
+	switch operation.Op {
+	case dto.BulkOpCreate:
+		var req dto.CreateSubscriptionRequest
+		if httpErr = decodeBulkData(operation.Data, &req); httpErr != nil {
+			break
+		}
+		var warnings []string
+		subscription, warnings, httpErr = s.createSubscription(ctx, repo, req, newDuplicateIndex(repo))
+		if httpErr == nil {
+			result.Code = http.StatusCreated
+			result.Subscription = dto.NewSubscriptionResponse(subscription)
+			result.Subscription.Warnings = warnings
+		}
+	case dto.BulkOpUpdate:
+		var req dto.UpdateSubscriptionRequest
+		if httpErr = requireBulkID(operation); httpErr != nil {
+			break
+		}
+		if httpErr = decodeBulkData(operation.Data, &req); httpErr != nil {
+			break
+		}
+		subscription, httpErr = s.updateSubscription(ctx, repo, int(operation.ID), req)
+		if httpErr == nil {
+			result.Code = http.StatusOK
+			result.Subscription = dto.NewSubscriptionResponse(subscription)
+		}
+	case dto.BulkOpDelete:
+		if httpErr = requireBulkID(operation); httpErr != nil {
+			break
+		}
+		if httpErr = s.deleteSubscription(ctx, repo, int(operation.ID)); httpErr == nil {
+			result.Code = http.StatusNoContent
+		}
+	default:
+		httpErr = exceptions.NewBadRequest(fmt.Sprintf("unknown operation %q, expected create, update or delete", operation.Op))
+	}
+
+	if httpErr != nil {
+		result.Status = dto.BulkStatusFailed
+		result.Code = httpErr.Status()
+		result.Error = httpErr.Error()
+		return result, nil
+	}
+	result.Status = dto.BulkStatusSucceeded
+	if subscription != nil {
+		result.ID = subscription.ID
+	}
+	return result, subscription
+}
+
+// decodeBulkData parses the data of an operation and applies the binding rules
+// the single-item endpoints enforce on their JSON bodies.
+func decodeBulkData(data json.RawMessage, req interface{}) exceptions.HTTPError {
+	if len(data) == 0 || string(data) == "null" {
+		return exceptions.NewBadRequest("data is required")
+	}
+	if err := json.Unmarshal(data, req); err != nil {
+		return exceptions.NewBadRequest(err.Error())
+	}
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return exceptions.NewBadRequest(err.Error())
+	}
+	return nil
+}
+
+func requireBulkID(operation dto.BulkOperation) exceptions.HTTPError {
+	if operation.ID == 0 {
+		return exceptions.NewBadRequest(fmt.Sprintf("id is required to %s a subscription", operation.Op))
+	}
+	return nil
+}
+
+// rollBackResults marks the operations of a rolled back atomic request as not applied.
+func rollBackResults(response *dto.BulkSubscriptionsResponse, operations []dto.BulkOperation) {
+	response.RolledBack = true
+	response.Succeeded = 0
+	for _, result := range response.Results {
+		if result.Status != dto.BulkStatusSucceeded {
+			continue
+		}
+		result.Status = dto.BulkStatusRolledBack
+		result.ID = operations[result.Index].ID
+		result.Code = 0
+		result.Subscription = nil
+	}
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/pkg/exceptions"
+	"github.com/rasadov/subscription-manager/pkg/ical"
+	"github.com/rasadov/subscription-manager/pkg/tenant"
+	"gorm.io/gorm"
+)
+
+// calendarHorizonMonths is how far ahead the feed lists renewals and end dates.
+const calendarHorizonMonths = 12
+
+type CalendarService interface {
+	IssueToken(ctx context.Context, userID string) (*dto.CalendarTokenResponse, exceptions.HTTPError)
+	WriteFeed(ctx context.Context, w io.Writer, userID, token string) exceptions.HTTPError
+}
+
+type calendarService struct {
+	tokens        repository.CalendarTokenRepository
+	subscriptions repository.SubscriptionRepository
+}
+
+func NewCalendarService(tokens repository.CalendarTokenRepository, subscriptions repository.SubscriptionRepository) CalendarService {
+	return &calendarService{tokens: tokens, subscriptions: subscriptions}
+}
+
+// IssueToken creates the user's feed token, or rotates it when one already exists,
+// which invalidates every previously shared feed URL. Only its hash is stored, so
+// the token is returned this once.
+func (s *calendarService) IssueToken(ctx context.Context, userID string) (*dto.CalendarTokenResponse, exceptions.HTTPError) {
+	if httpErr := authorizeUser(ctx, userID); httpErr != nil {
+		return nil, httpErr
+	}
+
+	token, err := s.tokens.GetCalendarToken(ctx, userID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, exceptions.NewInternalServerError(err.Error())
+		}
+		token = &models.CalendarToken{UserID: userID}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+	plain := base64.RawURLEncoding.EncodeToString(secret)
+	token.TokenHash = hashSecret(plain)
+
+	if err := s.tokens.SaveCalendarToken(ctx, token); err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	return &dto.CalendarTokenResponse{Token: plain}, nil
+}
+
+// WriteFeed renders the feed of the user if token is theirs. Calendar clients
+// cannot authenticate otherwise, so the token, which is unique across tenants, is
+// looked up in every tenant and the feed is built within the tenant of the token.
+func (s *calendarService) WriteFeed(ctx context.Context, w io.Writer, userID, token string) exceptions.HTTPError {
+	if token == "" {
+		return exceptions.NewNotFound("calendar not found")
+	}
+	stored, err := s.tokens.GetCalendarTokenByHash(tenant.WithAllTenants(ctx), hashSecret(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return exceptions.NewNotFound("calendar not found")
+		}
+		return exceptions.NewInternalServerError(err.Error())
+	}
+	if stored.UserID != userID {
+		return exceptions.NewNotFound("calendar not found")
+	}
+
+	ctx = tenant.WithTenant(ctx, stored.TenantID)
+
+	now := time.Now().UTC()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, calendarHorizonMonths, 0)
+
+	calendar := &ical.Calendar{
+		ProdID: "-//Subscription Manager//Renewals//EN",
+		Name:   "Subscription renewals",
+	}
+
+	filter := repository.SubscriptionFilter{UserID: &userID}
+	err = s.subscriptions.StreamSubscriptions(ctx, filter, func(subscription *models.Subscription) error {
+		calendar.Events = append(calendar.Events, subscriptionEvents(subscription, from, to)...)
+		return nil
+	})
+	if err != nil {
+		return exceptions.NewInternalServerError(err.Error())
+	}
+
+	if err := calendar.Write(w); err != nil {
+		return exceptions.NewInternalServerError(err.Error())
+	}
+
+	return nil
+}
+
+// subscriptionEvents builds one event per billing date in [from, to) and one for
+// the end of the subscription. UIDs only depend on the subscription ID and the date.
+func subscriptionEvents(subscription *models.Subscription, from, to time.Time) []ical.Event {
+	var events []ical.Event
+
+	for _, date := range subscription.BillingDates(from, to) {
+		events = append(events, ical.Event{
+			UID:         fmt.Sprintf("subscription-%d-renewal-%s@subscription-manager", subscription.ID, date.Format("20060102")),
+			Summary:     fmt.Sprintf("%s renewal: %d", subscription.ServiceName, subscription.Price),
+			Description: fmt.Sprintf("%s (%s) is charged %d.", subscription.ServiceName, subscription.BillingCycle, subscription.Price),
+			Date:        date,
+			Stamp:       subscription.UpdatedAt,
+		})
+	}
+
+	if end := subscription.EndDate; end != nil && !end.Before(from) && end.Before(to) {
+		events = append(events, ical.Event{
+			UID:     fmt.Sprintf("subscription-%d-end@subscription-manager", subscription.ID),
+			Summary: fmt.Sprintf("%s subscription ends", subscription.ServiceName),
+			Date:    *end,
+			Stamp:   subscription.UpdatedAt,
+		})
+	}
+
+	return events
+}
@@ -0,0 +1,368 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/pkg/exceptions"
+	"github.com/rasadov/subscription-manager/pkg/textmatch"
+	"gorm.io/gorm"
+)
+
+// catalogMatchThreshold is the minimal similarity between a typed name and a
+// catalog name or alias for them to be treated as the same service.
+const catalogMatchThreshold = 0.85
+
+type CatalogService interface {
+	CreateService(ctx context.Context, req dto.CreateServiceRequest) (*dto.ServiceResponse, exceptions.HTTPError)
+	GetService(ctx context.Context, id int) (*dto.ServiceResponse, exceptions.HTTPError)
+	UpdateService(ctx context.Context, id int, req dto.UpdateServiceRequest) (*dto.ServiceResponse, exceptions.HTTPError)
+	DeleteService(ctx context.Context, id int) exceptions.HTTPError
+	ListServices(ctx context.Context, query dto.ListServicesQuery) (*dto.ListServicesResponse, exceptions.HTTPError)
+	MatchService(ctx context.Context, name string) (*dto.ServiceMatchResponse, exceptions.HTTPError)
+	CreatePlan(ctx context.Context, serviceID int, req dto.CreatePlanRequest) (*dto.PlanResponse, exceptions.HTTPError)
+	UpdatePlan(ctx context.Context, serviceID, planID int, req dto.UpdatePlanRequest) (*dto.PlanResponse, exceptions.HTTPError)
+	DeletePlan(ctx context.Context, serviceID, planID int) exceptions.HTTPError
+}
+
+type catalogService struct {
+	repo repository.CatalogRepository
+}
+
+func NewCatalogService(repo repository.CatalogRepository) CatalogService {
+	return &catalogService{repo: repo}
+}
+
+func (s *catalogService) CreateService(ctx context.Context, req dto.CreateServiceRequest) (*dto.ServiceResponse, exceptions.HTTPError) {
+	service := &models.Service{
+		Name:     strings.TrimSpace(req.Name),
+		Aliases:  cleanAliases(req.Aliases),
+		Category: req.Category,
+		Website:  req.Website,
+		LogoURL:  req.LogoURL,
+	}
+	for _, planReq := range req.Plans {
+		service.Plans = append(service.Plans, *newPlanFromRequest(planReq))
+	}
+
+	if httpErr := s.checkNamesAvailable(ctx, service); httpErr != nil {
+		return nil, httpErr
+	}
+
+	if err := s.repo.CreateService(ctx, service); err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	return dto.NewServiceResponse(service), nil
+}
+
+func (s *catalogService) GetService(ctx context.Context, id int) (*dto.ServiceResponse, exceptions.HTTPError) {
+	service, httpErr := s.getService(ctx, id)
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
+	return dto.NewServiceResponse(service), nil
+}
+
+func (s *catalogService) UpdateService(ctx context.Context, id int, req dto.UpdateServiceRequest) (*dto.ServiceResponse, exceptions.HTTPError) {
+	service, httpErr := s.getService(ctx, id)
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
+	if req.Name != nil {
+		service.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Aliases != nil {
+		service.Aliases = cleanAliases(*req.Aliases)
+	}
+	if req.Category != nil {
+		service.Category = *req.Category
+	}
+	if req.Website != nil {
+		service.Website = *req.Website
+	}
+	if req.LogoURL != nil {
+		service.LogoURL = *req.LogoURL
+	}
+
+	if httpErr := s.checkNamesAvailable(ctx, service); httpErr != nil {
+		return nil, httpErr
+	}
+
+	if err := s.repo.UpdateService(ctx, service); err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	return dto.NewServiceResponse(service), nil
+}
+
+func (s *catalogService) DeleteService(ctx context.Context, id int) exceptions.HTTPError {
+	if err := s.repo.DeleteService(ctx, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return exceptions.NewNotFound(err.Error())
+		}
+		return exceptions.NewInternalServerError(err.Error())
+	}
+	return nil
+}
+
+func (s *catalogService) ListServices(ctx context.Context, query dto.ListServicesQuery) (*dto.ListServicesResponse, exceptions.HTTPError) {
+	services, err := s.repo.ListServices(ctx, query.Category)
+	if err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	response := &dto.ListServicesResponse{Data: []*dto.ServiceResponse{}}
+	for _, service := range services {
+		response.Data = append(response.Data, dto.NewServiceResponse(service))
+	}
+
+	return response, nil
+}
+
+func (s *catalogService) MatchService(ctx context.Context, name string) (*dto.ServiceMatchResponse, exceptions.HTTPError) {
+	services, err := s.repo.ListServices(ctx, nil)
+	if err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	match, ok := matchCatalog(services, name)
+	if !ok {
+		return nil, exceptions.NewNotFound(fmt.Sprintf("no catalog service matches %q", name))
+	}
+
+	response := &dto.ServiceMatchResponse{
+		Service: dto.NewServiceResponse(match.service),
+		Score:   match.score,
+	}
+	if match.plan != nil {
+		response.Plan = dto.NewPlanResponse(match.plan)
+	}
+
+	return response, nil
+}
+
+func (s *catalogService) CreatePlan(ctx context.Context, serviceID int, req dto.CreatePlanRequest) (*dto.PlanResponse, exceptions.HTTPError) {
+	service, httpErr := s.getService(ctx, serviceID)
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
+	plan := newPlanFromRequest(req)
+	plan.ServiceID = service.ID
+	if httpErr := checkPlanNameAvailable(service, plan); httpErr != nil {
+		return nil, httpErr
+	}
+
+	if err := s.repo.CreatePlan(ctx, plan); err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	return dto.NewPlanResponse(plan), nil
+}
+
+func (s *catalogService) UpdatePlan(ctx context.Context, serviceID, planID int, req dto.UpdatePlanRequest) (*dto.PlanResponse, exceptions.HTTPError) {
+	service, httpErr := s.getService(ctx, serviceID)
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
+	plan := findPlan(service, planID)
+	if plan == nil {
+		return nil, exceptions.NewNotFound(gorm.ErrRecordNotFound.Error())
+	}
+
+	if req.Name != nil {
+		plan.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Price != nil {
+		plan.Price = *req.Price
+	}
+	if req.Currency != nil {
+		plan.Currency = *req.Currency
+	}
+	if req.BillingCycle != nil {
+		plan.BillingCycle = *req.BillingCycle
+	}
+	if req.Seats != nil {
+		plan.Seats = *req.Seats
+	}
+
+	if httpErr := checkPlanNameAvailable(service, plan); httpErr != nil {
+		return nil, httpErr
+	}
+
+	if err := s.repo.UpdatePlan(ctx, plan); err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	return dto.NewPlanResponse(plan), nil
+}
+
+func (s *catalogService) DeletePlan(ctx context.Context, serviceID, planID int) exceptions.HTTPError {
+	service, httpErr := s.getService(ctx, serviceID)
+	if httpErr != nil {
+		return httpErr
+	}
+
+	if findPlan(service, planID) == nil {
+		return exceptions.NewNotFound(gorm.ErrRecordNotFound.Error())
+	}
+
+	if err := s.repo.DeletePlan(ctx, planID); err != nil {
+		return exceptions.NewInternalServerError(err.Error())
+	}
+
+	return nil
+}
+
+func (s *catalogService) getService(ctx context.Context, id int) (*models.Service, exceptions.HTTPError) {
+	service, err := s.repo.GetService(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, exceptions.NewNotFound(err.Error())
+		}
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+	return service, nil
+}
+
+// checkNamesAvailable rejects a service whose name or aliases are already used by
+// another service, since matching would then be ambiguous.
+func (s *catalogService) checkNamesAvailable(ctx context.Context, service *models.Service) exceptions.HTTPError {
+	if textmatch.Normalize(service.Name) == "" {
+		return exceptions.NewBadRequest("service name must contain letters or digits")
+	}
+
+	services, err := s.repo.ListServices(ctx, nil)
+	if err != nil {
+		return exceptions.NewInternalServerError(err.Error())
+	}
+
+	taken := make(map[string]string)
+	for _, other := range services {
+		if other.ID == service.ID {
+			continue
+		}
+		for _, name := range catalogNames(other) {
+			taken[textmatch.Normalize(name)] = other.Name
+		}
+	}
+
+	for _, name := range catalogNames(service) {
+		if owner, ok := taken[textmatch.Normalize(name)]; ok {
+			return exceptions.NewConflict(fmt.Sprintf("name %q is already used by service %q", name, owner))
+		}
+	}
+
+	return nil
+}
+
+func checkPlanNameAvailable(service *models.Service, plan *models.Plan) exceptions.HTTPError {
+	for _, other := range service.Plans {
+		if other.ID != plan.ID && textmatch.Normalize(other.Name) == textmatch.Normalize(plan.Name) {
+			return exceptions.NewConflict(fmt.Sprintf("service %q already has a plan named %q", service.Name, other.Name))
+		}
+	}
+	return nil
+}
+
+func newPlanFromRequest(req dto.CreatePlanRequest) *models.Plan {
+	plan := &models.Plan{
+		Name:         strings.TrimSpace(req.Name),
+		Price:        req.Price,
+		Currency:     req.Currency,
+		BillingCycle: req.BillingCycle,
+		Seats:        req.Seats,
+	}
+	if plan.Seats == 0 {
+		plan.Seats = 1
+	}
+	if plan.Currency == "" {
+		plan.Currency = models.DefaultCurrency
+	}
+	if plan.BillingCycle == "" {
+		plan.BillingCycle = models.BillingCycleMonthly
+	}
+	return plan
+}
+
+func findPlan(service *models.Service, planID int) *models.Plan {
+	for idx := range service.Plans {
+		if int(service.Plans[idx].ID) == planID {
+			return &service.Plans[idx]
+		}
+	}
+	return nil
+}
+
+// cleanAliases trims aliases and drops empty and repeated ones.
+func cleanAliases(aliases []string) []string {
+	cleaned := []string{}
+	seen := make(map[string]bool)
+	for _, alias := range aliases {
+		alias = strings.TrimSpace(alias)
+		key := textmatch.Normalize(alias)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		cleaned = append(cleaned, alias)
+	}
+	return cleaned
+}
+
+// catalogNames returns the canonical name followed by the aliases of a service.
+func catalogNames(service *models.Service) []string {
+	return append([]string{service.Name}, service.Aliases...)
+}
+
+type catalogMatch struct {
+	service *models.Service
+	plan    *models.Plan
+	score   float64
+}
+
+// matchCatalog resolves a free-text service name to a catalog service. A name
+// matches when it is close to the service name or an alias, or when it is a
+// name or alias followed by one of the service's plans ("Netflix Premium").
+func matchCatalog(services []*models.Service, name string) (catalogMatch, bool) {
+	key := textmatch.Normalize(name)
+	if key == "" {
+		return catalogMatch{}, false
+	}
+
+	var best catalogMatch
+	for _, service := range services {
+		for _, candidate := range catalogNames(service) {
+			candidateKey := textmatch.Normalize(candidate)
+			if candidateKey == "" {
+				continue
+			}
+
+			if rest, ok := strings.CutPrefix(key, candidateKey+" "); ok {
+				for idx := range service.Plans {
+					if textmatch.Normalize(service.Plans[idx].Name) == rest {
+						return catalogMatch{service: service, plan: &service.Plans[idx], score: 1}, true
+					}
+				}
+			}
+
+			if score := textmatch.Similarity(key, candidateKey); score > best.score {
+				best = catalogMatch{service: service, score: score}
+			}
+		}
+	}
+
+	if best.score < catalogMatchThreshold {
+		return catalogMatch{}, false
+	}
+	return best, true
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/pkg/exceptions"
+	"gorm.io/gorm"
+)
+
+type DraftService interface {
+	ListDrafts(ctx context.Context, userID string, query dto.ListDraftsQuery) (*dto.ListDraftsResponse, exceptions.HTTPError)
+	ConfirmDraft(ctx context.Context, userID string, id int, req dto.ConfirmDraftRequest) (*dto.SubscriptionResponse, exceptions.HTTPError)
+	DismissDraft(ctx context.Context, userID string, id int) exceptions.HTTPError
+}
+
+type draftService struct {
+	drafts        repository.DraftRepository
+	subscriptions SubscriptionService
+}
+
+func NewDraftService(drafts repository.DraftRepository, subscriptions SubscriptionService) DraftService {
+	return &draftService{drafts: drafts, subscriptions: subscriptions}
+}
+
+func (s *draftService) ListDrafts(ctx context.Context, userID string, query dto.ListDraftsQuery) (*dto.ListDraftsResponse, exceptions.HTTPError) {
+	if httpErr := authorizeUser(ctx, userID); httpErr != nil {
+		return nil, httpErr
+	}
+
+	drafts, err := s.drafts.ListDrafts(ctx, userID, query.Status)
+	if err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	response := &dto.ListDraftsResponse{Data: []*dto.DraftResponse{}}
+	for _, draft := range drafts {
+		response.Data = append(response.Data, dto.NewDraftResponse(draft))
+	}
+
+	return response, nil
+}
+
+// ConfirmDraft creates a subscription from a pending draft through the regular
+// creation path, so it is validated exactly like a manually created one.
+func (s *draftService) ConfirmDraft(ctx context.Context, userID string, id int, req dto.ConfirmDraftRequest) (*dto.SubscriptionResponse, exceptions.HTTPError) {
+	if httpErr := authorizeUser(ctx, userID); httpErr != nil {
+		return nil, httpErr
+	}
+
+	draft, httpErr := s.pendingDraft(ctx, userID, id)
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
+	create := dto.CreateSubscriptionRequest{
+		ServiceName:  draft.ServiceName,
+		Price:        draft.Price,
+		Currency:     draft.Currency,
+		UserID:       draft.UserID,
+		BillingCycle: draft.BillingCycle,
+		StartDate:    formatMonthYear(draft.StartDate),
+	}
+	if req.ServiceName != nil {
+		create.ServiceName = *req.ServiceName
+	}
+	if req.Price != nil {
+		create.Price = *req.Price
+	}
+	if req.Currency != nil {
+		create.Currency = *req.Currency
+	}
+	if req.BillingCycle != nil {
+		create.BillingCycle = *req.BillingCycle
+	}
+	if req.StartDate != nil {
+		create.StartDate = *req.StartDate
+	}
+	if req.EndDate != nil {
+		create.EndDate = *req.EndDate
+	}
+
+	subscription, httpErr := s.subscriptions.CreateSubscription(ctx, create)
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
+	draft.Status = models.DraftStatusConfirmed
+	draft.SubscriptionID = &subscription.ID
+	if err := s.drafts.SaveDraft(ctx, draft); err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	return subscription, nil
+}
+
+func (s *draftService) DismissDraft(ctx context.Context, userID string, id int) exceptions.HTTPError {
+	if httpErr := authorizeUser(ctx, userID); httpErr != nil {
+		return httpErr
+	}
+
+	draft, httpErr := s.pendingDraft(ctx, userID, id)
+	if httpErr != nil {
+		return httpErr
+	}
+
+	draft.Status = models.DraftStatusDismissed
+	if err := s.drafts.SaveDraft(ctx, draft); err != nil {
+		return exceptions.NewInternalServerError(err.Error())
+	}
+
+	return nil
+}
+
+// pendingDraft loads a draft owned by the user that has not been confirmed or dismissed yet.
+func (s *draftService) pendingDraft(ctx context.Context, userID string, id int) (*models.SubscriptionDraft, exceptions.HTTPError) {
+	draft, err := s.drafts.GetDraft(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, exceptions.NewNotFound(err.Error())
+		}
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	if draft.UserID != userID {
+		return nil, exceptions.NewNotFound(gorm.ErrRecordNotFound.Error())
+	}
+
+	if draft.Status != models.DraftStatusPending {
+		return nil, exceptions.NewConflict("draft is already " + draft.Status)
+	}
+
+	return draft, nil
+}
@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/pkg/exceptions"
+	"github.com/rasadov/subscription-manager/pkg/textmatch"
+)
+
+type DuplicateService interface {
+	FindDuplicates(ctx context.Context, userID string) (*dto.DuplicatesResponse, exceptions.HTTPError)
+}
+
+type duplicateService struct {
+	repo repository.SubscriptionRepository
+}
+
+func NewDuplicateService(repo repository.SubscriptionRepository) DuplicateService {
+	return &duplicateService{repo: repo}
+}
+
+// FindDuplicates reports the subscriptions of a user to the same service whose
+// periods overlap, with the spend wasted on them so far.
+func (s *duplicateService) FindDuplicates(ctx context.Context, userID string) (*dto.DuplicatesResponse, exceptions.HTTPError) {
+	if httpErr := authorizeUser(ctx, userID); httpErr != nil {
+		return nil, httpErr
+	}
+
+	var subscriptions []*models.Subscription
+	err := s.repo.StreamSubscriptions(ctx, repository.SubscriptionFilter{UserID: &userID}, func(subscription *models.Subscription) error {
+		subscriptions = append(subscriptions, subscription)
+		return nil
+	})
+	if err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	response := &dto.DuplicatesResponse{Groups: []*dto.DuplicateGroup{}}
+	for _, group := range findDuplicates(subscriptions, time.Now()) {
+		response.Groups = append(response.Groups, group)
+		response.MonthlyWaste += group.MonthlyWaste
+		response.WastedSpend += group.WastedSpend
+	}
+
+	return response, nil
+}
+
+// duplicateKey identifies the service of a subscription. Names of catalog
+// services are canonical, so spelling variants of custom names are normalized.
+func duplicateKey(subscription *models.Subscription) string {
+	return textmatch.Normalize(subscription.ServiceName)
+}
+
+// periodsOverlap reports whether two subscriptions run in at least one common month.
+func periodsOverlap(a, b *models.Subscription) bool {
+	return (b.EndDate == nil || !a.StartDate.After(*b.EndDate)) &&
+		(a.EndDate == nil || !b.StartDate.After(*a.EndDate))
+}
+
+// duplicateWarnings describes the existing subscriptions a new one would overlap with.
+func duplicateWarnings(subscription *models.Subscription, existing []*models.Subscription) []string {
+	var warnings []string
+	for _, other := range existing {
+		if other.ID == subscription.ID || duplicateKey(other) != duplicateKey(subscription) || !periodsOverlap(subscription, other) {
+			continue
+		}
+		warnings = append(warnings, fmt.Sprintf("overlaps with subscription %d (%s) started %s",
+			other.ID, other.ServiceName, formatMonthYear(other.StartDate)))
+	}
+	return warnings
+}
+
+// duplicateIndex holds the subscriptions new ones are checked against for
+// duplicates, read once per user. Imports keep one for the whole file and add the
+// rows they save, so they do not read every subscription of a user for each row.
+type duplicateIndex struct {
+	repo   repository.SubscriptionRepository
+	byUser map[string][]*models.Subscription
+}
+
+func newDuplicateIndex(repo repository.SubscriptionRepository) *duplicateIndex {
+	return &duplicateIndex{repo: repo, byUser: make(map[string][]*models.Subscription)}
+}
+
+// warnings describes the subscriptions of the user the subscription would overlap with.
+func (d *duplicateIndex) warnings(ctx context.Context, subscription *models.Subscription) ([]string, error) {
+	existing, ok := d.byUser[subscription.UserID]
+	if !ok {
+		filter := repository.SubscriptionFilter{UserID: &subscription.UserID}
+		err := d.repo.StreamSubscriptions(ctx, filter, func(other *models.Subscription) error {
+			existing = append(existing, other)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		d.byUser[subscription.UserID] = existing
+	}
+	return duplicateWarnings(subscription, existing), nil
+}
+
+// put adds a saved subscription to the index or replaces its previous version.
+// Users whose subscriptions were not read yet will be read with it.
+func (d *duplicateIndex) put(subscription *models.Subscription) {
+	existing, ok := d.byUser[subscription.UserID]
+	if !ok {
+		return
+	}
+	for idx, other := range existing {
+		if other.ID == subscription.ID {
+			existing[idx] = subscription
+			return
+		}
+	}
+	d.byUser[subscription.UserID] = append(existing, subscription)
+}
+
+// findDuplicates groups subscriptions by service and splits each group into runs
+// of overlapping periods. Runs with more than one subscription are duplicates.
+func findDuplicates(subscriptions []*models.Subscription, now time.Time) []*dto.DuplicateGroup {
+	byService := make(map[string][]*models.Subscription)
+	var keys []string
+	for _, subscription := range subscriptions {
+		key := duplicateKey(subscription)
+		if _, ok := byService[key]; !ok {
+			keys = append(keys, key)
+		}
+		byService[key] = append(byService[key], subscription)
+	}
+	slices.Sort(keys)
+
+	var groups []*dto.DuplicateGroup
+	for _, key := range keys {
+		members := byService[key]
+		slices.SortFunc(members, func(a, b *models.Subscription) int {
+			return a.StartDate.Compare(b.StartDate)
+		})
+
+		for start := 0; start < len(members); {
+			end, runEnd := start+1, members[start].EndDate
+			for end < len(members) && (runEnd == nil || !members[end].StartDate.After(*runEnd)) {
+				if runEnd != nil && (members[end].EndDate == nil || members[end].EndDate.After(*runEnd)) {
+					runEnd = members[end].EndDate
+				}
+				end++
+			}
+			if end-start > 1 {
+				groups = append(groups, newDuplicateGroup(members[start:end], now))
+			}
+			start = end
+		}
+	}
+
+	return groups
+}
+
+// newDuplicateGroup walks the months of overlapping subscriptions and sums the
+// monthly price of all but the most expensive subscription active each month.
+// Open-ended overlaps are counted up to the current month.
+func newDuplicateGroup(members []*models.Subscription, now time.Time) *dto.DuplicateGroup {
+	group := &dto.DuplicateGroup{
+		Kind:        dto.DuplicateKindOverlap,
+		ServiceName: members[0].ServiceName,
+	}
+
+	plans := make(map[uint]bool)
+	openEnded := 0
+	last := startOfMonth(now)
+	for _, member := range members {
+		group.Subscriptions = append(group.Subscriptions, dto.NewSubscriptionResponse(member))
+		if member.PlanID != nil {
+			plans[*member.PlanID] = true
+		}
+		if member.EndDate == nil {
+			openEnded++
+		} else if member.EndDate.After(last) {
+			last = *member.EndDate
+		}
+		if member.StartDate.After(last) {
+			last = member.StartDate
+		}
+	}
+	if len(plans) > 1 {
+		group.Kind = dto.DuplicateKindMultiplePlans
+	}
+
+	var overlapStart, overlapEnd time.Time
+	for month := members[0].StartDate; !month.After(last); month = month.AddDate(0, 1, 0) {
+		var active, total, highest int64
+		for _, member := range members {
+			if member.ActiveIn(month) {
+				price := member.MonthlyPrice()
+				active++
+				total += price
+				highest = max(highest, price)
+			}
+		}
+		if active < 2 {
+			continue
+		}
+
+		if overlapStart.IsZero() {
+			overlapStart = month
+		}
+		overlapEnd = month
+		group.MonthlyWaste = total - highest
+		group.WastedSpend += group.MonthlyWaste
+	}
+
+	group.OverlapStart = dto.MonthYear(overlapStart)
+	if openEnded < 2 {
+		end := dto.MonthYear(overlapEnd)
+		group.OverlapEnd = &end
+	}
+
+	return group
+}
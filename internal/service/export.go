@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/pkg/exceptions"
+	"github.com/rasadov/subscription-manager/pkg/xlsx"
+)
+
+var exportColumns = []string{"id", "service_name", "price", "currency", "billing_cycle", "user_id", "start_date", "end_date", "category", "tags", "created_at", "updated_at"}
+
+// csvFlushInterval controls how many rows are buffered before the CSV writer flushes to the client.
+const csvFlushInterval = 500
+
+type ExportService interface {
+	ExportSubscriptions(ctx context.Context, w io.Writer, query dto.ExportSubscriptionsQuery) exceptions.HTTPError
+}
+
+type exportService struct {
+	subscriptions *subscriptionService
+}
+
+// NewExportService exports the subscriptions the list endpoint of a subscription
+// service built with opts would return for the same filters.
+func NewExportService(repo repository.SubscriptionRepository, opts ...SubscriptionServiceOption) ExportService {
+	return &exportService{subscriptions: newSubscriptionService(repo, opts...)}
+}
+
+// ExportSubscriptions streams every subscription matching the query filters to w.
+// Nothing is written to w when the query is invalid, so callers can still report the error.
+func (s *exportService) ExportSubscriptions(ctx context.Context, w io.Writer, query dto.ExportSubscriptionsQuery) exceptions.HTTPError {
+	filter, httpErr := s.subscriptions.listFilter(ctx, query.ListSubscriptionsQuery)
+	if httpErr != nil {
+		return httpErr
+	}
+
+	var writer exportWriter
+	switch query.Format {
+	case dto.ExportFormatCSV, "":
+		writer = newCSVExportWriter(w)
+	case dto.ExportFormatJSONL:
+		writer = newJSONLExportWriter(w)
+	case dto.ExportFormatXLSX:
+		xlsxWriter, err := newXLSXExportWriter(w)
+		if err != nil {
+			return exceptions.NewInternalServerError(err.Error())
+		}
+		writer = xlsxWriter
+	default:
+		return exceptions.NewBadRequest("unsupported export format: " + query.Format)
+	}
+
+	err := s.subscriptions.repo.StreamSubscriptions(ctx, filter, writer.Write)
+	if err != nil {
+		return exceptions.NewInternalServerError(err.Error())
+	}
+
+	if err := writer.Close(); err != nil {
+		return exceptions.NewInternalServerError(err.Error())
+	}
+
+	return nil
+}
+
+type exportWriter interface {
+	Write(subscription *models.Subscription) error
+	Close() error
+}
+
+type csvExportWriter struct {
+	writer *csv.Writer
+	rows   int
+}
+
+func newCSVExportWriter(w io.Writer) *csvExportWriter {
+	return &csvExportWriter{writer: csv.NewWriter(w)}
+}
+
+func (e *csvExportWriter) Write(subscription *models.Subscription) error {
+	if e.rows == 0 {
+		if err := e.writer.Write(exportColumns); err != nil {
+			return err
+		}
+	}
+
+	endDate := ""
+	if subscription.EndDate != nil {
+		endDate = formatMonthYear(*subscription.EndDate)
+	}
+
+	e.rows++
+	err := e.writer.Write([]string{
+		strconv.FormatUint(uint64(subscription.ID), 10),
+		subscription.ServiceName,
+		strconv.FormatInt(subscription.Price, 10),
+		subscription.Currency,
+		subscription.BillingCycle,
+		subscription.UserID,
+		formatMonthYear(subscription.StartDate),
+		endDate,
+		subscription.Category,
+		formatTags(subscription),
+		subscription.CreatedAt.UTC().Format(time.RFC3339),
+		subscription.UpdatedAt.UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+
+	if e.rows%csvFlushInterval == 0 {
+		e.writer.Flush()
+	}
+	return e.writer.Error()
+}
+
+func (e *csvExportWriter) Close() error {
+	if e.rows == 0 {
+		if err := e.writer.Write(exportColumns); err != nil {
+			return err
+		}
+	}
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+type jsonlExportWriter struct {
+	encoder *json.Encoder
+}
+
+func newJSONLExportWriter(w io.Writer) *jsonlExportWriter {
+	return &jsonlExportWriter{encoder: json.NewEncoder(w)}
+}
+
+func (e *jsonlExportWriter) Write(subscription *models.Subscription) error {
+	return e.encoder.Encode(dto.NewSubscriptionResponse(subscription))
+}
+
+func (e *jsonlExportWriter) Close() error {
+	return nil
+}
+
+type xlsxExportWriter struct {
+	writer *xlsx.Writer
+}
+
+func newXLSXExportWriter(w io.Writer) (*xlsxExportWriter, error) {
+	writer, err := xlsx.NewWriter(w, "Subscriptions")
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]any, len(exportColumns))
+	for idx, column := range exportColumns {
+		header[idx] = column
+	}
+	if err := writer.WriteRow(header...); err != nil {
+		return nil, err
+	}
+
+	return &xlsxExportWriter{writer: writer}, nil
+}
+
+func (e *xlsxExportWriter) Write(subscription *models.Subscription) error {
+	var endDate any
+	if subscription.EndDate != nil {
+		endDate = formatMonthYear(*subscription.EndDate)
+	}
+
+	return e.writer.WriteRow(
+		subscription.ID,
+		subscription.ServiceName,
+		subscription.Price,
+		subscription.Currency,
+		subscription.BillingCycle,
+		subscription.UserID,
+		formatMonthYear(subscription.StartDate),
+		endDate,
+		subscription.Category,
+		formatTags(subscription),
+		subscription.CreatedAt.UTC().Format(time.RFC3339),
+		subscription.UpdatedAt.UTC().Format(time.RFC3339),
+	)
+}
+
+func (e *xlsxExportWriter) Close() error {
+	return e.writer.Close()
+}
+
+func formatMonthYear(t time.Time) string {
+	return t.Format("01-2006")
+}
+
+// formatTags joins the tag names with commas, like the tags filter of the list endpoint.
+func formatTags(subscription *models.Subscription) string {
+	names := make([]string, 0, len(subscription.Tags))
+	for _, tag := range subscription.Tags {
+		names = append(names, tag.Name)
+	}
+	return strings.Join(names, ",")
+}
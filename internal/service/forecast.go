@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/pkg/exceptions"
+	"gorm.io/gorm"
+)
+
+// ForecastService projects future spend and manages the scheduled price changes
+// the projection takes into account.
+type ForecastService interface {
+	Forecast(ctx context.Context, query dto.ForecastQuery) (*dto.ForecastResponse, exceptions.HTTPError)
+	SchedulePriceChange(ctx context.Context, subscriptionID int, req dto.CreatePriceChangeRequest) (*dto.PriceChangeResponse, exceptions.HTTPError)
+	ListPriceChanges(ctx context.Context, subscriptionID int) (*dto.ListPriceChangesResponse, exceptions.HTTPError)
+	CancelPriceChange(ctx context.Context, subscriptionID, id int) exceptions.HTTPError
+}
+
+type forecastService struct {
+	subscriptions repository.SubscriptionRepository
+	priceChanges  repository.PriceChangeRepository
+}
+
+func NewForecastService(subscriptions repository.SubscriptionRepository, priceChanges repository.PriceChangeRepository) ForecastService {
+	return &forecastService{subscriptions: subscriptions, priceChanges: priceChanges}
+}
+
+// Forecast walks the billing dates of every matching subscription over the window
+// and charges the price in effect on each date. Charges before the end of a trial
+// are free and subscriptions in other currencies are left out. With a user, the
+// subscriptions shared with them are included and the user is charged their part
+// of shared ones, like in budgets.
+func (s *forecastService) Forecast(ctx context.Context, query dto.ForecastQuery) (*dto.ForecastResponse, exceptions.HTTPError) {
+	from := startOfMonth(time.Now())
+	if query.Start != nil {
+		start, err := time.Parse("01-2006", *query.Start)
+		if err != nil {
+			return nil, exceptions.NewBadRequest(err.Error())
+		}
+		from = start
+	}
+	if query.Months == 0 {
+		query.Months = 12
+	}
+	currency := query.Currency
+	if currency == "" {
+		currency = models.DefaultCurrency
+	}
+
+	// The annual total needs at least twelve months
+	window := max(query.Months, 12)
+	to := from.AddDate(0, window, 0)
+
+	userID, httpErr := scopeUserFilter(ctx, nonEmpty(query.UserID), nil)
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
+	var subscriptions []*models.Subscription
+	filter := repository.SubscriptionFilter{UserID: userID, IncludeShared: true}
+	err := s.subscriptions.StreamSubscriptions(ctx, filter, func(subscription *models.Subscription) error {
+		if subscription.Currency == currency {
+			subscriptions = append(subscriptions, subscription)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	changes, err := s.priceChangesBySubscription(ctx, subscriptions)
+	if err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	months := make([]*dto.ForecastMonth, window)
+	for idx := range months {
+		months[idx] = &dto.ForecastMonth{
+			Month:   dto.MonthYear(from.AddDate(0, idx, 0)),
+			Charges: []*dto.ForecastCharge{},
+		}
+	}
+
+	for _, subscription := range subscriptions {
+		for _, date := range subscription.BillingDates(from, to) {
+			if subscription.InTrial(date) {
+				continue
+			}
+
+			price, changed := priceOn(subscription, changes[subscription.ID], date)
+			price = amountFor(subscription, userID, price)
+			month := months[(date.Year()-from.Year())*12+int(date.Month())-int(from.Month())]
+			month.Total += price
+			month.Charges = append(month.Charges, &dto.ForecastCharge{
+				SubscriptionID: subscription.ID,
+				ServiceName:    subscription.ServiceName,
+				Amount:         price,
+				TrialEnded:     subscription.TrialEndDate != nil && date.Equal(*subscription.TrialEndDate),
+				PriceChanged:   changed,
+			})
+		}
+	}
+
+	response := &dto.ForecastResponse{Currency: currency, Months: months[:query.Months]}
+	for idx, month := range months {
+		if idx < query.Months {
+			response.Total += month.Total
+		}
+		if idx < 12 {
+			response.AnnualTotal += month.Total
+		}
+	}
+
+	return response, nil
+}
+
+func (s *forecastService) SchedulePriceChange(ctx context.Context, subscriptionID int, req dto.CreatePriceChangeRequest) (*dto.PriceChangeResponse, exceptions.HTTPError) {
+	subscription, httpErr := s.getSubscription(ctx, subscriptionID, true)
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
+	effectiveDate, err := time.Parse("01-2006", req.EffectiveDate)
+	if err != nil {
+		return nil, exceptions.NewBadRequest(err.Error())
+	}
+	if !subscription.ActiveIn(effectiveDate) {
+		return nil, exceptions.NewBadRequest("effective_date must fall within the subscription period")
+	}
+
+	change := &models.PriceChange{
+		SubscriptionID: subscription.ID,
+		EffectiveDate:  effectiveDate,
+		Price:          *req.Price,
+	}
+	if err := s.priceChanges.CreatePriceChange(ctx, change); err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	return dto.NewPriceChangeResponse(change), nil
+}
+
+func (s *forecastService) ListPriceChanges(ctx context.Context, subscriptionID int) (*dto.ListPriceChangesResponse, exceptions.HTTPError) {
+	if _, httpErr := s.getSubscription(ctx, subscriptionID, false); httpErr != nil {
+		return nil, httpErr
+	}
+
+	changes, err := s.priceChanges.ListPriceChanges(ctx, []uint{uint(subscriptionID)})
+	if err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	response := &dto.ListPriceChangesResponse{Data: []*dto.PriceChangeResponse{}}
+	for _, change := range changes {
+		response.Data = append(response.Data, dto.NewPriceChangeResponse(change))
+	}
+
+	return response, nil
+}
+
+func (s *forecastService) CancelPriceChange(ctx context.Context, subscriptionID, id int) exceptions.HTTPError {
+	if _, httpErr := s.getSubscription(ctx, subscriptionID, true); httpErr != nil {
+		return httpErr
+	}
+
+	if err := s.priceChanges.DeletePriceChange(ctx, subscriptionID, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return exceptions.NewNotFound(err.Error())
+		}
+		return exceptions.NewInternalServerError(err.Error())
+	}
+	return nil
+}
+
+// getSubscription returns the subscription if the caller may read it, or change it
+// when write is set.
+func (s *forecastService) getSubscription(ctx context.Context, id int, write bool) (*models.Subscription, exceptions.HTTPError) {
+	subscription, err := s.subscriptions.GetSubscription(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, exceptions.NewNotFound(err.Error())
+		}
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+	if httpErr := authorizeSubscription(ctx, subscription, write); httpErr != nil {
+		return nil, httpErr
+	}
+	return subscription, nil
+}
+
+func (s *forecastService) priceChangesBySubscription(ctx context.Context, subscriptions []*models.Subscription) (map[uint][]*models.PriceChange, error) {
+	ids := make([]uint, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		ids = append(ids, subscription.ID)
+	}
+
+	changes, err := s.priceChanges.ListPriceChanges(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	bySubscription := make(map[uint][]*models.PriceChange)
+	for _, change := range changes {
+		bySubscription[change.SubscriptionID] = append(bySubscription[change.SubscriptionID], change)
+	}
+	return bySubscription, nil
+}
+
+// amountFor is the part of a charge of price the user pays, the whole charge
+// without a user. Shared subscriptions are split by their rule again when the price
+// changed, as they are when it is updated.
+func amountFor(subscription *models.Subscription, userID *string, price int64) int64 {
+	if userID == nil || subscription.SplitRule == "" {
+		return price
+	}
+	if price == subscription.Price {
+		return subscription.AmountFor(*userID)
+	}
+
+	repriced := *subscription
+	repriced.Price = price
+	repriced.Members = slices.Clone(subscription.Members)
+	if splitCost(&repriced) != nil {
+		// Fixed shares above the new price keep their current amounts
+		return subscription.AmountFor(*userID)
+	}
+	return repriced.AmountFor(*userID)
+}
+
+// priceOn returns the price charged on date: the latest scheduled change in
+// effect by then, or the subscription price. changes are ordered by effective date.
+func priceOn(subscription *models.Subscription, changes []*models.PriceChange, date time.Time) (int64, bool) {
+	price, changed := subscription.Price, false
+	for _, change := range changes {
+		if change.EffectiveDate.After(date) {
+			break
+		}
+		price, changed = change.Price, true
+	}
+	return price, changed
+}
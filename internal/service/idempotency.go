@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/pkg/auth"
+	"github.com/rasadov/subscription-manager/pkg/exceptions"
+	"github.com/rasadov/subscription-manager/pkg/tenant"
+	"gorm.io/gorm"
+)
+
+// maxIdempotencyKeyLength is the longest accepted Idempotency-Key.
+const maxIdempotencyKeyLength = 255
+
+type IdempotencyService interface {
+	// Reserve claims the key of the caller for a request with the fingerprint. It
+	// returns a new record for the first request, and the completed record of an
+	// earlier identical request. Keys of other requests and of requests still in
+	// progress are a conflict.
+	Reserve(ctx context.Context, key, fingerprint string) (*models.IdempotencyRecord, exceptions.HTTPError)
+	// Complete stores the response set on a reserved record.
+	Complete(ctx context.Context, record *models.IdempotencyRecord) error
+	// Release frees the key of a reserved record, so the request can be retried.
+	Release(ctx context.Context, record *models.IdempotencyRecord) error
+	// DeleteExpired removes the expired records of every tenant.
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type idempotencyService struct {
+	repo repository.IdempotencyRepository
+	ttl  time.Duration
+}
+
+// NewIdempotencyService returns a service remembering responses for ttl.
+func NewIdempotencyService(repo repository.IdempotencyRepository, ttl time.Duration) IdempotencyService {
+	return &idempotencyService{repo: repo, ttl: ttl}
+}
+
+func (s *idempotencyService) Reserve(ctx context.Context, key, fingerprint string) (*models.IdempotencyRecord, exceptions.HTTPError) {
+	if key == "" || len(key) > maxIdempotencyKeyLength {
+		return nil, exceptions.NewBadRequest("Idempotency-Key must have 1 to 255 characters")
+	}
+
+	var subject string
+	if principal, ok := auth.FromContext(ctx); ok {
+		subject = principal.Subject
+	}
+
+	// A second attempt covers records that expired or were released meanwhile
+	for range 2 {
+		now := time.Now()
+		record := &models.IdempotencyRecord{
+			Subject:     subject,
+			Key:         key,
+			Fingerprint: fingerprint,
+			ExpiresAt:   now.Add(s.ttl),
+		}
+		created, err := s.repo.CreateIdempotencyRecord(ctx, record)
+		if err != nil {
+			return nil, exceptions.NewInternalServerError(err.Error())
+		}
+		if created {
+			return record, nil
+		}
+
+		stored, err := s.repo.GetIdempotencyRecord(ctx, subject, key)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return nil, exceptions.NewInternalServerError(err.Error())
+		}
+		if !now.Before(stored.ExpiresAt) {
+			if err := s.repo.DeleteIdempotencyRecord(ctx, stored.ID); err != nil {
+				return nil, exceptions.NewInternalServerError(err.Error())
+			}
+			continue
+		}
+
+		if stored.Fingerprint != fingerprint {
+			return nil, exceptions.NewConflict("Idempotency-Key was already used for a different request")
+		}
+		if !stored.Completed() {
+			return nil, exceptions.NewConflict("a request with this Idempotency-Key is still in progress")
+		}
+		return stored, nil
+	}
+
+	return nil, exceptions.NewConflict("a request with this Idempotency-Key is still in progress")
+}
+
+func (s *idempotencyService) Complete(ctx context.Context, record *models.IdempotencyRecord) error {
+	return s.repo.CompleteIdempotencyRecord(ctx, record)
+}
+
+func (s *idempotencyService) Release(ctx context.Context, record *models.IdempotencyRecord) error {
+	return s.repo.DeleteIdempotencyRecord(ctx, record.ID)
+}
+
+func (s *idempotencyService) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	return s.repo.DeleteExpiredIdempotencyRecords(tenant.WithAllTenants(ctx), now)
+}
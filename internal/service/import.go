@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/pkg/exceptions"
+	"gorm.io/gorm"
+)
+
+// importColumns lists the CSV columns understood by the importer. By default a
+// column is looked up by a header with the same name; the mapping option renames them.
+var importColumns = []string{"service_name", "price", "currency", "user_id", "billing_cycle", "start_date", "end_date"}
+
+type ImportService interface {
+	ImportCSV(ctx context.Context, r io.Reader, query dto.ImportSubscriptionsQuery) (*dto.ImportSubscriptionsResponse, exceptions.HTTPError)
+}
+
+type importService struct {
+	subscriptions *subscriptionService
+}
+
+// NewImportService imports rows like the create and update endpoints of a
+// subscription service built with opts: they are linked to the catalog, checked
+// for duplicates and reported to the observers.
+func NewImportService(repo repository.SubscriptionRepository, opts ...SubscriptionServiceOption) ImportService {
+	return &importService{subscriptions: newSubscriptionService(repo, opts...)}
+}
+
+func (s *importService) ImportCSV(ctx context.Context, r io.Reader, query dto.ImportSubscriptionsQuery) (*dto.ImportSubscriptionsResponse, exceptions.HTTPError) {
+	mapping, err := parseColumnMapping(query.Mapping, importColumns)
+	if err != nil {
+		return nil, exceptions.NewBadRequest(err.Error())
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, exceptions.NewBadRequest("CSV file is empty")
+		}
+		return nil, exceptions.NewBadRequest(err.Error())
+	}
+
+	columns, err := resolveColumns(header, mapping)
+	if err != nil {
+		return nil, exceptions.NewBadRequest(err.Error())
+	}
+
+	response := &dto.ImportSubscriptionsResponse{
+		DryRun: query.DryRun,
+		Rows:   []*dto.ImportRowResult{},
+	}
+
+	duplicates := newDuplicateIndex(s.subscriptions.repo)
+	// line is where the last record read starts, the header at first
+	line, _ := reader.FieldPos(0)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		var result *dto.ImportRowResult
+		var parseErr *csv.ParseError
+		failed := err != nil && !errors.As(err, &parseErr)
+		if parseErr != nil {
+			result = rejectedRow(parseErr.StartLine, parseErr.Error())
+		} else if failed {
+			// The rest of the file cannot be read, but the rows before it may
+			// have been saved already and are reported
+			result = rejectedRow(line+1, err.Error())
+		} else {
+			line, _ = reader.FieldPos(0)
+			result = s.importRecord(ctx, line, record, columns, query, duplicates)
+		}
+
+		response.Total++
+		if result.Status == dto.ImportStatusAccepted {
+			response.Accepted++
+		} else {
+			response.Rejected++
+		}
+		response.Rows = append(response.Rows, result)
+		if failed {
+			break
+		}
+	}
+
+	return response, nil
+}
+
+func (s *importService) importRecord(ctx context.Context, line int, record []string, columns map[string]int, query dto.ImportSubscriptionsQuery, duplicates *duplicateIndex) *dto.ImportRowResult {
+	value := func(column string) string {
+		idx, ok := columns[column]
+		if !ok || idx >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[idx])
+	}
+
+	req := dto.CreateSubscriptionRequest{
+		ServiceName:  value("service_name"),
+		Currency:     strings.ToUpper(value("currency")),
+		UserID:       value("user_id"),
+		BillingCycle: value("billing_cycle"),
+		StartDate:    value("start_date"),
+		EndDate:      value("end_date"),
+	}
+
+	var reasons []string
+	if rawPrice := value("price"); rawPrice != "" {
+		price, err := strconv.ParseInt(rawPrice, 10, 64)
+		if err != nil {
+			reasons = append(reasons, fmt.Sprintf("invalid price %q", rawPrice))
+		}
+		req.Price = price
+	}
+
+	// Apply the same binding rules the create endpoint enforces on JSON bodies
+	if err := binding.Validator.ValidateStruct(&req); err != nil {
+		reasons = append(reasons, strings.Split(err.Error(), "\n")...)
+	}
+	if len(reasons) > 0 {
+		return rejectedRow(line, reasons...)
+	}
+	if httpErr := authorizeUser(ctx, req.UserID); httpErr != nil {
+		return rejectedRow(line, httpErr.Error())
+	}
+
+	// Resolved here as well, so upserts find rows saved under the catalog name
+	req, httpErr := s.subscriptions.resolveCatalog(ctx, req)
+	if httpErr != nil {
+		return rejectedRow(line, httpErr.Error())
+	}
+	subscription, httpErr := newSubscriptionFromRequest(req)
+	if httpErr != nil {
+		return rejectedRow(line, httpErr.Error())
+	}
+
+	result := &dto.ImportRowResult{
+		Row:    line,
+		Status: dto.ImportStatusAccepted,
+		Action: dto.ImportActionCreate,
+	}
+
+	var existing *models.Subscription
+	if query.Upsert {
+		var err error
+		existing, err = s.subscriptions.repo.GetSubscriptionByNaturalKey(ctx, subscription.UserID, subscription.ServiceName, subscription.StartDate)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return rejectedRow(line, err.Error())
+		}
+		if existing != nil {
+			if httpErr := authorizeSubscription(ctx, existing, true); httpErr != nil {
+				return rejectedRow(line, httpErr.Error())
+			}
+			result.Action = dto.ImportActionUpdate
+			result.ID = existing.ID
+		}
+	}
+
+	if query.DryRun {
+		return result
+	}
+
+	if existing != nil {
+		endDate := req.EndDate
+		subscription, httpErr = s.subscriptions.updateSubscription(ctx, s.subscriptions.repo, int(existing.ID), dto.UpdateSubscriptionRequest{
+			Price:        &subscription.Price,
+			Currency:     &subscription.Currency,
+			BillingCycle: &subscription.BillingCycle,
+			EndDate:      &endDate,
+		})
+		if httpErr == nil {
+			duplicates.put(subscription)
+		}
+	} else {
+		subscription, result.Warnings, httpErr = s.subscriptions.createSubscription(ctx, s.subscriptions.repo, req, duplicates)
+	}
+	if httpErr != nil {
+		return rejectedRow(line, httpErr.Error())
+	}
+	s.subscriptions.notify(ctx, subscription)
+	result.ID = subscription.ID
+
+	return result
+}
+
+func rejectedRow(line int, reasons ...string) *dto.ImportRowResult {
+	return &dto.ImportRowResult{
+		Row:    line,
+		Status: dto.ImportStatusRejected,
+		Errors: reasons,
+	}
+}
+
+// parseColumnMapping parses "field=Header,field=Header" into a field -> header map
+// covering every field, where unmapped fields keep their own name as header.
+func parseColumnMapping(raw string, fields []string) (map[string]string, error) {
+	mapping := make(map[string]string, len(fields))
+	for _, field := range fields {
+		mapping[field] = field
+	}
+
+	if strings.TrimSpace(raw) == "" {
+		return mapping, nil
+	}
+
+	for _, pair := range strings.Split(raw, ",") {
+		field, header, ok := strings.Cut(pair, "=")
+		field = strings.TrimSpace(field)
+		header = strings.TrimSpace(header)
+		if !ok || field == "" || header == "" {
+			return nil, fmt.Errorf("invalid column mapping %q, expected field=header", pair)
+		}
+		if _, known := mapping[field]; !known {
+			return nil, fmt.Errorf("unknown field %q in column mapping", field)
+		}
+		mapping[field] = header
+	}
+
+	return mapping, nil
+}
+
+// resolveColumns maps every known field to its index in the CSV header.
+// Headers are matched case-insensitively; fields without a header are left out.
+func resolveColumns(header []string, mapping map[string]string) (map[string]int, error) {
+	positions := make(map[string]int, len(header))
+	for idx, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, duplicate := positions[name]; !duplicate {
+			positions[name] = idx
+		}
+	}
+
+	columns := make(map[string]int, len(mapping))
+	for field, headerName := range mapping {
+		if idx, ok := positions[strings.ToLower(headerName)]; ok {
+			columns[field] = idx
+		}
+	}
+
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("CSV header does not contain any of the expected columns: %s", strings.Join(importColumns, ", "))
+	}
+
+	return columns, nil
+}
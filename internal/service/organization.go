@@ -0,0 +1,325 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/pkg/exceptions"
+	"gorm.io/gorm"
+)
+
+type OrganizationService interface {
+	HouseholdResolver
+	CreateOrganization(ctx context.Context, req dto.CreateOrganizationRequest) (*dto.OrganizationResponse, exceptions.HTTPError)
+	GetOrganization(ctx context.Context, id int) (*dto.OrganizationResponse, exceptions.HTTPError)
+	UpdateOrganization(ctx context.Context, id int, req dto.UpdateOrganizationRequest) (*dto.OrganizationResponse, exceptions.HTTPError)
+	DeleteOrganization(ctx context.Context, id int) exceptions.HTTPError
+	ListOrganizations(ctx context.Context, query dto.ListOrganizationsQuery) (*dto.ListOrganizationsResponse, exceptions.HTTPError)
+	AddMember(ctx context.Context, id int, req dto.AddOrganizationMemberRequest) (*dto.OrganizationMemberResponse, exceptions.HTTPError)
+	UpdateMember(ctx context.Context, id int, userID string, req dto.UpdateOrganizationMemberRequest) (*dto.OrganizationMemberResponse, exceptions.HTTPError)
+	RemoveMember(ctx context.Context, id int, userID string) exceptions.HTTPError
+	AcceptMember(ctx context.Context, id int, userID string) (*dto.OrganizationMemberResponse, exceptions.HTTPError)
+}
+
+type organizationService struct {
+	repo repository.OrganizationRepository
+}
+
+func NewOrganizationService(repo repository.OrganizationRepository) OrganizationService {
+	return &organizationService{repo: repo}
+}
+
+// CreateOrganization creates the organization with OwnerID as its owner and
+// invites the listed members, who get the member role unless the request says
+// otherwise.
+func (s *organizationService) CreateOrganization(ctx context.Context, req dto.CreateOrganizationRequest) (*dto.OrganizationResponse, exceptions.HTTPError) {
+	if httpErr := authorizeUser(ctx, req.OwnerID); httpErr != nil {
+		return nil, httpErr
+	}
+
+	now := time.Now()
+	organization := &models.Organization{
+		Name:    strings.TrimSpace(req.Name),
+		Kind:    req.Kind,
+		Members: []models.OrganizationMember{{UserID: req.OwnerID, Role: models.OrganizationRoleOwner, AcceptedAt: &now}},
+	}
+	if organization.Name == "" {
+		return nil, exceptions.NewBadRequest("name must not be blank")
+	}
+	if organization.Kind == "" {
+		organization.Kind = models.OrganizationKindCompany
+	}
+
+	seen := map[string]bool{req.OwnerID: true}
+	for _, member := range req.Members {
+		if seen[member.UserID] {
+			return nil, exceptions.NewBadRequest(fmt.Sprintf("user %s is listed twice", member.UserID))
+		}
+		seen[member.UserID] = true
+		organization.Members = append(organization.Members, models.OrganizationMember{
+			UserID: member.UserID,
+			Role:   memberRole(member.Role),
+		})
+	}
+
+	if err := s.repo.CreateOrganization(ctx, organization); err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	return dto.NewOrganizationResponse(organization), nil
+}
+
+func (s *organizationService) GetOrganization(ctx context.Context, id int) (*dto.OrganizationResponse, exceptions.HTTPError) {
+	organization, httpErr := s.getOrganization(ctx, id, false)
+	if httpErr != nil {
+		return nil, httpErr
+	}
+	return dto.NewOrganizationResponse(organization), nil
+}
+
+func (s *organizationService) UpdateOrganization(ctx context.Context, id int, req dto.UpdateOrganizationRequest) (*dto.OrganizationResponse, exceptions.HTTPError) {
+	organization, httpErr := s.getOrganization(ctx, id, true)
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
+	if req.Name != nil {
+		organization.Name = strings.TrimSpace(*req.Name)
+		if organization.Name == "" {
+			return nil, exceptions.NewBadRequest("name must not be blank")
+		}
+	}
+	if req.Kind != nil {
+		organization.Kind = *req.Kind
+	}
+
+	if err := s.repo.UpdateOrganization(ctx, organization); err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	return dto.NewOrganizationResponse(organization), nil
+}
+
+func (s *organizationService) DeleteOrganization(ctx context.Context, id int) exceptions.HTTPError {
+	if _, ok := restricted(ctx); ok {
+		if _, httpErr := s.getOrganization(ctx, id, true); httpErr != nil {
+			return httpErr
+		}
+	}
+
+	if err := s.repo.DeleteOrganization(ctx, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return exceptions.NewNotFound(err.Error())
+		}
+		return exceptions.NewInternalServerError(err.Error())
+	}
+	return nil
+}
+
+func (s *organizationService) ListOrganizations(ctx context.Context, query dto.ListOrganizationsQuery) (*dto.ListOrganizationsResponse, exceptions.HTTPError) {
+	userID, httpErr := scopeUserFilter(ctx, query.UserID, nil)
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
+	organizations, err := s.repo.ListOrganizations(ctx, userID, query.Kind)
+	if err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	response := &dto.ListOrganizationsResponse{Data: make([]*dto.OrganizationResponse, 0, len(organizations))}
+	for _, organization := range organizations {
+		response.Data = append(response.Data, dto.NewOrganizationResponse(organization))
+	}
+	return response, nil
+}
+
+// AddMember invites a user, who becomes a member once they accept. Only owners
+// may invite owners.
+func (s *organizationService) AddMember(ctx context.Context, id int, req dto.AddOrganizationMemberRequest) (*dto.OrganizationMemberResponse, exceptions.HTTPError) {
+	organization, httpErr := s.getOrganization(ctx, id, true)
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
+	for _, member := range organization.Members {
+		if member.UserID == req.UserID {
+			return nil, exceptions.NewConflict(fmt.Sprintf("user %s is already a member of organization %d", req.UserID, id))
+		}
+	}
+
+	member := &models.OrganizationMember{
+		OrganizationID: organization.ID,
+		UserID:         req.UserID,
+		Role:           memberRole(req.Role),
+	}
+	if member.Role == models.OrganizationRoleOwner {
+		if httpErr := authorizeOwner(ctx, organization); httpErr != nil {
+			return nil, httpErr
+		}
+	}
+	if err := s.repo.AddMember(ctx, member); err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	return dto.NewOrganizationMemberResponse(member), nil
+}
+
+// UpdateMember changes the role of a member. Only owners may make a member an
+// owner or change the role of an owner.
+func (s *organizationService) UpdateMember(ctx context.Context, id int, userID string, req dto.UpdateOrganizationMemberRequest) (*dto.OrganizationMemberResponse, exceptions.HTTPError) {
+	organization, httpErr := s.getOrganization(ctx, id, true)
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
+	member := findMember(organization, userID)
+	if member == nil {
+		return nil, exceptions.NewNotFound(fmt.Sprintf("user %s is not a member of organization %d", userID, id))
+	}
+	if member.Role == models.OrganizationRoleOwner || req.Role == models.OrganizationRoleOwner {
+		if httpErr := authorizeOwner(ctx, organization); httpErr != nil {
+			return nil, httpErr
+		}
+	}
+	if member.Role == models.OrganizationRoleOwner && member.Accepted() && req.Role != models.OrganizationRoleOwner && ownerCount(organization) == 1 {
+		return nil, exceptions.NewBadRequest("an organization must keep at least one owner")
+	}
+
+	member.Role = req.Role
+	if err := s.repo.UpdateMember(ctx, member); err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	return dto.NewOrganizationMemberResponse(member), nil
+}
+
+// RemoveMember removes a member. Members may remove themselves, which is how they
+// leave an organization they do not manage or decline an invitation; owners are
+// only removed by owners.
+func (s *organizationService) RemoveMember(ctx context.Context, id int, userID string) exceptions.HTTPError {
+	leaving := authorizeUser(ctx, userID) == nil
+	organization, httpErr := s.getOrganization(ctx, id, !leaving)
+	if httpErr != nil {
+		return httpErr
+	}
+
+	member := findMember(organization, userID)
+	if member == nil {
+		return exceptions.NewNotFound(fmt.Sprintf("user %s is not a member of organization %d", userID, id))
+	}
+	if member.Role == models.OrganizationRoleOwner && !leaving {
+		if httpErr := authorizeOwner(ctx, organization); httpErr != nil {
+			return httpErr
+		}
+	}
+	if member.Role == models.OrganizationRoleOwner && member.Accepted() && ownerCount(organization) == 1 {
+		return exceptions.NewBadRequest("an organization must keep at least one owner")
+	}
+
+	if err := s.repo.RemoveMember(ctx, id, userID); err != nil {
+		return exceptions.NewInternalServerError(err.Error())
+	}
+	return nil
+}
+
+// AcceptMember accepts the invitation of a user to the organization. Only the
+// invited user can accept it.
+func (s *organizationService) AcceptMember(ctx context.Context, id int, userID string) (*dto.OrganizationMemberResponse, exceptions.HTTPError) {
+	if httpErr := authorizeUser(ctx, userID); httpErr != nil {
+		return nil, httpErr
+	}
+
+	member, err := s.repo.GetMember(ctx, id, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, exceptions.NewNotFound(fmt.Sprintf("user %s is not invited to organization %d", userID, id))
+		}
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+	if member.Accepted() {
+		return dto.NewOrganizationMemberResponse(member), nil
+	}
+
+	now := time.Now()
+	member.AcceptedAt = &now
+	if err := s.repo.UpdateMember(ctx, member); err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	return dto.NewOrganizationMemberResponse(member), nil
+}
+
+// HouseholdMembers returns the user and everyone sharing a household with them.
+// Only accepted memberships count, on both sides.
+func (s *organizationService) HouseholdMembers(ctx context.Context, userID string) ([]string, error) {
+	kind := models.OrganizationKindHousehold
+	households, err := s.repo.ListOrganizations(ctx, &userID, &kind)
+	if err != nil {
+		return nil, err
+	}
+
+	members := []string{userID}
+	seen := map[string]bool{userID: true}
+	for _, household := range households {
+		if self := findMember(household, userID); self == nil || !self.Accepted() {
+			continue
+		}
+		for _, member := range household.Members {
+			if member.Accepted() && !seen[member.UserID] {
+				seen[member.UserID] = true
+				members = append(members, member.UserID)
+			}
+		}
+	}
+	return members, nil
+}
+
+// getOrganization returns the organization if the caller is a member of it, or
+// one of its owners and admins when manage is set.
+func (s *organizationService) getOrganization(ctx context.Context, id int, manage bool) (*models.Organization, exceptions.HTTPError) {
+	organization, err := s.repo.GetOrganization(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, exceptions.NewNotFound(err.Error())
+		}
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+	if httpErr := authorizeOrganization(ctx, organization, manage); httpErr != nil {
+		return nil, httpErr
+	}
+	return organization, nil
+}
+
+func findMember(organization *models.Organization, userID string) *models.OrganizationMember {
+	for idx := range organization.Members {
+		if organization.Members[idx].UserID == userID {
+			return &organization.Members[idx]
+		}
+	}
+	return nil
+}
+
+// ownerCount counts the owners who accepted their membership.
+func ownerCount(organization *models.Organization) int {
+	var owners int
+	for _, member := range organization.Members {
+		if member.Role == models.OrganizationRoleOwner && member.Accepted() {
+			owners++
+		}
+	}
+	return owners
+}
+
+func memberRole(role string) string {
+	if role == "" {
+		return models.OrganizationRoleMember
+	}
+	return role
+}
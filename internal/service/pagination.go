@@ -0,0 +1,96 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/pkg/exceptions"
+)
+
+// cursorToken is the content of the opaque cursors handed to clients. It records
+// the sort order the cursor was made for, since its values mean nothing in another.
+type cursorToken struct {
+	Sort string `json:"s"`
+	repository.Cursor
+}
+
+func encodeCursor(sort string, cursor *repository.Cursor) string {
+	if cursor == nil {
+		return ""
+	}
+	data, _ := json.Marshal(cursorToken{Sort: sort, Cursor: *cursor})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses a cursor of a listing sorted by sort. An empty token is the
+// start of the listing.
+func decodeCursor(token *string, sort string) (*repository.Cursor, exceptions.HTTPError) {
+	if token == nil || *token == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(*token)
+	if err != nil {
+		return nil, exceptions.NewBadRequest("invalid cursor")
+	}
+	var decoded cursorToken
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, exceptions.NewBadRequest("invalid cursor")
+	}
+	if decoded.Sort != sort {
+		return nil, exceptions.NewBadRequest("cursor was issued for another sort order")
+	}
+	return &decoded.Cursor, nil
+}
+
+// parseSort reads the sort order of a listing from the sort parameter, a comma
+// separated list of fields each prefixed with - for descending order, such as
+// -price,service_name. Without it the sort_by and sort_order parameters are used.
+// The repository checks the fields against its whitelist.
+func parseSort(query dto.ListSubscriptionsQuery) ([]repository.SortField, exceptions.HTTPError) {
+	if query.Sort == nil || *query.Sort == "" {
+		field := repository.SortField{Field: "created_at", Desc: true}
+		if query.SortBy != nil && *query.SortBy != "" {
+			field.Field = *query.SortBy
+		}
+		if query.SortOrder != nil && *query.SortOrder != "" {
+			if *query.SortOrder != "asc" && *query.SortOrder != "desc" {
+				return nil, exceptions.NewBadRequest(fmt.Sprintf("sort_order must be asc or desc, not %q", *query.SortOrder))
+			}
+			field.Desc = *query.SortOrder == "desc"
+		}
+		return []repository.SortField{field}, nil
+	}
+
+	var sort []repository.SortField
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(*query.Sort, ",") {
+		item = strings.TrimSpace(item)
+		field := repository.SortField{Field: strings.TrimPrefix(item, "-"), Desc: strings.HasPrefix(item, "-")}
+		if field.Field == "" {
+			return nil, exceptions.NewBadRequest(fmt.Sprintf("invalid sort %q", *query.Sort))
+		}
+		if seen[field.Field] {
+			return nil, exceptions.NewBadRequest(fmt.Sprintf("sort field %q is repeated", field.Field))
+		}
+		seen[field.Field] = true
+		sort = append(sort, field)
+	}
+	return sort, nil
+}
+
+// sortSpec describes a sort order, e.g. created_at:desc.
+func sortSpec(sort []repository.SortField) string {
+	fields := make([]string, 0, len(sort))
+	for _, field := range sort {
+		direction := "asc"
+		if field.Desc {
+			direction = "desc"
+		}
+		fields = append(fields, field.Field+":"+direction)
+	}
+	return strings.Join(fields, ",")
+}
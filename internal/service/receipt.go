@@ -0,0 +1,252 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/pkg/exceptions"
+	"github.com/rasadov/subscription-manager/pkg/receipt"
+	"github.com/rasadov/subscription-manager/pkg/statement"
+	"gorm.io/gorm"
+)
+
+const (
+	// Confidence of drafts read by a rule written for the sender and by the generic extractor
+	ruleConfidence    = 0.9
+	genericConfidence = 0.6
+)
+
+type ReceiptService interface {
+	ImportReceipts(ctx context.Context, userID, filename string, r io.Reader, query dto.ImportReceiptsQuery) (*dto.ImportReceiptsResponse, exceptions.HTTPError)
+	CreateRule(ctx context.Context, req dto.CreateReceiptRuleRequest) (*dto.ReceiptRuleResponse, exceptions.HTTPError)
+	ListRules(ctx context.Context) (*dto.ListReceiptRulesResponse, exceptions.HTTPError)
+	DeleteRule(ctx context.Context, id int) exceptions.HTTPError
+}
+
+type receiptService struct {
+	rules         repository.ReceiptRuleRepository
+	drafts        repository.DraftRepository
+	subscriptions repository.SubscriptionRepository
+}
+
+func NewReceiptService(rules repository.ReceiptRuleRepository, drafts repository.DraftRepository, subscriptions repository.SubscriptionRepository) ReceiptService {
+	return &receiptService{rules: rules, drafts: drafts, subscriptions: subscriptions}
+}
+
+// ImportReceipts reads forwarded receipts from an .eml or mbox file and turns each
+// recognised one into a pending draft. Services the user already tracks are skipped,
+// and several receipts of one service update a single draft.
+func (s *receiptService) ImportReceipts(ctx context.Context, userID, filename string, r io.Reader, query dto.ImportReceiptsQuery) (*dto.ImportReceiptsResponse, exceptions.HTTPError) {
+	if httpErr := authorizeUser(ctx, userID); httpErr != nil {
+		return nil, httpErr
+	}
+
+	messages, httpErr := parseReceipts(filename, r, query)
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
+	registry, httpErr := s.registry(ctx)
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
+	tracked := make(map[string]bool)
+	filter := repository.SubscriptionFilter{UserID: &userID}
+	err := s.subscriptions.StreamSubscriptions(ctx, filter, func(subscription *models.Subscription) error {
+		tracked[statement.NormalizeMerchant(subscription.ServiceName)] = true
+		return nil
+	})
+	if err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	response := &dto.ImportReceiptsResponse{
+		Messages: len(messages),
+		Drafts:   []*dto.DraftResponse{},
+		Skipped:  []*dto.SkippedReceipt{},
+	}
+	saved := make(map[uint]*models.SubscriptionDraft)
+	var order []uint
+
+	for _, msg := range messages {
+		skip := func(reason string) {
+			response.Skipped = append(response.Skipped, &dto.SkippedReceipt{From: msg.From, Subject: msg.Subject, Reason: reason})
+		}
+
+		extracted, err := registry.Extract(msg)
+		if err != nil {
+			skip(err.Error())
+			continue
+		}
+		if extracted.BillingDate.IsZero() {
+			skip("billing date is unknown")
+			continue
+		}
+		if tracked[statement.NormalizeMerchant(extracted.ServiceName)] {
+			skip(fmt.Sprintf("%s is already tracked", extracted.ServiceName))
+			continue
+		}
+
+		draft, err := s.drafts.FindPendingDraft(ctx, userID, models.DraftSourceReceipt, extracted.ServiceName)
+		if err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, exceptions.NewInternalServerError(err.Error())
+			}
+			draft = &models.SubscriptionDraft{
+				UserID:      userID,
+				Source:      models.DraftSourceReceipt,
+				ServiceName: extracted.ServiceName,
+				Status:      models.DraftStatusPending,
+			}
+		}
+
+		startDate := time.Date(extracted.BillingDate.Year(), extracted.BillingDate.Month(), 1, 0, 0, 0, 0, time.UTC)
+		if draft.ID == 0 || startDate.Before(draft.StartDate) {
+			draft.StartDate = startDate
+		}
+		draft.Price = int64(math.Round(float64(extracted.Amount) / 100))
+		draft.Currency = extracted.Currency
+		if draft.Currency == "" {
+			draft.Currency = models.DefaultCurrency
+		}
+		draft.BillingCycle = extracted.BillingCycle
+		draft.Confidence = ruleConfidence
+		if extracted.Fallback {
+			draft.Confidence = genericConfidence
+		}
+		draft.Details = fmt.Sprintf("Receipt from %s on %s: %s", msg.From, extracted.BillingDate.Format("2006-01-02"), msg.Subject)
+
+		if err := s.drafts.SaveDraft(ctx, draft); err != nil {
+			return nil, exceptions.NewInternalServerError(err.Error())
+		}
+		if _, seen := saved[draft.ID]; !seen {
+			order = append(order, draft.ID)
+		}
+		saved[draft.ID] = draft
+	}
+
+	for _, id := range order {
+		response.Drafts = append(response.Drafts, dto.NewDraftResponse(saved[id]))
+	}
+
+	return response, nil
+}
+
+// registry builds the extractor registry from the stored rules, with the generic
+// extractor as fallback.
+func (s *receiptService) registry(ctx context.Context) (*receipt.Registry, exceptions.HTTPError) {
+	rules, err := s.rules.ListReceiptRules(ctx)
+	if err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	registry := receipt.NewRegistry()
+	for _, rule := range rules {
+		extractor, err := newRuleExtractor(rule)
+		if err != nil {
+			// Rules are validated on creation, so this only happens after a manual edit
+			return nil, exceptions.NewInternalServerError(fmt.Sprintf("receipt rule %d: %s", rule.ID, err))
+		}
+		registry.Register(rule.SenderDomain, extractor)
+	}
+	registry.RegisterFallback(receipt.GenericExtractor{})
+
+	return registry, nil
+}
+
+func (s *receiptService) CreateRule(ctx context.Context, req dto.CreateReceiptRuleRequest) (*dto.ReceiptRuleResponse, exceptions.HTTPError) {
+	rule := &models.ReceiptRule{
+		SenderDomain:   strings.ToLower(req.SenderDomain),
+		ServiceName:    req.ServiceName,
+		SubjectPattern: req.SubjectPattern,
+		PricePattern:   req.PricePattern,
+		Currency:       req.Currency,
+		DatePattern:    req.DatePattern,
+		DateLayout:     req.DateLayout,
+		BillingCycle:   req.BillingCycle,
+		Priority:       req.Priority,
+	}
+
+	if _, err := newRuleExtractor(rule); err != nil {
+		return nil, exceptions.NewBadRequest(err.Error())
+	}
+
+	if err := s.rules.CreateReceiptRule(ctx, rule); err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	return dto.NewReceiptRuleResponse(rule), nil
+}
+
+func (s *receiptService) ListRules(ctx context.Context) (*dto.ListReceiptRulesResponse, exceptions.HTTPError) {
+	rules, err := s.rules.ListReceiptRules(ctx)
+	if err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	response := &dto.ListReceiptRulesResponse{Data: []*dto.ReceiptRuleResponse{}}
+	for _, rule := range rules {
+		response.Data = append(response.Data, dto.NewReceiptRuleResponse(rule))
+	}
+
+	return response, nil
+}
+
+func (s *receiptService) DeleteRule(ctx context.Context, id int) exceptions.HTTPError {
+	if err := s.rules.DeleteReceiptRule(ctx, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return exceptions.NewNotFound(err.Error())
+		}
+		return exceptions.NewInternalServerError(err.Error())
+	}
+	return nil
+}
+
+func newRuleExtractor(rule *models.ReceiptRule) (*receipt.RuleExtractor, error) {
+	return receipt.NewRuleExtractor(receipt.Rule{
+		ServiceName:    rule.ServiceName,
+		SubjectPattern: rule.SubjectPattern,
+		PricePattern:   rule.PricePattern,
+		Currency:       rule.Currency,
+		DatePattern:    rule.DatePattern,
+		DateLayout:     rule.DateLayout,
+		BillingCycle:   rule.BillingCycle,
+	})
+}
+
+func parseReceipts(filename string, r io.Reader, query dto.ImportReceiptsQuery) ([]*receipt.Message, exceptions.HTTPError) {
+	reader := bufio.NewReader(r)
+
+	format := query.Format
+	if format == "" {
+		format = "eml"
+		if prefix, _ := reader.Peek(5); strings.ToLower(filepath.Ext(filename)) == ".mbox" || string(prefix) == "From " {
+			format = "mbox"
+		}
+	}
+
+	if format == "mbox" {
+		messages, err := receipt.ParseMbox(reader)
+		if err != nil {
+			return nil, exceptions.NewBadRequest(err.Error())
+		}
+		return messages, nil
+	}
+
+	msg, err := receipt.ParseEML(reader)
+	if err != nil {
+		return nil, exceptions.NewBadRequest(err.Error())
+	}
+	return []*receipt.Message{msg}, nil
+}
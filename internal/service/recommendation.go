@@ -0,0 +1,394 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/pkg/exceptions"
+	"github.com/rasadov/subscription-manager/pkg/textmatch"
+	"gorm.io/gorm"
+)
+
+// defaultSnoozeDays is how long a snoozed recommendation stays hidden by default.
+const defaultSnoozeDays = 30
+
+// HouseholdResolver finds the users who share a household and could share a
+// family plan.
+type HouseholdResolver interface {
+	// HouseholdMembers returns the users sharing a household with userID, userID included.
+	HouseholdMembers(ctx context.Context, userID string) ([]string, error)
+}
+
+type RecommendationService interface {
+	ListRecommendations(ctx context.Context, userID string, query dto.ListRecommendationsQuery) (*dto.ListRecommendationsResponse, exceptions.HTTPError)
+	DismissRecommendation(ctx context.Context, userID, key string) exceptions.HTTPError
+	SnoozeRecommendation(ctx context.Context, userID, key string, req dto.SnoozeRecommendationRequest) exceptions.HTTPError
+	ReportUsage(ctx context.Context, subscriptionID int, req dto.ReportUsageRequest) (*dto.SubscriptionResponse, exceptions.HTTPError)
+}
+
+type recommendationService struct {
+	subscriptions repository.SubscriptionRepository
+	catalog       repository.CatalogRepository
+	states        repository.RecommendationRepository
+	households    HouseholdResolver
+}
+
+type RecommendationServiceOption func(*recommendationService)
+
+// WithHouseholds enables family plan recommendations across the members of a household.
+func WithHouseholds(households HouseholdResolver) RecommendationServiceOption {
+	return func(s *recommendationService) {
+		s.households = households
+	}
+}
+
+func NewRecommendationService(subscriptions repository.SubscriptionRepository, catalog repository.CatalogRepository,
+	states repository.RecommendationRepository, opts ...RecommendationServiceOption) RecommendationService {
+	s := &recommendationService{subscriptions: subscriptions, catalog: catalog, states: states}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// ListRecommendations evaluates every rule against the current subscriptions of
+// the user and returns the suggestions ordered by estimated savings. Dismissed
+// and snoozed suggestions are left out unless query.IncludeHidden is set.
+func (s *recommendationService) ListRecommendations(ctx context.Context, userID string, query dto.ListRecommendationsQuery) (*dto.ListRecommendationsResponse, exceptions.HTTPError) {
+	if httpErr := authorizeUser(ctx, userID); httpErr != nil {
+		return nil, httpErr
+	}
+
+	now := time.Now()
+	recommendations, err := s.recommend(ctx, userID, now)
+	if err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	states, err := s.states.ListStates(ctx, userID)
+	if err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+	byKey := make(map[string]*models.RecommendationState)
+	for _, state := range states {
+		byKey[state.Key] = state
+	}
+
+	response := &dto.ListRecommendationsResponse{Data: []*dto.RecommendationResponse{}}
+	for _, recommendation := range recommendations {
+		if state, ok := byKey[recommendation.Key]; ok {
+			hidden := state.Status == models.RecommendationStatusDismissed ||
+				(state.SnoozedUntil != nil && state.SnoozedUntil.After(now))
+			if hidden && !query.IncludeHidden {
+				continue
+			}
+			if hidden {
+				recommendation.Status = state.Status
+				recommendation.SnoozedUntil = state.SnoozedUntil
+			}
+		}
+		response.Data = append(response.Data, recommendation)
+	}
+
+	return response, nil
+}
+
+func (s *recommendationService) DismissRecommendation(ctx context.Context, userID, key string) exceptions.HTTPError {
+	if httpErr := authorizeUser(ctx, userID); httpErr != nil {
+		return httpErr
+	}
+
+	return s.saveState(ctx, userID, key, &models.RecommendationState{Status: models.RecommendationStatusDismissed})
+}
+
+func (s *recommendationService) SnoozeRecommendation(ctx context.Context, userID, key string, req dto.SnoozeRecommendationRequest) exceptions.HTTPError {
+	if httpErr := authorizeUser(ctx, userID); httpErr != nil {
+		return httpErr
+	}
+
+	days := req.Days
+	if days == 0 {
+		days = defaultSnoozeDays
+	}
+	until := time.Now().AddDate(0, 0, days)
+	return s.saveState(ctx, userID, key, &models.RecommendationState{Status: models.RecommendationStatusSnoozed, SnoozedUntil: &until})
+}
+
+// ReportUsage records how often the user uses a subscription; rarely and never
+// used subscriptions are recommended for cancellation.
+func (s *recommendationService) ReportUsage(ctx context.Context, subscriptionID int, req dto.ReportUsageRequest) (*dto.SubscriptionResponse, exceptions.HTTPError) {
+	subscription, err := s.subscriptions.GetSubscription(ctx, subscriptionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, exceptions.NewNotFound(err.Error())
+		}
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+	if httpErr := authorizeSubscription(ctx, subscription, true); httpErr != nil {
+		return nil, httpErr
+	}
+
+	now := time.Now()
+	subscription.Usage = req.Usage
+	subscription.UsageReportedAt = &now
+	if err := s.subscriptions.UpdateSubscription(ctx, subscriptionID, subscription); err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	return dto.NewSubscriptionResponse(subscription), nil
+}
+
+// saveState stores the state of a recommendation the user currently has.
+func (s *recommendationService) saveState(ctx context.Context, userID, key string, state *models.RecommendationState) exceptions.HTTPError {
+	recommendations, err := s.recommend(ctx, userID, time.Now())
+	if err != nil {
+		return exceptions.NewInternalServerError(err.Error())
+	}
+	if !slices.ContainsFunc(recommendations, func(r *dto.RecommendationResponse) bool { return r.Key == key }) {
+		return exceptions.NewNotFound(fmt.Sprintf("recommendation %q not found", key))
+	}
+
+	state.UserID = userID
+	state.Key = key
+	if err := s.states.SaveState(ctx, state); err != nil {
+		return exceptions.NewInternalServerError(err.Error())
+	}
+	return nil
+}
+
+func (s *recommendationService) recommend(ctx context.Context, userID string, now time.Time) ([]*dto.RecommendationResponse, error) {
+	subscriptions, err := s.activeSubscriptions(ctx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	services, err := s.catalog.ListServices(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	catalog := make(map[uint]*models.Service, len(services))
+	for _, service := range services {
+		catalog[service.ID] = service
+	}
+
+	recommendations := recommendAnnualBilling(subscriptions, catalog)
+	recommendations = append(recommendations, recommendCancelUnused(subscriptions)...)
+	recommendations = append(recommendations, recommendConsolidation(subscriptions, now)...)
+
+	if s.households != nil {
+		members, err := s.households.HouseholdMembers(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if len(members) > 1 {
+			var shared []*models.Subscription
+			for _, member := range members {
+				memberSubscriptions, err := s.activeSubscriptions(ctx, member, now)
+				if err != nil {
+					return nil, err
+				}
+				shared = append(shared, memberSubscriptions...)
+			}
+			recommendations = append(recommendations, recommendFamilyPlans(userID, shared, catalog)...)
+		}
+	}
+
+	slices.SortFunc(recommendations, func(a, b *dto.RecommendationResponse) int {
+		return cmp.Or(cmp.Compare(b.EstimatedSavings, a.EstimatedSavings), strings.Compare(a.Key, b.Key))
+	})
+
+	return recommendations, nil
+}
+
+func (s *recommendationService) activeSubscriptions(ctx context.Context, userID string, now time.Time) ([]*models.Subscription, error) {
+	var subscriptions []*models.Subscription
+	err := s.subscriptions.StreamSubscriptions(ctx, repository.SubscriptionFilter{UserID: &userID}, func(subscription *models.Subscription) error {
+		if subscription.ActiveIn(now) {
+			subscriptions = append(subscriptions, subscription)
+		}
+		return nil
+	})
+	return subscriptions, err
+}
+
+// annualCost is what a subscription costs over a year of billing cycles.
+func annualCost(subscription *models.Subscription) int64 {
+	return subscription.Price * int64(12/subscription.CycleMonths())
+}
+
+// recommendAnnualBilling suggests the yearly plan of a catalog service when it
+// is cheaper than a year of the current billing cycle. When the subscription is
+// on a plan, only yearly plans named like it ("Premium Annual" for "Premium") qualify.
+func recommendAnnualBilling(subscriptions []*models.Subscription, catalog map[uint]*models.Service) []*dto.RecommendationResponse {
+	var recommendations []*dto.RecommendationResponse
+	for _, subscription := range subscriptions {
+		if subscription.ServiceID == nil || subscription.BillingCycle == models.BillingCycleYearly {
+			continue
+		}
+		service, ok := catalog[*subscription.ServiceID]
+		if !ok {
+			continue
+		}
+
+		var current *models.Plan
+		if subscription.PlanID != nil {
+			current = findPlan(service, int(*subscription.PlanID))
+		}
+
+		var best *models.Plan
+		for idx := range service.Plans {
+			plan := &service.Plans[idx]
+			if plan.BillingCycle != models.BillingCycleYearly || plan.Currency != subscription.Currency {
+				continue
+			}
+			if current != nil && !strings.HasPrefix(textmatch.Normalize(plan.Name), textmatch.Normalize(current.Name)) {
+				continue
+			}
+			if best == nil || plan.Price < best.Price {
+				best = plan
+			}
+		}
+		if best == nil || best.Price >= annualCost(subscription) {
+			continue
+		}
+
+		recommendations = append(recommendations, &dto.RecommendationResponse{
+			Key:              fmt.Sprintf("%s:%d", models.RecommendationAnnualBilling, subscription.ID),
+			Type:             models.RecommendationAnnualBilling,
+			Title:            fmt.Sprintf("Switch %s to the yearly plan %s", subscription.ServiceName, best.Name),
+			ServiceName:      subscription.ServiceName,
+			SubscriptionIDs:  []uint{subscription.ID},
+			PlanID:           &best.ID,
+			Currency:         subscription.Currency,
+			EstimatedSavings: annualCost(subscription) - best.Price,
+		})
+	}
+	return recommendations
+}
+
+// recommendCancelUnused suggests cancelling subscriptions the user reported to use rarely or never.
+func recommendCancelUnused(subscriptions []*models.Subscription) []*dto.RecommendationResponse {
+	var recommendations []*dto.RecommendationResponse
+	for _, subscription := range subscriptions {
+		if subscription.Usage != models.UsageRarely && subscription.Usage != models.UsageNever {
+			continue
+		}
+
+		recommendations = append(recommendations, &dto.RecommendationResponse{
+			Key:              fmt.Sprintf("%s:%d", models.RecommendationCancelUnused, subscription.ID),
+			Type:             models.RecommendationCancelUnused,
+			Title:            fmt.Sprintf("Cancel %s, you use it %s", subscription.ServiceName, subscription.Usage),
+			ServiceName:      subscription.ServiceName,
+			SubscriptionIDs:  []uint{subscription.ID},
+			Currency:         subscription.Currency,
+			EstimatedSavings: annualCost(subscription),
+		})
+	}
+	return recommendations
+}
+
+// recommendConsolidation suggests keeping one of the duplicate subscriptions that overlap now.
+func recommendConsolidation(subscriptions []*models.Subscription, now time.Time) []*dto.RecommendationResponse {
+	var recommendations []*dto.RecommendationResponse
+	for _, group := range findDuplicates(subscriptions, now) {
+		if group.MonthlyWaste <= 0 {
+			continue
+		}
+
+		ids := make([]uint, 0, len(group.Subscriptions))
+		keyParts := make([]string, 0, len(group.Subscriptions))
+		for _, subscription := range group.Subscriptions {
+			ids = append(ids, subscription.ID)
+			keyParts = append(keyParts, fmt.Sprint(subscription.ID))
+		}
+
+		recommendations = append(recommendations, &dto.RecommendationResponse{
+			Key:              models.RecommendationConsolidate + ":" + strings.Join(keyParts, "-"),
+			Type:             models.RecommendationConsolidate,
+			Title:            fmt.Sprintf("Keep one of your %d %s subscriptions", len(ids), group.ServiceName),
+			ServiceName:      group.ServiceName,
+			SubscriptionIDs:  ids,
+			Currency:         group.Subscriptions[0].Currency,
+			EstimatedSavings: group.MonthlyWaste * 12,
+		})
+	}
+	return recommendations
+}
+
+// recommendFamilyPlans suggests a shared plan of a catalog service that several
+// members of the household, the user among them, pay for separately, when a plan
+// with enough seats costs less than their subscriptions together.
+func recommendFamilyPlans(userID string, subscriptions []*models.Subscription, catalog map[uint]*models.Service) []*dto.RecommendationResponse {
+	byService := make(map[uint][]*models.Subscription)
+	var serviceIDs []uint
+	for _, subscription := range subscriptions {
+		if subscription.ServiceID == nil {
+			continue
+		}
+		if _, ok := byService[*subscription.ServiceID]; !ok {
+			serviceIDs = append(serviceIDs, *subscription.ServiceID)
+		}
+		byService[*subscription.ServiceID] = append(byService[*subscription.ServiceID], subscription)
+	}
+	slices.Sort(serviceIDs)
+
+	var recommendations []*dto.RecommendationResponse
+	for _, serviceID := range serviceIDs {
+		service, ok := catalog[serviceID]
+		if !ok {
+			continue
+		}
+
+		members := make(map[string]bool)
+		var ids []uint
+		var monthly int64
+		currency := byService[serviceID][0].Currency
+		for _, subscription := range byService[serviceID] {
+			if subscription.Currency != currency {
+				continue
+			}
+			members[subscription.UserID] = true
+			ids = append(ids, subscription.ID)
+			monthly += subscription.MonthlyPrice()
+		}
+		if len(members) < 2 || !members[userID] {
+			continue
+		}
+
+		var best *models.Plan
+		var bestMonthly int64
+		for idx := range service.Plans {
+			plan := &service.Plans[idx]
+			if plan.Seats < len(members) || plan.Currency != currency {
+				continue
+			}
+			planMonthly := (&models.Subscription{Price: plan.Price, BillingCycle: plan.BillingCycle}).MonthlyPrice()
+			if best == nil || planMonthly < bestMonthly {
+				best, bestMonthly = plan, planMonthly
+			}
+		}
+		if best == nil || bestMonthly >= monthly {
+			continue
+		}
+
+		recommendations = append(recommendations, &dto.RecommendationResponse{
+			Key:              fmt.Sprintf("%s:%d", models.RecommendationFamilyPlan, serviceID),
+			Type:             models.RecommendationFamilyPlan,
+			Title:            fmt.Sprintf("Share the %s plan %s with %d members of your household", service.Name, best.Name, len(members)),
+			ServiceName:      service.Name,
+			SubscriptionIDs:  ids,
+			PlanID:           &best.ID,
+			Currency:         currency,
+			EstimatedSavings: (monthly - bestMonthly) * 12,
+		})
+	}
+	return recommendations
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/pkg/auth"
+	"github.com/rasadov/subscription-manager/pkg/exceptions"
+	"gorm.io/gorm"
+)
+
+type RoleService interface {
+	AssignRole(ctx context.Context, req dto.CreateRoleAssignmentRequest) (*dto.RoleAssignmentResponse, exceptions.HTTPError)
+	ListRoleAssignments(ctx context.Context, query dto.ListRoleAssignmentsQuery) (*dto.ListRoleAssignmentsResponse, exceptions.HTTPError)
+	RevokeRole(ctx context.Context, id int) exceptions.HTTPError
+	// ResolveRoles adds the stored roles of the principal to the roles of its
+	// token and grants their permissions.
+	ResolveRoles(ctx context.Context, principal *auth.Principal) error
+}
+
+type roleService struct {
+	repo repository.RoleRepository
+	// defaultRole is the role of users that have no other role.
+	defaultRole string
+}
+
+// NewRoleService returns a role service giving defaultRole to users without
+// roles. An empty defaultRole leaves such users without permissions.
+func NewRoleService(repo repository.RoleRepository, defaultRole string) RoleService {
+	return &roleService{repo: repo, defaultRole: defaultRole}
+}
+
+func (s *roleService) AssignRole(ctx context.Context, req dto.CreateRoleAssignmentRequest) (*dto.RoleAssignmentResponse, exceptions.HTTPError) {
+	if httpErr := requireAdmin(ctx); httpErr != nil {
+		return nil, httpErr
+	}
+
+	existing, err := s.repo.ListRoleAssignments(ctx, &req.UserID)
+	if err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+	for _, assignment := range existing {
+		if assignment.Role == req.Role {
+			return nil, exceptions.NewConflict(fmt.Sprintf("user %s already has the %s role", req.UserID, req.Role))
+		}
+	}
+
+	assignment := &models.RoleAssignment{UserID: req.UserID, Role: req.Role}
+	if principal, ok := auth.FromContext(ctx); ok {
+		assignment.CreatedBy = principal.Subject
+	}
+	if err := s.repo.CreateRoleAssignment(ctx, assignment); err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	return dto.NewRoleAssignmentResponse(assignment), nil
+}
+
+func (s *roleService) ListRoleAssignments(ctx context.Context, query dto.ListRoleAssignmentsQuery) (*dto.ListRoleAssignmentsResponse, exceptions.HTTPError) {
+	if httpErr := requireAdmin(ctx); httpErr != nil {
+		return nil, httpErr
+	}
+
+	assignments, err := s.repo.ListRoleAssignments(ctx, query.UserID)
+	if err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	response := &dto.ListRoleAssignmentsResponse{Data: make([]*dto.RoleAssignmentResponse, 0, len(assignments))}
+	for _, assignment := range assignments {
+		response.Data = append(response.Data, dto.NewRoleAssignmentResponse(assignment))
+	}
+	return response, nil
+}
+
+func (s *roleService) RevokeRole(ctx context.Context, id int) exceptions.HTTPError {
+	if httpErr := requireAdmin(ctx); httpErr != nil {
+		return httpErr
+	}
+
+	if err := s.repo.DeleteRoleAssignment(ctx, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return exceptions.NewNotFound(err.Error())
+		}
+		return exceptions.NewInternalServerError(err.Error())
+	}
+	return nil
+}
+
+func (s *roleService) ResolveRoles(ctx context.Context, principal *auth.Principal) error {
+	assignments, err := s.repo.ListRoleAssignments(ctx, &principal.Subject)
+	if err != nil {
+		return err
+	}
+
+	roles := append([]string{}, principal.Roles...)
+	for _, assignment := range assignments {
+		roles = append(roles, assignment.Role)
+	}
+	if len(roles) == 0 && s.defaultRole != "" {
+		roles = append(roles, s.defaultRole)
+	}
+
+	principal.SetRoles(roles)
+	return nil
+}
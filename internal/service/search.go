@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/pkg/exceptions"
+)
+
+// SearchSubscriptions finds the subscriptions whose service name, tags or notes
+// resemble the query, tolerating typos, within the caller's user scope.
+func (s *subscriptionService) SearchSubscriptions(ctx context.Context, query dto.SearchSubscriptionsQuery) (*dto.SearchSubscriptionsResponse, exceptions.HTTPError) {
+	q := strings.TrimSpace(query.Q)
+	if q == "" {
+		return nil, exceptions.NewBadRequest("q must not be blank")
+	}
+
+	userID, httpErr := scopeUserFilter(ctx, query.UserID, query.OrganizationID)
+	if httpErr != nil {
+		return nil, httpErr
+	}
+	filter := repository.SubscriptionFilter{UserID: userID}
+	if httpErr := s.resolveOrganizationFilter(ctx, query.OrganizationID, &filter); httpErr != nil {
+		return nil, httpErr
+	}
+
+	matches, err := s.repo.SearchSubscriptions(ctx, filter, q, query.Limit)
+	if err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	results := make([]*dto.SubscriptionSearchResult, 0, len(matches))
+	for _, match := range matches {
+		results = append(results, &dto.SubscriptionSearchResult{
+			SubscriptionResponse: dto.NewSubscriptionResponse(match.Subscription),
+			Score:                match.Score,
+		})
+	}
+	return &dto.SearchSubscriptionsResponse{Data: results}, nil
+}
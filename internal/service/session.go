@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/pkg/auth"
+	"github.com/rasadov/subscription-manager/pkg/exceptions"
+	"github.com/rasadov/subscription-manager/pkg/oidc"
+	"github.com/rasadov/subscription-manager/pkg/tenant"
+	"gorm.io/gorm"
+)
+
+// LoginAttemptTTL is how long a browser may take to log in at the provider.
+const LoginAttemptTTL = 10 * time.Minute
+
+// ErrInvalidSession is returned for session tokens that are unknown or expired.
+var ErrInvalidSession = errors.New("invalid session")
+
+type SessionService interface {
+	// BeginLogin returns the provider URL starting a login and the attempt that
+	// CompleteLogin checks the callback against.
+	BeginLogin(ctx context.Context, returnTo string) (*dto.LoginRedirect, exceptions.HTTPError)
+	// CompleteLogin redeems the code of the callback and creates a session.
+	CompleteLogin(ctx context.Context, attempt string, query dto.LoginCallbackQuery) (*dto.LoginSession, exceptions.HTTPError)
+	// Logout ends the session of the token, if any, and returns the URL the
+	// browser is sent to.
+	Logout(ctx context.Context, token string) (string, exceptions.HTTPError)
+	// Authenticate returns the principal of an active session.
+	Authenticate(ctx context.Context, token string) (*auth.Principal, error)
+	// DeleteExpiredSessions removes the expired sessions of every tenant.
+	DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error)
+}
+
+// SessionConfig configures the sessions created by the login flow.
+type SessionConfig struct {
+	// TTL is how long a session lasts.
+	TTL time.Duration
+	// AdminRole is the value of the "roles" claim standing for the admin role.
+	AdminRole string
+	// TenantClaim is the ID token claim holding the tenant of the user.
+	TenantClaim string
+	// PostLogoutRedirectURL is where browsers are sent after logging out.
+	PostLogoutRedirectURL string
+}
+
+type sessionService struct {
+	repo   repository.SessionRepository
+	client *oidc.Client
+	config SessionConfig
+}
+
+func NewSessionService(repo repository.SessionRepository, client *oidc.Client, config SessionConfig) SessionService {
+	return &sessionService{repo: repo, client: client, config: config}
+}
+
+// loginAttempt is the state of a login between its start and the callback. It is
+// only kept in an HTTP-only cookie of the browser that started the login, which
+// the state parameter of the callback must match.
+type loginAttempt struct {
+	State        string    `json:"state"`
+	Nonce        string    `json:"nonce"`
+	CodeVerifier string    `json:"code_verifier"`
+	ReturnTo     string    `json:"return_to"`
+	StartedAt    time.Time `json:"started_at"`
+}
+
+func (s *sessionService) BeginLogin(ctx context.Context, returnTo string) (*dto.LoginRedirect, exceptions.HTTPError) {
+	attempt := loginAttempt{ReturnTo: safeReturnTo(returnTo), StartedAt: time.Now()}
+	for _, value := range []*string{&attempt.State, &attempt.Nonce, &attempt.CodeVerifier} {
+		random, err := oidc.RandomString(32)
+		if err != nil {
+			return nil, exceptions.NewInternalServerError(err.Error())
+		}
+		*value = random
+	}
+
+	url, err := s.client.AuthCodeURL(ctx, attempt.State, attempt.Nonce, oidc.S256Challenge(attempt.CodeVerifier))
+	if err != nil {
+		return nil, exceptions.NewHTTPError(http.StatusBadGateway, err.Error())
+	}
+
+	data, err := json.Marshal(attempt)
+	if err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	return &dto.LoginRedirect{URL: url, Attempt: base64.RawURLEncoding.EncodeToString(data)}, nil
+}
+
+func (s *sessionService) CompleteLogin(ctx context.Context, encoded string, query dto.LoginCallbackQuery) (*dto.LoginSession, exceptions.HTTPError) {
+	if query.Error != "" {
+		return nil, exceptions.NewUnauthorized(strings.TrimSpace("login failed: " + query.Error + " " + query.ErrorDescription))
+	}
+
+	var attempt loginAttempt
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || json.Unmarshal(data, &attempt) != nil || attempt.State == "" {
+		return nil, exceptions.NewBadRequest("login attempt not found")
+	}
+	if subtle.ConstantTimeCompare([]byte(attempt.State), []byte(query.State)) != 1 {
+		return nil, exceptions.NewBadRequest("login state does not match")
+	}
+	if time.Since(attempt.StartedAt) > LoginAttemptTTL {
+		return nil, exceptions.NewBadRequest("login attempt expired")
+	}
+	if query.Code == "" {
+		return nil, exceptions.NewBadRequest("code is required")
+	}
+
+	tokens, err := s.client.Exchange(ctx, query.Code, attempt.CodeVerifier)
+	if err != nil {
+		return nil, exceptions.NewUnauthorized(err.Error())
+	}
+	claims, err := s.client.VerifyIDToken(ctx, tokens.IDToken, attempt.Nonce)
+	if err != nil {
+		return nil, exceptions.NewUnauthorized(err.Error())
+	}
+	if claims.Subject == "" {
+		return nil, exceptions.NewUnauthorized("ID token has no subject")
+	}
+
+	token, err := oidc.RandomString(32)
+	if err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	tenantID := claims.String(s.config.TenantClaim)
+	if tenantID == "" {
+		tenantID = tenant.Default
+	}
+	session := &models.Session{
+		TokenHash: hashSecret(token),
+		UserID:    claims.Subject,
+		Roles:     auth.RolesFromClaim(claims.Strings("roles"), s.config.AdminRole),
+		ExpiresAt: time.Now().Add(s.config.TTL),
+	}
+	if err := s.repo.CreateSession(tenant.WithTenant(ctx, tenantID), session); err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	return &dto.LoginSession{UserID: session.UserID, Token: token, ExpiresAt: session.ExpiresAt, ReturnTo: attempt.ReturnTo}, nil
+}
+
+// Logout deletes the session and sends the browser to the provider to end its
+// session there too, when the provider supports it.
+func (s *sessionService) Logout(ctx context.Context, token string) (string, exceptions.HTTPError) {
+	if token != "" {
+		session, err := s.repo.GetSessionByTokenHash(tenant.WithAllTenants(ctx), hashSecret(token))
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", exceptions.NewInternalServerError(err.Error())
+		}
+		if session != nil {
+			if err := s.repo.DeleteSession(tenant.WithTenant(ctx, session.TenantID), session.ID); err != nil {
+				return "", exceptions.NewInternalServerError(err.Error())
+			}
+		}
+	}
+
+	// The local session is gone either way, so an unavailable provider only
+	// skips its logout
+	if url, err := s.client.LogoutURL(ctx, s.config.PostLogoutRedirectURL); err == nil && url != "" {
+		return url, nil
+	}
+	if s.config.PostLogoutRedirectURL != "" {
+		return s.config.PostLogoutRedirectURL, nil
+	}
+	return "/", nil
+}
+
+// Authenticate looks the session up in every tenant, since the tenant of a
+// request is only known once its credentials are. Permissions are granted once
+// the roles are resolved.
+func (s *sessionService) Authenticate(ctx context.Context, token string) (*auth.Principal, error) {
+	session, err := s.repo.GetSessionByTokenHash(tenant.WithAllTenants(ctx), hashSecret(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidSession
+		}
+		return nil, err
+	}
+	if !time.Now().Before(session.ExpiresAt) {
+		return nil, ErrInvalidSession
+	}
+
+	return &auth.Principal{
+		Subject:  session.UserID,
+		TenantID: session.TenantID,
+		Roles:    append([]string{}, session.Roles...),
+	}, nil
+}
+
+func (s *sessionService) DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error) {
+	return s.repo.DeleteExpiredSessions(tenant.WithAllTenants(ctx), now)
+}
+
+// safeReturnTo only accepts local paths, so the login cannot be used to send
+// browsers to other sites.
+func safeReturnTo(returnTo string) string {
+	// Browsers drop tabs and newlines, which would turn "/\t/host" into "//host"
+	if !strings.HasPrefix(returnTo, "/") || strings.ContainsAny(returnTo, "\\\t\r\n") || strings.HasPrefix(returnTo, "//") {
+		return "/"
+	}
+	return returnTo
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/pkg/exceptions"
+)
+
+// newMembers converts the members of a request. The owner pays the remainder of
+// the price and cannot be listed.
+func newMembers(owner string, reqs []dto.SubscriptionMemberRequest) ([]models.SubscriptionMember, exceptions.HTTPError) {
+	members := make([]models.SubscriptionMember, 0, len(reqs))
+	seen := make(map[string]bool)
+	for _, req := range reqs {
+		if req.UserID == owner {
+			return nil, exceptions.NewBadRequest("the owner pays the remainder and cannot be listed as a member")
+		}
+		if seen[req.UserID] {
+			return nil, exceptions.NewBadRequest(fmt.Sprintf("user %s is listed twice", req.UserID))
+		}
+		seen[req.UserID] = true
+		members = append(members, models.SubscriptionMember{UserID: req.UserID, Share: req.Share})
+	}
+	return members, nil
+}
+
+// splitCost computes what every member of a shared subscription pays and adds the
+// owner as the member paying the rest. A subscription without other members is
+// not shared.
+func splitCost(subscription *models.Subscription) exceptions.HTTPError {
+	var others []models.SubscriptionMember
+	for _, member := range subscription.Members {
+		if member.UserID != subscription.UserID {
+			others = append(others, member)
+		}
+	}
+	if len(others) == 0 {
+		subscription.SplitRule = ""
+		subscription.Members = nil
+		return nil
+	}
+	if subscription.SplitRule == "" {
+		subscription.SplitRule = models.SplitEqual
+	}
+
+	var assigned, shares int64
+	for idx := range others {
+		member := &others[idx]
+		switch subscription.SplitRule {
+		case models.SplitEqual:
+			member.Share = 0
+			member.Amount = subscription.Price / int64(len(others)+1)
+		case models.SplitPercentage:
+			if member.Share <= 0 {
+				return exceptions.NewBadRequest(fmt.Sprintf("share of user %s must be a positive percentage", member.UserID))
+			}
+			member.Amount = subscription.Price * member.Share / 100
+		case models.SplitFixed:
+			if member.Share <= 0 {
+				return exceptions.NewBadRequest(fmt.Sprintf("share of user %s must be a positive amount", member.UserID))
+			}
+			member.Amount = member.Share
+		default:
+			return exceptions.NewBadRequest(fmt.Sprintf("unknown split rule %q", subscription.SplitRule))
+		}
+		shares += member.Share
+		assigned += member.Amount
+	}
+
+	owner := models.SubscriptionMember{UserID: subscription.UserID, Amount: subscription.Price - assigned}
+	switch subscription.SplitRule {
+	case models.SplitPercentage:
+		if shares > 100 {
+			return exceptions.NewBadRequest(fmt.Sprintf("member shares add up to %d%%", shares))
+		}
+		owner.Share = 100 - shares
+	case models.SplitFixed:
+		if shares > subscription.Price {
+			return exceptions.NewBadRequest(fmt.Sprintf("member shares add up to %d, more than the price %d", shares, subscription.Price))
+		}
+		owner.Share = owner.Amount
+	}
+
+	subscription.Members = append([]models.SubscriptionMember{owner}, others...)
+	return nil
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/pkg/exceptions"
+	"github.com/rasadov/subscription-manager/pkg/statement"
+	"gorm.io/gorm"
+)
+
+var statementColumns = []string{"date", "description", "amount"}
+
+type StatementService interface {
+	ImportStatement(ctx context.Context, userID, filename string, r io.Reader, query dto.ImportStatementQuery) (*dto.ImportStatementResponse, exceptions.HTTPError)
+}
+
+type statementService struct {
+	drafts        repository.DraftRepository
+	subscriptions repository.SubscriptionRepository
+}
+
+func NewStatementService(drafts repository.DraftRepository, subscriptions repository.SubscriptionRepository) StatementService {
+	return &statementService{drafts: drafts, subscriptions: subscriptions}
+}
+
+// ImportStatement parses a bank or card statement, detects recurring charges and
+// stores each one as a pending draft. Merchants the user already tracks are skipped,
+// and re-importing a statement refreshes the existing pending drafts.
+func (s *statementService) ImportStatement(ctx context.Context, userID, filename string, r io.Reader, query dto.ImportStatementQuery) (*dto.ImportStatementResponse, exceptions.HTTPError) {
+	if httpErr := authorizeUser(ctx, userID); httpErr != nil {
+		return nil, httpErr
+	}
+
+	transactions, httpErr := parseStatement(filename, r, query)
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
+	currency := query.Currency
+	if currency == "" {
+		currency = models.DefaultCurrency
+	}
+
+	tracked := make(map[string]bool)
+	filter := repository.SubscriptionFilter{UserID: &userID}
+	err := s.subscriptions.StreamSubscriptions(ctx, filter, func(subscription *models.Subscription) error {
+		tracked[statement.NormalizeMerchant(subscription.ServiceName)] = true
+		return nil
+	})
+	if err != nil {
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	response := &dto.ImportStatementResponse{
+		Transactions: len(transactions),
+		Drafts:       []*dto.DraftResponse{},
+	}
+
+	for _, charge := range statement.DetectRecurring(transactions) {
+		if tracked[charge.Key] {
+			continue
+		}
+
+		draft, err := s.drafts.FindPendingDraft(ctx, userID, models.DraftSourceStatement, charge.Merchant)
+		if err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, exceptions.NewInternalServerError(err.Error())
+			}
+			draft = &models.SubscriptionDraft{
+				UserID:      userID,
+				Source:      models.DraftSourceStatement,
+				ServiceName: charge.Merchant,
+				Status:      models.DraftStatusPending,
+			}
+		}
+
+		draft.Price = int64(math.Round(float64(charge.Amount) / 100))
+		draft.Currency = currency
+		draft.BillingCycle = charge.Cycle
+		draft.StartDate = time.Date(charge.FirstCharged.Year(), charge.FirstCharged.Month(), 1, 0, 0, 0, 0, time.UTC)
+		draft.Confidence = charge.Confidence
+		draft.Details = fmt.Sprintf("%d %s charges between %s and %s, next expected around %s",
+			charge.Occurrences, charge.Cycle,
+			charge.FirstCharged.Format("2006-01-02"), charge.LastCharged.Format("2006-01-02"),
+			charge.NextExpected.Format("2006-01-02"))
+
+		if err := s.drafts.SaveDraft(ctx, draft); err != nil {
+			return nil, exceptions.NewInternalServerError(err.Error())
+		}
+		response.Drafts = append(response.Drafts, dto.NewDraftResponse(draft))
+	}
+
+	return response, nil
+}
+
+func parseStatement(filename string, r io.Reader, query dto.ImportStatementQuery) ([]statement.Transaction, exceptions.HTTPError) {
+	format := query.Format
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(filename)) {
+		case ".ofx", ".qfx":
+			format = "ofx"
+		default:
+			format = "csv"
+		}
+	}
+
+	var transactions []statement.Transaction
+	var err error
+	switch format {
+	case "ofx", "qfx":
+		transactions, err = statement.ParseOFX(r)
+	default:
+		mapping, mappingErr := parseColumnMapping(query.Mapping, statementColumns)
+		if mappingErr != nil {
+			return nil, exceptions.NewBadRequest(mappingErr.Error())
+		}
+		transactions, err = statement.ParseCSV(r, statement.CSVOptions{
+			Columns:    mapping,
+			DateLayout: query.DateFormat,
+		})
+	}
+	if err != nil {
+		return nil, exceptions.NewBadRequest(err.Error())
+	}
+
+	return transactions, nil
+}
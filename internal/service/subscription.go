@@ -3,12 +3,15 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/rasadov/subscription-manager/internal/dto"
 	"github.com/rasadov/subscription-manager/internal/models"
 	"github.com/rasadov/subscription-manager/internal/repository"
 	"github.com/rasadov/subscription-manager/pkg/exceptions"
+	"github.com/rasadov/subscription-manager/pkg/filter"
 	"gorm.io/gorm"
 )
 
@@ -17,19 +20,118 @@ type SubscriptionService interface {
 	GetSubscription(ctx context.Context, id int) (*dto.SubscriptionResponse, exceptions.HTTPError)
 	UpdateSubscription(ctx context.Context, id int, req dto.UpdateSubscriptionRequest) (*dto.SubscriptionResponse, exceptions.HTTPError)
 	DeleteSubscription(ctx context.Context, id int) exceptions.HTTPError
+	BulkSubscriptions(ctx context.Context, operations []dto.BulkOperation, atomic bool) (*dto.BulkSubscriptionsResponse, exceptions.HTTPError)
 	ListSubscriptions(ctx context.Context, query dto.ListSubscriptionsQuery) (*dto.ListSubscriptionsResponse, exceptions.HTTPError)
+	SearchSubscriptions(ctx context.Context, query dto.SearchSubscriptionsQuery) (*dto.SearchSubscriptionsResponse, exceptions.HTTPError)
 	CalculateTotalCost(ctx context.Context, query dto.TotalCostQuery) (*dto.TotalCostResponse, exceptions.HTTPError)
 }
 
+// SubscriptionObserver is notified after a subscription has been created or updated.
+type SubscriptionObserver interface {
+	SubscriptionChanged(ctx context.Context, subscription *models.Subscription)
+}
+
 type subscriptionService struct {
-	repo repository.SubscriptionRepository
+	repo          repository.SubscriptionRepository
+	catalog       repository.CatalogRepository
+	organizations repository.OrganizationRepository
+	observers     []SubscriptionObserver
+	// checkDuplicates warns about existing subscriptions a new one overlaps with.
+	checkDuplicates bool
+}
+
+type SubscriptionServiceOption func(*subscriptionService)
+
+// WithDuplicateCheck makes CreateSubscription warn when the user already has an
+// overlapping subscription to the same service. The subscription is created anyway.
+func WithDuplicateCheck() SubscriptionServiceOption {
+	return func(s *subscriptionService) {
+		s.checkDuplicates = true
+	}
+}
+
+// WithObserver registers an observer of subscription changes, such as budget evaluation.
+func WithObserver(observer SubscriptionObserver) SubscriptionServiceOption {
+	return func(s *subscriptionService) {
+		s.observers = append(s.observers, observer)
+	}
+}
+
+// WithCatalog links subscriptions to the service catalog on create and resolves
+// service_name filters through catalog names and aliases.
+func WithCatalog(catalog repository.CatalogRepository) SubscriptionServiceOption {
+	return func(s *subscriptionService) {
+		s.catalog = catalog
+	}
 }
 
-func NewSubscriptionService(repo repository.SubscriptionRepository) SubscriptionService {
-	return &subscriptionService{repo: repo}
+// WithOrganizations enables the organization_id filter of listing and cost queries.
+func WithOrganizations(organizations repository.OrganizationRepository) SubscriptionServiceOption {
+	return func(s *subscriptionService) {
+		s.organizations = organizations
+	}
+}
+
+func NewSubscriptionService(repo repository.SubscriptionRepository, opts ...SubscriptionServiceOption) SubscriptionService {
+	return newSubscriptionService(repo, opts...)
+}
+
+func newSubscriptionService(repo repository.SubscriptionRepository, opts ...SubscriptionServiceOption) *subscriptionService {
+	s := &subscriptionService{repo: repo}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *subscriptionService) CreateSubscription(ctx context.Context, req dto.CreateSubscriptionRequest) (*dto.SubscriptionResponse, exceptions.HTTPError) {
+	subscription, warnings, httpErr := s.createSubscription(ctx, s.repo, req, newDuplicateIndex(s.repo))
+	if httpErr != nil {
+		return nil, httpErr
+	}
+	s.notify(ctx, subscription)
+
+	response := dto.NewSubscriptionResponse(subscription)
+	response.Warnings = warnings
+	return response, nil
+}
+
+// createSubscription creates the subscription with repo without notifying the
+// observers, so bulk operations can do that once their transaction is committed.
+// It is checked against the duplicates index, which it is added to once created.
+func (s *subscriptionService) createSubscription(ctx context.Context, repo repository.SubscriptionRepository, req dto.CreateSubscriptionRequest, duplicates *duplicateIndex) (*models.Subscription, []string, exceptions.HTTPError) {
+	if httpErr := authorizeUser(ctx, req.UserID); httpErr != nil {
+		return nil, nil, httpErr
+	}
+
+	req, httpErr := s.resolveCatalog(ctx, req)
+	if httpErr != nil {
+		return nil, nil, httpErr
+	}
+
+	subscription, httpErr := newSubscriptionFromRequest(req)
+	if httpErr != nil {
+		return nil, nil, httpErr
+	}
+
+	var warnings []string
+	if s.checkDuplicates {
+		var err error
+		if warnings, err = duplicates.warnings(ctx, subscription); err != nil {
+			return nil, nil, exceptions.NewInternalServerError(err.Error())
+		}
+	}
+
+	if err := repo.CreateSubscription(ctx, subscription); err != nil {
+		return nil, nil, exceptions.NewInternalServerError(err.Error())
+	}
+	duplicates.put(subscription)
+	return subscription, warnings, nil
+}
+
+// newSubscriptionFromRequest converts a create request into a model, parsing
+// its MM-YYYY dates. It is shared by every code path that creates subscriptions.
+func newSubscriptionFromRequest(req dto.CreateSubscriptionRequest) (*models.Subscription, exceptions.HTTPError) {
 	startDate, err := time.Parse("01-2006", req.StartDate)
 	if err != nil {
 		return nil, exceptions.NewBadRequest(err.Error())
@@ -42,19 +144,47 @@ func (s *subscriptionService) CreateSubscription(ctx context.Context, req dto.Cr
 		}
 		endDatePtr = &endDate
 	}
-	subscription := models.Subscription{
-		ServiceName: req.ServiceName,
-		Price:       req.Price,
-		UserID:      req.UserID,
-		StartDate:   startDate,
-		EndDate:     endDatePtr,
+	var trialEndDatePtr *time.Time
+	if req.TrialEndDate != "" {
+		trialEndDate, err := time.Parse("01-2006", req.TrialEndDate)
+		if err != nil {
+			return nil, exceptions.NewBadRequest(err.Error())
+		}
+		trialEndDatePtr = &trialEndDate
 	}
-
-	if err := s.repo.CreateSubscription(ctx, &subscription); err != nil {
-		return nil, exceptions.NewInternalServerError(err.Error())
+	billingCycle := req.BillingCycle
+	if billingCycle == "" {
+		billingCycle = models.BillingCycleMonthly
 	}
-
-	return dto.NewSubscriptionResponse(&subscription), nil
+	currency := req.Currency
+	if currency == "" {
+		currency = models.DefaultCurrency
+	}
+	members, httpErr := newMembers(req.UserID, req.Members)
+	if httpErr != nil {
+		return nil, httpErr
+	}
+	subscription := &models.Subscription{
+		ServiceName:  req.ServiceName,
+		ServiceID:    req.ServiceID,
+		PlanID:       req.PlanID,
+		Price:        req.Price,
+		Currency:     currency,
+		UserID:       req.UserID,
+		BillingCycle: billingCycle,
+		StartDate:    startDate,
+		EndDate:      endDatePtr,
+		TrialEndDate: trialEndDatePtr,
+		Category:     normalizeCategory(req.Category),
+		Tags:         newTags(req.Tags),
+		Notes:        req.Notes,
+		SplitRule:    req.SplitRule,
+		Members:      members,
+	}
+	if httpErr := splitCost(subscription); httpErr != nil {
+		return nil, httpErr
+	}
+	return subscription, nil
 }
 
 func (s *subscriptionService) GetSubscription(ctx context.Context, id int) (*dto.SubscriptionResponse, exceptions.HTTPError) {
@@ -65,27 +195,67 @@ func (s *subscriptionService) GetSubscription(ctx context.Context, id int) (*dto
 		}
 		return nil, exceptions.NewInternalServerError(err.Error())
 	}
+	if httpErr := authorizeSubscription(ctx, subscription, false); httpErr != nil {
+		return nil, httpErr
+	}
 
 	return dto.NewSubscriptionResponse(subscription), nil
 }
 
 func (s *subscriptionService) UpdateSubscription(ctx context.Context, id int, req dto.UpdateSubscriptionRequest) (*dto.SubscriptionResponse, exceptions.HTTPError) {
-	subscription, err := s.repo.GetSubscription(ctx, id)
+	subscription, httpErr := s.updateSubscription(ctx, s.repo, id, req)
+	if httpErr != nil {
+		return nil, httpErr
+	}
+	s.notify(ctx, subscription)
+
+	return dto.NewSubscriptionResponse(subscription), nil
+}
+
+// updateSubscription is UpdateSubscription with repo and without notifying the observers.
+func (s *subscriptionService) updateSubscription(ctx context.Context, repo repository.SubscriptionRepository, id int, req dto.UpdateSubscriptionRequest) (*models.Subscription, exceptions.HTTPError) {
+	subscription, err := repo.GetSubscription(ctx, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, exceptions.NewNotFound(err.Error())
 		}
 		return nil, exceptions.NewInternalServerError(err.Error())
 	}
+	if httpErr := authorizeSubscription(ctx, subscription, true); httpErr != nil {
+		return nil, httpErr
+	}
 
-	if req.ServiceName != nil {
+	if req.ServiceName != nil && *req.ServiceName != subscription.ServiceName {
 		subscription.ServiceName = *req.ServiceName
+		subscription.ServiceID = nil
+		subscription.PlanID = nil
+		if s.catalog != nil {
+			match, ok, httpErr := s.matchCatalog(ctx, subscription.ServiceName)
+			if httpErr != nil {
+				return nil, httpErr
+			}
+			if ok {
+				subscription.ServiceName = match.service.Name
+				subscription.ServiceID = &match.service.ID
+				if match.plan != nil {
+					subscription.PlanID = &match.plan.ID
+				}
+			}
+		}
 	}
 
 	if req.Price != nil {
 		subscription.Price = *req.Price
 	}
 
+	if req.Currency != nil {
+		subscription.Currency = *req.Currency
+	}
+
+	if req.BillingCycle != nil {
+		subscription.BillingCycle = *req.BillingCycle
+	}
+
 	if req.StartDate != nil {
 		startDate, err := time.Parse("01-2006", *req.StartDate)
 		if err != nil {
@@ -95,22 +265,82 @@ func (s *subscriptionService) UpdateSubscription(ctx context.Context, id int, re
 	}
 
 	if req.EndDate != nil {
-		endDate, err := time.Parse("01-2006", *req.EndDate)
-		if err != nil {
-			return nil, exceptions.NewBadRequest(err.Error())
+		subscription.EndDate = nil
+		if *req.EndDate != "" {
+			endDate, err := time.Parse("01-2006", *req.EndDate)
+			if err != nil {
+				return nil, exceptions.NewBadRequest(err.Error())
+			}
+			subscription.EndDate = &endDate
+		}
+	}
+
+	if req.TrialEndDate != nil {
+		subscription.TrialEndDate = nil
+		if *req.TrialEndDate != "" {
+			trialEndDate, err := time.Parse("01-2006", *req.TrialEndDate)
+			if err != nil {
+				return nil, exceptions.NewBadRequest(err.Error())
+			}
+			subscription.TrialEndDate = &trialEndDate
 		}
-		subscription.EndDate = &endDate
 	}
 
-	if err := s.repo.UpdateSubscription(ctx, id, subscription); err != nil {
+	if req.Category != nil {
+		subscription.Category = normalizeCategory(*req.Category)
+	}
+
+	if req.Tags != nil {
+		subscription.Tags = newTags(*req.Tags)
+	}
+
+	if req.Notes != nil {
+		subscription.Notes = *req.Notes
+	}
+
+	if req.SplitRule != nil {
+		subscription.SplitRule = *req.SplitRule
+	}
+
+	if req.Members != nil {
+		members, httpErr := newMembers(subscription.UserID, *req.Members)
+		if httpErr != nil {
+			return nil, httpErr
+		}
+		subscription.Members = members
+	}
+
+	// Shares depend on the price, so they are recomputed on every update
+	if httpErr := splitCost(subscription); httpErr != nil {
+		return nil, httpErr
+	}
+
+	if err := repo.UpdateSubscription(ctx, id, subscription); err != nil {
 		return nil, exceptions.NewInternalServerError(err.Error())
 	}
 
-	return dto.NewSubscriptionResponse(subscription), nil
+	return subscription, nil
 }
 
 func (s *subscriptionService) DeleteSubscription(ctx context.Context, id int) exceptions.HTTPError {
-	err := s.repo.DeleteSubscription(ctx, id)
+	return s.deleteSubscription(ctx, s.repo, id)
+}
+
+func (s *subscriptionService) deleteSubscription(ctx context.Context, repo repository.SubscriptionRepository, id int) exceptions.HTTPError {
+	if _, ok := restricted(ctx); ok {
+		subscription, err := repo.GetSubscription(ctx, id)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return exceptions.NewNotFound(err.Error())
+			}
+			return exceptions.NewInternalServerError(err.Error())
+		}
+		if httpErr := authorizeSubscription(ctx, subscription, true); httpErr != nil {
+			return httpErr
+		}
+	}
+
+	err := repo.DeleteSubscription(ctx, id)
 	if err != nil {
 		return exceptions.NewInternalServerError(err.Error())
 	}
@@ -125,26 +355,113 @@ func (s *subscriptionService) ListSubscriptions(ctx context.Context, query dto.L
 		query.Limit = 10
 	}
 
-	sortBy := "created_at"
-	if query.SortBy != nil && *query.SortBy != "" {
-		sortBy = *query.SortBy
+	sort, httpErr := parseSort(query)
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
+	filter, httpErr := s.listFilter(ctx, query)
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
+	if query.After != nil || query.Before != nil {
+		return s.listSubscriptionsByCursor(ctx, query, filter, sort)
+	}
+
+	subscriptions, total, err := s.repo.ListSubscriptions(ctx, int(query.Page), int(query.Limit), filter, sort)
+	if err != nil {
+		if errors.Is(err, repository.ErrUnknownSortField) {
+			return nil, exceptions.NewBadRequest(err.Error())
+		}
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	var subscriptionResponses []*dto.SubscriptionResponse
+	for _, subscription := range subscriptions {
+		subscriptionResponses = append(subscriptionResponses, dto.NewSubscriptionResponse(subscription))
 	}
 
-	sortOrder := "desc"
-	if query.SortOrder != nil && (*query.SortOrder == "asc" || *query.SortOrder == "desc") {
-		sortOrder = *query.SortOrder
+	return &dto.ListSubscriptionsResponse{
+		Data: subscriptionResponses,
+		Pagination: &dto.Pagination{
+			Page:       query.Page,
+			Limit:      query.Limit,
+			Total:      int(total),
+			TotalPages: (int(total) + query.Limit - 1) / query.Limit,
+		},
+	}, nil
+}
+
+// listSubscriptionsByCursor lists the page following the after cursor or preceding
+// the before one. Unlike page numbers, cursors are not thrown off by subscriptions
+// created or deleted while a client pages through the list.
+func (s *subscriptionService) listSubscriptionsByCursor(ctx context.Context, query dto.ListSubscriptionsQuery, filter repository.SubscriptionFilter, sort []repository.SortField) (*dto.ListSubscriptionsResponse, exceptions.HTTPError) {
+	if query.After != nil && query.Before != nil {
+		return nil, exceptions.NewBadRequest("after and before cannot be combined")
+	}
+	if query.Before != nil && *query.Before == "" {
+		return nil, exceptions.NewBadRequest("before requires a cursor")
+	}
+
+	spec := sortSpec(sort)
+	after, httpErr := decodeCursor(query.After, spec)
+	if httpErr != nil {
+		return nil, httpErr
+	}
+	before, httpErr := decodeCursor(query.Before, spec)
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
+	subscriptions, next, prev, err := s.repo.ListSubscriptionsPage(ctx, filter, repository.SubscriptionPage{
+		Sort:   sort,
+		Limit:  query.Limit,
+		After:  after,
+		Before: before,
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrUnknownSortField) || errors.Is(err, repository.ErrCursorSortField) ||
+			errors.Is(err, repository.ErrInvalidCursor) {
+			return nil, exceptions.NewBadRequest(err.Error())
+		}
+		return nil, exceptions.NewInternalServerError(err.Error())
+	}
+
+	var subscriptionResponses []*dto.SubscriptionResponse
+	for _, subscription := range subscriptions {
+		subscriptionResponses = append(subscriptionResponses, dto.NewSubscriptionResponse(subscription))
 	}
 
+	cursors := &dto.CursorPagination{
+		Limit:      query.Limit,
+		NextCursor: encodeCursor(spec, next),
+		PrevCursor: encodeCursor(spec, prev),
+	}
+	if !query.SkipTotal {
+		total, err := s.repo.CountSubscriptions(ctx, filter)
+		if err != nil {
+			return nil, exceptions.NewInternalServerError(err.Error())
+		}
+		count := int(total)
+		cursors.Total = &count
+	}
+
+	return &dto.ListSubscriptionsResponse{Data: subscriptionResponses, Cursors: cursors}, nil
+}
+
+// newSubscriptionFilter parses the MM-YYYY date filters and the filter expression of a
+// list query into a repository filter.
+func newSubscriptionFilter(query dto.ListSubscriptionsQuery) (repository.SubscriptionFilter, exceptions.HTTPError) {
 	var startDateFrom *time.Time
 	var startDateTo *time.Time
 	var endDateFrom *time.Time
 	var endDateTo *time.Time
-	var err error
 
 	if query.StartDateFrom != nil {
 		startDateFromParsed, err := time.Parse("01-2006", *query.StartDateFrom)
 		if err != nil {
-			return nil, exceptions.NewBadRequest(err.Error())
+			return repository.SubscriptionFilter{}, exceptions.NewBadRequest(err.Error())
 		}
 		startDateFrom = &startDateFromParsed
 	}
@@ -152,7 +469,7 @@ func (s *subscriptionService) ListSubscriptions(ctx context.Context, query dto.L
 	if query.StartDateTo != nil {
 		startDateToParsed, err := time.Parse("01-2006", *query.StartDateTo)
 		if err != nil {
-			return nil, exceptions.NewBadRequest(err.Error())
+			return repository.SubscriptionFilter{}, exceptions.NewBadRequest(err.Error())
 		}
 		startDateTo = &startDateToParsed
 	}
@@ -160,7 +477,7 @@ func (s *subscriptionService) ListSubscriptions(ctx context.Context, query dto.L
 	if query.EndDateFrom != nil {
 		endDateFromParsed, err := time.Parse("01-2006", *query.EndDateFrom)
 		if err != nil {
-			return nil, exceptions.NewBadRequest(err.Error())
+			return repository.SubscriptionFilter{}, exceptions.NewBadRequest(err.Error())
 		}
 		endDateFrom = &endDateFromParsed
 	}
@@ -168,30 +485,35 @@ func (s *subscriptionService) ListSubscriptions(ctx context.Context, query dto.L
 	if query.EndDateTo != nil {
 		endDateToParsed, err := time.Parse("01-2006", *query.EndDateTo)
 		if err != nil {
-			return nil, exceptions.NewBadRequest(err.Error())
+			return repository.SubscriptionFilter{}, exceptions.NewBadRequest(err.Error())
 		}
 		endDateTo = &endDateToParsed
 	}
 
-	subscriptions, total, err := s.repo.ListSubscriptions(ctx, int(query.Page), int(query.Limit), query.UserID, query.ServiceName,
-		startDateFrom, startDateTo, endDateFrom, endDateTo, &sortBy, &sortOrder)
-	if err != nil {
-		return nil, exceptions.NewInternalServerError(err.Error())
-	}
-
-	var subscriptionResponses []*dto.SubscriptionResponse
-	for _, subscription := range subscriptions {
-		subscriptionResponses = append(subscriptionResponses, dto.NewSubscriptionResponse(subscription))
+	var expression *repository.FilterExpression
+	if query.Filter != nil && strings.TrimSpace(*query.Filter) != "" {
+		node, err := filter.Parse(*query.Filter)
+		if err != nil {
+			return repository.SubscriptionFilter{}, exceptions.NewBadRequest(err.Error())
+		}
+		expression, err = repository.NewFilterExpression(node)
+		if err != nil {
+			return repository.SubscriptionFilter{}, exceptions.NewBadRequest(err.Error())
+		}
 	}
 
-	return &dto.ListSubscriptionsResponse{
-		Data: subscriptionResponses,
-		Pagination: &dto.Pagination{
-			Page:       query.Page,
-			Limit:      query.Limit,
-			Total:      int(total),
-			TotalPages: (int(total) + query.Limit - 1) / query.Limit,
-		},
+	return repository.SubscriptionFilter{
+		UserID:        query.UserID,
+		ServiceName:   query.ServiceName,
+		StartDateFrom: startDateFrom,
+		StartDateTo:   startDateTo,
+		EndDateFrom:   endDateFrom,
+		EndDateTo:     endDateTo,
+		Category:      categoryFilter(query.Category),
+		Tags:          tagFilter(query.Tags),
+		MatchAllTags:  query.TagMatch == "all",
+		IncludeShared: query.IncludeShared,
+		Expression:    expression,
 	}, nil
 }
 
@@ -204,17 +526,260 @@ func (s *subscriptionService) CalculateTotalCost(ctx context.Context, query dto.
 	if err != nil {
 		return nil, exceptions.NewBadRequest(err.Error())
 	}
+	userID, httpErr := scopeUserFilter(ctx, nonEmpty(query.UserID), query.OrganizationID)
+	if httpErr != nil {
+		return nil, httpErr
+	}
 
-	totalCost, err := s.repo.CalculateTotalCost(ctx, *query.UserID, *query.ServiceName, &startDateParsed, &endDateParsed)
+	filter := repository.SubscriptionFilter{
+		UserID:        userID,
+		ServiceName:   nonEmpty(query.ServiceName),
+		StartDateFrom: &startDateParsed,
+		EndDateTo:     &endDateParsed,
+		Category:      categoryFilter(query.Category),
+		Tags:          tagFilter(query.Tags),
+		MatchAllTags:  query.TagMatch == "all",
+		IncludeShared: true,
+	}
+	if httpErr := s.resolveServiceFilter(ctx, &filter); httpErr != nil {
+		return nil, httpErr
+	}
+	if httpErr := s.resolveOrganizationFilter(ctx, query.OrganizationID, &filter); httpErr != nil {
+		return nil, httpErr
+	}
+
+	totalCost, err := s.repo.CalculateTotalCost(ctx, filter)
 	if err != nil {
 		return nil, exceptions.NewInternalServerError(err.Error())
 	}
 
-	return &dto.TotalCostResponse{
+	response := &dto.TotalCostResponse{
 		TotalCost: totalCost,
 		Period: &dto.Period{
 			StartDate: query.StartDate,
 			EndDate:   query.EndDate,
 		},
-	}, nil
+	}
+
+	if query.GroupBy != "" {
+		groups, err := s.repo.CalculateCostByGroup(ctx, filter, query.GroupBy)
+		if err != nil {
+			return nil, exceptions.NewInternalServerError(err.Error())
+		}
+		response.Groups = make([]*dto.CostGroup, 0, len(groups))
+		for _, group := range groups {
+			response.Groups = append(response.Groups, &dto.CostGroup{Key: group.Key, TotalCost: group.TotalCost})
+		}
+	}
+
+	return response, nil
+}
+
+// resolveCatalog links a create request to the catalog. An explicit plan or service
+// fills in the values the request omits; otherwise the service name is matched
+// against catalog names and aliases and replaced with the canonical name.
+func (s *subscriptionService) resolveCatalog(ctx context.Context, req dto.CreateSubscriptionRequest) (dto.CreateSubscriptionRequest, exceptions.HTTPError) {
+	if s.catalog == nil {
+		return req, nil
+	}
+
+	if req.PlanID != nil {
+		plan, err := s.catalog.GetPlan(ctx, int(*req.PlanID))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return req, exceptions.NewBadRequest(fmt.Sprintf("plan %d does not exist", *req.PlanID))
+			}
+			return req, exceptions.NewInternalServerError(err.Error())
+		}
+		if req.ServiceID != nil && *req.ServiceID != plan.ServiceID {
+			return req, exceptions.NewBadRequest(fmt.Sprintf("plan %d does not belong to service %d", plan.ID, *req.ServiceID))
+		}
+		req.ServiceID = &plan.ServiceID
+		applyPlanDefaults(&req, plan)
+	}
+
+	if req.ServiceID != nil {
+		service, err := s.catalog.GetService(ctx, int(*req.ServiceID))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return req, exceptions.NewBadRequest(fmt.Sprintf("service %d does not exist", *req.ServiceID))
+			}
+			return req, exceptions.NewInternalServerError(err.Error())
+		}
+		if req.ServiceName == "" {
+			req.ServiceName = service.Name
+		}
+		if req.Category == "" {
+			req.Category = service.Category
+		}
+		return req, nil
+	}
+
+	match, ok, httpErr := s.matchCatalog(ctx, req.ServiceName)
+	if httpErr != nil || !ok {
+		return req, httpErr
+	}
+
+	req.ServiceName = match.service.Name
+	req.ServiceID = &match.service.ID
+	if req.Category == "" {
+		req.Category = match.service.Category
+	}
+	if match.plan != nil {
+		req.PlanID = &match.plan.ID
+		applyPlanDefaults(&req, match.plan)
+	}
+
+	return req, nil
+}
+
+func applyPlanDefaults(req *dto.CreateSubscriptionRequest, plan *models.Plan) {
+	if req.Price == 0 {
+		req.Price = plan.Price
+	}
+	if req.Currency == "" {
+		req.Currency = plan.Currency
+	}
+	if req.BillingCycle == "" {
+		req.BillingCycle = plan.BillingCycle
+	}
+}
+
+func (s *subscriptionService) matchCatalog(ctx context.Context, name string) (catalogMatch, bool, exceptions.HTTPError) {
+	services, err := s.catalog.ListServices(ctx, nil)
+	if err != nil {
+		return catalogMatch{}, false, exceptions.NewInternalServerError(err.Error())
+	}
+
+	match, ok := matchCatalog(services, name)
+	return match, ok, nil
+}
+
+// listFilter builds the filter of a list query scoped to the caller, with the
+// service name resolved through the catalog and the organization to its members.
+func (s *subscriptionService) listFilter(ctx context.Context, query dto.ListSubscriptionsQuery) (repository.SubscriptionFilter, exceptions.HTTPError) {
+	userID, httpErr := scopeUserFilter(ctx, query.UserID, query.OrganizationID)
+	if httpErr != nil {
+		return repository.SubscriptionFilter{}, httpErr
+	}
+	query.UserID = userID
+
+	filter, httpErr := newSubscriptionFilter(query)
+	if httpErr != nil {
+		return repository.SubscriptionFilter{}, httpErr
+	}
+	if httpErr := s.resolveServiceFilter(ctx, &filter); httpErr != nil {
+		return repository.SubscriptionFilter{}, httpErr
+	}
+	if httpErr := s.resolveOrganizationFilter(ctx, query.OrganizationID, &filter); httpErr != nil {
+		return repository.SubscriptionFilter{}, httpErr
+	}
+	return filter, nil
+}
+
+// resolveServiceFilter turns a service_name filter that matches the catalog into a
+// filter on the catalog service (or plan), so every spelling of it is selected.
+func (s *subscriptionService) resolveServiceFilter(ctx context.Context, filter *repository.SubscriptionFilter) exceptions.HTTPError {
+	if s.catalog == nil || filter.ServiceName == nil {
+		return nil
+	}
+
+	match, ok, httpErr := s.matchCatalog(ctx, *filter.ServiceName)
+	if httpErr != nil || !ok {
+		return httpErr
+	}
+
+	filter.ServiceID = &match.service.ID
+	if match.plan != nil {
+		filter.PlanID = &match.plan.ID
+		filter.ServiceNames = []string{*filter.ServiceName}
+	} else {
+		filter.ServiceNames = append(catalogNames(match.service), *filter.ServiceName)
+	}
+
+	return nil
+}
+
+// resolveOrganizationFilter restricts the filter to the members of the organization
+// who accepted their membership; invited users have not agreed to share their data.
+func (s *subscriptionService) resolveOrganizationFilter(ctx context.Context, organizationID *uint, filter *repository.SubscriptionFilter) exceptions.HTTPError {
+	if organizationID == nil {
+		return nil
+	}
+	if s.organizations == nil {
+		return exceptions.NewBadRequest("organizations are not enabled")
+	}
+
+	organization, err := s.organizations.GetOrganization(ctx, int(*organizationID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return exceptions.NewNotFound(err.Error())
+		}
+		return exceptions.NewInternalServerError(err.Error())
+	}
+	if httpErr := authorizeOrganization(ctx, organization, true); httpErr != nil {
+		return httpErr
+	}
+
+	filter.UserIDs = make([]string, 0, len(organization.Members))
+	for _, member := range organization.Members {
+		if member.Accepted() {
+			filter.UserIDs = append(filter.UserIDs, member.UserID)
+		}
+	}
+	return nil
+}
+
+func (s *subscriptionService) notify(ctx context.Context, subscription *models.Subscription) {
+	for _, observer := range s.observers {
+		observer.SubscriptionChanged(ctx, subscription)
+	}
+}
+
+// normalizeCategory lowercases a category so filters and groups do not depend on spelling.
+func normalizeCategory(category string) string {
+	return strings.ToLower(strings.TrimSpace(category))
+}
+
+// newTags builds the tags of a subscription from their names, normalized like
+// categories and without duplicates. The repository links them to stored tags.
+func newTags(names []string) []models.Tag {
+	tags := []models.Tag{}
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = normalizeCategory(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		tags = append(tags, models.Tag{Name: name})
+	}
+	return tags
+}
+
+func categoryFilter(category *string) *string {
+	if category == nil {
+		return nil
+	}
+	normalized := normalizeCategory(*category)
+	return nonEmpty(&normalized)
+}
+
+// tagFilter splits a comma separated tags query parameter.
+func tagFilter(tags *string) []string {
+	if tags == nil {
+		return nil
+	}
+	var names []string
+	for _, tag := range newTags(strings.Split(*tags, ",")) {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+func nonEmpty(value *string) *string {
+	if value == nil || *value == "" {
+		return nil
+	}
+	return value
 }
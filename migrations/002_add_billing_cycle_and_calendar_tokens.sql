@@ -0,0 +1,9 @@
+ALTER TABLE subscriptions ADD COLUMN billing_cycle VARCHAR(16) NOT NULL DEFAULT 'monthly';
+
+CREATE TABLE calendar_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id UUID NOT NULL UNIQUE,
+    token VARCHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
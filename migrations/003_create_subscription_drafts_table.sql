@@ -0,0 +1,18 @@
+CREATE TABLE subscription_drafts (
+    id SERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    source VARCHAR(32) NOT NULL,
+    service_name VARCHAR(255) NOT NULL,
+    price INTEGER NOT NULL,
+    billing_cycle VARCHAR(16) NOT NULL DEFAULT 'monthly',
+    start_date TIMESTAMP NOT NULL,
+    confidence DOUBLE PRECISION NOT NULL DEFAULT 0,
+    details TEXT,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    subscription_id INTEGER REFERENCES subscriptions (id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_subscription_drafts_user_id ON subscription_drafts (user_id);
+CREATE INDEX idx_subscription_drafts_status ON subscription_drafts (status);
@@ -0,0 +1,19 @@
+ALTER TABLE subscriptions ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'RUB';
+ALTER TABLE subscription_drafts ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'RUB';
+
+CREATE TABLE receipt_rules (
+    id SERIAL PRIMARY KEY,
+    sender_domain VARCHAR(255) NOT NULL,
+    service_name VARCHAR(255),
+    subject_pattern TEXT,
+    price_pattern TEXT NOT NULL,
+    currency VARCHAR(3),
+    date_pattern TEXT,
+    date_layout VARCHAR(64),
+    billing_cycle VARCHAR(16),
+    priority INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_receipt_rules_sender_domain ON receipt_rules (sender_domain);
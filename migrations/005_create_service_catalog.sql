@@ -0,0 +1,32 @@
+CREATE TABLE services (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    aliases TEXT,
+    category VARCHAR(64),
+    website VARCHAR(255),
+    logo_url VARCHAR(512),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_services_name ON services (name);
+CREATE INDEX idx_services_category ON services (category);
+
+CREATE TABLE plans (
+    id SERIAL PRIMARY KEY,
+    service_id INTEGER NOT NULL REFERENCES services (id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    price INTEGER NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'RUB',
+    billing_cycle VARCHAR(16) NOT NULL DEFAULT 'monthly',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_plans_service_id_name ON plans (service_id, name);
+
+ALTER TABLE subscriptions ADD COLUMN service_id INTEGER REFERENCES services (id) ON DELETE SET NULL;
+ALTER TABLE subscriptions ADD COLUMN plan_id INTEGER REFERENCES plans (id) ON DELETE SET NULL;
+
+CREATE INDEX idx_subscriptions_service_id ON subscriptions (service_id);
+CREATE INDEX idx_subscriptions_plan_id ON subscriptions (plan_id);
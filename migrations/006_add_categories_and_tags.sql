@@ -0,0 +1,25 @@
+ALTER TABLE subscriptions ADD COLUMN category VARCHAR(64);
+
+CREATE INDEX idx_subscriptions_category ON subscriptions (category);
+
+UPDATE subscriptions
+SET category = LOWER(services.category)
+FROM services
+WHERE services.id = subscriptions.service_id AND services.category IS NOT NULL;
+
+CREATE TABLE tags (
+    id SERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    name VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_tags_user_id_name ON tags (user_id, name);
+
+CREATE TABLE subscription_tags (
+    subscription_id INTEGER NOT NULL REFERENCES subscriptions (id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+    PRIMARY KEY (subscription_id, tag_id)
+);
+
+CREATE INDEX idx_subscription_tags_tag_id ON subscription_tags (tag_id);
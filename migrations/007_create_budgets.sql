@@ -0,0 +1,29 @@
+CREATE TABLE budgets (
+    id SERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    scope VARCHAR(16) NOT NULL DEFAULT 'all',
+    category VARCHAR(64),
+    service_name VARCHAR(255),
+    monthly_limit BIGINT NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'RUB',
+    thresholds TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_budgets_user_id ON budgets (user_id);
+
+CREATE TABLE budget_alerts (
+    id SERIAL PRIMARY KEY,
+    budget_id INTEGER NOT NULL REFERENCES budgets (id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    month TIMESTAMP NOT NULL,
+    threshold INTEGER NOT NULL,
+    spend BIGINT NOT NULL,
+    monthly_limit BIGINT NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_budget_alerts_budget_id_month_threshold ON budget_alerts (budget_id, month, threshold);
+CREATE INDEX idx_budget_alerts_user_id ON budget_alerts (user_id);
@@ -0,0 +1,11 @@
+ALTER TABLE subscriptions ADD COLUMN trial_end_date TIMESTAMP;
+
+CREATE TABLE price_changes (
+    id SERIAL PRIMARY KEY,
+    subscription_id INTEGER NOT NULL REFERENCES subscriptions (id) ON DELETE CASCADE,
+    effective_date TIMESTAMP NOT NULL,
+    price BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_price_changes_subscription_id ON price_changes (subscription_id);
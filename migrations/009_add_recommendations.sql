@@ -0,0 +1,16 @@
+ALTER TABLE plans ADD COLUMN seats INTEGER NOT NULL DEFAULT 1;
+
+ALTER TABLE subscriptions ADD COLUMN usage VARCHAR(16);
+ALTER TABLE subscriptions ADD COLUMN usage_reported_at TIMESTAMP;
+
+CREATE TABLE recommendation_states (
+    id SERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    key VARCHAR(128) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    snoozed_until TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_recommendation_states_user_id_key ON recommendation_states (user_id, key);
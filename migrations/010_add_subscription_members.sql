@@ -0,0 +1,13 @@
+ALTER TABLE subscriptions ADD COLUMN split_rule VARCHAR(16);
+
+CREATE TABLE subscription_members (
+    id SERIAL PRIMARY KEY,
+    subscription_id INTEGER NOT NULL REFERENCES subscriptions (id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    share BIGINT NOT NULL DEFAULT 0,
+    amount BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_subscription_members_subscription_id_user_id ON subscription_members (subscription_id, user_id);
+CREATE INDEX idx_subscription_members_user_id ON subscription_members (user_id);
@@ -0,0 +1,37 @@
+-- Existing rows belong to the default tenant
+ALTER TABLE subscriptions ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE subscription_members ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE price_changes ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE tags ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE calendar_tokens ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE subscription_drafts ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE budgets ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE budget_alerts ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE recommendation_states ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE organizations ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE organization_members ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+
+CREATE INDEX idx_subscriptions_tenant_id ON subscriptions (tenant_id);
+CREATE INDEX idx_price_changes_tenant_id ON price_changes (tenant_id);
+CREATE INDEX idx_subscription_drafts_tenant_id ON subscription_drafts (tenant_id);
+CREATE INDEX idx_budgets_tenant_id ON budgets (tenant_id);
+CREATE INDEX idx_organizations_tenant_id ON organizations (tenant_id);
+
+-- Unique keys are unique per tenant
+ALTER TABLE calendar_tokens DROP CONSTRAINT calendar_tokens_user_id_key;
+CREATE UNIQUE INDEX idx_calendar_tokens_tenant_id_user_id ON calendar_tokens (tenant_id, user_id);
+
+DROP INDEX idx_tags_user_id_name;
+CREATE UNIQUE INDEX idx_tags_tenant_id_user_id_name ON tags (tenant_id, user_id, name);
+
+DROP INDEX idx_budget_alerts_budget_id_month_threshold;
+CREATE UNIQUE INDEX idx_budget_alerts_tenant_id_budget_id_month_threshold ON budget_alerts (tenant_id, budget_id, month, threshold);
+
+DROP INDEX idx_recommendation_states_user_id_key;
+CREATE UNIQUE INDEX idx_recommendation_states_tenant_id_user_id_key ON recommendation_states (tenant_id, user_id, key);
+
+DROP INDEX idx_subscription_members_subscription_id_user_id;
+CREATE UNIQUE INDEX idx_subscription_members_tenant_id_subscription_id_user_id ON subscription_members (tenant_id, subscription_id, user_id);
+
+DROP INDEX idx_organization_members_organization_id_user_id;
+CREATE UNIQUE INDEX idx_organization_members_tenant_id_organization_id_user_id ON organization_members (tenant_id, organization_id, user_id);
@@ -0,0 +1,16 @@
+CREATE TABLE api_keys (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL DEFAULT 'default',
+    name VARCHAR(255) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    secret_hash VARCHAR(64) NOT NULL,
+    scopes TEXT,
+    created_by VARCHAR(255),
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_api_keys_tenant_id ON api_keys (tenant_id);
+CREATE UNIQUE INDEX idx_api_keys_secret_hash ON api_keys (secret_hash);
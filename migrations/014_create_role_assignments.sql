@@ -0,0 +1,10 @@
+CREATE TABLE role_assignments (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL DEFAULT 'default',
+    user_id UUID NOT NULL,
+    role VARCHAR(16) NOT NULL,
+    created_by VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_role_assignments_tenant_id_user_id_role ON role_assignments (tenant_id, user_id, role);
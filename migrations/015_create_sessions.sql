@@ -0,0 +1,13 @@
+CREATE TABLE sessions (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL DEFAULT 'default',
+    token_hash VARCHAR(64) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    roles TEXT,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_sessions_tenant_id ON sessions (tenant_id);
+CREATE UNIQUE INDEX idx_sessions_token_hash ON sessions (token_hash);
+CREATE INDEX idx_sessions_expires_at ON sessions (expires_at);
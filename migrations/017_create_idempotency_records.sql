@@ -0,0 +1,15 @@
+CREATE TABLE idempotency_records (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL DEFAULT 'default',
+    subject VARCHAR(255) NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    fingerprint VARCHAR(64) NOT NULL,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    content_type VARCHAR(255),
+    body BYTEA,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_idempotency_records_tenant_id_subject_key ON idempotency_records (tenant_id, subject, key);
+CREATE INDEX idx_idempotency_records_expires_at ON idempotency_records (expires_at);
@@ -0,0 +1,3 @@
+-- Cursor pagination seeks to (sort value, id) within a tenant
+CREATE INDEX idx_subscriptions_tenant_id_created_at_id ON subscriptions (tenant_id, created_at, id);
+CREATE INDEX idx_subscriptions_tenant_id_price_id ON subscriptions (tenant_id, price, id);
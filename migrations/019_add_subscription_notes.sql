@@ -0,0 +1,5 @@
+-- Free-form notes, searched together with the service name and tags
+ALTER TABLE subscriptions ADD COLUMN notes TEXT;
+
+-- Typo-tolerant search scores with trigram word similarity
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
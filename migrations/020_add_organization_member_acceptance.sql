@@ -0,0 +1,4 @@
+-- Members join an organization by accepting an invitation. Existing memberships
+-- were never confirmed by the invited users, so only owners stay accepted.
+ALTER TABLE organization_members ADD COLUMN accepted_at TIMESTAMP;
+UPDATE organization_members SET accepted_at = created_at WHERE role = 'owner';
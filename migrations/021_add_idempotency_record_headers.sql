@@ -0,0 +1,2 @@
+-- Response headers replayed to retries, such as Location
+ALTER TABLE idempotency_records ADD COLUMN headers TEXT;
@@ -0,0 +1,19 @@
+-- The service catalog and receipt rules belong to a tenant like every other
+-- record; the existing ones were shared and stay with the default tenant
+ALTER TABLE services ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE plans ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE receipt_rules ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+
+CREATE INDEX idx_receipt_rules_tenant_id ON receipt_rules (tenant_id);
+
+-- Unique keys are unique per tenant
+DROP INDEX idx_services_name;
+CREATE UNIQUE INDEX idx_services_tenant_id_name ON services (tenant_id, name);
+
+DROP INDEX idx_plans_service_id_name;
+CREATE UNIQUE INDEX idx_plans_tenant_id_service_id_name ON plans (tenant_id, service_id, name);
+
+-- Subscriptions of other tenants cannot reference the default catalog anymore;
+-- they keep their name and become custom subscriptions
+UPDATE subscriptions SET service_id = NULL, plan_id = NULL
+WHERE tenant_id <> 'default' AND (service_id IS NOT NULL OR plan_id IS NOT NULL);
@@ -0,0 +1,6 @@
+-- Calendar feed tokens are stored as SHA-256 hashes like API keys and sessions.
+-- Existing tokens are hashed in place, so shared feed URLs keep working.
+ALTER TABLE calendar_tokens DROP CONSTRAINT calendar_tokens_token_key;
+ALTER TABLE calendar_tokens RENAME COLUMN token TO token_hash;
+UPDATE calendar_tokens SET token_hash = encode(sha256(convert_to(token_hash, 'UTF8')), 'hex');
+CREATE UNIQUE INDEX idx_calendar_tokens_token_hash ON calendar_tokens (token_hash);
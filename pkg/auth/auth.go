@@ -0,0 +1,123 @@
+// Package auth carries the authenticated caller of a request through its context
+// and defines the roles and permissions callers are granted.
+package auth
+
+import (
+	"context"
+	"slices"
+)
+
+// Permissions allow operations. PermissionAdmin grants every permission.
+const (
+	PermissionSubscriptionsRead  = "subscriptions:read"
+	PermissionSubscriptionsWrite = "subscriptions:write"
+	PermissionCostsRead          = "costs:read"
+	PermissionAdmin              = "admin:*"
+)
+
+// Permissions lists every known permission.
+var Permissions = []string{PermissionSubscriptionsRead, PermissionSubscriptionsWrite, PermissionCostsRead, PermissionAdmin}
+
+// Roles bundle permissions. Viewers and editors work with their own data, while
+// auditors and admins act on the data of every user in their tenant.
+const (
+	RoleViewer  = "viewer"
+	RoleEditor  = "editor"
+	RoleAuditor = "auditor"
+	RoleAdmin   = "admin"
+)
+
+// Roles lists every known role.
+var Roles = []string{RoleViewer, RoleEditor, RoleAuditor, RoleAdmin}
+
+var rolePermissions = map[string][]string{
+	RoleViewer:  {PermissionSubscriptionsRead, PermissionCostsRead},
+	RoleEditor:  {PermissionSubscriptionsRead, PermissionSubscriptionsWrite, PermissionCostsRead},
+	RoleAuditor: {PermissionSubscriptionsRead, PermissionCostsRead},
+	RoleAdmin:   {PermissionAdmin},
+}
+
+// IsRole reports whether the role is known.
+func IsRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// RolesFromClaim returns the known roles among the values of a "roles" claim,
+// where adminRole stands for RoleAdmin.
+func RolesFromClaim(values []string, adminRole string) []string {
+	var roles []string
+	for _, role := range values {
+		if adminRole != "" && role == adminRole {
+			role = RoleAdmin
+		}
+		if IsRole(role) {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	// Subject identifies the caller. For users it is their user ID.
+	Subject string
+	// TenantID is the tenant the caller belongs to.
+	TenantID string
+	Roles    []string
+	// AllUsers callers may access the data of every user in their tenant, within
+	// their permissions. Other callers only access their own data.
+	AllUsers bool
+	// Permissions restrict the operations of the principal. A nil slice means
+	// the principal is not restricted.
+	Permissions []string
+}
+
+// Allows reports whether the permissions of the principal include the permission.
+func (p *Principal) Allows(permission string) bool {
+	if p.Permissions == nil {
+		return true
+	}
+	return slices.Contains(p.Permissions, permission) || slices.Contains(p.Permissions, PermissionAdmin)
+}
+
+// HasRole reports whether the principal has the role.
+func (p *Principal) HasRole(role string) bool {
+	return slices.Contains(p.Roles, role)
+}
+
+// SetRoles replaces the roles of the principal, ignoring unknown ones, and grants
+// the permissions of the roles.
+func (p *Principal) SetRoles(roles []string) {
+	p.Roles = []string{}
+	p.Permissions = []string{}
+	p.AllUsers = false
+	for _, role := range roles {
+		if !IsRole(role) || slices.Contains(p.Roles, role) {
+			continue
+		}
+		p.Roles = append(p.Roles, role)
+		for _, permission := range rolePermissions[role] {
+			if !slices.Contains(p.Permissions, permission) {
+				p.Permissions = append(p.Permissions, permission)
+			}
+		}
+		if role == RoleAuditor || role == RoleAdmin {
+			p.AllUsers = true
+		}
+	}
+}
+
+type contextKey struct{}
+
+// WithPrincipal returns a context carrying the principal.
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, principal)
+}
+
+// FromContext returns the principal of the context. Contexts of unauthenticated
+// requests and of background jobs have none.
+func FromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(contextKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
@@ -7,7 +7,8 @@ import (
 	"gorm.io/gorm"
 )
 
-// NewPostgresDB - returns connection to the postgres database
+// NewPostgresDB - returns connection to the postgres database, with every
+// statement scoped to the tenant of its context (see RegisterTenantScope)
 func NewPostgresDB(
 	host string,
 	user string,
@@ -24,6 +25,10 @@ func NewPostgresDB(
 		return nil, err
 	}
 
+	if err := RegisterTenantScope(db); err != nil {
+		return nil, err
+	}
+
 	// Check whether db works correctly
 	sqlDB, err := db.DB()
 
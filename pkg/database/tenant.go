@@ -0,0 +1,96 @@
+package database
+
+import (
+	"reflect"
+
+	"github.com/rasadov/subscription-manager/pkg/tenant"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// TenantField is the model field that holds the tenant of a record.
+const TenantField = "TenantID"
+
+// RegisterTenantScope installs callbacks that restrict every statement on a model
+// with a TenantID field to the tenant of the statement context (see package
+// tenant): reads, updates and deletes get a tenant_id condition, and created
+// records are assigned the tenant whatever value they carried. Raw SQL is not
+// affected.
+func RegisterTenantScope(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Query().Before("gorm:query").Register("tenant:query", scopeTenant); err != nil {
+		return err
+	}
+	if err := callbacks.Row().Before("gorm:row").Register("tenant:row", scopeTenant); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("tenant:update", scopeTenant); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("gorm:delete").Register("tenant:delete", scopeTenant); err != nil {
+		return err
+	}
+	return callbacks.Create().Before("gorm:create").Register("tenant:create", assignTenant)
+}
+
+func tenantField(db *gorm.DB) *schema.Field {
+	if db.Error != nil || db.Statement.Schema == nil || tenant.AllTenants(db.Statement.Context) {
+		return nil
+	}
+	return db.Statement.Schema.LookUpField(TenantField)
+}
+
+func scopeTenant(db *gorm.DB) {
+	field := tenantField(db)
+	if field == nil {
+		return
+	}
+
+	tenantID := tenant.FromContext(db.Statement.Context)
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: tenantID},
+	}})
+
+	// Records cannot be moved to another tenant either
+	if db.Statement.ReflectValue.Kind() == reflect.Struct && db.Statement.Dest != nil {
+		setTenant(db, field, db.Statement.ReflectValue, tenantID)
+	}
+}
+
+func assignTenant(db *gorm.DB) {
+	field := tenantField(db)
+	if field == nil {
+		return
+	}
+
+	tenantID := tenant.FromContext(db.Statement.Context)
+	switch value := db.Statement.ReflectValue; value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			setTenant(db, field, reflect.Indirect(value.Index(i)), tenantID)
+		}
+	case reflect.Struct:
+		setTenant(db, field, value, tenantID)
+	}
+
+	// An upsert must not overwrite the conflicting record of another tenant
+	if c, ok := db.Statement.Clauses["ON CONFLICT"]; ok {
+		if onConflict, ok := c.Expression.(clause.OnConflict); ok && !onConflict.DoNothing {
+			onConflict.Where.Exprs = append(onConflict.Where.Exprs, clause.Eq{
+				Column: clause.Column{Table: db.Statement.Table, Name: field.DBName},
+				Value:  tenantID,
+			})
+			db.Statement.AddClause(onConflict)
+		}
+	}
+}
+
+func setTenant(db *gorm.DB, field *schema.Field, value reflect.Value, tenantID string) {
+	if value.Kind() != reflect.Struct || value.Type() != db.Statement.Schema.ModelType {
+		return
+	}
+	if err := field.Set(db.Statement.Context, value, tenantID); err != nil {
+		db.AddError(err)
+	}
+}
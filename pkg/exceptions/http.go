@@ -38,3 +38,15 @@ func NewNotFound(message string) HTTPError {
 func NewInternalServerError(message string) HTTPError {
 	return NewHTTPError(http.StatusInternalServerError, message)
 }
+
+func NewConflict(message string) HTTPError {
+	return NewHTTPError(http.StatusConflict, message)
+}
+
+func NewUnauthorized(message string) HTTPError {
+	return NewHTTPError(http.StatusUnauthorized, message)
+}
+
+func NewForbidden(message string) HTTPError {
+	return NewHTTPError(http.StatusForbidden, message)
+}
@@ -0,0 +1,312 @@
+// Package filter parses filter expressions such as
+//
+//	price > 1000 and service_name ilike 'net%' and end_date is null
+//
+// into a syntax tree. Conditions compare a field with a literal using =, !=, <>,
+// <, <=, >, >=, [not] in (...), [not] like, [not] ilike and is [not] null, and are
+// combined with and, or, not and parentheses. Keywords are case-insensitive and
+// strings are single-quoted, with a quote inside a string escaped by doubling it:
+//
+//	service_name = 'O''Reilly'
+//
+// The tree says nothing about which fields exist; callers check them when they
+// translate it.
+package filter
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// MaxLength is the longest expression Parse accepts.
+	MaxLength = 2000
+	// MaxConditions is the largest number of conditions in an expression.
+	MaxConditions = 50
+	// MaxValues is the largest number of values in an in list.
+	MaxValues = 100
+)
+
+// Node is a node of the syntax tree: And, Or, Not or Condition.
+type Node interface {
+	node()
+}
+
+type And struct {
+	Left, Right Node
+}
+
+type Or struct {
+	Left, Right Node
+}
+
+type Not struct {
+	Operand Node
+}
+
+// Operators of conditions.
+const (
+	OpEqual        = "="
+	OpNotEqual     = "!="
+	OpLess         = "<"
+	OpLessEqual    = "<="
+	OpGreater      = ">"
+	OpGreaterEqual = ">="
+	OpIn           = "in"
+	OpNotIn        = "not in"
+	OpLike         = "like"
+	OpNotLike      = "not like"
+	OpILike        = "ilike"
+	OpNotILike     = "not ilike"
+	OpIsNull       = "is null"
+	OpIsNotNull    = "is not null"
+)
+
+// Condition compares Field with Values: one value for comparisons and patterns,
+// one or more for in lists and none for null checks.
+type Condition struct {
+	Field  string
+	Op     string
+	Values []Value
+}
+
+// Value is a literal: a number or a string.
+type Value struct {
+	Text   string
+	String bool
+}
+
+func (And) node()       {}
+func (Or) node()        {}
+func (Not) node()       {}
+func (Condition) node() {}
+
+// SyntaxError reports where and why an expression could not be parsed.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("filter: %s at position %d", e.Msg, e.Pos+1)
+}
+
+// Parse parses an expression.
+func Parse(input string) (Node, error) {
+	if len(input) > MaxLength {
+		return nil, &SyntaxError{Pos: MaxLength, Msg: fmt.Sprintf("expression is longer than %d characters", MaxLength)}
+	}
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.unexpected(tok)
+	}
+	return node, nil
+}
+
+type parser struct {
+	tokens     []token
+	pos        int
+	conditions int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// keyword consumes the next token if it is the keyword.
+func (p *parser) keyword(word string) bool {
+	if tok := p.peek(); tok.kind == tokenIdent && strings.EqualFold(tok.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(kind tokenKind, text string) error {
+	tok := p.next()
+	if tok.kind != kind || (text != "" && tok.text != text) {
+		if text == "" {
+			text = kind.String()
+		}
+		return &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("expected %s, found %s", text, tok)}
+	}
+	return nil
+}
+
+func (p *parser) unexpected(tok token) error {
+	return &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("unexpected %s", tok)}
+}
+
+func (p *parser) parseOr() (Node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = Or{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = And{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	if p.keyword("not") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Operand: operand}, nil
+	}
+	if tok := p.peek(); tok.kind == tokenPunct && tok.text == "(" {
+		p.next()
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenPunct, ")"); err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+	return p.parseCondition()
+}
+
+func (p *parser) parseCondition() (Node, error) {
+	field := p.next()
+	if field.kind != tokenIdent || isKeyword(field.text) {
+		return nil, &SyntaxError{Pos: field.pos, Msg: fmt.Sprintf("expected a field, found %s", field)}
+	}
+	p.conditions++
+	if p.conditions > MaxConditions {
+		return nil, &SyntaxError{Pos: field.pos, Msg: fmt.Sprintf("expression has more than %d conditions", MaxConditions)}
+	}
+	condition := Condition{Field: strings.ToLower(field.text)}
+
+	if p.keyword("is") {
+		condition.Op = OpIsNull
+		if p.keyword("not") {
+			condition.Op = OpIsNotNull
+		}
+		if !p.keyword("null") {
+			return nil, p.unexpected(p.peek())
+		}
+		return condition, nil
+	}
+
+	negated := p.keyword("not")
+	switch {
+	case p.keyword("in"):
+		condition.Op = OpIn
+		values, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		condition.Values = values
+	case p.keyword("like"):
+		condition.Op = OpLike
+	case p.keyword("ilike"):
+		condition.Op = OpILike
+	case negated:
+		return nil, &SyntaxError{Pos: p.peek().pos, Msg: fmt.Sprintf("expected in, like or ilike, found %s", p.peek())}
+	default:
+		op := p.next()
+		if op.kind != tokenOperator {
+			return nil, &SyntaxError{Pos: op.pos, Msg: fmt.Sprintf("expected an operator, found %s", op)}
+		}
+		condition.Op = op.text
+		if op.text == "<>" {
+			condition.Op = OpNotEqual
+		}
+	}
+	if negated {
+		condition.Op = "not " + condition.Op
+	}
+
+	if condition.Values == nil {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		if (condition.Op == OpLike || condition.Op == OpNotLike || condition.Op == OpILike || condition.Op == OpNotILike) && !value.String {
+			return nil, &SyntaxError{Pos: p.tokens[p.pos-1].pos, Msg: "expected a string pattern"}
+		}
+		condition.Values = []Value{value}
+	}
+	return condition, nil
+}
+
+func (p *parser) parseList() ([]Value, error) {
+	if err := p.expect(tokenPunct, "("); err != nil {
+		return nil, err
+	}
+	var values []Value
+	for {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		if len(values) > MaxValues {
+			return nil, &SyntaxError{Pos: p.tokens[p.pos-1].pos, Msg: fmt.Sprintf("in list has more than %d values", MaxValues)}
+		}
+		if tok := p.peek(); tok.kind == tokenPunct && tok.text == "," {
+			p.next()
+			continue
+		}
+		if err := p.expect(tokenPunct, ")"); err != nil {
+			return nil, err
+		}
+		return values, nil
+	}
+}
+
+func (p *parser) parseValue() (Value, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenString:
+		return Value{Text: tok.text, String: true}, nil
+	case tokenNumber:
+		return Value{Text: tok.text}, nil
+	}
+	return Value{}, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("expected a number or a string, found %s", tok)}
+}
+
+var keywords = map[string]bool{"and": true, "or": true, "not": true, "in": true, "like": true, "ilike": true, "is": true, "null": true}
+
+func isKeyword(word string) bool {
+	return keywords[strings.ToLower(word)]
+}
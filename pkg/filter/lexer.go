@@ -0,0 +1,124 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOperator
+	tokenPunct
+)
+
+func (k tokenKind) String() string {
+	switch k {
+	case tokenIdent:
+		return "a field or keyword"
+	case tokenNumber:
+		return "a number"
+	case tokenString:
+		return "a string"
+	case tokenOperator:
+		return "an operator"
+	case tokenPunct:
+		return "punctuation"
+	}
+	return "the end of the expression"
+}
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "the end of the expression"
+	case tokenString:
+		return fmt.Sprintf("'%s'", t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	for pos := 0; pos < len(input); {
+		c := input[pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			pos++
+		case c == '(' || c == ')' || c == ',':
+			tokens = append(tokens, token{kind: tokenPunct, text: string(c), pos: pos})
+			pos++
+		case strings.ContainsRune("=!<>", rune(c)):
+			start := pos
+			pos++
+			if pos < len(input) && (input[pos] == '=' || (c == '<' && input[pos] == '>')) {
+				pos++
+			}
+			text := input[start:pos]
+			if text == "!" {
+				return nil, &SyntaxError{Pos: start, Msg: `expected "!="`}
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: text, pos: start})
+		case c == '\'':
+			text, end, err := scanString(input, pos)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text, pos: pos})
+			pos = end
+		case isDigit(c) || (c == '-' && pos+1 < len(input) && isDigit(input[pos+1])):
+			start := pos
+			pos++
+			for pos < len(input) && (isDigit(input[pos]) || input[pos] == '.') {
+				pos++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: input[start:pos], pos: start})
+		case isIdentStart(c):
+			start := pos
+			for pos < len(input) && (isIdentStart(input[pos]) || isDigit(input[pos])) {
+				pos++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: input[start:pos], pos: start})
+		default:
+			return nil, &SyntaxError{Pos: pos, Msg: fmt.Sprintf("unexpected character %q", c)}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(input)}), nil
+}
+
+// scanString reads the quoted string starting at pos and returns its content and
+// the position after the closing quote.
+func scanString(input string, pos int) (string, int, error) {
+	var sb strings.Builder
+	for i := pos + 1; i < len(input); i++ {
+		if input[i] != '\'' {
+			sb.WriteByte(input[i])
+			continue
+		}
+		if i+1 < len(input) && input[i+1] == '\'' {
+			sb.WriteByte('\'')
+			i++
+			continue
+		}
+		return sb.String(), i + 1, nil
+	}
+	return "", 0, &SyntaxError{Pos: pos, Msg: "unterminated string"}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
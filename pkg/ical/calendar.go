@@ -0,0 +1,95 @@
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+)
+
+// Calendar is a minimal RFC 5545 VCALENDAR with all-day events.
+type Calendar struct {
+	ProdID string
+	Name   string
+	Events []Event
+}
+
+// Event is an all-day VEVENT. UID must stay the same across feed refreshes so
+// calendar clients update the event in place instead of duplicating it.
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Date        time.Time
+	Stamp       time.Time
+}
+
+const (
+	dateFormat     = "20060102"
+	dateTimeFormat = "20060102T150405Z"
+	maxLineOctets  = 75
+)
+
+// Write serializes the calendar using CRLF line endings and folded content lines.
+func (c *Calendar) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:" + escapeText(c.ProdID),
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+	}
+	if c.Name != "" {
+		lines = append(lines, "X-WR-CALNAME:"+escapeText(c.Name))
+	}
+
+	for _, event := range c.Events {
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:"+escapeText(event.UID),
+			"DTSTAMP:"+event.Stamp.UTC().Format(dateTimeFormat),
+			"DTSTART;VALUE=DATE:"+event.Date.Format(dateFormat),
+			"DTEND;VALUE=DATE:"+event.Date.AddDate(0, 0, 1).Format(dateFormat),
+			"SUMMARY:"+escapeText(event.Summary),
+		)
+		if event.Description != "" {
+			lines = append(lines, "DESCRIPTION:"+escapeText(event.Description))
+		}
+		lines = append(lines, "TRANSP:TRANSPARENT", "END:VEVENT")
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	for _, line := range lines {
+		if _, err := bw.WriteString(fold(line)); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+// escapeText escapes TEXT property values as described in RFC 5545 section 3.3.11.
+func escapeText(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	return replacer.Replace(value)
+}
+
+// fold splits a content line into chunks of at most 75 octets, continuing each
+// chunk with a single space, without breaking multi-byte UTF-8 sequences.
+func fold(line string) string {
+	var sb strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > maxLineOctets {
+			sb.WriteString("\r\n ")
+			width = 1
+		}
+		sb.WriteRune(r)
+		width += size
+	}
+	sb.WriteString("\r\n")
+	return sb.String()
+}
@@ -0,0 +1,244 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// JWKS is a JSON Web Key Set (RFC 7517). Only RSA, P-256 EC and symmetric keys
+// are kept, other keys are ignored.
+type JWKS struct {
+	keys []jwk
+}
+
+type jwk struct {
+	kid string
+	alg string
+	key any
+}
+
+type rawJWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// ParseJWKS parses a key set document.
+func ParseJWKS(data []byte) (*JWKS, error) {
+	var document struct {
+		Keys []rawJWK `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("jwt: invalid key set: %w", err)
+	}
+
+	set := &JWKS{}
+	for _, raw := range document.Keys {
+		if raw.Use != "" && raw.Use != "sig" {
+			continue
+		}
+		key, alg, err := raw.parse()
+		if err != nil {
+			return nil, fmt.Errorf("jwt: invalid key %q: %w", raw.Kid, err)
+		}
+		if key == nil {
+			continue
+		}
+		if raw.Alg != "" && raw.Alg != alg {
+			continue
+		}
+		set.keys = append(set.keys, jwk{kid: raw.Kid, alg: alg, key: key})
+	}
+	return set, nil
+}
+
+// LoadJWKSFile reads a key set from a file.
+func LoadJWKSFile(path string) (*JWKS, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("jwt: read key set: %w", err)
+	}
+	return ParseJWKS(data)
+}
+
+// Key returns the key with the key ID that can verify the algorithm. Tokens
+// without a key ID match when the set has exactly one key for the algorithm.
+func (s *JWKS) Key(_ context.Context, kid, alg string) (any, error) {
+	var candidates []any
+	for _, key := range s.keys {
+		if key.alg != alg {
+			continue
+		}
+		if kid != "" && key.kid == kid {
+			return key.key, nil
+		}
+		candidates = append(candidates, key.key)
+	}
+	if kid == "" && len(candidates) == 1 {
+		return candidates[0], nil
+	}
+	return nil, ErrUnknownKey
+}
+
+func (s *JWKS) hasKey(kid string) bool {
+	for _, key := range s.keys {
+		if key.kid == kid {
+			return true
+		}
+	}
+	return false
+}
+
+// parse returns the public key and the algorithm it verifies, or a nil key for
+// key types that are not supported.
+func (raw rawJWK) parse() (any, string, error) {
+	switch raw.Kty {
+	case "RSA":
+		n, err := decodeBigInt(raw.N)
+		if err != nil {
+			return nil, "", err
+		}
+		e, err := decodeBigInt(raw.E)
+		if err != nil {
+			return nil, "", err
+		}
+		if !e.IsInt64() {
+			return nil, "", fmt.Errorf("exponent is too large")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, RS256, nil
+	case "EC":
+		if raw.Crv != "P-256" {
+			return nil, "", nil
+		}
+		x, err := decodeBigInt(raw.X)
+		if err != nil {
+			return nil, "", err
+		}
+		y, err := decodeBigInt(raw.Y)
+		if err != nil {
+			return nil, "", err
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return nil, "", fmt.Errorf("point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, ES256, nil
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(raw.K)
+		if err != nil || len(secret) == 0 {
+			return nil, "", fmt.Errorf("invalid secret")
+		}
+		return secret, HS256, nil
+	}
+	return nil, "", nil
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(data) == 0 {
+		return nil, fmt.Errorf("invalid base64url integer")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+// minRefetchInterval limits how often an unknown key ID triggers a fetch, so
+// tokens with made up key IDs cannot flood the identity provider, and how often a
+// failed fetch is retried, so an unavailable provider does not stall every request.
+const minRefetchInterval = time.Minute
+
+// RemoteJWKS is a key set fetched from a URL. It is refetched when it is older
+// than the refresh interval or when a token names a key ID it does not contain,
+// which happens after the identity provider rotates its keys.
+type RemoteJWKS struct {
+	url     string
+	refresh time.Duration
+	client  *http.Client
+
+	mu      sync.Mutex
+	set     *JWKS
+	fetched time.Time
+	// attempted is the time of the last fetch and err its error, if it failed.
+	attempted time.Time
+	err       error
+}
+
+// NewRemoteJWKS returns a key set served at the URL. The keys are fetched on
+// first use.
+func NewRemoteJWKS(url string, refresh time.Duration, client *http.Client) *RemoteJWKS {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &RemoteJWKS{url: url, refresh: refresh, client: client}
+}
+
+func (r *RemoteJWKS) Key(ctx context.Context, kid, alg string) (any, error) {
+	set, err := r.keys(ctx, kid)
+	if err != nil {
+		return nil, err
+	}
+	return set.Key(ctx, kid, alg)
+}
+
+func (r *RemoteJWKS) keys(ctx context.Context, kid string) (*JWKS, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	age := time.Since(r.fetched)
+	stale := r.set == nil || (r.refresh > 0 && age >= r.refresh)
+	missing := r.set != nil && kid != "" && !r.set.hasKey(kid) && age >= minRefetchInterval
+	if !stale && !missing {
+		return r.set, nil
+	}
+
+	// After a failed fetch, wait before trying again
+	if r.err == nil || time.Since(r.attempted) >= minRefetchInterval {
+		r.attempted = time.Now()
+		var set *JWKS
+		if set, r.err = r.fetch(ctx); r.err == nil {
+			r.set, r.fetched = set, r.attempted
+		}
+	}
+	// Keep verifying with the previous keys while the provider is unavailable
+	if r.set == nil {
+		return nil, r.err
+	}
+	return r.set, nil
+}
+
+func (r *RemoteJWKS) fetch(ctx context.Context) (*JWKS, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("jwt: fetch key set: %w", err)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("jwt: fetch key set: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwt: fetch key set: unexpected status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("jwt: fetch key set: %w", err)
+	}
+	return ParseJWKS(data)
+}
@@ -0,0 +1,318 @@
+// Package jwt verifies JSON Web Tokens in compact serialization. It supports the
+// HS256, RS256 and ES256 algorithms, which covers shared secrets as well as keys
+// published by an identity provider as a JSON Web Key Set.
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Supported signing algorithms.
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	ES256 = "ES256"
+)
+
+var (
+	ErrMalformed            = errors.New("jwt: malformed token")
+	ErrUnsupportedAlgorithm = errors.New("jwt: unsupported algorithm")
+	ErrUnknownKey           = errors.New("jwt: no key for token")
+	ErrInvalidSignature     = errors.New("jwt: invalid signature")
+	ErrExpired              = errors.New("jwt: token is expired")
+	ErrNotYetValid          = errors.New("jwt: token is not valid yet")
+	ErrInvalidIssuer        = errors.New("jwt: invalid issuer")
+	ErrInvalidAudience      = errors.New("jwt: invalid audience")
+)
+
+// KeySet provides the verification key for a token. The key is a []byte secret
+// for HS256, an *rsa.PublicKey for RS256 and an *ecdsa.PublicKey for ES256.
+type KeySet interface {
+	// Key returns the key for the key ID and algorithm of a token header, or
+	// ErrUnknownKey when the set has none.
+	Key(ctx context.Context, kid, alg string) (any, error)
+}
+
+// Secret is a KeySet holding one HS256 shared secret, used for every token
+// regardless of its key ID.
+type Secret []byte
+
+func (s Secret) Key(_ context.Context, _ string, alg string) (any, error) {
+	if alg != HS256 || len(s) == 0 {
+		return nil, ErrUnknownKey
+	}
+	return []byte(s), nil
+}
+
+// Claims are the claims of a verified token.
+type Claims struct {
+	Subject   string
+	Issuer    string
+	Audience  []string
+	ExpiresAt time.Time
+	NotBefore time.Time
+	IssuedAt  time.Time
+	// Raw holds every claim of the payload, including the registered ones.
+	Raw map[string]any
+}
+
+// String returns a string claim, or "" when it is missing or not a string.
+func (c *Claims) String(name string) string {
+	value, _ := c.Raw[name].(string)
+	return value
+}
+
+// Strings returns a claim that is either a string or an array of strings.
+func (c *Claims) Strings(name string) []string {
+	switch value := c.Raw[name].(type) {
+	case string:
+		if value == "" {
+			return nil
+		}
+		return strings.Fields(value)
+	case []any:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// Verifier checks the signature and the registered claims of tokens.
+type Verifier struct {
+	// Keys are tried in order until one of them has a key for the token.
+	Keys []KeySet
+	// Issuer is the required "iss" claim. It is not checked when empty.
+	Issuer string
+	// Audience is a value the "aud" claim must contain. It is not checked when
+	// empty.
+	Audience string
+	// Leeway is the clock skew tolerated when checking "exp", "nbf" and "iat".
+	Leeway time.Duration
+	// Now returns the current time. It defaults to time.Now.
+	Now func() time.Time
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type registeredClaims struct {
+	Subject   string          `json:"sub"`
+	Issuer    string          `json:"iss"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt *json.Number    `json:"exp"`
+	NotBefore *json.Number    `json:"nbf"`
+	IssuedAt  *json.Number    `json:"iat"`
+}
+
+// Verify parses the token, checks its signature and claims and returns the
+// claims. Tokens without an "exp" claim are rejected.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, err
+	}
+	if h.Alg != HS256 && h.Alg != RS256 && h.Alg != ES256 {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, h.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	key, err := v.key(ctx, h.Kid, h.Alg)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(h.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	claims, err := parseClaims(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	if err := v.validate(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (v *Verifier) key(ctx context.Context, kid, alg string) (any, error) {
+	for _, keys := range v.Keys {
+		key, err := keys.Key(ctx, kid, alg)
+		if errors.Is(err, ErrUnknownKey) {
+			continue
+		}
+		return key, err
+	}
+	return nil, ErrUnknownKey
+}
+
+func (v *Verifier) validate(claims *Claims) error {
+	now := time.Now()
+	if v.Now != nil {
+		now = v.Now()
+	}
+
+	if claims.ExpiresAt.IsZero() || !now.Before(claims.ExpiresAt.Add(v.Leeway)) {
+		return ErrExpired
+	}
+	if !claims.NotBefore.IsZero() && now.Add(v.Leeway).Before(claims.NotBefore) {
+		return ErrNotYetValid
+	}
+	if !claims.IssuedAt.IsZero() && now.Add(v.Leeway).Before(claims.IssuedAt) {
+		return ErrNotYetValid
+	}
+	if v.Issuer != "" && claims.Issuer != v.Issuer {
+		return ErrInvalidIssuer
+	}
+	if v.Audience != "" && !slices.Contains(claims.Audience, v.Audience) {
+		return ErrInvalidAudience
+	}
+	return nil
+}
+
+func verifySignature(alg string, key any, signingInput string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signingInput))
+
+	switch alg {
+	case HS256:
+		secret, ok := key.([]byte)
+		if !ok {
+			return ErrUnknownKey
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return ErrInvalidSignature
+		}
+	case RS256:
+		publicKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return ErrUnknownKey
+		}
+		if err := rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature); err != nil {
+			return ErrInvalidSignature
+		}
+	case ES256:
+		publicKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || publicKey.Curve != elliptic.P256() {
+			return ErrUnknownKey
+		}
+		// JWS encodes the signature as the fixed size concatenation of r and s
+		// rather than ASN.1
+		if len(signature) != 64 {
+			return ErrInvalidSignature
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(publicKey, digest[:], r, s) {
+			return ErrInvalidSignature
+		}
+	default:
+		return ErrUnsupportedAlgorithm
+	}
+	return nil
+}
+
+func parseClaims(segment string) (*Claims, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	claims := &Claims{}
+	if err := json.Unmarshal(payload, &claims.Raw); err != nil {
+		return nil, ErrMalformed
+	}
+
+	var registered registeredClaims
+	decoder := json.NewDecoder(strings.NewReader(string(payload)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&registered); err != nil {
+		return nil, ErrMalformed
+	}
+
+	claims.Subject = registered.Subject
+	claims.Issuer = registered.Issuer
+	if claims.Audience, err = parseAudience(registered.Audience); err != nil {
+		return nil, err
+	}
+	if claims.ExpiresAt, err = parseNumericDate(registered.ExpiresAt); err != nil {
+		return nil, err
+	}
+	if claims.NotBefore, err = parseNumericDate(registered.NotBefore); err != nil {
+		return nil, err
+	}
+	if claims.IssuedAt, err = parseNumericDate(registered.IssuedAt); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// parseAudience accepts both forms of the "aud" claim, a single string and an
+// array of strings.
+func parseAudience(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return []string{single}, nil
+	}
+	var many []string
+	if err := json.Unmarshal(raw, &many); err != nil {
+		return nil, ErrMalformed
+	}
+	return many, nil
+}
+
+func parseNumericDate(value *json.Number) (time.Time, error) {
+	if value == nil {
+		return time.Time{}, nil
+	}
+	seconds, err := value.Float64()
+	if err != nil {
+		return time.Time{}, ErrMalformed
+	}
+	return time.Unix(int64(seconds), 0), nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return ErrMalformed
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return ErrMalformed
+	}
+	return nil
+}
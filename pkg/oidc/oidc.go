@@ -0,0 +1,260 @@
+// Package oidc implements the relying party side of the OpenID Connect
+// authorization code flow with PKCE (RFC 7636).
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rasadov/subscription-manager/pkg/jwt"
+)
+
+// ErrNonceMismatch is returned for ID tokens issued for another login attempt.
+var ErrNonceMismatch = errors.New("oidc: nonce mismatch")
+
+// Config configures a client registered at an OpenID provider.
+type Config struct {
+	// Issuer is the issuer URL of the provider. Its discovery document is served
+	// at Issuer + "/.well-known/openid-configuration".
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the callback URL registered at the provider.
+	RedirectURL string
+	// Scopes are requested in addition to "openid".
+	Scopes []string
+	// Leeway is the clock skew tolerated when checking ID token lifetimes.
+	Leeway time.Duration
+	// HTTPClient calls the provider. It defaults to a client with a timeout.
+	HTTPClient *http.Client
+}
+
+// Provider holds the endpoints of a discovery document.
+type Provider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	EndSessionEndpoint    string `json:"end_session_endpoint"`
+}
+
+// Tokens is the response of the token endpoint.
+type Tokens struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// Client runs the login flow against a provider. The discovery document is
+// fetched on first use and kept once it was fetched successfully, so the service
+// starts even while the provider is unavailable.
+type Client struct {
+	config Config
+
+	mu       sync.Mutex
+	provider *Provider
+	verifier *jwt.Verifier
+}
+
+func NewClient(config Config) *Client {
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	config.Issuer = strings.TrimSuffix(config.Issuer, "/")
+	return &Client{config: config}
+}
+
+// Provider returns the discovered endpoints of the provider.
+func (c *Client) Provider(ctx context.Context) (*Provider, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.provider != nil {
+		return c.provider, nil
+	}
+
+	var provider Provider
+	if err := c.getJSON(ctx, c.config.Issuer+"/.well-known/openid-configuration", &provider); err != nil {
+		return nil, fmt.Errorf("oidc: discovery: %w", err)
+	}
+	if strings.TrimSuffix(provider.Issuer, "/") != c.config.Issuer {
+		return nil, fmt.Errorf("oidc: discovery: issuer %q does not match %q", provider.Issuer, c.config.Issuer)
+	}
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" || provider.JWKSURI == "" {
+		return nil, errors.New("oidc: discovery: missing endpoints")
+	}
+
+	c.provider = &provider
+	c.verifier = &jwt.Verifier{
+		Keys:     []jwt.KeySet{jwt.NewRemoteJWKS(provider.JWKSURI, time.Hour, c.config.HTTPClient)},
+		Issuer:   provider.Issuer,
+		Audience: c.config.ClientID,
+		Leeway:   c.config.Leeway,
+	}
+	return c.provider, nil
+}
+
+// AuthCodeURL returns the authorization endpoint URL the browser is sent to.
+// The challenge is the S256 challenge of the PKCE verifier of the attempt.
+func (c *Client) AuthCodeURL(ctx context.Context, state, nonce, challenge string) (string, error) {
+	provider, err := c.Provider(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	scopes := append([]string{"openid"}, c.config.Scopes...)
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {c.config.ClientID},
+		"redirect_uri":          {c.config.RedirectURL},
+		"scope":                 {strings.Join(scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {challenge},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(provider.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return provider.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange redeems an authorization code at the token endpoint. Confidential
+// clients authenticate with HTTP basic authentication.
+func (c *Client) Exchange(ctx context.Context, code, codeVerifier string) (*Tokens, error) {
+	provider, err := c.Provider(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {c.config.RedirectURL},
+		"code_verifier": {codeVerifier},
+	}
+	if c.config.ClientSecret == "" {
+		form.Set("client_id", c.config.ClientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("oidc: token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(c.config.ClientID), url.QueryEscape(c.config.ClientSecret))
+	}
+
+	var tokens Tokens
+	if err := c.doJSON(req, &tokens); err != nil {
+		return nil, fmt.Errorf("oidc: token request: %w", err)
+	}
+	if tokens.IDToken == "" {
+		return nil, errors.New("oidc: token response has no id_token")
+	}
+	return &tokens, nil
+}
+
+// VerifyIDToken checks the signature, issuer, audience and lifetime of an ID
+// token and that it was issued for the login attempt with the nonce.
+func (c *Client) VerifyIDToken(ctx context.Context, idToken, nonce string) (*jwt.Claims, error) {
+	if _, err := c.Provider(ctx); err != nil {
+		return nil, err
+	}
+
+	claims, err := c.verifier.Verify(ctx, idToken)
+	if err != nil {
+		return nil, err
+	}
+	if nonce == "" || claims.String("nonce") != nonce {
+		return nil, ErrNonceMismatch
+	}
+	return claims, nil
+}
+
+// LogoutURL returns the URL ending the session at the provider, or an empty
+// string when the provider does not support RP-initiated logout.
+func (c *Client) LogoutURL(ctx context.Context, postLogoutRedirectURL string) (string, error) {
+	provider, err := c.Provider(ctx)
+	if err != nil {
+		return "", err
+	}
+	if provider.EndSessionEndpoint == "" {
+		return "", nil
+	}
+
+	query := url.Values{"client_id": {c.config.ClientID}}
+	if postLogoutRedirectURL != "" {
+		query.Set("post_logout_redirect_uri", postLogoutRedirectURL)
+	}
+	separator := "?"
+	if strings.Contains(provider.EndSessionEndpoint, "?") {
+		separator = "&"
+	}
+	return provider.EndSessionEndpoint + separator + query.Encode(), nil
+}
+
+func (c *Client) getJSON(ctx context.Context, endpoint string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	return c.doJSON(req, v)
+}
+
+func (c *Client) doJSON(req *http.Request, v any) error {
+	resp, err := c.config.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+		}
+		if json.Unmarshal(data, &body) == nil && body.Error != "" {
+			return fmt.Errorf("unexpected status %d: %s %s", resp.StatusCode, body.Error, body.Description)
+		}
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return json.Unmarshal(data, v)
+}
+
+// RandomString returns a URL safe string of n random bytes, used for states,
+// nonces and PKCE verifiers.
+func RandomString(n int) (string, error) {
+	data := make([]byte, n)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// S256Challenge returns the PKCE code challenge of a verifier.
+func S256Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often full buckets are dropped from a MemoryStore.
+const sweepInterval = time.Minute
+
+// MemoryStore keeps buckets in process memory. Every replica of the service
+// limits on its own, so it suits single instance deployments.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*memoryBucket
+	swept   time.Time
+}
+
+type memoryBucket struct {
+	Bucket
+	limit Limit
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*memoryBucket{}}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.swept) >= sweepInterval {
+		s.sweep(now)
+	}
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{}
+		s.buckets[key] = bucket
+	}
+	bucket.limit = limit
+	return bucket.Take(limit, now), nil
+}
+
+// sweep drops full buckets, which behave like missing ones, so clients that stop
+// calling do not take up memory.
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, bucket := range s.buckets {
+		if bucket.Full(bucket.limit, now) {
+			delete(s.buckets, key)
+		}
+	}
+	s.swept = now
+}
@@ -0,0 +1,98 @@
+// Package ratelimit implements token bucket rate limiting. A bucket holds up to
+// Limit.Requests tokens and refills at Requests per Limit.Per; every request
+// takes a token and is rejected when the bucket is empty.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit allows Requests requests per Per, all of which may be spent at once.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// ParseLimit parses limits such as "100/1m".
+func ParseLimit(value string) (Limit, error) {
+	requests, per, ok := strings.Cut(strings.TrimSpace(value), "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("ratelimit: invalid limit %q, expected <requests>/<duration>", value)
+	}
+	n, err := strconv.Atoi(requests)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("ratelimit: invalid request count in %q", value)
+	}
+	d, err := time.ParseDuration(per)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("ratelimit: invalid duration in %q", value)
+	}
+	return Limit{Requests: n, Per: d}, nil
+}
+
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Requests, l.Per)
+}
+
+// interval is the time it takes to refill one token.
+func (l Limit) interval() time.Duration {
+	return l.Per / time.Duration(l.Requests)
+}
+
+// Result is the outcome of taking a token.
+type Result struct {
+	Allowed bool
+	// Remaining is the number of whole tokens left.
+	Remaining int
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the time until the next token, when the request was rejected.
+	RetryAfter time.Duration
+}
+
+// Store keeps the buckets of every key.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+// Bucket is the state of a key. The zero Bucket is full.
+type Bucket struct {
+	// Tokens is the number of tokens at Updated.
+	Tokens  float64
+	Updated time.Time
+}
+
+// Take refills the bucket up to now and takes a token if there is one.
+func (b *Bucket) Take(limit Limit, now time.Time) Result {
+	capacity := float64(limit.Requests)
+	if b.Updated.IsZero() {
+		b.Tokens = capacity
+		b.Updated = now
+	} else if elapsed := now.Sub(b.Updated); elapsed > 0 {
+		// Clocks of replicas may lag behind, which must not refill twice
+		b.Tokens = math.Min(capacity, b.Tokens+elapsed.Seconds()/limit.interval().Seconds())
+		b.Updated = now
+	}
+
+	result := Result{Allowed: b.Tokens >= 1}
+	if result.Allowed {
+		b.Tokens--
+	} else {
+		result.RetryAfter = time.Duration((1 - b.Tokens) * float64(limit.interval()))
+	}
+	result.Remaining = int(b.Tokens)
+	result.Reset = time.Duration((capacity - b.Tokens) * float64(limit.interval()))
+	return result
+}
+
+// Full reports whether the bucket has refilled completely by now, so it can be
+// forgotten.
+func (b *Bucket) Full(limit Limit, now time.Time) bool {
+	missing := float64(limit.Requests) - b.Tokens
+	return now.Sub(b.Updated).Seconds() >= missing*limit.interval().Seconds()
+}
@@ -0,0 +1,354 @@
+package receipt
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rasadov/subscription-manager/pkg/statement"
+)
+
+const (
+	CycleMonthly   = "monthly"
+	CycleQuarterly = "quarterly"
+	CycleYearly    = "yearly"
+)
+
+// ErrNoMatch is returned by extractors that do not recognise a message.
+var ErrNoMatch = errors.New("no receipt data found in message")
+
+// Receipt is the subscription data extracted from a message. Amount is in minor
+// currency units. Empty fields are completed by the Registry from the message.
+type Receipt struct {
+	ServiceName  string
+	Amount       int64
+	Currency     string
+	BillingDate  time.Time
+	BillingCycle string
+	// Fallback is set when no extractor registered for the sender domain matched.
+	Fallback bool
+}
+
+// Extractor recognises receipts of one format. It returns ErrNoMatch when the
+// message is not in its format so that the next extractor can be tried.
+type Extractor interface {
+	Extract(msg *Message) (*Receipt, error)
+}
+
+// Registry picks extractors by sender domain. Extractors registered for a domain
+// also apply to its subdomains; fallback extractors are tried for every message
+// after the domain-specific ones.
+type Registry struct {
+	domains  map[string][]Extractor
+	fallback []Extractor
+}
+
+func NewRegistry() *Registry {
+	return &Registry{domains: make(map[string][]Extractor)}
+}
+
+func (r *Registry) Register(domain string, extractors ...Extractor) {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	r.domains[domain] = append(r.domains[domain], extractors...)
+}
+
+func (r *Registry) RegisterFallback(extractors ...Extractor) {
+	r.fallback = append(r.fallback, extractors...)
+}
+
+// Extract runs the extractors for the most specific matching domain first,
+// e.g. "mail.netflix.com", then "netflix.com", then the fallback extractors.
+func (r *Registry) Extract(msg *Message) (*Receipt, error) {
+	var candidates []Extractor
+	labels := strings.Split(msg.Domain, ".")
+	for i := 0; i < len(labels)-1; i++ {
+		candidates = append(candidates, r.domains[strings.Join(labels[i:], ".")]...)
+	}
+	specific := len(candidates)
+	candidates = append(candidates, r.fallback...)
+
+	for idx, extractor := range candidates {
+		result, err := extractor.Extract(msg)
+		if errors.Is(err, ErrNoMatch) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		result.Fallback = idx >= specific
+		complete(result, msg)
+		return result, nil
+	}
+
+	return nil, ErrNoMatch
+}
+
+func complete(result *Receipt, msg *Message) {
+	if result.ServiceName == "" {
+		result.ServiceName = SenderName(msg)
+	}
+	if result.BillingDate.IsZero() {
+		result.BillingDate = msg.Date
+	}
+	if result.BillingCycle == "" {
+		result.BillingCycle = guessCycle(msg.Subject + "\n" + msg.Text)
+	}
+}
+
+// Rule describes a receipt format with regular expressions, typically stored per sender domain.
+type Rule struct {
+	// ServiceName overrides the name derived from the sender.
+	ServiceName string
+	// SubjectPattern, when set, must match the subject for the rule to apply.
+	SubjectPattern string
+	// PricePattern must match the body; its first group (or the group named
+	// "amount") is the price, an optional group named "currency" the currency.
+	PricePattern string
+	// Currency is used when the price pattern captures no currency.
+	Currency string
+	// DatePattern captures the billing date, parsed with DateLayout.
+	// Without it the date the message was sent is used.
+	DatePattern  string
+	DateLayout   string
+	BillingCycle string
+}
+
+// RuleExtractor is an Extractor built from a Rule.
+type RuleExtractor struct {
+	rule    Rule
+	subject *regexp.Regexp
+	price   *regexp.Regexp
+	date    *regexp.Regexp
+}
+
+// NewRuleExtractor compiles the patterns of a rule.
+func NewRuleExtractor(rule Rule) (*RuleExtractor, error) {
+	extractor := &RuleExtractor{rule: rule}
+
+	var err error
+	if rule.PricePattern == "" {
+		return nil, errors.New("price pattern is required")
+	}
+	if extractor.price, err = regexp.Compile(rule.PricePattern); err != nil {
+		return nil, fmt.Errorf("invalid price pattern: %w", err)
+	}
+	if extractor.price.NumSubexp() == 0 {
+		return nil, errors.New("price pattern must capture the amount in a group")
+	}
+
+	if rule.SubjectPattern != "" {
+		if extractor.subject, err = regexp.Compile(rule.SubjectPattern); err != nil {
+			return nil, fmt.Errorf("invalid subject pattern: %w", err)
+		}
+	}
+
+	if rule.DatePattern != "" {
+		if rule.DateLayout == "" {
+			return nil, errors.New("date layout is required with a date pattern")
+		}
+		if extractor.date, err = regexp.Compile(rule.DatePattern); err != nil {
+			return nil, fmt.Errorf("invalid date pattern: %w", err)
+		}
+		if extractor.date.NumSubexp() == 0 {
+			return nil, errors.New("date pattern must capture the date in a group")
+		}
+	}
+
+	return extractor, nil
+}
+
+func (e *RuleExtractor) Extract(msg *Message) (*Receipt, error) {
+	if e.subject != nil && !e.subject.MatchString(msg.Subject) {
+		return nil, ErrNoMatch
+	}
+
+	match := e.price.FindStringSubmatch(msg.Text)
+	if match == nil {
+		return nil, ErrNoMatch
+	}
+
+	rawAmount := match[1]
+	currency := e.rule.Currency
+	for idx, name := range e.price.SubexpNames() {
+		switch name {
+		case "amount":
+			rawAmount = match[idx]
+		case "currency":
+			if code := currencyCode(match[idx]); code != "" {
+				currency = code
+			}
+		}
+	}
+
+	amount, err := statement.ParseAmount(rawAmount)
+	if err != nil {
+		return nil, err
+	}
+	if amount < 0 {
+		amount = -amount
+	}
+
+	result := &Receipt{
+		ServiceName:  e.rule.ServiceName,
+		Amount:       amount,
+		Currency:     currency,
+		BillingCycle: e.rule.BillingCycle,
+	}
+
+	if e.date != nil {
+		if dateMatch := e.date.FindStringSubmatch(msg.Text); dateMatch != nil {
+			date, err := time.Parse(e.rule.DateLayout, strings.TrimSpace(dateMatch[1]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid billing date: %w", err)
+			}
+			result.BillingDate = date
+		}
+	}
+
+	return result, nil
+}
+
+// currencySymbols maps symbols and local abbreviations to ISO 4217 codes.
+var currencySymbols = map[string]string{
+	"$":    "USD",
+	"us$":  "USD",
+	"€":    "EUR",
+	"£":    "GBP",
+	"₽":    "RUB",
+	"руб":  "RUB",
+	"руб.": "RUB",
+	"р.":   "RUB",
+	"₸":    "KZT",
+	"₴":    "UAH",
+	"₺":    "TRY",
+	"₼":    "AZN",
+}
+
+var currencyCodes = []string{"USD", "EUR", "GBP", "RUB", "KZT", "UAH", "BYN", "TRY", "AZN", "CHF", "CAD", "AUD", "PLN", "GEL", "AMD", "UZS", "JPY", "CNY"}
+
+func currencyCode(raw string) string {
+	raw = strings.ToLower(strings.TrimSpace(raw))
+	if code, ok := currencySymbols[raw]; ok {
+		return code
+	}
+	for _, code := range currencyCodes {
+		if strings.EqualFold(raw, code) {
+			return code
+		}
+	}
+	return ""
+}
+
+var (
+	// A number with optional thousands groups ("1 234,56", "1,234.50") or a plain one ("1599.00")
+	amountPattern = `\d{1,3}(?:[ \x{00a0}.,]\d{3})+(?:[.,]\d{1,2})?|\d+(?:[.,]\d{1,2})?`
+	codePattern   = `\b(?:` + strings.Join(currencyCodes, "|") + `)\b`
+	moneyPattern  = regexp.MustCompile(
+		`(?i)(?:(?P<pre>us\$|[$€£₽₸₴₺₼]|` + codePattern + `)[ \x{00a0}]?(?P<pre_amount>` + amountPattern + `)` +
+			`|(?P<post_amount>` + amountPattern + `)[ \x{00a0}]?(?P<post>[$€£₽₸₴₺₼]|руб\.?|р\.|` + codePattern + `))`)
+	totalLinePattern = regexp.MustCompile(`(?i)\b(total|amount|charged|paid|price|you paid|order total)\b|итого|сумма|к оплате|списано|оплачено`)
+	noiseLinePattern = regexp.MustCompile(`(?i)\b(subtotal|tax|vat|discount|balance|credit)\b|ндс|скидка`)
+)
+
+// GenericExtractor finds the first amount with a currency on a "total"-like
+// line, falling back to the first amount with a currency anywhere in the body.
+// It works for most simple receipts that have no rule of their own.
+type GenericExtractor struct{}
+
+func (GenericExtractor) Extract(msg *Message) (*Receipt, error) {
+	var fallback *Receipt
+	for _, line := range strings.Split(msg.Text, "\n") {
+		money := findMoney(line)
+		if money == nil {
+			continue
+		}
+		if totalLinePattern.MatchString(line) && !noiseLinePattern.MatchString(line) {
+			return money, nil
+		}
+		if fallback == nil {
+			fallback = money
+		}
+	}
+
+	if fallback == nil {
+		return nil, ErrNoMatch
+	}
+	return fallback, nil
+}
+
+func findMoney(line string) *Receipt {
+	match := moneyPattern.FindStringSubmatch(line)
+	if match == nil {
+		return nil
+	}
+
+	values := make(map[string]string, 4)
+	for idx, name := range moneyPattern.SubexpNames() {
+		if name != "" && match[idx] != "" {
+			values[name] = match[idx]
+		}
+	}
+
+	rawAmount, symbol := values["pre_amount"], values["pre"]
+	if rawAmount == "" {
+		rawAmount, symbol = values["post_amount"], values["post"]
+	}
+
+	amount, err := statement.ParseAmount(rawAmount)
+	if err != nil || amount <= 0 {
+		return nil
+	}
+
+	return &Receipt{Amount: amount, Currency: currencyCode(symbol)}
+}
+
+var (
+	yearlyPattern    = regexp.MustCompile(`(?i)\b(annual|annually|yearly|per year|a year|/\s?yr|/\s?year|12 months)\b|годов|в год|на год`)
+	quarterlyPattern = regexp.MustCompile(`(?i)\b(quarterly|per quarter|3 months)\b|квартал`)
+)
+
+func guessCycle(text string) string {
+	switch {
+	case yearlyPattern.MatchString(text):
+		return CycleYearly
+	case quarterlyPattern.MatchString(text):
+		return CycleQuarterly
+	default:
+		return CycleMonthly
+	}
+}
+
+// senderNoise are display name words that describe the mailbox rather than the service.
+var senderNoise = map[string]bool{
+	"billing": true, "receipts": true, "receipt": true, "payments": true, "payment": true,
+	"team": true, "support": true, "noreply": true, "no-reply": true, "info": true,
+	"notifications": true, "account": true, "accounts": true, "store": true,
+}
+
+// SenderName derives a service name from the sender: the display name without
+// words like "Billing" or "Team", or else the registrable part of the domain.
+func SenderName(msg *Message) string {
+	var kept []string
+	for _, word := range strings.Fields(msg.FromName) {
+		if !senderNoise[strings.ToLower(strings.Trim(word, ",.-"))] {
+			kept = append(kept, word)
+		}
+	}
+	if len(kept) > 0 {
+		return strings.Join(kept, " ")
+	}
+
+	labels := strings.Split(msg.Domain, ".")
+	if len(labels) < 2 {
+		return msg.Domain
+	}
+	name := labels[len(labels)-2]
+	// Second level public suffixes like co.uk or com.au
+	if len(labels) >= 3 && len(name) <= 3 {
+		name = labels[len(labels)-3]
+	}
+	runes := []rune(name)
+	return strings.ToUpper(string(runes[:1])) + string(runes[1:])
+}
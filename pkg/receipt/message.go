@@ -0,0 +1,240 @@
+package receipt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxMessageSize bounds a single message so a malformed mbox cannot exhaust memory.
+const maxMessageSize = 10 << 20
+
+// Message is an email reduced to what the extractors need.
+type Message struct {
+	FromName string
+	From     string
+	Domain   string
+	Subject  string
+	Date     time.Time
+	// Text is the plain text body, or the HTML body converted to text when the
+	// message has no plain text part.
+	Text string
+}
+
+var wordDecoder = &mime.WordDecoder{
+	// Only UTF-8 and ASCII are decoded, other charsets are passed through as is
+	CharsetReader: func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	},
+}
+
+// ParseEML parses a single RFC 5322 message.
+func ParseEML(r io.Reader) (*Message, error) {
+	raw, err := mail.ReadMessage(io.LimitReader(r, maxMessageSize))
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &Message{}
+	if subject, err := wordDecoder.DecodeHeader(raw.Header.Get("Subject")); err == nil {
+		msg.Subject = strings.TrimSpace(subject)
+	} else {
+		msg.Subject = strings.TrimSpace(raw.Header.Get("Subject"))
+	}
+
+	if from := raw.Header.Get("From"); from != "" {
+		parser := mail.AddressParser{WordDecoder: wordDecoder}
+		address, err := parser.Parse(from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid From header: %w", err)
+		}
+		msg.FromName = strings.TrimSpace(address.Name)
+		msg.From = strings.ToLower(address.Address)
+		if _, domain, ok := strings.Cut(msg.From, "@"); ok {
+			msg.Domain = domain
+		}
+	}
+
+	if date, err := raw.Header.Date(); err == nil {
+		msg.Date = date
+	}
+
+	plain, htmlBody, err := readBody(raw.Header.Get("Content-Type"), raw.Header.Get("Content-Transfer-Encoding"), raw.Body)
+	if err != nil {
+		return nil, err
+	}
+	msg.Text = plain
+	if strings.TrimSpace(msg.Text) == "" {
+		msg.Text = HTMLToText(htmlBody)
+	}
+
+	return msg, nil
+}
+
+// ParseMbox splits an mbox file into messages. Lines starting with "From "
+// separate messages and ">From " escapes in bodies are undone.
+func ParseMbox(r io.Reader) ([]*Message, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+
+	var messages []*Message
+	var current bytes.Buffer
+	started := false
+
+	flush := func() error {
+		if !started || len(bytes.TrimSpace(current.Bytes())) == 0 {
+			return nil
+		}
+		msg, err := ParseEML(bytes.NewReader(current.Bytes()))
+		if err != nil {
+			return fmt.Errorf("message %d: %w", len(messages)+1, err)
+		}
+		messages = append(messages, msg)
+		return nil
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "From ") {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			current.Reset()
+			started = true
+			continue
+		}
+		if !started {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			return nil, errors.New("mbox file must start with a \"From \" separator line")
+		}
+		if strings.HasPrefix(strings.TrimLeft(line, ">"), "From ") {
+			line = line[1:]
+		}
+		current.WriteString(line)
+		current.WriteString("\r\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// readBody walks the MIME tree and returns the first text/plain and text/html parts.
+func readBody(contentType, transferEncoding string, body io.Reader) (string, string, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		var plain, htmlBody string
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return "", "", err
+			}
+			if strings.HasPrefix(part.Header.Get("Content-Disposition"), "attachment") {
+				continue
+			}
+			partPlain, partHTML, err := readBody(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part)
+			if err != nil {
+				return "", "", err
+			}
+			if plain == "" {
+				plain = partPlain
+			}
+			if htmlBody == "" {
+				htmlBody = partHTML
+			}
+		}
+		return plain, htmlBody, nil
+	}
+
+	if !strings.HasPrefix(mediaType, "text/") {
+		return "", "", nil
+	}
+
+	decoded, err := io.ReadAll(decodeTransfer(transferEncoding, body))
+	if err != nil {
+		return "", "", err
+	}
+
+	if mediaType == "text/html" {
+		return "", string(decoded), nil
+	}
+	return string(decoded), "", nil
+}
+
+func decodeTransfer(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &newlineSkipper{r: r})
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	default:
+		return r
+	}
+}
+
+// newlineSkipper drops line breaks, which the base64 decoder does not accept.
+type newlineSkipper struct {
+	r io.Reader
+}
+
+func (n *newlineSkipper) Read(p []byte) (int, error) {
+	count, err := n.r.Read(p)
+	kept := 0
+	for _, b := range p[:count] {
+		if b != '\r' && b != '\n' {
+			p[kept] = b
+			kept++
+		}
+	}
+	return kept, err
+}
+
+var (
+	htmlInvisiblePattern = regexp.MustCompile(`(?is)<(style|script|head)[^>]*>.*?</(style|script|head)>`)
+	htmlBreakPattern     = regexp.MustCompile(`(?i)<(br|/p|/div|/tr|/h[1-6]|/li)[^>]*>`)
+	htmlCellPattern      = regexp.MustCompile(`(?i)</t[dh]>`)
+	htmlTagPattern       = regexp.MustCompile(`<[^>]*>`)
+	blankSpacePattern    = regexp.MustCompile(`[ \t\x{00a0}]+`)
+	blankLinesPattern    = regexp.MustCompile(`\n\s*\n+`)
+)
+
+// HTMLToText converts an HTML email body to plain text, keeping table cells
+// and block elements on separate lines so labels stay next to their values.
+func HTMLToText(body string) string {
+	if body == "" {
+		return ""
+	}
+	text := htmlInvisiblePattern.ReplaceAllString(body, "")
+	text = htmlBreakPattern.ReplaceAllString(text, "\n")
+	text = htmlCellPattern.ReplaceAllString(text, " ")
+	text = htmlTagPattern.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+	text = blankSpacePattern.ReplaceAllString(text, " ")
+	text = blankLinesPattern.ReplaceAllString(text, "\n")
+	return strings.TrimSpace(text)
+}
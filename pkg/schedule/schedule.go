@@ -0,0 +1,23 @@
+// Package schedule runs background jobs at a fixed interval.
+package schedule
+
+import (
+	"context"
+	"time"
+)
+
+// Every calls fn once per interval until ctx is cancelled. The first call happens
+// after one interval. Every blocks, so it is usually started in a goroutine.
+func Every(ctx context.Context, interval time.Duration, fn func(ctx context.Context)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fn(ctx)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package statement
+
+import (
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rasadov/subscription-manager/pkg/textmatch"
+)
+
+const (
+	CycleMonthly   = "monthly"
+	CycleQuarterly = "quarterly"
+	CycleYearly    = "yearly"
+)
+
+// amountTolerance is the relative deviation from the median charge that still
+// counts as the same subscription, which absorbs small price changes and FX noise.
+const amountTolerance = 0.15
+
+// minRegularity is the share of intervals that must match the detected cycle.
+const minRegularity = 0.6
+
+type cycle struct {
+	name           string
+	days           float64
+	toleranceDays  float64
+	minOccurrences int
+}
+
+var cycles = []cycle{
+	{name: CycleMonthly, days: 30.4, toleranceDays: 4, minOccurrences: 3},
+	{name: CycleQuarterly, days: 91.3, toleranceDays: 8, minOccurrences: 2},
+	{name: CycleYearly, days: 365.25, toleranceDays: 15, minOccurrences: 2},
+}
+
+// RecurringCharge is a series of charges that look like a subscription.
+type RecurringCharge struct {
+	Merchant     string
+	Key          string
+	Amount       int64
+	Cycle        string
+	Occurrences  int
+	FirstCharged time.Time
+	LastCharged  time.Time
+	NextExpected time.Time
+	Confidence   float64
+}
+
+var (
+	merchantNoisePattern = regexp.MustCompile(`\b(pos|purchase|payment|card|debit|recurring|online|www|com|net|inc|ltd|llc|gmbh|bv|sa|ooo)\b`)
+	merchantDigitPattern = regexp.MustCompile(`\d+`)
+	merchantSpacePattern = regexp.MustCompile(`\s+`)
+)
+
+// NormalizeMerchant reduces a statement description to a key that is equal for
+// all charges of one merchant, e.g. "NETFLIX.COM 8445*123" and "Netflix.com" both
+// become "netflix". Reference numbers, card fragments and legal suffixes are dropped.
+func NormalizeMerchant(description string) string {
+	// Processor prefixes like "PAYPAL *" or "SQ *" precede the actual merchant
+	if prefix, rest, ok := strings.Cut(description, "*"); ok && len(strings.TrimSpace(prefix)) <= 7 && strings.TrimSpace(rest) != "" {
+		description = rest
+	}
+
+	key := textmatch.Normalize(description)
+	key = merchantDigitPattern.ReplaceAllString(key, " ")
+	key = merchantNoisePattern.ReplaceAllString(key, " ")
+	return strings.TrimSpace(merchantSpacePattern.ReplaceAllString(key, " "))
+}
+
+// DetectRecurring groups outgoing charges by normalized merchant and amount and
+// returns the groups whose dates repeat with a monthly, quarterly or yearly period.
+// When the statement has negative amounts only those are considered charges,
+// otherwise every transaction is (card statements usually list debits as positive).
+func DetectRecurring(transactions []Transaction) []RecurringCharge {
+	hasNegative := false
+	for _, txn := range transactions {
+		if txn.Amount < 0 {
+			hasNegative = true
+			break
+		}
+	}
+
+	groups := make(map[string][]Transaction)
+	names := make(map[string]map[string]int)
+	for _, txn := range transactions {
+		if hasNegative && txn.Amount >= 0 || txn.Amount == 0 {
+			continue
+		}
+		key := NormalizeMerchant(txn.Description)
+		if key == "" {
+			continue
+		}
+		if hasNegative {
+			txn.Amount = -txn.Amount
+		}
+		groups[key] = append(groups[key], txn)
+		if names[key] == nil {
+			names[key] = make(map[string]int)
+		}
+		names[key][strings.TrimSpace(txn.Description)]++
+	}
+
+	var detected []RecurringCharge
+	for key, charges := range groups {
+		charges = withSimilarAmounts(charges)
+		if len(charges) < 2 {
+			continue
+		}
+		sort.Slice(charges, func(i, j int) bool { return charges[i].Date.Before(charges[j].Date) })
+
+		match, ok := detectCycle(charges)
+		if !ok {
+			continue
+		}
+		match.Key = key
+		match.Merchant = displayName(key, names[key])
+		detected = append(detected, match)
+	}
+
+	sort.Slice(detected, func(i, j int) bool {
+		if detected[i].Confidence != detected[j].Confidence {
+			return detected[i].Confidence > detected[j].Confidence
+		}
+		return detected[i].Key < detected[j].Key
+	})
+
+	return detected
+}
+
+// withSimilarAmounts keeps the charges within amountTolerance of the median amount.
+func withSimilarAmounts(charges []Transaction) []Transaction {
+	median := medianAmount(charges)
+	var similar []Transaction
+	for _, txn := range charges {
+		if math.Abs(float64(txn.Amount-median)) <= amountTolerance*float64(median) {
+			similar = append(similar, txn)
+		}
+	}
+	return similar
+}
+
+func detectCycle(charges []Transaction) (RecurringCharge, bool) {
+	intervals := make([]float64, 0, len(charges)-1)
+	for i := 1; i < len(charges); i++ {
+		intervals = append(intervals, charges[i].Date.Sub(charges[i-1].Date).Hours()/24)
+	}
+	medianInterval := median(intervals)
+
+	for _, c := range cycles {
+		if math.Abs(medianInterval-c.days) > c.toleranceDays || len(charges) < c.minOccurrences {
+			continue
+		}
+
+		regular := 0
+		for _, interval := range intervals {
+			if math.Abs(interval-c.days) <= c.toleranceDays {
+				regular++
+			}
+		}
+		regularity := float64(regular) / float64(len(intervals))
+		if regularity < minRegularity {
+			return RecurringCharge{}, false
+		}
+
+		amounts := make([]float64, len(charges))
+		for i, txn := range charges {
+			amounts[i] = float64(txn.Amount)
+		}
+		amount := medianAmount(charges)
+		spread := 0.0
+		if amount > 0 {
+			spread = (slices.Max(amounts) - slices.Min(amounts)) / float64(amount)
+		}
+
+		// More occurrences, regular intervals and a stable amount all raise confidence
+		occurrenceScore := math.Min(float64(len(charges))/6, 1)
+		amountScore := 1 - math.Min(spread/amountTolerance, 1)*0.5
+		confidence := math.Round((0.4*regularity+0.35*occurrenceScore+0.25*amountScore)*100) / 100
+
+		last := charges[len(charges)-1]
+		return RecurringCharge{
+			Amount:       charges[len(charges)-1].Amount,
+			Cycle:        c.name,
+			Occurrences:  len(charges),
+			FirstCharged: charges[0].Date,
+			LastCharged:  last.Date,
+			NextExpected: last.Date.AddDate(0, 0, int(math.Round(c.days))),
+			Confidence:   confidence,
+		}, true
+	}
+
+	return RecurringCharge{}, false
+}
+
+// displayName picks the most frequent raw description of the group and falls back to the key.
+func displayName(key string, raw map[string]int) string {
+	best, count := "", 0
+	for name, n := range raw {
+		if n > count || n == count && name < best {
+			best, count = name, n
+		}
+	}
+
+	words := strings.Fields(key)
+	for i, word := range words {
+		runes := []rune(word)
+		words[i] = strings.ToUpper(string(runes[:1])) + string(runes[1:])
+	}
+	title := strings.Join(words, " ")
+
+	// Raw descriptions are usually noisy ("NETFLIX.COM 8445"), prefer the cleaned key
+	// unless the raw name is already short and readable
+	if best != "" && NormalizeMerchant(best) == textmatch.Normalize(best) && len(best) <= 40 {
+		return best
+	}
+	return title
+}
+
+func medianAmount(charges []Transaction) int64 {
+	values := make([]float64, len(charges))
+	for i, txn := range charges {
+		values[i] = float64(txn.Amount)
+	}
+	return int64(math.Round(median(values)))
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
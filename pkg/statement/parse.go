@@ -0,0 +1,230 @@
+package statement
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Transaction is a single statement line. Amount is in minor currency units
+// (cents, kopecks) and negative for money leaving the account.
+type Transaction struct {
+	Date        time.Time
+	Description string
+	Amount      int64
+}
+
+// CSVOptions configures how statement CSV files are read.
+type CSVOptions struct {
+	// Columns maps the "date", "description" and "amount" fields to header names.
+	// Missing entries default to the field name.
+	Columns map[string]string
+	// DateLayout is a Go time layout. When empty, common layouts are tried in order.
+	DateLayout string
+}
+
+var defaultDateLayouts = []string{
+	"2006-01-02",
+	"02.01.2006",
+	"01/02/2006",
+	"02/01/2006",
+	"2006/01/02",
+	"02-01-2006",
+	time.RFC3339,
+}
+
+// ParseCSV reads transactions from a CSV statement with a header row.
+func ParseCSV(r io.Reader, opts CSVOptions) ([]Transaction, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("statement is empty")
+		}
+		return nil, err
+	}
+
+	positions := make(map[string]int, len(header))
+	for idx, name := range header {
+		positions[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = idx
+	}
+
+	columns := make(map[string]int, 3)
+	for _, field := range []string{"date", "description", "amount"} {
+		name := field
+		if mapped, ok := opts.Columns[field]; ok && mapped != "" {
+			name = mapped
+		}
+		idx, ok := positions[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("statement header has no %q column for %s", name, field)
+		}
+		columns[field] = idx
+	}
+
+	var transactions []Transaction
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		field := func(name string) string {
+			idx := columns[name]
+			if idx >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[idx])
+		}
+
+		if field("date") == "" && field("amount") == "" {
+			continue
+		}
+
+		date, err := parseDate(field("date"), opts.DateLayout)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		amount, err := ParseAmount(field("amount"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		transactions = append(transactions, Transaction{
+			Date:        date,
+			Description: field("description"),
+			Amount:      amount,
+		})
+	}
+
+	return transactions, nil
+}
+
+var (
+	ofxTransactionPattern = regexp.MustCompile(`(?is)<STMTTRN>(.*?)</STMTTRN>`)
+	ofxLeafPattern        = regexp.MustCompile(`(?i)<(DTPOSTED|TRNAMT|NAME|MEMO)>([^<\r\n]*)`)
+)
+
+// ParseOFX reads transactions from an OFX or QFX file. Both the SGML flavour
+// (OFX 1.x, unclosed leaf elements) and the XML flavour (OFX 2.x) are supported.
+func ParseOFX(r io.Reader) ([]Transaction, error) {
+	content, err := io.ReadAll(bufio.NewReader(r))
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := ofxTransactionPattern.FindAllSubmatch(content, -1)
+	if len(blocks) == 0 {
+		return nil, errors.New("no STMTTRN records found in OFX file")
+	}
+
+	transactions := make([]Transaction, 0, len(blocks))
+	for idx, block := range blocks {
+		values := make(map[string]string, 4)
+		for _, leaf := range ofxLeafPattern.FindAllSubmatch(block[1], -1) {
+			values[strings.ToUpper(string(leaf[1]))] = strings.TrimSpace(string(leaf[2]))
+		}
+
+		posted := values["DTPOSTED"]
+		if len(posted) < 8 {
+			return nil, fmt.Errorf("transaction %d: invalid DTPOSTED %q", idx+1, posted)
+		}
+		date, err := time.Parse("20060102", posted[:8])
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", idx+1, err)
+		}
+
+		amount, err := ParseAmount(values["TRNAMT"])
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", idx+1, err)
+		}
+
+		description := values["NAME"]
+		if description == "" {
+			description = values["MEMO"]
+		}
+
+		transactions = append(transactions, Transaction{
+			Date:        date,
+			Description: unescapeSGML(description),
+			Amount:      amount,
+		})
+	}
+
+	return transactions, nil
+}
+
+// ParseAmount converts a decimal amount such as "-1 234,56", "(15.99)" or
+// "$9.99" into minor units. Both "." and "," are accepted as decimal separator.
+func ParseAmount(raw string) (int64, error) {
+	value := strings.TrimSpace(raw)
+	if value == "" {
+		return 0, errors.New("amount is empty")
+	}
+
+	negative := false
+	if strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")") {
+		negative = true
+		value = value[1 : len(value)-1]
+	}
+
+	var sb strings.Builder
+	for _, r := range value {
+		switch {
+		case r >= '0' && r <= '9', r == '.', r == ',':
+			sb.WriteRune(r)
+		case r == '-':
+			negative = !negative
+		}
+	}
+	value = sb.String()
+
+	// The last separator followed by one or two digits is the decimal separator,
+	// every other separator groups thousands.
+	if idx := strings.LastIndexAny(value, ".,"); idx >= 0 && len(value)-idx-1 <= 2 {
+		value = strings.NewReplacer(".", "", ",", "").Replace(value[:idx]) + "." + value[idx+1:]
+	} else {
+		value = strings.NewReplacer(".", "", ",", "").Replace(value)
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", raw)
+	}
+
+	minor := int64(math.Round(parsed * 100))
+	if negative {
+		minor = -minor
+	}
+	return minor, nil
+}
+
+func parseDate(raw, layout string) (time.Time, error) {
+	if layout != "" {
+		return time.Parse(layout, raw)
+	}
+	for _, candidate := range defaultDateLayouts {
+		if date, err := time.Parse(candidate, raw); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date %q", raw)
+}
+
+func unescapeSGML(value string) string {
+	return strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">", "&apos;", "'", "&quot;", `"`).Replace(value)
+}
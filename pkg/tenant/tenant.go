@@ -0,0 +1,41 @@
+// Package tenant carries the tenant of a request through its context. Every
+// stored record belongs to a tenant and is only visible within it.
+package tenant
+
+import "context"
+
+// Default is the tenant of requests that do not carry one, so a deployment that
+// hosts a single client needs no configuration.
+const Default = "default"
+
+type contextKey struct{}
+
+type scope struct {
+	tenantID string
+	all      bool
+}
+
+// WithTenant returns a context scoped to the tenant.
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, contextKey{}, scope{tenantID: tenantID})
+}
+
+// WithAllTenants returns a context that is not restricted to a tenant. It is meant
+// for background jobs that process every tenant, never for request handling.
+func WithAllTenants(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKey{}, scope{all: true})
+}
+
+// FromContext returns the tenant of the context, or Default when it has none.
+func FromContext(ctx context.Context) string {
+	if s, ok := ctx.Value(contextKey{}).(scope); ok && s.tenantID != "" {
+		return s.tenantID
+	}
+	return Default
+}
+
+// AllTenants reports whether the context was created by WithAllTenants.
+func AllTenants(ctx context.Context) bool {
+	s, ok := ctx.Value(contextKey{}).(scope)
+	return ok && s.all
+}
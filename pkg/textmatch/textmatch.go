@@ -0,0 +1,88 @@
+package textmatch
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Normalize lowercases s, replaces every run of characters that are not letters
+// or digits with a single space and trims the result.
+func Normalize(s string) string {
+	var sb strings.Builder
+	space := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if space && sb.Len() > 0 {
+				sb.WriteByte(' ')
+			}
+			sb.WriteRune(r)
+			space = false
+		} else {
+			space = true
+		}
+	}
+	return sb.String()
+}
+
+// Levenshtein returns the edit distance between a and b, counted in runes.
+func Levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 {
+		return len(rb)
+	}
+	if len(rb) == 0 {
+		return len(ra)
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
+
+// Similarity returns a score in [0, 1] derived from the edit distance of the
+// normalized strings, where 1 means the strings are equal after normalization.
+func Similarity(a, b string) float64 {
+	na, nb := Normalize(a), Normalize(b)
+	longest := max(len([]rune(na)), len([]rune(nb)))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(Levenshtein(na, nb))/float64(longest)
+}
+
+// WordSimilarity returns how well query matches a part of text: 1 when the
+// normalized text contains the normalized query, otherwise the highest Similarity
+// between the query and a run of as many consecutive words of text.
+func WordSimilarity(query, text string) float64 {
+	nq, nt := Normalize(query), Normalize(text)
+	if nq == "" || nt == "" {
+		return 0
+	}
+	if strings.Contains(nt, nq) {
+		return 1
+	}
+
+	words := strings.Fields(nt)
+	size := min(len(strings.Fields(nq)), len(words))
+	best := 0.0
+	for start := 0; start+size <= len(words); start++ {
+		best = max(best, Similarity(nq, strings.Join(words[start:start+size], " ")))
+	}
+	return best
+}
@@ -0,0 +1,147 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Writer streams a single-sheet XLSX workbook. Rows are written straight into
+// the compressed worksheet entry, so memory usage does not grow with the row count.
+type Writer struct {
+	zip   *zip.Writer
+	sheet *bufio.Writer
+	row   int
+}
+
+const (
+	contentTypesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`
+
+	rootRelsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+
+	workbookRelsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`
+
+	workbookXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`
+
+	sheetHeaderXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+
+	sheetFooterXML = `</sheetData></worksheet>`
+)
+
+// NewWriter writes the workbook skeleton to w and opens the worksheet for rows.
+func NewWriter(w io.Writer, sheetName string) (*Writer, error) {
+	zw := zip.NewWriter(w)
+
+	static := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", contentTypesXML},
+		{"_rels/.rels", rootRelsXML},
+		{"xl/workbook.xml", fmt.Sprintf(workbookXML, escape(sheetName))},
+		{"xl/_rels/workbook.xml.rels", workbookRelsXML},
+	}
+	for _, file := range static {
+		entry, err := zw.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(entry, file.content); err != nil {
+			return nil, err
+		}
+	}
+
+	entry, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	sheet := bufio.NewWriter(entry)
+	if _, err := sheet.WriteString(sheetHeaderXML); err != nil {
+		return nil, err
+	}
+
+	return &Writer{zip: zw, sheet: sheet}, nil
+}
+
+// WriteRow appends a row. Integers and floats become numeric cells, nil becomes
+// an empty cell and everything else is written as an inline string.
+func (w *Writer) WriteRow(cells ...any) error {
+	w.row++
+	if _, err := fmt.Fprintf(w.sheet, `<row r="%d">`, w.row); err != nil {
+		return err
+	}
+
+	for idx, cell := range cells {
+		ref := columnName(idx) + strconv.Itoa(w.row)
+
+		var err error
+		switch value := cell.(type) {
+		case nil:
+			continue
+		case int:
+			_, err = fmt.Fprintf(w.sheet, `<c r="%s"><v>%d</v></c>`, ref, value)
+		case int64:
+			_, err = fmt.Fprintf(w.sheet, `<c r="%s"><v>%d</v></c>`, ref, value)
+		case uint:
+			_, err = fmt.Fprintf(w.sheet, `<c r="%s"><v>%d</v></c>`, ref, value)
+		case float64:
+			_, err = fmt.Fprintf(w.sheet, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(value, 'f', -1, 64))
+		default:
+			_, err = fmt.Fprintf(w.sheet, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, escape(fmt.Sprint(value)))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := w.sheet.WriteString(`</row>`)
+	return err
+}
+
+// Close finishes the worksheet and the zip archive. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if _, err := w.sheet.WriteString(sheetFooterXML); err != nil {
+		return err
+	}
+	if err := w.sheet.Flush(); err != nil {
+		return err
+	}
+	return w.zip.Close()
+}
+
+// columnName converts a zero-based column index into a spreadsheet column name (A, B, ..., AA).
+func columnName(idx int) string {
+	name := ""
+	for idx >= 0 {
+		name = string(rune('A'+idx%26)) + name
+		idx = idx/26 - 1
+	}
+	return name
+}
+
+// escape returns value with XML special characters escaped.
+func escape(value string) string {
+	var sb strings.Builder
+	_ = xml.EscapeText(&sb, []byte(value))
+	return sb.String()
+}
@@ -0,0 +1,147 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/middleware"
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/internal/service"
+	"github.com/rasadov/subscription-manager/pkg/auth"
+	"github.com/rasadov/subscription-manager/pkg/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupAPIKeys(t *testing.T) service.APIKeyService {
+	SetupRepo(t)
+	return service.NewAPIKeyService(repository.NewAPIKeyRepository(db))
+}
+
+func adminCtx(ctx context.Context) context.Context {
+	return auth.WithPrincipal(ctx, &auth.Principal{Subject: catalogUserID, TenantID: tenant.FromContext(ctx), AllUsers: true})
+}
+
+func TestAPIKey_CreateAndAuthenticate(t *testing.T) {
+	keys := setupAPIKeys(t)
+
+	created, httpErr := keys.CreateAPIKey(adminCtx(acmeCtx), dto.CreateAPIKeyRequest{
+		Name:   "billing job",
+		Scopes: []string{auth.PermissionCostsRead, auth.PermissionCostsRead},
+	})
+	require.Nil(t, httpErr)
+	assert.True(t, strings.HasPrefix(created.Key, "sm_"))
+	assert.True(t, strings.HasPrefix(created.Key, created.Prefix))
+	assert.Len(t, created.Prefix, 11)
+	assert.Equal(t, []string{auth.PermissionCostsRead}, created.Scopes)
+	assert.Equal(t, catalogUserID, created.CreatedBy)
+
+	var stored models.APIKey
+	require.NoError(t, db.WithContext(acmeCtx).First(&stored, created.ID).Error)
+	assert.NotContains(t, stored.SecretHash, created.Key)
+	assert.Len(t, stored.SecretHash, 64)
+
+	principal, err := keys.Authenticate(context.Background(), created.Key)
+	require.NoError(t, err)
+	assert.Equal(t, "acme", principal.TenantID)
+	assert.True(t, principal.AllUsers)
+	assert.True(t, principal.Allows(auth.PermissionCostsRead))
+	assert.False(t, principal.Allows(auth.PermissionSubscriptionsRead))
+
+	list, httpErr := keys.ListAPIKeys(adminCtx(acmeCtx))
+	require.Nil(t, httpErr)
+	require.Len(t, list.Data, 1)
+	assert.NotNil(t, list.Data[0].LastUsedAt)
+
+	// Keys are listed per tenant
+	list, httpErr = keys.ListAPIKeys(adminCtx(globexCtx))
+	require.Nil(t, httpErr)
+	assert.Empty(t, list.Data)
+
+	_, err = keys.Authenticate(context.Background(), created.Key+"x")
+	assert.ErrorIs(t, err, service.ErrInvalidAPIKey)
+}
+
+func TestAPIKey_RevokeAndExpiry(t *testing.T) {
+	keys := setupAPIKeys(t)
+	ctx := adminCtx(context.Background())
+
+	revoked, httpErr := keys.CreateAPIKey(ctx, dto.CreateAPIKeyRequest{Name: "old", Scopes: []string{auth.PermissionSubscriptionsRead}})
+	require.Nil(t, httpErr)
+	require.Nil(t, keys.RevokeAPIKey(ctx, int(revoked.ID)))
+	_, err := keys.Authenticate(context.Background(), revoked.Key)
+	assert.ErrorIs(t, err, service.ErrInvalidAPIKey)
+
+	list, httpErr := keys.ListAPIKeys(ctx)
+	require.Nil(t, httpErr)
+	require.Len(t, list.Data, 1)
+	assert.NotNil(t, list.Data[0].RevokedAt)
+
+	expiresAt := time.Now().Add(time.Hour)
+	expiring, httpErr := keys.CreateAPIKey(ctx, dto.CreateAPIKeyRequest{Name: "temp", Scopes: []string{auth.PermissionSubscriptionsRead}, ExpiresAt: &expiresAt})
+	require.Nil(t, httpErr)
+	_, err = keys.Authenticate(context.Background(), expiring.Key)
+	require.NoError(t, err)
+
+	require.NoError(t, db.Exec("UPDATE api_keys SET expires_at = ? WHERE id = ?", time.Now().Add(-time.Minute), expiring.ID).Error)
+	_, err = keys.Authenticate(context.Background(), expiring.Key)
+	assert.ErrorIs(t, err, service.ErrInvalidAPIKey)
+
+	past := time.Now().Add(-time.Hour)
+	_, httpErr = keys.CreateAPIKey(ctx, dto.CreateAPIKeyRequest{Name: "past", Scopes: []string{auth.PermissionCostsRead}, ExpiresAt: &past})
+	require.NotNil(t, httpErr)
+	assert.Equal(t, http.StatusBadRequest, httpErr.Status())
+
+	httpErr = keys.RevokeAPIKey(ctx, 999)
+	require.NotNil(t, httpErr)
+	assert.Equal(t, http.StatusNotFound, httpErr.Status())
+}
+
+func TestAPIKey_Authorization(t *testing.T) {
+	keys := setupAPIKeys(t)
+
+	_, httpErr := keys.CreateAPIKey(principalCtx(catalogUserID, false), dto.CreateAPIKeyRequest{Name: "mine", Scopes: []string{auth.PermissionCostsRead}})
+	require.NotNil(t, httpErr)
+	assert.Equal(t, http.StatusForbidden, httpErr.Status())
+
+	// A key cannot mint keys with more scopes than its own
+	scoped := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "api-key:1", AllUsers: true, Permissions: []string{auth.PermissionCostsRead}})
+	_, httpErr = keys.CreateAPIKey(scoped, dto.CreateAPIKeyRequest{Name: "wider", Scopes: []string{auth.PermissionSubscriptionsWrite}})
+	require.NotNil(t, httpErr)
+	assert.Equal(t, http.StatusForbidden, httpErr.Status())
+}
+
+func TestAPIKey_Middleware(t *testing.T) {
+	keys := setupAPIKeys(t)
+	created, httpErr := keys.CreateAPIKey(adminCtx(context.Background()), dto.CreateAPIKeyRequest{Name: "billing", Scopes: []string{auth.PermissionCostsRead}})
+	require.Nil(t, httpErr)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Authenticate(slog.New(slog.NewTextHandler(io.Discard, nil)), middleware.NewAPIKeyAuthenticator(keys)))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/subscriptions/total-cost", middleware.RequirePermission(auth.PermissionCostsRead), ok)
+	router.GET("/subscriptions", middleware.RequirePermission(auth.PermissionSubscriptionsRead), ok)
+
+	call := func(path, authorization string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", authorization)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusOK, call("/subscriptions/total-cost", "ApiKey "+created.Key))
+	assert.Equal(t, http.StatusForbidden, call("/subscriptions", "ApiKey "+created.Key))
+	assert.Equal(t, http.StatusUnauthorized, call("/subscriptions/total-cost", "ApiKey sm_unknown"))
+	assert.Equal(t, http.StatusUnauthorized, call("/subscriptions/total-cost", "Bearer "+created.Key))
+}
@@ -0,0 +1,408 @@
+package tests
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/middleware"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/internal/service"
+	"github.com/rasadov/subscription-manager/pkg/auth"
+	"github.com/rasadov/subscription-manager/pkg/jwt"
+	"github.com/rasadov/subscription-manager/pkg/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "test-secret"
+
+func segment(v any) string {
+	data, _ := json.Marshal(v)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// signToken creates a compact JWT signed with key, which is a []byte secret, an
+// *rsa.PrivateKey or an *ecdsa.PrivateKey.
+func signToken(t *testing.T, alg, kid string, key any, claims map[string]any) string {
+	header := map[string]any{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	input := segment(header) + "." + segment(claims)
+	digest := sha256.Sum256([]byte(input))
+
+	var signature []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(input))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		require.NoError(t, err)
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func userClaims(subject string, extra map[string]any) map[string]any {
+	claims := map[string]any{
+		"sub": subject,
+		"iss": "https://issuer.test",
+		"aud": []string{"subscriptions"},
+		"exp": time.Now().Add(time.Hour).Unix(),
+		"iat": time.Now().Unix(),
+	}
+	for name, value := range extra {
+		claims[name] = value
+	}
+	return claims
+}
+
+func jwksDocument(rsaKey *rsa.PrivateKey, ecKey *ecdsa.PrivateKey) []byte {
+	encode := func(data []byte) string { return base64.RawURLEncoding.EncodeToString(data) }
+	x := make([]byte, 32)
+	y := make([]byte, 32)
+	ecKey.PublicKey.X.FillBytes(x)
+	ecKey.PublicKey.Y.FillBytes(y)
+	data, _ := json.Marshal(map[string]any{"keys": []map[string]any{
+		{"kty": "RSA", "kid": "rsa-1", "use": "sig", "n": encode(rsaKey.N.Bytes()), "e": encode([]byte{1, 0, 1})},
+		{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": encode(x), "y": encode(y)},
+	}})
+	return data
+}
+
+func newAuthRouter(verifier *jwt.Verifier) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	router.Use(middleware.Authenticate(logger, middleware.NewJWTAuthenticator(verifier, "admin", "tenant")))
+	router.GET("/whoami", func(c *gin.Context) {
+		principal, _ := auth.FromContext(c.Request.Context())
+		c.JSON(http.StatusOK, gin.H{
+			"subject": principal.Subject,
+			"roles":   principal.Roles,
+			"tenant":  tenant.FromContext(c.Request.Context()),
+		})
+	})
+	return router
+}
+
+func callWhoami(router *gin.Engine, authorization string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestAuthMiddleware_HS256(t *testing.T) {
+	router := newAuthRouter(&jwt.Verifier{
+		Keys:     []jwt.KeySet{jwt.Secret(testSecret)},
+		Issuer:   "https://issuer.test",
+		Audience: "subscriptions",
+	})
+
+	token := signToken(t, jwt.HS256, "", []byte(testSecret), userClaims(catalogUserID, map[string]any{
+		"tenant": "acme",
+		"roles":  []string{"admin"},
+	}))
+	recorder := callWhoami(router, "Bearer "+token)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	assert.Equal(t, catalogUserID, body["subject"])
+	assert.Equal(t, []any{"admin"}, body["roles"])
+	assert.Equal(t, "acme", body["tenant"])
+}
+
+func TestAuthMiddleware_Rejected(t *testing.T) {
+	router := newAuthRouter(&jwt.Verifier{
+		Keys:     []jwt.KeySet{jwt.Secret(testSecret)},
+		Issuer:   "https://issuer.test",
+		Audience: "subscriptions",
+	})
+	sign := func(claims map[string]any) string {
+		return "Bearer " + signToken(t, jwt.HS256, "", []byte(testSecret), claims)
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+	}{
+		{"missing header", ""},
+		{"other scheme", "Basic dXNlcjpwYXNz"},
+		{"malformed", "Bearer not-a-token"},
+		{"wrong secret", "Bearer " + signToken(t, jwt.HS256, "", []byte("other"), userClaims(catalogUserID, nil))},
+		{"expired", sign(userClaims(catalogUserID, map[string]any{"exp": time.Now().Add(-time.Hour).Unix()}))},
+		{"not yet valid", sign(userClaims(catalogUserID, map[string]any{"nbf": time.Now().Add(time.Hour).Unix()}))},
+		{"without expiry", sign(map[string]any{"sub": catalogUserID, "iss": "https://issuer.test", "aud": "subscriptions"})},
+		{"wrong issuer", sign(userClaims(catalogUserID, map[string]any{"iss": "https://evil.test"}))},
+		{"wrong audience", sign(userClaims(catalogUserID, map[string]any{"aud": "billing"}))},
+		{"without subject", sign(userClaims("", nil))},
+		{"alg none", "Bearer " + segment(map[string]any{"alg": "none"}) + "." + segment(userClaims(catalogUserID, nil)) + "."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := callWhoami(router, tt.authorization)
+			assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+			assert.Contains(t, recorder.Header().Get("WWW-Authenticate"), "Bearer")
+		})
+	}
+}
+
+func TestAuthMiddleware_JWKSFile(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, jwksDocument(rsaKey, ecKey), 0o600))
+	keys, err := jwt.LoadJWKSFile(path)
+	require.NoError(t, err)
+	router := newAuthRouter(&jwt.Verifier{Keys: []jwt.KeySet{keys}, Audience: "subscriptions"})
+
+	rs256 := signToken(t, jwt.RS256, "rsa-1", rsaKey, userClaims(catalogUserID, nil))
+	assert.Equal(t, http.StatusOK, callWhoami(router, "Bearer "+rs256).Code)
+
+	es256 := signToken(t, jwt.ES256, "ec-1", ecKey, userClaims(catalogUserID, nil))
+	assert.Equal(t, http.StatusOK, callWhoami(router, "Bearer "+es256).Code)
+
+	// A key ID of the wrong type must not be used for another algorithm
+	mismatched := signToken(t, jwt.ES256, "rsa-1", ecKey, userClaims(catalogUserID, nil))
+	assert.Equal(t, http.StatusUnauthorized, callWhoami(router, "Bearer "+mismatched).Code)
+
+	// HS256 tokens signed with public key material are rejected
+	confused := signToken(t, jwt.HS256, "rsa-1", rsaKey.N.Bytes(), userClaims(catalogUserID, nil))
+	assert.Equal(t, http.StatusUnauthorized, callWhoami(router, "Bearer "+confused).Code)
+}
+
+func TestAuthMiddleware_JWKSURL(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(jwksDocument(rsaKey, ecKey))
+	}))
+	defer server.Close()
+
+	keys := jwt.NewRemoteJWKS(server.URL, time.Hour, server.Client())
+	router := newAuthRouter(&jwt.Verifier{Keys: []jwt.KeySet{keys}})
+
+	for range 3 {
+		token := signToken(t, jwt.ES256, "ec-1", ecKey, userClaims(catalogUserID, nil))
+		assert.Equal(t, http.StatusOK, callWhoami(router, "Bearer "+token).Code)
+	}
+	assert.Equal(t, 1, fetches, "the key set is cached")
+
+	unknown := signToken(t, jwt.RS256, "rsa-2", rsaKey, userClaims(catalogUserID, nil))
+	assert.Equal(t, http.StatusUnauthorized, callWhoami(router, "Bearer "+unknown).Code)
+}
+
+func principalCtx(subject string, admin bool) context.Context {
+	return auth.WithPrincipal(context.Background(), &auth.Principal{Subject: subject, TenantID: tenant.Default, AllUsers: admin})
+}
+
+func TestAuthorization_Subscriptions(t *testing.T) {
+	SetupRepo(t)
+	ownerCtx := principalCtx(catalogUserID, false)
+	otherCtx := principalCtx(otherBudgetUserID, false)
+	adminCtx := principalCtx(outsiderUserID, true)
+
+	_, httpErr := testService.CreateSubscription(otherCtx, dto.CreateSubscriptionRequest{
+		ServiceName: "Netflix", Price: 1000, UserID: catalogUserID, StartDate: "01-2025",
+	})
+	require.NotNil(t, httpErr)
+	assert.Equal(t, http.StatusForbidden, httpErr.Status())
+
+	created, httpErr := testService.CreateSubscription(ownerCtx, dto.CreateSubscriptionRequest{
+		ServiceName: "Netflix", Price: 1000, UserID: catalogUserID, StartDate: "01-2025",
+	})
+	require.Nil(t, httpErr)
+	id := int(created.ID)
+
+	_, httpErr = testService.GetSubscription(ownerCtx, id)
+	assert.Nil(t, httpErr)
+	_, httpErr = testService.GetSubscription(adminCtx, id)
+	assert.Nil(t, httpErr)
+
+	_, httpErr = testService.GetSubscription(otherCtx, id)
+	require.NotNil(t, httpErr)
+	assert.Equal(t, http.StatusNotFound, httpErr.Status())
+
+	_, httpErr = testService.UpdateSubscription(otherCtx, id, dto.UpdateSubscriptionRequest{Price: int64Ptr(1)})
+	require.NotNil(t, httpErr)
+	assert.Equal(t, http.StatusNotFound, httpErr.Status())
+
+	httpErr = testService.DeleteSubscription(otherCtx, id)
+	require.NotNil(t, httpErr)
+	assert.Equal(t, http.StatusNotFound, httpErr.Status())
+
+	// Listing without a user filter only returns the caller's subscriptions
+	list, httpErr := testService.ListSubscriptions(otherCtx, dto.ListSubscriptionsQuery{})
+	require.Nil(t, httpErr)
+	assert.Empty(t, list.Data)
+	list, httpErr = testService.ListSubscriptions(adminCtx, dto.ListSubscriptionsQuery{})
+	require.Nil(t, httpErr)
+	assert.Len(t, list.Data, 1)
+
+	_, httpErr = testService.ListSubscriptions(otherCtx, dto.ListSubscriptionsQuery{UserID: strPtr(catalogUserID)})
+	require.NotNil(t, httpErr)
+	assert.Equal(t, http.StatusForbidden, httpErr.Status())
+
+	_, httpErr = testService.CalculateTotalCost(otherCtx, dto.TotalCostQuery{
+		UserID: strPtr(catalogUserID), StartDate: strPtr("01-2025"), EndDate: strPtr("12-2025"),
+	})
+	require.NotNil(t, httpErr)
+	assert.Equal(t, http.StatusForbidden, httpErr.Status())
+
+	httpErr = testService.DeleteSubscription(ownerCtx, id)
+	assert.Nil(t, httpErr)
+}
+
+func TestAuthorization_SharedSubscriptionIsReadOnlyForMembers(t *testing.T) {
+	SetupRepo(t)
+	created, httpErr := testService.CreateSubscription(principalCtx(catalogUserID, false), dto.CreateSubscriptionRequest{
+		ServiceName: "Spotify Family",
+		Price:       1800,
+		UserID:      catalogUserID,
+		StartDate:   "01-2025",
+		Members:     []dto.SubscriptionMemberRequest{{UserID: otherBudgetUserID}},
+	})
+	require.Nil(t, httpErr)
+
+	memberCtx := principalCtx(otherBudgetUserID, false)
+	_, httpErr = testService.GetSubscription(memberCtx, int(created.ID))
+	assert.Nil(t, httpErr)
+
+	_, httpErr = testService.UpdateSubscription(memberCtx, int(created.ID), dto.UpdateSubscriptionRequest{Price: int64Ptr(1)})
+	require.NotNil(t, httpErr)
+	assert.Equal(t, http.StatusNotFound, httpErr.Status())
+}
+
+func TestAuthorization_UserScopedServices(t *testing.T) {
+	SetupRepo(t)
+	otherCtx := principalCtx(otherBudgetUserID, false)
+
+	budgets := service.NewBudgetService(repository.NewBudgetRepository(db), testRepository, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	_, httpErr := budgets.ListBudgets(otherCtx, catalogUserID)
+	require.NotNil(t, httpErr)
+	assert.Equal(t, http.StatusForbidden, httpErr.Status())
+
+	_, httpErr = budgets.ListBudgets(principalCtx(catalogUserID, false), catalogUserID)
+	assert.Nil(t, httpErr)
+
+	duplicates := service.NewDuplicateService(testRepository)
+	_, httpErr = duplicates.FindDuplicates(otherCtx, catalogUserID)
+	require.NotNil(t, httpErr)
+	assert.Equal(t, http.StatusForbidden, httpErr.Status())
+}
+
+func TestAuthorization_Organizations(t *testing.T) {
+	SetupRepo(t)
+	organizations := service.NewOrganizationService(repository.NewOrganizationRepository(db))
+	subscriptions := service.NewSubscriptionService(testRepository, service.WithOrganizations(repository.NewOrganizationRepository(db)))
+
+	created, httpErr := organizations.CreateOrganization(principalCtx(catalogUserID, false), dto.CreateOrganizationRequest{
+		Name:    "Acme",
+		OwnerID: catalogUserID,
+		Members: []dto.AddOrganizationMemberRequest{{UserID: otherBudgetUserID}},
+	})
+	require.Nil(t, httpErr)
+	id := int(created.ID)
+
+	memberCtx := principalCtx(otherBudgetUserID, false)
+	_, httpErr = organizations.GetOrganization(memberCtx, id)
+	assert.Nil(t, httpErr)
+
+	_, httpErr = organizations.UpdateOrganization(memberCtx, id, dto.UpdateOrganizationRequest{Name: strPtr("Renamed")})
+	require.NotNil(t, httpErr)
+	assert.Equal(t, http.StatusForbidden, httpErr.Status())
+
+	_, httpErr = organizations.GetOrganization(principalCtx(outsiderUserID, false), id)
+	require.NotNil(t, httpErr)
+	assert.Equal(t, http.StatusNotFound, httpErr.Status())
+
+	organizationID := uint(id)
+	_, httpErr = subscriptions.ListSubscriptions(memberCtx, dto.ListSubscriptionsQuery{OrganizationID: &organizationID})
+	require.NotNil(t, httpErr)
+	assert.Equal(t, http.StatusForbidden, httpErr.Status())
+
+	_, httpErr = subscriptions.ListSubscriptions(principalCtx(catalogUserID, false), dto.ListSubscriptionsQuery{OrganizationID: &organizationID})
+	assert.Nil(t, httpErr)
+
+	// Members may leave on their own
+	httpErr = organizations.RemoveMember(memberCtx, id, otherBudgetUserID)
+	assert.Nil(t, httpErr)
+}
+
+func TestAuthMiddleware_JWKSURLUnavailable(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	fetches, available := 0, false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		if !available {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(jwksDocument(rsaKey, ecKey))
+	}))
+	defer server.Close()
+
+	// Without keys every request fails, but the provider is only asked once
+	unavailable := newAuthRouter(&jwt.Verifier{Keys: []jwt.KeySet{jwt.NewRemoteJWKS(server.URL, time.Hour, server.Client())}})
+	token := signToken(t, jwt.ES256, "ec-1", ecKey, userClaims(catalogUserID, nil))
+	for range 3 {
+		assert.Equal(t, http.StatusUnauthorized, callWhoami(unavailable, "Bearer "+token).Code)
+	}
+	assert.Equal(t, 1, fetches)
+
+	// A stale key set stays in use, again with a single failed refetch
+	available, fetches = true, 0
+	stale := newAuthRouter(&jwt.Verifier{Keys: []jwt.KeySet{jwt.NewRemoteJWKS(server.URL, time.Nanosecond, server.Client())}})
+	assert.Equal(t, http.StatusOK, callWhoami(stale, "Bearer "+token).Code)
+	available = false
+	for range 3 {
+		assert.Equal(t, http.StatusOK, callWhoami(stale, "Bearer "+token).Code)
+	}
+	assert.Equal(t, 2, fetches)
+}
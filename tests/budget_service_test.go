@@ -0,0 +1,234 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const otherBudgetUserID = "223e4567-e89b-12d3-a456-426614174000"
+
+func setupBudgets(t *testing.T) (service.BudgetService, service.SubscriptionService) {
+	SetupRepo(t)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	budgetService := service.NewBudgetService(repository.NewBudgetRepository(db), testRepository, logger)
+	subscriptionService := service.NewSubscriptionService(testRepository, service.WithObserver(budgetService))
+	return budgetService, subscriptionService
+}
+
+func alertThresholds(t *testing.T, budgetService service.BudgetService, userID string) []int {
+	alerts, httpErr := budgetService.ListAlerts(context.Background(), userID)
+	require.Nil(t, httpErr)
+
+	thresholds := []int{}
+	for _, alert := range alerts.Data {
+		thresholds = append(thresholds, alert.Threshold)
+	}
+	return thresholds
+}
+
+func TestBudget_AlertsOnSubscriptionChanges(t *testing.T) {
+	budgetService, subscriptionService := setupBudgets(t)
+
+	budget, httpErr := budgetService.CreateBudget(context.Background(), catalogUserID, dto.CreateBudgetRequest{
+		Scope: models.BudgetScopeAll, MonthlyLimit: 1000,
+	})
+	require.Nil(t, httpErr)
+	assert.Equal(t, []int{80, 100}, budget.Thresholds)
+	assert.Equal(t, models.DefaultCurrency, budget.Currency)
+
+	create := func(name string, price int64, cycle string) *dto.SubscriptionResponse {
+		response, httpErr := subscriptionService.CreateSubscription(context.Background(), dto.CreateSubscriptionRequest{
+			ServiceName: name, Price: price, BillingCycle: cycle, UserID: catalogUserID, StartDate: "01-2025",
+		})
+		require.Nil(t, httpErr)
+		return response
+	}
+
+	create("Spotify", 500, "")
+	create("iCloud", 2400, models.BillingCycleYearly)
+	assert.Empty(t, alertThresholds(t, budgetService, catalogUserID))
+
+	youtube := create("YouTube", 200, "")
+	assert.Equal(t, []int{80}, alertThresholds(t, budgetService, catalogUserID))
+
+	price := int64(400)
+	_, httpErr = subscriptionService.UpdateSubscription(context.Background(), int(youtube.ID), dto.UpdateSubscriptionRequest{Price: &price})
+	require.Nil(t, httpErr)
+	assert.Equal(t, []int{100, 80}, alertThresholds(t, budgetService, catalogUserID))
+
+	// Thresholds alert once per month
+	alerts, err := budgetService.EvaluateUser(context.Background(), catalogUserID, time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, alerts)
+
+	budgets, httpErr := budgetService.ListBudgets(context.Background(), catalogUserID)
+	require.Nil(t, httpErr)
+	require.Len(t, budgets.Data, 1)
+	require.NotNil(t, budgets.Data[0].Current)
+	assert.Equal(t, int64(1100), budgets.Data[0].Current.Spend)
+	assert.InDelta(t, 110, budgets.Data[0].Current.Percent, 0.001)
+}
+
+func TestBudget_ScopesAndCurrencies(t *testing.T) {
+	budgetService, subscriptionService := setupBudgets(t)
+
+	requests := []dto.CreateSubscriptionRequest{
+		{ServiceName: "Spotify", Price: 300, Category: "music"},
+		{ServiceName: "Apple Music", Price: 900, Currency: "USD", Category: "music"},
+		{ServiceName: "Netflix", Price: 600, Category: "video"},
+		{ServiceName: "Netflix", Price: 600, Category: "video", StartDate: "05-2025"},
+		{ServiceName: "Kinopoisk", Price: 400, Category: "video", EndDate: "02-2025"},
+	}
+	for _, req := range requests {
+		req.UserID = catalogUserID
+		if req.StartDate == "" {
+			req.StartDate = "01-2025"
+		}
+		_, httpErr := subscriptionService.CreateSubscription(context.Background(), req)
+		require.Nil(t, httpErr)
+	}
+
+	music, httpErr := budgetService.CreateBudget(context.Background(), catalogUserID, dto.CreateBudgetRequest{
+		Scope: models.BudgetScopeCategory, Category: " Music", MonthlyLimit: 300, Thresholds: []int{100, 50, 100},
+	})
+	require.Nil(t, httpErr)
+	assert.Equal(t, "music", music.Category)
+	assert.Equal(t, []int{50, 100}, music.Thresholds)
+
+	netflix, httpErr := budgetService.CreateBudget(context.Background(), catalogUserID, dto.CreateBudgetRequest{
+		Scope: models.BudgetScopeService, ServiceName: "netflix", MonthlyLimit: 1000,
+	})
+	require.Nil(t, httpErr)
+
+	usd, httpErr := budgetService.CreateBudget(context.Background(), catalogUserID, dto.CreateBudgetRequest{
+		Scope: models.BudgetScopeAll, MonthlyLimit: 1000, Currency: "USD",
+	})
+	require.Nil(t, httpErr)
+
+	evaluate := func(userID string, month time.Time) map[uint][]int64 {
+		alerts, err := budgetService.EvaluateUser(context.Background(), userID, month)
+		require.NoError(t, err)
+
+		spend := make(map[uint][]int64)
+		for _, alert := range alerts {
+			spend[alert.BudgetID] = append(spend[alert.BudgetID], int64(alert.Threshold), alert.Spend)
+		}
+		return spend
+	}
+
+	march := time.Date(2025, time.March, 10, 0, 0, 0, 0, time.UTC)
+	assert.Empty(t, evaluate(otherBudgetUserID, march))
+
+	// In March only the first Netflix subscription runs and Kinopoisk has ended
+	assert.Equal(t, map[uint][]int64{
+		music.ID: {50, 300, 100, 300},
+		usd.ID:   {80, 900},
+	}, evaluate(catalogUserID, march))
+
+	// From May both Netflix subscriptions count
+	assert.Equal(t, map[uint][]int64{
+		music.ID:   {50, 300, 100, 300},
+		netflix.ID: {80, 1200, 100, 1200},
+		usd.ID:     {80, 900},
+	}, evaluate(catalogUserID, march.AddDate(0, 2, 0)))
+}
+
+func TestBudget_EvaluateAllAndOwnership(t *testing.T) {
+	budgetService, _ := setupBudgets(t)
+
+	for _, userID := range []string{catalogUserID, otherBudgetUserID} {
+		sub := createTestSubscription("Spotify", userID, "01-2025", "12-2025", 500)
+		require.NoError(t, testRepository.CreateSubscription(context.Background(), sub))
+	}
+
+	var budgetIDs []uint
+	for _, userID := range []string{catalogUserID, otherBudgetUserID} {
+		budget, httpErr := budgetService.CreateBudget(context.Background(), userID, dto.CreateBudgetRequest{
+			Scope: models.BudgetScopeAll, MonthlyLimit: 500,
+		})
+		require.Nil(t, httpErr)
+		budgetIDs = append(budgetIDs, budget.ID)
+	}
+
+	require.NoError(t, budgetService.EvaluateAll(context.Background(), time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, []int{100, 80}, alertThresholds(t, budgetService, catalogUserID))
+	assert.Equal(t, []int{100, 80}, alertThresholds(t, budgetService, otherBudgetUserID))
+
+	limit := int64(2000)
+	_, httpErr := budgetService.UpdateBudget(context.Background(), catalogUserID, int(budgetIDs[1]), dto.UpdateBudgetRequest{MonthlyLimit: &limit})
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 404, httpErr.Status())
+
+	require.Nil(t, budgetService.DeleteBudget(context.Background(), catalogUserID, int(budgetIDs[0])))
+	assert.Empty(t, alertThresholds(t, budgetService, catalogUserID))
+
+	httpErr = budgetService.DeleteBudget(context.Background(), catalogUserID, int(budgetIDs[0]))
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 404, httpErr.Status())
+}
+
+func TestBudget_CountsOnlyTheUsersShare(t *testing.T) {
+	budgetService, subscriptionService := setupBudgets(t)
+
+	for _, userID := range []string{catalogUserID, otherBudgetUserID} {
+		_, httpErr := budgetService.CreateBudget(context.Background(), userID, dto.CreateBudgetRequest{
+			Scope: models.BudgetScopeAll, MonthlyLimit: 1000,
+		})
+		require.Nil(t, httpErr)
+	}
+
+	// The owner pays 1200 - 300, the member 300 a month
+	_, httpErr := subscriptionService.CreateSubscription(context.Background(), dto.CreateSubscriptionRequest{
+		ServiceName: "Family Plan", Price: 1200, UserID: catalogUserID, StartDate: "01-2025",
+		SplitRule: models.SplitFixed, Members: []dto.SubscriptionMemberRequest{{UserID: otherBudgetUserID, Share: 300}},
+	})
+	require.Nil(t, httpErr)
+	assert.Equal(t, []int{80}, alertThresholds(t, budgetService, catalogUserID))
+	assert.Empty(t, alertThresholds(t, budgetService, otherBudgetUserID))
+
+	spend := func(userID string) int64 {
+		budgets, httpErr := budgetService.ListBudgets(context.Background(), userID)
+		require.Nil(t, httpErr)
+		require.Len(t, budgets.Data, 1)
+		return budgets.Data[0].Current.Spend
+	}
+	assert.Equal(t, int64(900), spend(catalogUserID))
+	assert.Equal(t, int64(300), spend(otherBudgetUserID))
+
+	// A subscription of the member's own adds its full price and alerts the member
+	_, httpErr = subscriptionService.CreateSubscription(context.Background(), dto.CreateSubscriptionRequest{
+		ServiceName: "Netflix", Price: 600, UserID: otherBudgetUserID, StartDate: "01-2025",
+	})
+	require.Nil(t, httpErr)
+	assert.Equal(t, int64(900), spend(otherBudgetUserID))
+	assert.Equal(t, []int{80}, alertThresholds(t, budgetService, otherBudgetUserID))
+}
+
+func TestBudget_AlertsOnImports(t *testing.T) {
+	budgetService, _ := setupBudgets(t)
+	importService := service.NewImportService(testRepository, service.WithObserver(budgetService))
+
+	_, httpErr := budgetService.CreateBudget(context.Background(), catalogUserID, dto.CreateBudgetRequest{
+		Scope: models.BudgetScopeAll, MonthlyLimit: 1000,
+	})
+	require.Nil(t, httpErr)
+
+	csv := "service_name,price,user_id,start_date\n" +
+		"Spotify,500," + catalogUserID + ",01-2025\n" +
+		"YouTube,600," + catalogUserID + ",01-2025\n"
+	result, httpErr := importService.ImportCSV(context.Background(), strings.NewReader(csv), dto.ImportSubscriptionsQuery{})
+	require.Nil(t, httpErr)
+	require.Equal(t, 2, result.Accepted)
+	assert.Equal(t, []int{100, 80}, alertThresholds(t, budgetService, catalogUserID))
+}
@@ -0,0 +1,209 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/handlers"
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingObserver struct {
+	changed []uint
+}
+
+func (o *recordingObserver) SubscriptionChanged(_ context.Context, subscription *models.Subscription) {
+	o.changed = append(o.changed, subscription.ID)
+}
+
+func setupBulk(t *testing.T) (service.SubscriptionService, *recordingObserver, *dto.SubscriptionResponse) {
+	SetupRepo(t)
+	observer := &recordingObserver{}
+	subscriptionService := service.NewSubscriptionService(testRepository, service.WithObserver(observer))
+
+	existing, httpErr := subscriptionService.CreateSubscription(context.Background(), dto.CreateSubscriptionRequest{
+		ServiceName: "Spotify",
+		Price:       500,
+		UserID:      catalogUserID,
+		StartDate:   "01-2025",
+	})
+	require.Nil(t, httpErr)
+	observer.changed = nil
+	return subscriptionService, observer, existing
+}
+
+func bulkOperation(t *testing.T, op string, id uint, data interface{}) dto.BulkOperation {
+	operation := dto.BulkOperation{Op: op, ID: id}
+	if data != nil {
+		raw, err := json.Marshal(data)
+		require.NoError(t, err)
+		operation.Data = raw
+	}
+	return operation
+}
+
+func TestBulkSubscriptions_PerItemResults(t *testing.T) {
+	subscriptionService, observer, existing := setupBulk(t)
+
+	response, httpErr := subscriptionService.BulkSubscriptions(context.Background(), []dto.BulkOperation{
+		bulkOperation(t, dto.BulkOpCreate, 0, dto.CreateSubscriptionRequest{ServiceName: "Netflix", Price: 1000, UserID: catalogUserID, StartDate: "02-2025"}),
+		bulkOperation(t, dto.BulkOpCreate, 0, map[string]interface{}{"service_name": "Broken", "price": 100, "user_id": "not-a-uuid", "start_date": "01-2025"}),
+		bulkOperation(t, dto.BulkOpUpdate, existing.ID, dto.UpdateSubscriptionRequest{Price: int64Ptr(700)}),
+		bulkOperation(t, dto.BulkOpUpdate, 9999, dto.UpdateSubscriptionRequest{Price: int64Ptr(700)}),
+		bulkOperation(t, dto.BulkOpDelete, 0, nil),
+		{Op: "archive", ID: existing.ID},
+	}, false)
+	require.Nil(t, httpErr)
+
+	assert.False(t, response.RolledBack)
+	assert.Equal(t, 6, response.Total)
+	assert.Equal(t, 2, response.Succeeded)
+	assert.Equal(t, 4, response.Failed)
+	require.Len(t, response.Results, 6)
+
+	created := response.Results[0]
+	assert.Equal(t, dto.BulkStatusSucceeded, created.Status)
+	assert.Equal(t, http.StatusCreated, created.Code)
+	require.NotNil(t, created.Subscription)
+	assert.Equal(t, created.Subscription.ID, created.ID)
+
+	assert.Equal(t, dto.BulkStatusFailed, response.Results[1].Status)
+	assert.Equal(t, http.StatusBadRequest, response.Results[1].Code)
+	assert.Contains(t, response.Results[1].Error, "UserID")
+
+	assert.Equal(t, dto.BulkStatusSucceeded, response.Results[2].Status)
+	assert.Equal(t, int64(700), response.Results[2].Subscription.Price)
+
+	assert.Equal(t, http.StatusNotFound, response.Results[3].Code)
+	assert.Contains(t, response.Results[4].Error, "id is required")
+	assert.Contains(t, response.Results[5].Error, "unknown operation")
+
+	assert.Equal(t, int64(2), countSubscriptions(t))
+	assert.ElementsMatch(t, []uint{created.ID, existing.ID}, observer.changed)
+}
+
+func TestBulkSubscriptions_AtomicRollsBack(t *testing.T) {
+	subscriptionService, observer, existing := setupBulk(t)
+
+	response, httpErr := subscriptionService.BulkSubscriptions(context.Background(), []dto.BulkOperation{
+		bulkOperation(t, dto.BulkOpCreate, 0, dto.CreateSubscriptionRequest{ServiceName: "Netflix", Price: 1000, UserID: catalogUserID, StartDate: "02-2025", Tags: []string{"video"}}),
+		bulkOperation(t, dto.BulkOpUpdate, existing.ID, dto.UpdateSubscriptionRequest{Price: int64Ptr(700)}),
+		bulkOperation(t, dto.BulkOpCreate, 0, dto.CreateSubscriptionRequest{ServiceName: "Hulu", Price: 800, UserID: catalogUserID, StartDate: "13-2025"}),
+		bulkOperation(t, dto.BulkOpDelete, existing.ID, nil),
+	}, true)
+	require.Nil(t, httpErr)
+
+	assert.True(t, response.RolledBack)
+	assert.Equal(t, 0, response.Succeeded)
+	assert.Equal(t, 1, response.Failed)
+	statuses := make([]string, 0, len(response.Results))
+	for _, result := range response.Results {
+		statuses = append(statuses, result.Status)
+	}
+	assert.Equal(t, []string{dto.BulkStatusRolledBack, dto.BulkStatusRolledBack, dto.BulkStatusFailed, dto.BulkStatusRolledBack}, statuses)
+	assert.Zero(t, response.Results[0].ID, "rolled back subscriptions do not exist")
+	assert.Nil(t, response.Results[0].Subscription)
+
+	// Nothing was applied, not even the tags of the rolled back subscription
+	assert.Equal(t, int64(1), countSubscriptions(t))
+	stored, httpErr := subscriptionService.GetSubscription(context.Background(), int(existing.ID))
+	require.Nil(t, httpErr)
+	assert.Equal(t, int64(500), stored.Price)
+	var tags int64
+	require.NoError(t, db.Model(&models.Tag{}).Count(&tags).Error)
+	assert.Zero(t, tags)
+	assert.Empty(t, observer.changed)
+}
+
+func TestBulkSubscriptions_AtomicCommits(t *testing.T) {
+	subscriptionService, observer, existing := setupBulk(t)
+
+	response, httpErr := subscriptionService.BulkSubscriptions(context.Background(), []dto.BulkOperation{
+		bulkOperation(t, dto.BulkOpCreate, 0, dto.CreateSubscriptionRequest{ServiceName: "Netflix", Price: 1000, UserID: catalogUserID, StartDate: "02-2025"}),
+		bulkOperation(t, dto.BulkOpDelete, existing.ID, nil),
+	}, true)
+	require.Nil(t, httpErr)
+
+	assert.False(t, response.RolledBack)
+	assert.Equal(t, 2, response.Succeeded)
+	assert.Equal(t, http.StatusNoContent, response.Results[1].Code)
+
+	_, httpErr = subscriptionService.GetSubscription(context.Background(), int(existing.ID))
+	require.NotNil(t, httpErr)
+	assert.Equal(t, http.StatusNotFound, httpErr.Status())
+	assert.Equal(t, int64(1), countSubscriptions(t))
+	assert.Equal(t, []uint{response.Results[0].ID}, observer.changed)
+}
+
+func TestBulkSubscriptions_AuthorizesEveryOperation(t *testing.T) {
+	subscriptionService, _, existing := setupBulk(t)
+	ctx := principalCtx(otherBudgetUserID, false)
+
+	response, httpErr := subscriptionService.BulkSubscriptions(ctx, []dto.BulkOperation{
+		bulkOperation(t, dto.BulkOpCreate, 0, dto.CreateSubscriptionRequest{ServiceName: "Netflix", Price: 1000, UserID: otherBudgetUserID, StartDate: "02-2025"}),
+		bulkOperation(t, dto.BulkOpCreate, 0, dto.CreateSubscriptionRequest{ServiceName: "Netflix", Price: 1000, UserID: catalogUserID, StartDate: "02-2025"}),
+		bulkOperation(t, dto.BulkOpDelete, existing.ID, nil),
+	}, false)
+	require.Nil(t, httpErr)
+
+	assert.Equal(t, dto.BulkStatusSucceeded, response.Results[0].Status)
+	assert.Equal(t, http.StatusForbidden, response.Results[1].Code)
+	// Subscriptions of other users are hidden like on the single-item endpoints
+	assert.Equal(t, http.StatusNotFound, response.Results[2].Code)
+	assert.Equal(t, int64(2), countSubscriptions(t))
+}
+
+func TestBulkSubscriptions_Limits(t *testing.T) {
+	subscriptionService, _, _ := setupBulk(t)
+
+	_, httpErr := subscriptionService.BulkSubscriptions(context.Background(), nil, false)
+	require.NotNil(t, httpErr)
+	assert.Equal(t, http.StatusBadRequest, httpErr.Status())
+
+	_, httpErr = subscriptionService.BulkSubscriptions(context.Background(), make([]dto.BulkOperation, dto.MaxBulkOperations+1), false)
+	require.NotNil(t, httpErr)
+	assert.Equal(t, http.StatusBadRequest, httpErr.Status())
+}
+
+func TestBulkSubscriptions_Handler(t *testing.T) {
+	SetupRepo(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/subscriptions/bulk", handlers.NewSubscriptionHandler(testService, slog.New(slog.NewTextHandler(io.Discard, nil))).BulkSubscriptions)
+
+	body := `[
+		{"op": "create", "data": {"service_name": "Netflix", "price": 1000, "user_id": "` + catalogUserID + `", "start_date": "01-2025"}},
+		{"op": "create", "data": {"service_name": "Hulu", "price": 800, "user_id": "` + catalogUserID + `", "start_date": "02-2025"}}
+	]`
+	req := httptest.NewRequest(http.MethodPost, "/subscriptions/bulk?atomic=true", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+
+	var response struct {
+		Atomic    bool `json:"atomic"`
+		Succeeded int  `json:"succeeded"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.True(t, response.Atomic)
+	assert.Equal(t, 2, response.Succeeded)
+	assert.Equal(t, int64(2), countSubscriptions(t))
+
+	req = httptest.NewRequest(http.MethodPost, "/subscriptions/bulk", strings.NewReader(`{"op": "create"}`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
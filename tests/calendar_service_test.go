@@ -0,0 +1,110 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const calendarUserID = "123e4567-e89b-12d3-a456-426614174000"
+
+func TestBillingDates_RespectsCycleAndEndDate(t *testing.T) {
+	start := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+	from := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	quarterly := &models.Subscription{BillingCycle: models.BillingCycleQuarterly, StartDate: start, EndDate: &end}
+	dates := quarterly.BillingDates(from, to)
+
+	assert.Equal(t, []time.Time{
+		time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC),
+	}, dates)
+
+	yearly := &models.Subscription{BillingCycle: models.BillingCycleYearly, StartDate: start}
+	assert.Empty(t, yearly.BillingDates(from, to))
+}
+
+func TestCalendarFeed_RequiresValidToken(t *testing.T) {
+	SetupRepo(t)
+	calendarService := service.NewCalendarService(repository.NewCalendarTokenRepository(db), testRepository)
+
+	var feed bytes.Buffer
+	err := calendarService.WriteFeed(context.Background(), &feed, calendarUserID, "missing")
+	require.NotNil(t, err)
+	assert.Equal(t, 404, err.Status())
+
+	issued, err := calendarService.IssueToken(context.Background(), calendarUserID)
+	require.Nil(t, err)
+	require.NotEmpty(t, issued.Token)
+
+	err = calendarService.WriteFeed(context.Background(), &feed, calendarUserID, issued.Token+"x")
+	require.NotNil(t, err)
+	assert.Equal(t, 404, err.Status())
+
+	rotated, err := calendarService.IssueToken(context.Background(), calendarUserID)
+	require.Nil(t, err)
+	assert.NotEqual(t, issued.Token, rotated.Token)
+
+	err = calendarService.WriteFeed(context.Background(), &feed, calendarUserID, issued.Token)
+	require.NotNil(t, err)
+	assert.Equal(t, 404, err.Status())
+
+	// Only the hash of the token is stored, and it is no token itself
+	stored, getErr := repository.NewCalendarTokenRepository(db).GetCalendarToken(context.Background(), calendarUserID)
+	require.NoError(t, getErr)
+	assert.NotEqual(t, rotated.Token, stored.TokenHash)
+	err = calendarService.WriteFeed(context.Background(), &feed, calendarUserID, stored.TokenHash)
+	require.NotNil(t, err)
+	assert.Equal(t, 404, err.Status())
+	require.Nil(t, calendarService.WriteFeed(context.Background(), &feed, calendarUserID, rotated.Token))
+}
+
+func TestCalendarFeed_ContainsRenewalsAndEndDate(t *testing.T) {
+	SetupRepo(t)
+	calendarService := service.NewCalendarService(repository.NewCalendarTokenRepository(db), testRepository)
+
+	now := time.Now().UTC()
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	endDate := thisMonth.AddDate(0, 2, 0)
+
+	subscription := &models.Subscription{
+		ServiceName:  "Netflix",
+		Price:        1500,
+		UserID:       calendarUserID,
+		BillingCycle: models.BillingCycleMonthly,
+		StartDate:    thisMonth,
+		EndDate:      &endDate,
+	}
+	require.NoError(t, testRepository.CreateSubscription(context.Background(), subscription))
+
+	issued, httpErr := calendarService.IssueToken(context.Background(), calendarUserID)
+	require.Nil(t, httpErr)
+
+	var feed bytes.Buffer
+	httpErr = calendarService.WriteFeed(context.Background(), &feed, calendarUserID, issued.Token)
+	require.Nil(t, httpErr)
+
+	content := feed.String()
+	assert.True(t, strings.HasPrefix(content, "BEGIN:VCALENDAR\r\n"))
+	assert.True(t, strings.HasSuffix(content, "END:VCALENDAR\r\n"))
+	assert.Equal(t, 4, strings.Count(content, "BEGIN:VEVENT"))
+	assert.Contains(t, content, fmt.Sprintf("UID:subscription-%d-renewal-%s@subscription-manager", subscription.ID, thisMonth.Format("20060102")))
+	assert.Contains(t, content, fmt.Sprintf("UID:subscription-%d-end@subscription-manager", subscription.ID))
+	assert.Contains(t, content, "DTSTART;VALUE=DATE:"+endDate.Format("20060102"))
+
+	var again bytes.Buffer
+	httpErr = calendarService.WriteFeed(context.Background(), &again, calendarUserID, issued.Token)
+	require.Nil(t, httpErr)
+	assert.Equal(t, content, again.String())
+}
@@ -0,0 +1,184 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const catalogUserID = "123e4567-e89b-12d3-a456-426614174000"
+
+func setupCatalog(t *testing.T) (service.CatalogService, service.SubscriptionService, *dto.ServiceResponse) {
+	SetupRepo(t)
+	catalogRepo := repository.NewCatalogRepository(db)
+	catalogService := service.NewCatalogService(catalogRepo)
+	subscriptionService := service.NewSubscriptionService(testRepository, service.WithCatalog(catalogRepo))
+
+	netflix, httpErr := catalogService.CreateService(context.Background(), dto.CreateServiceRequest{
+		Name:     "Netflix",
+		Aliases:  []string{"netflix.com", "Нетфликс", " "},
+		Category: "video",
+		Website:  "https://www.netflix.com",
+		Plans: []dto.CreatePlanRequest{
+			{Name: "Basic", Price: 599},
+			{Name: "Premium", Price: 1899, Currency: "USD", BillingCycle: models.BillingCycleYearly},
+		},
+	})
+	require.Nil(t, httpErr)
+
+	return catalogService, subscriptionService, netflix
+}
+
+func TestCatalog_CreateServiceRejectsTakenAlias(t *testing.T) {
+	catalogService, _, netflix := setupCatalog(t)
+
+	assert.Equal(t, []string{"netflix.com", "Нетфликс"}, netflix.Aliases)
+	require.Len(t, netflix.Plans, 2)
+	assert.Equal(t, models.DefaultCurrency, netflix.Plans[0].Currency)
+	assert.Equal(t, models.BillingCycleMonthly, netflix.Plans[0].BillingCycle)
+
+	_, httpErr := catalogService.CreateService(context.Background(), dto.CreateServiceRequest{
+		Name:    "Netflix Clone",
+		Aliases: []string{"NETFLIX COM"},
+	})
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 409, httpErr.Status())
+
+	_, httpErr = catalogService.CreatePlan(context.Background(), int(netflix.ID), dto.CreatePlanRequest{Name: "basic", Price: 1})
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 409, httpErr.Status())
+}
+
+func TestCatalog_MatchService(t *testing.T) {
+	catalogService, _, netflix := setupCatalog(t)
+
+	match, httpErr := catalogService.MatchService(context.Background(), "Netflx")
+	require.Nil(t, httpErr)
+	assert.Equal(t, netflix.ID, match.Service.ID)
+	assert.Nil(t, match.Plan)
+
+	match, httpErr = catalogService.MatchService(context.Background(), "netflix.com premium")
+	require.Nil(t, httpErr)
+	require.NotNil(t, match.Plan)
+	assert.Equal(t, "Premium", match.Plan.Name)
+
+	_, httpErr = catalogService.MatchService(context.Background(), "Spotify")
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 404, httpErr.Status())
+}
+
+func TestCreateSubscription_LinksCatalogService(t *testing.T) {
+	_, subscriptionService, netflix := setupCatalog(t)
+
+	custom, httpErr := subscriptionService.CreateSubscription(context.Background(), dto.CreateSubscriptionRequest{
+		ServiceName: "My Gym", Price: 3000, UserID: catalogUserID, StartDate: "01-2025",
+	})
+	require.Nil(t, httpErr)
+	assert.Equal(t, "My Gym", custom.ServiceName)
+	assert.Nil(t, custom.ServiceID)
+
+	byAlias, httpErr := subscriptionService.CreateSubscription(context.Background(), dto.CreateSubscriptionRequest{
+		ServiceName: "нетфликс", Price: 599, UserID: catalogUserID, StartDate: "01-2025",
+	})
+	require.Nil(t, httpErr)
+	assert.Equal(t, "Netflix", byAlias.ServiceName)
+	require.NotNil(t, byAlias.ServiceID)
+	assert.Equal(t, netflix.ID, *byAlias.ServiceID)
+	assert.Nil(t, byAlias.PlanID)
+
+	withPlanName, httpErr := subscriptionService.CreateSubscription(context.Background(), dto.CreateSubscriptionRequest{
+		ServiceName: "Netflix Premium", Price: 1999, UserID: catalogUserID, StartDate: "01-2025",
+	})
+	require.Nil(t, httpErr)
+	assert.Equal(t, "Netflix", withPlanName.ServiceName)
+	require.NotNil(t, withPlanName.PlanID)
+	assert.Equal(t, netflix.Plans[1].ID, *withPlanName.PlanID)
+	assert.Equal(t, int64(1999), withPlanName.Price)
+	assert.Equal(t, "USD", withPlanName.Currency)
+	assert.Equal(t, models.BillingCycleYearly, withPlanName.BillingCycle)
+
+	planID := netflix.Plans[0].ID
+	byPlan, httpErr := subscriptionService.CreateSubscription(context.Background(), dto.CreateSubscriptionRequest{
+		PlanID: &planID, UserID: catalogUserID, StartDate: "02-2025",
+	})
+	require.Nil(t, httpErr)
+	assert.Equal(t, "Netflix", byPlan.ServiceName)
+	assert.Equal(t, int64(599), byPlan.Price)
+	assert.Equal(t, netflix.ID, *byPlan.ServiceID)
+
+	missing := uint(9999)
+	_, httpErr = subscriptionService.CreateSubscription(context.Background(), dto.CreateSubscriptionRequest{
+		PlanID: &missing, UserID: catalogUserID, StartDate: "02-2025",
+	})
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 400, httpErr.Status())
+}
+
+func TestServiceNameFilter_UsesCatalog(t *testing.T) {
+	_, subscriptionService, _ := setupCatalog(t)
+
+	// Created before the catalog entry existed, so it is not linked
+	legacy := createTestSubscription("netflix.com", catalogUserID, "01-2025", "12-2025", 500)
+	require.NoError(t, testRepository.CreateSubscription(context.Background(), legacy))
+	other := createTestSubscription("Spotify", catalogUserID, "01-2025", "12-2025", 300)
+	require.NoError(t, testRepository.CreateSubscription(context.Background(), other))
+
+	for _, name := range []string{"Netflix", "Netflix Basic"} {
+		_, httpErr := subscriptionService.CreateSubscription(context.Background(), dto.CreateSubscriptionRequest{
+			ServiceName: name, Price: 600, UserID: catalogUserID, StartDate: "01-2025", EndDate: "12-2025",
+		})
+		require.Nil(t, httpErr)
+	}
+
+	filterName := "NETFLIX"
+	list, httpErr := subscriptionService.ListSubscriptions(context.Background(), dto.ListSubscriptionsQuery{ServiceName: &filterName})
+	require.Nil(t, httpErr)
+	assert.Equal(t, 3, list.Pagination.Total)
+
+	userID := catalogUserID
+	startDate, endDate := "01-2025", "12-2025"
+	total, httpErr := subscriptionService.CalculateTotalCost(context.Background(), dto.TotalCostQuery{
+		UserID: &userID, ServiceName: &filterName, StartDate: &startDate, EndDate: &endDate,
+	})
+	require.Nil(t, httpErr)
+	assert.Equal(t, int64(1700), total.TotalCost)
+
+	planName := "netflix basic"
+	list, httpErr = subscriptionService.ListSubscriptions(context.Background(), dto.ListSubscriptionsQuery{ServiceName: &planName})
+	require.Nil(t, httpErr)
+	assert.Equal(t, 1, list.Pagination.Total)
+
+	// Without a user filter every user is included
+	total, httpErr = subscriptionService.CalculateTotalCost(context.Background(), dto.TotalCostQuery{
+		StartDate: &startDate, EndDate: &endDate,
+	})
+	require.Nil(t, httpErr)
+	assert.Equal(t, int64(2000), total.TotalCost)
+}
+
+func TestDeleteCatalogService_UnlinksSubscriptions(t *testing.T) {
+	catalogService, subscriptionService, netflix := setupCatalog(t)
+
+	created, httpErr := subscriptionService.CreateSubscription(context.Background(), dto.CreateSubscriptionRequest{
+		ServiceName: "Netflix", Price: 600, UserID: catalogUserID, StartDate: "01-2025",
+	})
+	require.Nil(t, httpErr)
+	require.NotNil(t, created.ServiceID)
+
+	require.Nil(t, catalogService.DeleteService(context.Background(), int(netflix.ID)))
+
+	reloaded, httpErr := subscriptionService.GetSubscription(context.Background(), int(created.ID))
+	require.Nil(t, httpErr)
+	assert.Equal(t, "Netflix", reloaded.ServiceName)
+	assert.Nil(t, reloaded.ServiceID)
+
+	httpErr = catalogService.DeleteService(context.Background(), int(netflix.ID))
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 404, httpErr.Status())
+}
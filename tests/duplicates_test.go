@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func seedDuplicates(t *testing.T) {
+	firstPlan, secondPlan := uint(1), uint(2)
+	disneyBasic := createTestSubscription("Disney+", catalogUserID, "01-2025", "", 500)
+	disneyBasic.EndDate, disneyBasic.PlanID = nil, &firstPlan
+	disneyPremium := createTestSubscription("Disney+", catalogUserID, "04-2025", "", 900)
+	disneyPremium.EndDate, disneyPremium.PlanID = nil, &secondPlan
+
+	subscriptions := []any{
+		createTestSubscription("Netflix", catalogUserID, "01-2025", "06-2025", 600),
+		createTestSubscription("netflix ", catalogUserID, "03-2025", "12-2025", 400),
+		createTestSubscription("Spotify", catalogUserID, "01-2025", "12-2025", 300),
+		createTestSubscription("Spotify", catalogUserID, "01-2026", "12-2026", 300),
+		createTestSubscription("Netflix", otherBudgetUserID, "01-2025", "12-2025", 600),
+		disneyBasic,
+		disneyPremium,
+	}
+	for _, subscription := range subscriptions {
+		require.NoError(t, db.Create(subscription).Error)
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	SetupRepo(t)
+	seedDuplicates(t)
+
+	response, httpErr := service.NewDuplicateService(testRepository).FindDuplicates(context.Background(), catalogUserID)
+	require.Nil(t, httpErr)
+	require.Len(t, response.Groups, 2)
+
+	disney := response.Groups[0]
+	assert.Equal(t, dto.DuplicateKindMultiplePlans, disney.Kind)
+	assert.Len(t, disney.Subscriptions, 2)
+	assert.Nil(t, disney.OverlapEnd)
+	assert.Equal(t, int64(500), disney.MonthlyWaste)
+
+	netflix := response.Groups[1]
+	assert.Equal(t, dto.DuplicateKindOverlap, netflix.Kind)
+	assert.Len(t, netflix.Subscriptions, 2)
+	start, _ := netflix.OverlapStart.MarshalJSON()
+	assert.Equal(t, `"03-2025"`, string(start))
+	require.NotNil(t, netflix.OverlapEnd)
+	end, _ := netflix.OverlapEnd.MarshalJSON()
+	assert.Equal(t, `"06-2025"`, string(end))
+	assert.Equal(t, int64(400), netflix.MonthlyWaste)
+	assert.Equal(t, int64(1600), netflix.WastedSpend)
+
+	assert.Equal(t, disney.WastedSpend+1600, response.WastedSpend)
+}
+
+func TestCreateSubscription_WarnsAboutDuplicates(t *testing.T) {
+	SetupRepo(t)
+	seedDuplicates(t)
+	subscriptionService := service.NewSubscriptionService(testRepository, service.WithDuplicateCheck())
+
+	created, httpErr := subscriptionService.CreateSubscription(context.Background(), dto.CreateSubscriptionRequest{
+		ServiceName: "NETFLIX", Price: 500, UserID: catalogUserID, StartDate: "05-2025", EndDate: "05-2025",
+	})
+	require.Nil(t, httpErr)
+	assert.NotZero(t, created.ID)
+	assert.Len(t, created.Warnings, 2)
+
+	created, httpErr = subscriptionService.CreateSubscription(context.Background(), dto.CreateSubscriptionRequest{
+		ServiceName: "Netflix", Price: 500, UserID: catalogUserID, StartDate: "01-2027",
+	})
+	require.Nil(t, httpErr)
+	assert.Empty(t, created.Warnings)
+
+	// Without the option nothing is checked
+	created, httpErr = testService.CreateSubscription(context.Background(), dto.CreateSubscriptionRequest{
+		ServiceName: "Spotify", Price: 300, UserID: catalogUserID, StartDate: "05-2025",
+	})
+	require.Nil(t, httpErr)
+	assert.Empty(t, created.Warnings)
+}
@@ -0,0 +1,208 @@
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamSubscriptions_VisitsAllMatchingRows(t *testing.T) {
+	SetupRepo(t)
+	seedTestSubscriptions(t)
+
+	userID := "user-1"
+	var visited []*models.Subscription
+	err := testRepository.StreamSubscriptions(context.Background(), repository.SubscriptionFilter{UserID: &userID},
+		func(subscription *models.Subscription) error {
+			visited = append(visited, subscription)
+			return nil
+		})
+
+	require.NoError(t, err)
+	assert.Len(t, visited, 3)
+	for i := 1; i < len(visited); i++ {
+		assert.Less(t, visited[i-1].ID, visited[i].ID)
+	}
+}
+
+func TestExportSubscriptions_CSV(t *testing.T) {
+	SetupRepo(t)
+	seedTestSubscriptions(t)
+	exportService := service.NewExportService(testRepository)
+
+	serviceName := "Netflix"
+	query := dto.ExportSubscriptionsQuery{Format: dto.ExportFormatCSV}
+	query.ServiceName = &serviceName
+
+	var buf bytes.Buffer
+	err := exportService.ExportSubscriptions(context.Background(), &buf, query)
+	require.Nil(t, err)
+
+	records, csvErr := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, csvErr)
+	require.Len(t, records, 3)
+	assert.Equal(t, "service_name", records[0][1])
+	assert.Equal(t, "Netflix", records[1][1])
+	assert.Equal(t, "01-2025", records[1][6])
+}
+
+func TestExportSubscriptions_JSONLines(t *testing.T) {
+	SetupRepo(t)
+	subs := seedTestSubscriptions(t)
+	exportService := service.NewExportService(testRepository)
+
+	var buf bytes.Buffer
+	err := exportService.ExportSubscriptions(context.Background(), &buf, dto.ExportSubscriptionsQuery{Format: dto.ExportFormatJSONL})
+	require.Nil(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, len(subs))
+
+	var first map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	assert.Equal(t, "Netflix", first["service_name"])
+}
+
+func TestExportSubscriptions_XLSX(t *testing.T) {
+	SetupRepo(t)
+	seedTestSubscriptions(t)
+	exportService := service.NewExportService(testRepository)
+
+	var buf bytes.Buffer
+	err := exportService.ExportSubscriptions(context.Background(), &buf, dto.ExportSubscriptionsQuery{Format: dto.ExportFormatXLSX})
+	require.Nil(t, err)
+
+	archive, zipErr := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, zipErr)
+
+	var sheet string
+	for _, file := range archive.File {
+		if file.Name == "xl/worksheets/sheet1.xml" {
+			rc, openErr := file.Open()
+			require.NoError(t, openErr)
+			content, readErr := io.ReadAll(rc)
+			require.NoError(t, readErr)
+			rc.Close()
+			sheet = string(content)
+		}
+	}
+
+	require.NotEmpty(t, sheet)
+	assert.Equal(t, 6, strings.Count(sheet, "<row "))
+	assert.Contains(t, sheet, "Apple Music")
+	assert.Contains(t, sheet, "<v>1500</v>")
+}
+
+func TestExportSubscriptions_TagsAndCategory(t *testing.T) {
+	SetupRepo(t)
+	exportService := service.NewExportService(testRepository)
+	created, httpErr := testService.CreateSubscription(context.Background(), dto.CreateSubscriptionRequest{
+		ServiceName: "Slack", Price: 700, UserID: catalogUserID, StartDate: "01-2025",
+		Category: "work", Tags: []string{"Team", "chat"},
+	})
+	require.Nil(t, httpErr)
+
+	var buf bytes.Buffer
+	require.Nil(t, exportService.ExportSubscriptions(context.Background(), &buf, dto.ExportSubscriptionsQuery{Format: dto.ExportFormatJSONL}))
+	var exported struct {
+		Category string   `json:"category"`
+		Tags     []string `json:"tags"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &exported))
+	assert.ElementsMatch(t, created.Tags, exported.Tags)
+	assert.ElementsMatch(t, []string{"team", "chat"}, exported.Tags)
+	assert.Equal(t, "work", exported.Category)
+
+	buf.Reset()
+	require.Nil(t, exportService.ExportSubscriptions(context.Background(), &buf, dto.ExportSubscriptionsQuery{Format: dto.ExportFormatCSV}))
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	row := map[string]string{}
+	for idx, column := range records[0] {
+		row[column] = records[1][idx]
+	}
+	assert.Equal(t, "work", row["category"])
+	assert.ElementsMatch(t, []string{"team", "chat"}, strings.Split(row["tags"], ","))
+}
+
+func TestExportSubscriptions_InvalidFilterWritesNothing(t *testing.T) {
+	SetupRepo(t)
+	exportService := service.NewExportService(testRepository)
+
+	invalidDate := "2025-01"
+	query := dto.ExportSubscriptionsQuery{Format: dto.ExportFormatXLSX}
+	query.StartDateFrom = &invalidDate
+
+	var buf bytes.Buffer
+	err := exportService.ExportSubscriptions(context.Background(), &buf, query)
+
+	require.NotNil(t, err)
+	assert.Equal(t, 400, err.Status())
+	assert.Zero(t, buf.Len())
+}
+
+func TestExportSubscriptions_ResolvesFiltersLikeTheList(t *testing.T) {
+	setupCatalog(t)
+	organizationService := service.NewOrganizationService(repository.NewOrganizationRepository(db))
+	organization := createCompany(t, organizationService)
+	_, httpErr := organizationService.AcceptMember(context.Background(), int(organization.ID), otherBudgetUserID)
+	require.Nil(t, httpErr)
+	options := []service.SubscriptionServiceOption{
+		service.WithCatalog(repository.NewCatalogRepository(db)),
+		service.WithOrganizations(repository.NewOrganizationRepository(db)),
+	}
+	subscriptionService := service.NewSubscriptionService(testRepository, options...)
+	exportService := service.NewExportService(testRepository, options...)
+
+	for _, req := range []dto.CreateSubscriptionRequest{
+		{ServiceName: "netflix.com", Price: 599, UserID: catalogUserID},
+		{ServiceName: "Нетфликс", Price: 599, UserID: otherBudgetUserID},
+		{ServiceName: "Netflix", Price: 599, UserID: outsiderUserID},
+		{ServiceName: "Spotify", Price: 300, UserID: catalogUserID},
+	} {
+		req.StartDate = "01-2025"
+		_, httpErr = subscriptionService.CreateSubscription(context.Background(), req)
+		require.Nil(t, httpErr)
+	}
+
+	query := dto.ExportSubscriptionsQuery{Format: dto.ExportFormatJSONL}
+	query.ServiceName = strPtr("Netflix")
+	query.OrganizationID = &organization.ID
+
+	list, httpErr := subscriptionService.ListSubscriptions(context.Background(), query.ListSubscriptionsQuery)
+	require.Nil(t, httpErr)
+	require.Equal(t, 2, list.Pagination.Total)
+
+	var buf bytes.Buffer
+	require.Nil(t, exportService.ExportSubscriptions(context.Background(), &buf, query))
+	var exported []uint
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var row struct {
+			ID uint `json:"id"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(line), &row))
+		exported = append(exported, row.ID)
+	}
+	assert.ElementsMatch(t, listIDs(list), exported)
+
+	// Callers outside the organization cannot export it
+	query.ServiceName = nil
+	buf.Reset()
+	httpErr = exportService.ExportSubscriptions(principalCtx(outsiderUserID, false), &buf, query)
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 404, httpErr.Status())
+	assert.Zero(t, buf.Len())
+}
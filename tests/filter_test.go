@@ -0,0 +1,184 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/pkg/filter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterParse_Precedence(t *testing.T) {
+	node, err := filter.Parse("price > 1000 OR service_name ilike 'net%' and not (end_date is null)")
+	require.NoError(t, err)
+
+	assert.Equal(t, filter.Or{
+		Left: filter.Condition{Field: "price", Op: filter.OpGreater, Values: []filter.Value{{Text: "1000"}}},
+		Right: filter.And{
+			Left:  filter.Condition{Field: "service_name", Op: filter.OpILike, Values: []filter.Value{{Text: "net%", String: true}}},
+			Right: filter.Not{Operand: filter.Condition{Field: "end_date", Op: filter.OpIsNull}},
+		},
+	}, node)
+}
+
+func TestFilterParse_Conditions(t *testing.T) {
+	for input, expected := range map[string]filter.Condition{
+		"price <> 5":                    {Field: "price", Op: filter.OpNotEqual, Values: []filter.Value{{Text: "5"}}},
+		"price >= -1.5":                 {Field: "price", Op: filter.OpGreaterEqual, Values: []filter.Value{{Text: "-1.5"}}},
+		"Category NOT IN ('a', 'b''s')": {Field: "category", Op: filter.OpNotIn, Values: []filter.Value{{Text: "a", String: true}, {Text: "b's", String: true}}},
+		"service_name not like 'N%'":    {Field: "service_name", Op: filter.OpNotLike, Values: []filter.Value{{Text: "N%", String: true}}},
+		"end_date is not null":          {Field: "end_date", Op: filter.OpIsNotNull},
+		"currency='USD'":                {Field: "currency", Op: filter.OpEqual, Values: []filter.Value{{Text: "USD", String: true}}},
+		"(((price < 3)))":               {Field: "price", Op: filter.OpLess, Values: []filter.Value{{Text: "3"}}},
+	} {
+		node, err := filter.Parse(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, expected, node, input)
+	}
+}
+
+func TestFilterParse_SyntaxErrors(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"price >",
+		"price > 1000 and",
+		"price 1000",
+		"(price > 1",
+		"price > 1)",
+		"service_name = 'unterminated",
+		"service_name like 5",
+		"price ! 5",
+		"price in ()",
+		"end_date is nothing",
+		"and = 1",
+		"price > 1; DROP TABLE subscriptions",
+		"price > 1 -- comment",
+		"price > price",
+		strings.Repeat("price > 1 or ", 50) + "price > 1",
+		"price in (" + strings.Repeat("1, ", 100) + "1)",
+		strings.Repeat(" ", filter.MaxLength) + "price > 1",
+	} {
+		_, err := filter.Parse(input)
+		var syntaxErr *filter.SyntaxError
+		assert.True(t, errors.As(err, &syntaxErr), "%q: %v", input, err)
+	}
+}
+
+func TestFilterExpression_RejectsInvalidFields(t *testing.T) {
+	for input, target := range map[string]error{
+		"password = 'x'":          repository.ErrUnknownFilterField,
+		"tenant_id = 'other'":     repository.ErrUnknownFilterField,
+		"price = '1000'":          repository.ErrInvalidFilter,
+		"service_name = 5":        repository.ErrInvalidFilter,
+		"start_date > '2025'":     repository.ErrInvalidFilter,
+		"price like '1%'":         repository.ErrInvalidFilter,
+		"price is null":           repository.ErrInvalidFilter,
+		"price in (1, 'two')":     repository.ErrInvalidFilter,
+		"price > 1 and bogus < 2": repository.ErrUnknownFilterField,
+	} {
+		node, err := filter.Parse(input)
+		require.NoError(t, err, input)
+		_, err = repository.NewFilterExpression(node)
+		assert.ErrorIs(t, err, target, input)
+	}
+}
+
+func filteredIDs(t *testing.T, expression string) []uint {
+	return sortedIDs(t, dto.ListSubscriptionsQuery{Limit: 100, Sort: strPtr("id"), Filter: strPtr(expression)})
+}
+
+func TestFilter_ListSubscriptions(t *testing.T) {
+	ids := seedSorting(t,
+		dto.CreateSubscriptionRequest{ServiceName: "Netflix", Price: 1500, Category: "video"},
+		dto.CreateSubscriptionRequest{ServiceName: "NetEase Music", Price: 900, EndDate: "06-2025"},
+		dto.CreateSubscriptionRequest{ServiceName: "Spotify", Price: 1200, Category: "music", StartDate: "03-2025"},
+		dto.CreateSubscriptionRequest{ServiceName: "Yearly Netflix", Price: 12000, BillingCycle: "yearly"},
+		dto.CreateSubscriptionRequest{ServiceName: "O'Reilly", Price: 500},
+	)
+
+	for expression, expected := range map[string][]uint{
+		"price > 1000 and service_name ilike 'net%' and end_date is null": {ids[0]},
+		"price > 1000":                                     {ids[0], ids[2], ids[3]},
+		"service_name like 'Net%'":                         {ids[0], ids[1]},
+		"service_name ilike '%NETFLIX%'":                   {ids[0], ids[3]},
+		"service_name not ilike 'net%'":                    {ids[2], ids[3], ids[4]},
+		"category in ('video', 'music')":                   {ids[0], ids[2]},
+		"category not in ('video')":                        {ids[2]},
+		"category is null":                                 {ids[1], ids[3], ids[4]},
+		"category is not null":                             {ids[0], ids[2]},
+		"end_date is not null or start_date >= '03-2025'":  {ids[1], ids[2]},
+		"end_date < '2025-07-01'":                          {ids[1]},
+		"monthly_cost <= 1000":                             {ids[1], ids[3], ids[4]},
+		"monthly_cost = 1000 and billing_cycle = 'yearly'": {ids[3]},
+		"not (price < 1000 or price > 1400)":               {ids[2]},
+		"service_name = 'O''Reilly'":                       {ids[4]},
+		"price != 1500 and price <> 900 and id > 0":        {ids[2], ids[3], ids[4]},
+	} {
+		assert.Equal(t, expected, filteredIDs(t, expression), expression)
+	}
+
+	// The expression narrows the other filters and works with cursors
+	list, httpErr := testService.ListSubscriptions(context.Background(), dto.ListSubscriptionsQuery{
+		Limit: 1, After: strPtr(""), Sort: strPtr("id"), Category: strPtr("music"), Filter: strPtr("price > 1000"),
+	})
+	require.Nil(t, httpErr)
+	assert.Equal(t, []uint{ids[2]}, listIDs(list))
+	assert.Equal(t, 1, *list.Cursors.Total)
+	assert.Empty(t, list.Cursors.NextCursor)
+
+	// A blank expression filters nothing
+	assert.Len(t, filteredIDs(t, "  "), len(ids))
+}
+
+func TestFilter_KeepsUserScope(t *testing.T) {
+	ids := seedSorting(t, dto.CreateSubscriptionRequest{ServiceName: "Netflix", Price: 1500})
+	created, httpErr := testService.CreateSubscription(context.Background(), dto.CreateSubscriptionRequest{
+		ServiceName: "Netflix", Price: 1500, UserID: otherBudgetUserID, StartDate: "01-2025",
+	})
+	require.Nil(t, httpErr)
+
+	ctx := principalCtx(catalogUserID, false)
+	for _, expression := range []string{
+		"price > 0 or price <= 0",
+		"user_id = '" + otherBudgetUserID + "' or id > 0",
+		"service_name = 'x' or 1 = 1",
+		"id = " + fmt.Sprint(created.ID) + " or not (id = " + fmt.Sprint(created.ID) + ")",
+	} {
+		list, httpErr := testService.ListSubscriptions(ctx, dto.ListSubscriptionsQuery{Limit: 100, Filter: strPtr(expression)})
+		if httpErr != nil {
+			assert.Equal(t, http.StatusBadRequest, httpErr.Status(), expression)
+			continue
+		}
+		assert.Equal(t, ids, listIDs(list), expression)
+	}
+}
+
+func TestFilter_RejectsInvalidExpressions(t *testing.T) {
+	seedSorting(t, dto.CreateSubscriptionRequest{ServiceName: "Netflix", Price: 100})
+
+	for _, expression := range []string{
+		"price > 1; DROP TABLE subscriptions",
+		"price > 1 or 1=1",
+		"service_name = '' or ''=''",
+		"tenant_id != ''",
+		"price = 'abc'",
+		"service_name ilike",
+		"popularity > 3",
+	} {
+		_, httpErr := testService.ListSubscriptions(context.Background(), dto.ListSubscriptionsQuery{Limit: 10, Filter: strPtr(expression)})
+		require.NotNil(t, httpErr, expression)
+		assert.Equal(t, http.StatusBadRequest, httpErr.Status(), expression)
+	}
+
+	_, httpErr := testService.ListSubscriptions(context.Background(), dto.ListSubscriptionsQuery{Limit: 10, Filter: strPtr("popularity > 3")})
+	require.NotNil(t, httpErr)
+	assert.Contains(t, httpErr.Error(), "monthly_cost", "the error lists the filterable fields")
+	assert.Equal(t, int64(1), countSubscriptions(t))
+}
@@ -0,0 +1,146 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupForecast(t *testing.T) (service.ForecastService, map[string]*dto.SubscriptionResponse) {
+	SetupRepo(t)
+	forecastService := service.NewForecastService(testRepository, repository.NewPriceChangeRepository(db))
+
+	requests := []dto.CreateSubscriptionRequest{
+		{ServiceName: "Spotify", Price: 500, StartDate: "01-2025"},
+		{ServiceName: "iCloud", Price: 1200, BillingCycle: models.BillingCycleYearly, StartDate: "03-2025"},
+		{ServiceName: "Kinopoisk", Price: 300, StartDate: "01-2025", EndDate: "03-2026"},
+		{ServiceName: "YouTube", Price: 400, StartDate: "12-2025", TrialEndDate: "02-2026"},
+		{ServiceName: "Netflix", Price: 20, Currency: "USD", StartDate: "01-2025"},
+	}
+
+	created := make(map[string]*dto.SubscriptionResponse)
+	for _, req := range requests {
+		req.UserID = catalogUserID
+		response, httpErr := testService.CreateSubscription(context.Background(), req)
+		require.Nil(t, httpErr)
+		created[req.ServiceName] = response
+	}
+
+	other := createTestSubscription("Spotify", otherBudgetUserID, "01-2025", "12-2027", 500)
+	require.NoError(t, testRepository.CreateSubscription(context.Background(), other))
+
+	return forecastService, created
+}
+
+func TestForecast_ProjectsMonthlySpend(t *testing.T) {
+	forecastService, created := setupForecast(t)
+
+	_, httpErr := forecastService.SchedulePriceChange(context.Background(), int(created["Spotify"].ID), dto.CreatePriceChangeRequest{
+		EffectiveDate: "04-2026", Price: int64Ptr(600),
+	})
+	require.Nil(t, httpErr)
+
+	userID, start := catalogUserID, "01-2026"
+	forecast, httpErr := forecastService.Forecast(context.Background(), dto.ForecastQuery{UserID: &userID, Months: 6, Start: &start})
+	require.Nil(t, httpErr)
+
+	assert.Equal(t, models.DefaultCurrency, forecast.Currency)
+	require.Len(t, forecast.Months, 6)
+
+	var totals []int64
+	for _, month := range forecast.Months {
+		totals = append(totals, month.Total)
+	}
+	assert.Equal(t, []int64{800, 1200, 2400, 1000, 1000, 1000}, totals)
+	assert.Equal(t, int64(7400), forecast.Total)
+	assert.Equal(t, int64(13400), forecast.AnnualTotal)
+
+	var trialEnded []string
+	for _, charge := range forecast.Months[1].Charges {
+		if charge.TrialEnded {
+			trialEnded = append(trialEnded, charge.ServiceName)
+		}
+	}
+	assert.Equal(t, []string{"YouTube"}, trialEnded)
+
+	for _, charge := range forecast.Months[3].Charges {
+		if charge.ServiceName == "Spotify" {
+			assert.True(t, charge.PriceChanged)
+			assert.Equal(t, int64(600), charge.Amount)
+		}
+	}
+
+	usd, httpErr := forecastService.Forecast(context.Background(), dto.ForecastQuery{UserID: &userID, Months: 12, Start: &start, Currency: "USD"})
+	require.Nil(t, httpErr)
+	assert.Equal(t, int64(240), usd.Total)
+}
+
+func TestForecast_PriceChanges(t *testing.T) {
+	forecastService, created := setupForecast(t)
+	kinopoisk := int(created["Kinopoisk"].ID)
+
+	_, httpErr := forecastService.SchedulePriceChange(context.Background(), kinopoisk, dto.CreatePriceChangeRequest{
+		EffectiveDate: "05-2026", Price: int64Ptr(350),
+	})
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 400, httpErr.Status())
+
+	_, httpErr = forecastService.SchedulePriceChange(context.Background(), 9999, dto.CreatePriceChangeRequest{
+		EffectiveDate: "05-2026", Price: int64Ptr(350),
+	})
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 404, httpErr.Status())
+
+	change, httpErr := forecastService.SchedulePriceChange(context.Background(), kinopoisk, dto.CreatePriceChangeRequest{
+		EffectiveDate: "02-2026", Price: int64Ptr(350),
+	})
+	require.Nil(t, httpErr)
+
+	changes, httpErr := forecastService.ListPriceChanges(context.Background(), kinopoisk)
+	require.Nil(t, httpErr)
+	require.Len(t, changes.Data, 1)
+	assert.Equal(t, int64(350), changes.Data[0].Price)
+
+	require.Nil(t, forecastService.CancelPriceChange(context.Background(), kinopoisk, int(change.ID)))
+	httpErr = forecastService.CancelPriceChange(context.Background(), kinopoisk, int(change.ID))
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 404, httpErr.Status())
+}
+
+func TestForecast_SharedSubscriptions(t *testing.T) {
+	forecastService, _ := setupForecast(t)
+	shared, httpErr := testService.CreateSubscription(context.Background(), dto.CreateSubscriptionRequest{
+		ServiceName: "YouTube Family", Price: 1000, UserID: catalogUserID, StartDate: "01-2025",
+		SplitRule: models.SplitEqual, Members: []dto.SubscriptionMemberRequest{{UserID: otherBudgetUserID}},
+	})
+	require.Nil(t, httpErr)
+	_, httpErr = forecastService.SchedulePriceChange(context.Background(), int(shared.ID), dto.CreatePriceChangeRequest{
+		EffectiveDate: "02-2026", Price: int64Ptr(1200),
+	})
+	require.Nil(t, httpErr)
+
+	totals := func(userID string) []int64 {
+		start := "01-2026"
+		forecast, httpErr := forecastService.Forecast(context.Background(), dto.ForecastQuery{UserID: &userID, Months: 3, Start: &start})
+		require.Nil(t, httpErr)
+		var totals []int64
+		for _, month := range forecast.Months {
+			totals = append(totals, month.Total)
+		}
+		return totals
+	}
+
+	// Both pay half of the shared subscription, also after its price changes
+	assert.Equal(t, []int64{1300, 1800, 3000}, totals(catalogUserID))
+	assert.Equal(t, []int64{1000, 1100, 1100}, totals(otherBudgetUserID))
+}
+
+func int64Ptr(value int64) *int64 {
+	return &value
+}
@@ -0,0 +1,201 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasadov/subscription-manager/internal/handlers"
+	"github.com/rasadov/subscription-manager/internal/middleware"
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/internal/service"
+	"github.com/rasadov/subscription-manager/pkg/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupIdempotency(t *testing.T) (*gin.Engine, service.IdempotencyService) {
+	SetupRepo(t)
+	idempotency := service.NewIdempotencyService(repository.NewIdempotencyRepository(db), time.Hour)
+
+	gin.SetMode(gin.TestMode)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	verifier := &jwt.Verifier{Keys: []jwt.KeySet{jwt.Secret(testSecret)}}
+	router := gin.New()
+	router.Use(middleware.Authenticate(logger, middleware.NewJWTAuthenticator(verifier, "admin", "tenant")))
+	idempotent := middleware.Idempotency(idempotency, logger)
+	router.POST("/subscriptions", idempotent, handlers.NewSubscriptionHandler(testService, logger).CreateSubscription)
+
+	router.POST("/located", idempotent, func(c *gin.Context) {
+		c.Header("Location", "/subscriptions/42")
+		c.Header("X-Request-Detail", "not replayed")
+		c.JSON(http.StatusCreated, gin.H{"id": 42})
+	})
+
+	failures := 1
+	router.POST("/flaky", idempotent, func(c *gin.Context) {
+		if failures > 0 {
+			failures--
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "try again"})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"status": "done"})
+	})
+	return router, idempotency
+}
+
+func postIdempotent(t *testing.T, router *gin.Engine, path, subject, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+signToken(t, jwt.HS256, "", []byte(testSecret), userClaims(subject, nil)))
+	if key != "" {
+		req.Header.Set(middleware.IdempotencyKeyHeader, key)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func countSubscriptions(t *testing.T) int64 {
+	var count int64
+	require.NoError(t, db.Model(&models.Subscription{}).Count(&count).Error)
+	return count
+}
+
+const idempotentSubscription = `{"service_name": "Netflix", "price": 1000, "user_id": "` + catalogUserID + `", "start_date": "01-2025"}`
+
+func TestIdempotency_ReplaysResponse(t *testing.T) {
+	router, _ := setupIdempotency(t)
+
+	first := postIdempotent(t, router, "/subscriptions", catalogUserID, "tap-1", idempotentSubscription)
+	require.Equal(t, http.StatusCreated, first.Code, first.Body.String())
+	assert.Empty(t, first.Header().Get("Idempotent-Replayed"))
+
+	retry := postIdempotent(t, router, "/subscriptions", catalogUserID, "tap-1", idempotentSubscription)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, first.Header().Get("Content-Type"), retry.Header().Get("Content-Type"))
+	assert.JSONEq(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, int64(1), countSubscriptions(t))
+
+	// The key belongs to the caller that sent it
+	other := postIdempotent(t, router, "/subscriptions", otherBudgetUserID, "tap-1",
+		strings.ReplaceAll(idempotentSubscription, catalogUserID, otherBudgetUserID))
+	assert.Equal(t, http.StatusCreated, other.Code)
+	assert.Empty(t, other.Header().Get("Idempotent-Replayed"))
+
+	// Requests without a key are not deduplicated
+	require.Equal(t, http.StatusCreated, postIdempotent(t, router, "/subscriptions", catalogUserID, "", idempotentSubscription).Code)
+	require.Equal(t, http.StatusCreated, postIdempotent(t, router, "/subscriptions", catalogUserID, "", idempotentSubscription).Code)
+	assert.Equal(t, int64(4), countSubscriptions(t))
+}
+
+func TestIdempotency_Conflicts(t *testing.T) {
+	router, idempotency := setupIdempotency(t)
+
+	require.Equal(t, http.StatusCreated, postIdempotent(t, router, "/subscriptions", catalogUserID, "tap-1", idempotentSubscription).Code)
+	changed := postIdempotent(t, router, "/subscriptions", catalogUserID, "tap-1", strings.Replace(idempotentSubscription, "1000", "2000", 1))
+	assert.Equal(t, http.StatusConflict, changed.Code)
+	assert.Contains(t, changed.Body.String(), "different request")
+
+	// A key whose first request is still in progress
+	ctx := principalCtx(catalogUserID, false)
+	_, httpErr := idempotency.Reserve(ctx, "tap-2", "in-progress")
+	require.Nil(t, httpErr)
+	_, httpErr = idempotency.Reserve(ctx, "tap-2", "in-progress")
+	require.NotNil(t, httpErr)
+	assert.Equal(t, http.StatusConflict, httpErr.Status())
+
+	assert.Equal(t, http.StatusBadRequest, postIdempotent(t, router, "/subscriptions", catalogUserID, strings.Repeat("k", 256), idempotentSubscription).Code)
+	assert.Equal(t, int64(1), countSubscriptions(t))
+}
+
+func TestIdempotency_ServerErrorsAreNotStored(t *testing.T) {
+	router, _ := setupIdempotency(t)
+
+	assert.Equal(t, http.StatusServiceUnavailable, postIdempotent(t, router, "/flaky", catalogUserID, "retry-1", `{}`).Code)
+	retry := postIdempotent(t, router, "/flaky", catalogUserID, "retry-1", `{}`)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Empty(t, retry.Header().Get("Idempotent-Replayed"))
+
+	replay := postIdempotent(t, router, "/flaky", catalogUserID, "retry-1", `{}`)
+	assert.Equal(t, http.StatusCreated, replay.Code)
+	assert.Equal(t, "true", replay.Header().Get("Idempotent-Replayed"))
+}
+
+func TestIdempotency_Expiry(t *testing.T) {
+	router, idempotency := setupIdempotency(t)
+
+	require.Equal(t, http.StatusCreated, postIdempotent(t, router, "/subscriptions", catalogUserID, "tap-1", idempotentSubscription).Code)
+	require.NoError(t, db.Exec("UPDATE idempotency_records SET expires_at = ?", time.Now().Add(-time.Minute)).Error)
+
+	// Expired keys may be used again, even for other requests
+	changed := postIdempotent(t, router, "/subscriptions", catalogUserID, "tap-1", strings.Replace(idempotentSubscription, "1000", "2000", 1))
+	assert.Equal(t, http.StatusCreated, changed.Code)
+	assert.Empty(t, changed.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, int64(2), countSubscriptions(t))
+
+	require.NoError(t, db.Exec("UPDATE idempotency_records SET expires_at = ?", time.Now().Add(-time.Minute)).Error)
+	removed, err := idempotency.DeleteExpired(context.Background(), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+}
+
+func TestIdempotency_ReplaysHeaders(t *testing.T) {
+	router, _ := setupIdempotency(t)
+
+	first := postIdempotent(t, router, "/located", catalogUserID, "tap-1", `{}`)
+	require.Equal(t, http.StatusCreated, first.Code)
+
+	retry := postIdempotent(t, router, "/located", catalogUserID, "tap-1", `{}`)
+	assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, "/subscriptions/42", retry.Header().Get("Location"))
+	assert.Empty(t, retry.Header().Get("X-Request-Detail"))
+}
+
+func TestIdempotency_RejectsLargeBodies(t *testing.T) {
+	router, _ := setupIdempotency(t)
+
+	body := `{"service_name": "` + strings.Repeat("x", middleware.MaxIdempotentBodySize) + `"}`
+	assert.Equal(t, http.StatusRequestEntityTooLarge, postIdempotent(t, router, "/subscriptions", catalogUserID, "tap-1", body).Code)
+	assert.Zero(t, countSubscriptions(t))
+
+	var records int64
+	require.NoError(t, db.Model(&models.IdempotencyRecord{}).Count(&records).Error)
+	assert.Zero(t, records)
+}
+
+// failingCompletion is an idempotency store that cannot store responses.
+type failingCompletion struct {
+	service.IdempotencyService
+}
+
+func (failingCompletion) Complete(context.Context, *models.IdempotencyRecord) error {
+	return errors.New("store unavailable")
+}
+
+func TestIdempotency_FailedCompletionReleasesTheKey(t *testing.T) {
+	_, idempotency := setupIdempotency(t)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	verifier := &jwt.Verifier{Keys: []jwt.KeySet{jwt.Secret(testSecret)}}
+	router := gin.New()
+	router.Use(middleware.Authenticate(logger, middleware.NewJWTAuthenticator(verifier, "admin", "tenant")))
+	router.POST("/subscriptions", middleware.Idempotency(failingCompletion{idempotency}, logger),
+		handlers.NewSubscriptionHandler(testService, logger).CreateSubscription)
+
+	// Retries are handled again instead of being rejected as in progress
+	for range 2 {
+		retry := postIdempotent(t, router, "/subscriptions", catalogUserID, "tap-1", idempotentSubscription)
+		assert.Equal(t, http.StatusCreated, retry.Code)
+		assert.Empty(t, retry.Header().Get("Idempotent-Replayed"))
+	}
+	assert.Equal(t, int64(2), countSubscriptions(t))
+}
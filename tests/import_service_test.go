@@ -0,0 +1,238 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const importUserID = "123e4567-e89b-12d3-a456-426614174000"
+
+func TestImportCSV_AcceptsAndRejectsRows(t *testing.T) {
+	SetupRepo(t)
+	importService := service.NewImportService(testRepository)
+
+	csv := "service_name,price,user_id,start_date,end_date\n" +
+		"Netflix,1500," + importUserID + ",01-2025,12-2025\n" +
+		"Spotify,abc," + importUserID + ",01-2025,\n" +
+		",990,not-a-uuid,13-2025,\n"
+
+	result, err := importService.ImportCSV(context.Background(), strings.NewReader(csv), dto.ImportSubscriptionsQuery{})
+
+	require.Nil(t, err)
+	assert.Equal(t, 3, result.Total)
+	assert.Equal(t, 1, result.Accepted)
+	assert.Equal(t, 2, result.Rejected)
+
+	assert.Equal(t, dto.ImportStatusAccepted, result.Rows[0].Status)
+	assert.Equal(t, 2, result.Rows[0].Row)
+	assert.NotZero(t, result.Rows[0].ID)
+
+	assert.Equal(t, dto.ImportStatusRejected, result.Rows[1].Status)
+	assert.Contains(t, result.Rows[1].Errors[0], "invalid price")
+
+	assert.Equal(t, dto.ImportStatusRejected, result.Rows[2].Status)
+	assert.GreaterOrEqual(t, len(result.Rows[2].Errors), 2)
+
+	_, total, listErr := testRepository.ListSubscriptions(context.Background(), 1, 10, repository.SubscriptionFilter{}, nil)
+	require.NoError(t, listErr)
+	assert.Equal(t, int64(1), total)
+}
+
+func TestImportCSV_DryRunDoesNotPersist(t *testing.T) {
+	SetupRepo(t)
+	importService := service.NewImportService(testRepository)
+
+	csv := "service_name,price,user_id,start_date\nNetflix,1500," + importUserID + ",01-2025\n"
+
+	result, err := importService.ImportCSV(context.Background(), strings.NewReader(csv), dto.ImportSubscriptionsQuery{DryRun: true})
+
+	require.Nil(t, err)
+	assert.True(t, result.DryRun)
+	assert.Equal(t, 1, result.Accepted)
+	assert.Equal(t, dto.ImportActionCreate, result.Rows[0].Action)
+
+	_, total, listErr := testRepository.ListSubscriptions(context.Background(), 1, 10, repository.SubscriptionFilter{}, nil)
+	require.NoError(t, listErr)
+	assert.Equal(t, int64(0), total)
+}
+
+func TestImportCSV_ColumnMappingAndUpsert(t *testing.T) {
+	SetupRepo(t)
+	importService := service.NewImportService(testRepository)
+
+	query := dto.ImportSubscriptionsQuery{
+		Upsert:  true,
+		Mapping: "service_name=Service,price=Monthly Cost,user_id=Customer,start_date=Since",
+	}
+
+	first := "Service,Monthly Cost,Customer,Since\nNetflix,1500," + importUserID + ",01-2025\n"
+	result, err := importService.ImportCSV(context.Background(), strings.NewReader(first), query)
+	require.Nil(t, err)
+	require.Equal(t, 1, result.Accepted)
+	createdID := result.Rows[0].ID
+
+	second := "Service,Monthly Cost,Customer,Since\nNetflix,1700," + importUserID + ",01-2025\n"
+	result, err = importService.ImportCSV(context.Background(), strings.NewReader(second), query)
+	require.Nil(t, err)
+	require.Equal(t, 1, result.Accepted)
+	assert.Equal(t, dto.ImportActionUpdate, result.Rows[0].Action)
+	assert.Equal(t, createdID, result.Rows[0].ID)
+
+	updated, getErr := testRepository.GetSubscription(context.Background(), int(createdID))
+	require.NoError(t, getErr)
+	assert.Equal(t, int64(1700), updated.Price)
+}
+
+func TestImportCSV_InvalidMapping(t *testing.T) {
+	SetupRepo(t)
+	importService := service.NewImportService(testRepository)
+
+	result, err := importService.ImportCSV(context.Background(), strings.NewReader("a,b\n"),
+		dto.ImportSubscriptionsQuery{Mapping: "unknown=Header"})
+
+	assert.Nil(t, result)
+	require.NotNil(t, err)
+	assert.Equal(t, 400, err.Status())
+}
+
+func TestImportCSV_BehavesLikeTheCreateEndpoint(t *testing.T) {
+	_, _, netflix := setupCatalog(t)
+	observer := &recordingObserver{}
+	importService := service.NewImportService(testRepository,
+		service.WithCatalog(repository.NewCatalogRepository(db)), service.WithObserver(observer), service.WithDuplicateCheck())
+	query := dto.ImportSubscriptionsQuery{Upsert: true}
+
+	csv := "service_name,price,user_id,start_date\nnetflix.com,1500," + importUserID + ",01-2025\n"
+	result, err := importService.ImportCSV(context.Background(), strings.NewReader(csv), query)
+	require.Nil(t, err)
+	require.Equal(t, 1, result.Accepted)
+	createdID := result.Rows[0].ID
+
+	// The alias is linked to the catalog service
+	created, getErr := testRepository.GetSubscription(context.Background(), int(createdID))
+	require.NoError(t, getErr)
+	assert.Equal(t, "Netflix", created.ServiceName)
+	require.NotNil(t, created.ServiceID)
+	assert.Equal(t, netflix.ID, *created.ServiceID)
+	assert.Equal(t, "video", created.Category)
+
+	// The alias finds the row saved under the catalog name, and both changes are observed
+	csv = "service_name,price,user_id,start_date,end_date\nНетфликс,1700," + importUserID + ",01-2025,12-2025\n"
+	result, err = importService.ImportCSV(context.Background(), strings.NewReader(csv), query)
+	require.Nil(t, err)
+	require.Equal(t, 1, result.Accepted)
+	assert.Equal(t, dto.ImportActionUpdate, result.Rows[0].Action)
+	assert.Equal(t, createdID, result.Rows[0].ID)
+	assert.Equal(t, []uint{createdID, createdID}, observer.changed)
+
+	// A blank end date removes it again
+	csv = "service_name,price,user_id,start_date,end_date\nNetflix,1700," + importUserID + ",01-2025,\n"
+	_, err = importService.ImportCSV(context.Background(), strings.NewReader(csv), query)
+	require.Nil(t, err)
+	updated, getErr := testRepository.GetSubscription(context.Background(), int(createdID))
+	require.NoError(t, getErr)
+	assert.Nil(t, updated.EndDate)
+
+	// Overlapping rows are created with the duplicate warning
+	csv = "service_name,price,user_id,start_date\nNetflix,1500," + importUserID + ",03-2025\n"
+	result, err = importService.ImportCSV(context.Background(), strings.NewReader(csv), dto.ImportSubscriptionsQuery{})
+	require.Nil(t, err)
+	require.Equal(t, 1, result.Accepted)
+	assert.NotEmpty(t, result.Rows[0].Warnings)
+}
+
+func TestImportCSV_UpsertChecksOwnership(t *testing.T) {
+	SetupRepo(t)
+	importService := service.NewImportService(testRepository)
+
+	csv := "service_name,price,user_id,start_date\nNetflix,1500," + otherBudgetUserID + ",01-2025\n"
+	result, err := importService.ImportCSV(context.Background(), strings.NewReader(csv), dto.ImportSubscriptionsQuery{})
+	require.Nil(t, err)
+	require.Equal(t, 1, result.Accepted)
+	createdID := result.Rows[0].ID
+
+	csv = "service_name,price,user_id,start_date\nNetflix,1," + otherBudgetUserID + ",01-2025\n"
+	for _, dryRun := range []bool{true, false} {
+		result, err = importService.ImportCSV(principalCtx(catalogUserID, false), strings.NewReader(csv),
+			dto.ImportSubscriptionsQuery{Upsert: true, DryRun: dryRun})
+		require.Nil(t, err)
+		assert.Equal(t, 1, result.Rejected)
+	}
+
+	existing, getErr := testRepository.GetSubscription(context.Background(), int(createdID))
+	require.NoError(t, getErr)
+	assert.Equal(t, int64(1500), existing.Price)
+}
+
+// streamCountingRepository counts the subscriptions read with StreamSubscriptions.
+type streamCountingRepository struct {
+	repository.SubscriptionRepository
+	streams int
+}
+
+func (r *streamCountingRepository) StreamSubscriptions(ctx context.Context, filter repository.SubscriptionFilter, fn func(subscription *models.Subscription) error) error {
+	r.streams++
+	return r.SubscriptionRepository.StreamSubscriptions(ctx, filter, fn)
+}
+
+func TestImportCSV_ReadsExistingSubscriptionsOncePerUser(t *testing.T) {
+	SetupRepo(t)
+	existing := createTestSubscription("Netflix", importUserID, "01-2025", "12-2025", 1500)
+	require.NoError(t, testRepository.CreateSubscription(context.Background(), existing))
+	repo := &streamCountingRepository{SubscriptionRepository: testRepository}
+	importService := service.NewImportService(repo, service.WithDuplicateCheck())
+
+	csv := "service_name,price,user_id,start_date\n" +
+		"Spotify,300," + importUserID + ",01-2025\n" +
+		"Netflix,1500," + importUserID + ",02-2025\n" +
+		"Spotify,300," + importUserID + ",03-2025\n" +
+		"Spotify,300," + otherBudgetUserID + ",03-2025\n"
+	result, err := importService.ImportCSV(context.Background(), strings.NewReader(csv), dto.ImportSubscriptionsQuery{})
+	require.Nil(t, err)
+	require.Equal(t, 4, result.Accepted)
+	assert.Equal(t, 2, repo.streams)
+
+	// Rows are checked against the stored subscriptions and the ones imported before them
+	assert.Empty(t, result.Rows[0].Warnings)
+	require.Len(t, result.Rows[1].Warnings, 1)
+	assert.Contains(t, result.Rows[1].Warnings[0], fmt.Sprintf("subscription %d", existing.ID))
+	require.Len(t, result.Rows[2].Warnings, 1)
+	assert.Contains(t, result.Rows[2].Warnings[0], fmt.Sprintf("subscription %d", result.Rows[0].ID))
+	assert.Empty(t, result.Rows[3].Warnings)
+}
+
+func TestImportCSV_ReportsRowsSavedBeforeAReadError(t *testing.T) {
+	SetupRepo(t)
+	importService := service.NewImportService(testRepository)
+
+	csv := "service_name,price,user_id,start_date\n" +
+		"Netflix,1500," + importUserID + ",01-2025\n" +
+		"Spotify,300," + importUserID + ",01-2025\n"
+	input := io.MultiReader(strings.NewReader(csv), iotest.ErrReader(errors.New("connection reset")))
+	result, err := importService.ImportCSV(context.Background(), input, dto.ImportSubscriptionsQuery{})
+	require.Nil(t, err)
+	assert.Equal(t, 3, result.Total)
+	assert.Equal(t, 2, result.Accepted)
+	assert.Equal(t, 1, result.Rejected)
+
+	require.Len(t, result.Rows, 3)
+	assert.Equal(t, 4, result.Rows[2].Row)
+	assert.Equal(t, dto.ImportStatusRejected, result.Rows[2].Status)
+	assert.Equal(t, []string{"connection reset"}, result.Rows[2].Errors)
+
+	list, httpErr := testService.ListSubscriptions(context.Background(), dto.ListSubscriptionsQuery{UserID: strPtr(importUserID)})
+	require.Nil(t, httpErr)
+	assert.Equal(t, 2, list.Pagination.Total)
+}
@@ -6,6 +6,7 @@ import (
 	context "context"
 
 	models "github.com/rasadov/subscription-manager/internal/models"
+	repository "github.com/rasadov/subscription-manager/internal/repository"
 	mock "github.com/stretchr/testify/mock"
 
 	time "time"
@@ -16,9 +17,9 @@ type SubscriptionRepository struct {
 	mock.Mock
 }
 
-// CalculateTotalCost provides a mock function with given fields: ctx, userID, serviceName, startDate, endDate
-func (_m *SubscriptionRepository) CalculateTotalCost(ctx context.Context, userID string, serviceName string, startDate *time.Time, endDate *time.Time) (int64, error) {
-	ret := _m.Called(ctx, userID, serviceName, startDate, endDate)
+// CalculateTotalCost provides a mock function with given fields: ctx, filter
+func (_m *SubscriptionRepository) CalculateTotalCost(ctx context.Context, filter repository.SubscriptionFilter) (int64, error) {
+	ret := _m.Called(ctx, filter)
 
 	if len(ret) == 0 {
 		panic("no return value specified for CalculateTotalCost")
@@ -26,17 +27,75 @@ func (_m *SubscriptionRepository) CalculateTotalCost(ctx context.Context, userID
 
 	var r0 int64
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string, *time.Time, *time.Time) (int64, error)); ok {
-		return rf(ctx, userID, serviceName, startDate, endDate)
+	if rf, ok := ret.Get(0).(func(context.Context, repository.SubscriptionFilter) (int64, error)); ok {
+		return rf(ctx, filter)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string, string, *time.Time, *time.Time) int64); ok {
-		r0 = rf(ctx, userID, serviceName, startDate, endDate)
+	if rf, ok := ret.Get(0).(func(context.Context, repository.SubscriptionFilter) int64); ok {
+		r0 = rf(ctx, filter)
 	} else {
 		r0 = ret.Get(0).(int64)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string, string, *time.Time, *time.Time) error); ok {
-		r1 = rf(ctx, userID, serviceName, startDate, endDate)
+	if rf, ok := ret.Get(1).(func(context.Context, repository.SubscriptionFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CalculateCostByGroup provides a mock function with given fields: ctx, filter, groupBy
+func (_m *SubscriptionRepository) CalculateCostByGroup(ctx context.Context, filter repository.SubscriptionFilter, groupBy string) ([]*repository.CostGroup, error) {
+	ret := _m.Called(ctx, filter, groupBy)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CalculateCostByGroup")
+	}
+
+	var r0 []*repository.CostGroup
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, repository.SubscriptionFilter, string) ([]*repository.CostGroup, error)); ok {
+		return rf(ctx, filter, groupBy)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, repository.SubscriptionFilter, string) []*repository.CostGroup); ok {
+		r0 = rf(ctx, filter, groupBy)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*repository.CostGroup)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, repository.SubscriptionFilter, string) error); ok {
+		r1 = rf(ctx, filter, groupBy)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CountSubscriptions provides a mock function with given fields: ctx, filter
+func (_m *SubscriptionRepository) CountSubscriptions(ctx context.Context, filter repository.SubscriptionFilter) (int64, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountSubscriptions")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, repository.SubscriptionFilter) (int64, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, repository.SubscriptionFilter) int64); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, repository.SubscriptionFilter) error); ok {
+		r1 = rf(ctx, filter)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -110,9 +169,39 @@ func (_m *SubscriptionRepository) GetSubscription(ctx context.Context, id int) (
 	return r0, r1
 }
 
-// ListSubscriptions provides a mock function with given fields: ctx, page, elements, userID, serviceName, startDateFrom, startDateTo, endDateFrom, endDateTo, sortBy, sortOrder
-func (_m *SubscriptionRepository) ListSubscriptions(ctx context.Context, page int, elements int, userID *string, serviceName *string, startDateFrom *time.Time, startDateTo *time.Time, endDateFrom *time.Time, endDateTo *time.Time, sortBy *string, sortOrder *string) ([]*models.Subscription, int64, error) {
-	ret := _m.Called(ctx, page, elements, userID, serviceName, startDateFrom, startDateTo, endDateFrom, endDateTo, sortBy, sortOrder)
+// GetSubscriptionByNaturalKey provides a mock function with given fields: ctx, userID, serviceName, startDate
+func (_m *SubscriptionRepository) GetSubscriptionByNaturalKey(ctx context.Context, userID string, serviceName string, startDate time.Time) (*models.Subscription, error) {
+	ret := _m.Called(ctx, userID, serviceName, startDate)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSubscriptionByNaturalKey")
+	}
+
+	var r0 *models.Subscription
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time) (*models.Subscription, error)); ok {
+		return rf(ctx, userID, serviceName, startDate)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time) *models.Subscription); ok {
+		r0 = rf(ctx, userID, serviceName, startDate)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Subscription)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, time.Time) error); ok {
+		r1 = rf(ctx, userID, serviceName, startDate)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListSubscriptions provides a mock function with given fields: ctx, page, elements, filter, sort
+func (_m *SubscriptionRepository) ListSubscriptions(ctx context.Context, page int, elements int, filter repository.SubscriptionFilter, sort []repository.SortField) ([]*models.Subscription, int64, error) {
+	ret := _m.Called(ctx, page, elements, filter, sort)
 
 	if len(ret) == 0 {
 		panic("no return value specified for ListSubscriptions")
@@ -121,25 +210,25 @@ func (_m *SubscriptionRepository) ListSubscriptions(ctx context.Context, page in
 	var r0 []*models.Subscription
 	var r1 int64
 	var r2 error
-	if rf, ok := ret.Get(0).(func(context.Context, int, int, *string, *string, *time.Time, *time.Time, *time.Time, *time.Time, *string, *string) ([]*models.Subscription, int64, error)); ok {
-		return rf(ctx, page, elements, userID, serviceName, startDateFrom, startDateTo, endDateFrom, endDateTo, sortBy, sortOrder)
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, repository.SubscriptionFilter, []repository.SortField) ([]*models.Subscription, int64, error)); ok {
+		return rf(ctx, page, elements, filter, sort)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, int, int, *string, *string, *time.Time, *time.Time, *time.Time, *time.Time, *string, *string) []*models.Subscription); ok {
-		r0 = rf(ctx, page, elements, userID, serviceName, startDateFrom, startDateTo, endDateFrom, endDateTo, sortBy, sortOrder)
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, repository.SubscriptionFilter, []repository.SortField) []*models.Subscription); ok {
+		r0 = rf(ctx, page, elements, filter, sort)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]*models.Subscription)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, int, int, *string, *string, *time.Time, *time.Time, *time.Time, *time.Time, *string, *string) int64); ok {
-		r1 = rf(ctx, page, elements, userID, serviceName, startDateFrom, startDateTo, endDateFrom, endDateTo, sortBy, sortOrder)
+	if rf, ok := ret.Get(1).(func(context.Context, int, int, repository.SubscriptionFilter, []repository.SortField) int64); ok {
+		r1 = rf(ctx, page, elements, filter, sort)
 	} else {
 		r1 = ret.Get(1).(int64)
 	}
 
-	if rf, ok := ret.Get(2).(func(context.Context, int, int, *string, *string, *time.Time, *time.Time, *time.Time, *time.Time, *string, *string) error); ok {
-		r2 = rf(ctx, page, elements, userID, serviceName, startDateFrom, startDateTo, endDateFrom, endDateTo, sortBy, sortOrder)
+	if rf, ok := ret.Get(2).(func(context.Context, int, int, repository.SubscriptionFilter, []repository.SortField) error); ok {
+		r2 = rf(ctx, page, elements, filter, sort)
 	} else {
 		r2 = ret.Error(2)
 	}
@@ -147,6 +236,120 @@ func (_m *SubscriptionRepository) ListSubscriptions(ctx context.Context, page in
 	return r0, r1, r2
 }
 
+// ListSubscriptionsPage provides a mock function with given fields: ctx, filter, page
+func (_m *SubscriptionRepository) ListSubscriptionsPage(ctx context.Context, filter repository.SubscriptionFilter, page repository.SubscriptionPage) ([]*models.Subscription, *repository.Cursor, *repository.Cursor, error) {
+	ret := _m.Called(ctx, filter, page)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListSubscriptionsPage")
+	}
+
+	var r0 []*models.Subscription
+	var r1 *repository.Cursor
+	var r2 *repository.Cursor
+	var r3 error
+	if rf, ok := ret.Get(0).(func(context.Context, repository.SubscriptionFilter, repository.SubscriptionPage) ([]*models.Subscription, *repository.Cursor, *repository.Cursor, error)); ok {
+		return rf(ctx, filter, page)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, repository.SubscriptionFilter, repository.SubscriptionPage) []*models.Subscription); ok {
+		r0 = rf(ctx, filter, page)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Subscription)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, repository.SubscriptionFilter, repository.SubscriptionPage) *repository.Cursor); ok {
+		r1 = rf(ctx, filter, page)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*repository.Cursor)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, repository.SubscriptionFilter, repository.SubscriptionPage) *repository.Cursor); ok {
+		r2 = rf(ctx, filter, page)
+	} else {
+		if ret.Get(2) != nil {
+			r2 = ret.Get(2).(*repository.Cursor)
+		}
+	}
+
+	if rf, ok := ret.Get(3).(func(context.Context, repository.SubscriptionFilter, repository.SubscriptionPage) error); ok {
+		r3 = rf(ctx, filter, page)
+	} else {
+		r3 = ret.Error(3)
+	}
+
+	return r0, r1, r2, r3
+}
+
+// SearchSubscriptions provides a mock function with given fields: ctx, filter, query, limit
+func (_m *SubscriptionRepository) SearchSubscriptions(ctx context.Context, filter repository.SubscriptionFilter, query string, limit int) ([]*repository.SubscriptionMatch, error) {
+	ret := _m.Called(ctx, filter, query, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SearchSubscriptions")
+	}
+
+	var r0 []*repository.SubscriptionMatch
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, repository.SubscriptionFilter, string, int) ([]*repository.SubscriptionMatch, error)); ok {
+		return rf(ctx, filter, query, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, repository.SubscriptionFilter, string, int) []*repository.SubscriptionMatch); ok {
+		r0 = rf(ctx, filter, query, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*repository.SubscriptionMatch)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, repository.SubscriptionFilter, string, int) error); ok {
+		r1 = rf(ctx, filter, query, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// StreamSubscriptions provides a mock function with given fields: ctx, filter, fn
+func (_m *SubscriptionRepository) StreamSubscriptions(ctx context.Context, filter repository.SubscriptionFilter, fn func(*models.Subscription) error) error {
+	ret := _m.Called(ctx, filter, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for StreamSubscriptions")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, repository.SubscriptionFilter, func(*models.Subscription) error) error); ok {
+		r0 = rf(ctx, filter, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Transaction provides a mock function with given fields: ctx, fn
+func (_m *SubscriptionRepository) Transaction(ctx context.Context, fn func(repository.SubscriptionRepository) error) error {
+	ret := _m.Called(ctx, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Transaction")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, func(repository.SubscriptionRepository) error) error); ok {
+		r0 = rf(ctx, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UpdateSubscription provides a mock function with given fields: ctx, id, subscription
 func (_m *SubscriptionRepository) UpdateSubscription(ctx context.Context, id int, subscription *models.Subscription) error {
 	ret := _m.Called(ctx, id, subscription)
@@ -4,6 +4,7 @@ import (
 	"log"
 
 	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/pkg/database"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -18,7 +19,11 @@ func NewTestDB() *gorm.DB {
 		log.Fatal("failed to connect to test database:", err)
 	}
 
-	err = db.AutoMigrate(&models.Subscription{})
+	if err := database.RegisterTenantScope(db); err != nil {
+		log.Fatal("failed to register tenant scope:", err)
+	}
+
+	err = db.AutoMigrate(&models.Subscription{}, &models.CalendarToken{}, &models.SubscriptionDraft{}, &models.ReceiptRule{}, &models.Service{}, &models.Plan{}, &models.Tag{}, &models.Budget{}, &models.BudgetAlert{}, &models.PriceChange{}, &models.RecommendationState{}, &models.SubscriptionMember{}, &models.Organization{}, &models.OrganizationMember{}, &models.APIKey{}, &models.RoleAssignment{}, &models.Session{}, &models.RateLimitBucket{}, &models.IdempotencyRecord{})
 	if err != nil {
 		log.Fatal("failed to migrate test database:", err)
 	}
@@ -38,5 +43,10 @@ func ResetDB(db *gorm.DB) error {
 	if db == nil {
 		return nil
 	}
-	return db.Exec("DELETE FROM subscriptions").Error
+	for _, table := range []string{"recommendation_states", "price_changes", "budget_alerts", "budgets", "subscription_tags", "tags", "subscription_members", "subscriptions", "calendar_tokens", "subscription_drafts", "receipt_rules", "plans", "services", "organization_members", "organizations", "api_keys", "role_assignments", "sessions", "rate_limit_buckets", "idempotency_records"} {
+		if err := db.Exec("DELETE FROM " + table).Error; err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,157 @@
+package mocks
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// OIDCProvider is a local OpenID provider. Its authorization endpoint logs in
+// Subject without asking and redirects back with a code, which the token
+// endpoint only redeems with the PKCE verifier of the login.
+type OIDCProvider struct {
+	*httptest.Server
+	ClientID string
+	// Subject and Claims make up the ID tokens of the next logins.
+	Subject string
+	Claims  map[string]any
+
+	key   *rsa.PrivateKey
+	mu    sync.Mutex
+	codes map[string]authorization
+}
+
+type authorization struct {
+	redirectURI string
+	challenge   string
+	nonce       string
+}
+
+func NewOIDCProvider(clientID string) *OIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatal("failed to generate provider key:", err)
+	}
+
+	provider := &OIDCProvider{ClientID: clientID, Claims: map[string]any{}, key: key, codes: map[string]authorization{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", provider.discovery)
+	mux.HandleFunc("GET /jwks", provider.jwks)
+	mux.HandleFunc("GET /authorize", provider.authorize)
+	mux.HandleFunc("POST /token", provider.token)
+	provider.Server = httptest.NewServer(mux)
+	return provider
+}
+
+func (p *OIDCProvider) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                 p.URL,
+		"authorization_endpoint": p.URL + "/authorize",
+		"token_endpoint":         p.URL + "/token",
+		"jwks_uri":               p.URL + "/jwks",
+		"end_session_endpoint":   p.URL + "/logout",
+	})
+}
+
+func (p *OIDCProvider) jwks(w http.ResponseWriter, _ *http.Request) {
+	encode := base64.RawURLEncoding.EncodeToString
+	writeJSON(w, http.StatusOK, map[string]any{"keys": []map[string]any{{
+		"kty": "RSA", "kid": "mock", "use": "sig", "alg": "RS256",
+		"n": encode(p.key.N.Bytes()), "e": encode([]byte{1, 0, 1}),
+	}}})
+}
+
+func (p *OIDCProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != p.ClientID || query.Get("response_type") != "code" || query.Get("code_challenge_method") != "S256" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_request"})
+		return
+	}
+
+	code := randomString()
+	p.mu.Lock()
+	p.codes[code] = authorization{
+		redirectURI: query.Get("redirect_uri"),
+		challenge:   query.Get("code_challenge"),
+		nonce:       query.Get("nonce"),
+	}
+	p.mu.Unlock()
+
+	redirect := query.Get("redirect_uri") + "?" + url.Values{"code": {code}, "state": {query.Get("state")}}.Encode()
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (p *OIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_request"})
+		return
+	}
+
+	// Codes are single use
+	p.mu.Lock()
+	grant, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || r.PostForm.Get("grant_type") != "authorization_code" ||
+		r.PostForm.Get("client_id") != p.ClientID ||
+		r.PostForm.Get("redirect_uri") != grant.redirectURI ||
+		base64.RawURLEncoding.EncodeToString(sum[:]) != grant.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_grant"})
+		return
+	}
+
+	claims := map[string]any{
+		"iss":   p.URL,
+		"sub":   p.Subject,
+		"aud":   p.ClientID,
+		"nonce": grant.nonce,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}
+	for name, value := range p.Claims {
+		claims[name] = value
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"id_token":     p.sign(claims),
+		"expires_in":   3600,
+	})
+}
+
+func (p *OIDCProvider) sign(claims map[string]any) string {
+	segment := func(v any) string {
+		data, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	input := segment(map[string]any{"alg": "RS256", "kid": "mock", "typ": "JWT"}) + "." + segment(claims)
+	digest := sha256.Sum256([]byte(input))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		log.Fatal("failed to sign ID token:", err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func randomString() string {
+	data := make([]byte, 16)
+	rand.Read(data)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
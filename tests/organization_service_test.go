@@ -0,0 +1,340 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const outsiderUserID = "423e4567-e89b-12d3-a456-426614174000"
+
+func setupOrganizations(t *testing.T) (service.OrganizationService, service.SubscriptionService) {
+	SetupRepo(t)
+	organizationRepo := repository.NewOrganizationRepository(db)
+	organizationService := service.NewOrganizationService(organizationRepo)
+	subscriptionService := service.NewSubscriptionService(testRepository, service.WithOrganizations(organizationRepo))
+	return organizationService, subscriptionService
+}
+
+func createCompany(t *testing.T, organizationService service.OrganizationService) *dto.OrganizationResponse {
+	organization, httpErr := organizationService.CreateOrganization(context.Background(), dto.CreateOrganizationRequest{
+		Name:    " Acme ",
+		OwnerID: catalogUserID,
+		Members: []dto.AddOrganizationMemberRequest{
+			{UserID: otherBudgetUserID},
+			{UserID: thirdMemberUserID, Role: models.OrganizationRoleAdmin},
+		},
+	})
+	require.Nil(t, httpErr)
+	return organization
+}
+
+func TestCreateOrganization(t *testing.T) {
+	organizationService, _ := setupOrganizations(t)
+
+	organization := createCompany(t, organizationService)
+	assert.Equal(t, "Acme", organization.Name)
+	assert.Equal(t, models.OrganizationKindCompany, organization.Kind)
+	require.Len(t, organization.Members, 3)
+	assert.Equal(t, models.OrganizationRoleOwner, organization.Members[0].Role)
+	assert.Equal(t, models.OrganizationRoleMember, organization.Members[1].Role)
+	assert.Equal(t, models.OrganizationRoleAdmin, organization.Members[2].Role)
+
+	_, httpErr := organizationService.CreateOrganization(context.Background(), dto.CreateOrganizationRequest{
+		Name:    "Home",
+		OwnerID: catalogUserID,
+		Members: []dto.AddOrganizationMemberRequest{{UserID: catalogUserID}},
+	})
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 400, httpErr.Status())
+
+	_, httpErr = organizationService.CreateOrganization(context.Background(), dto.CreateOrganizationRequest{
+		Name:    "Home",
+		Kind:    models.OrganizationKindHousehold,
+		OwnerID: outsiderUserID,
+	})
+	require.Nil(t, httpErr)
+
+	list, httpErr := organizationService.ListOrganizations(context.Background(), dto.ListOrganizationsQuery{UserID: strPtr(otherBudgetUserID)})
+	require.Nil(t, httpErr)
+	require.Len(t, list.Data, 1)
+	assert.Equal(t, organization.ID, list.Data[0].ID)
+
+	list, httpErr = organizationService.ListOrganizations(context.Background(), dto.ListOrganizationsQuery{Kind: strPtr(models.OrganizationKindHousehold)})
+	require.Nil(t, httpErr)
+	require.Len(t, list.Data, 1)
+	assert.Equal(t, "Home", list.Data[0].Name)
+
+	require.Nil(t, organizationService.DeleteOrganization(context.Background(), int(organization.ID)))
+	_, httpErr = organizationService.GetOrganization(context.Background(), int(organization.ID))
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 404, httpErr.Status())
+}
+
+func TestOrganizationMembers(t *testing.T) {
+	organizationService, _ := setupOrganizations(t)
+	organization := createCompany(t, organizationService)
+	id := int(organization.ID)
+
+	_, httpErr := organizationService.AddMember(context.Background(), id, dto.AddOrganizationMemberRequest{UserID: otherBudgetUserID})
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 409, httpErr.Status())
+
+	member, httpErr := organizationService.AddMember(context.Background(), id, dto.AddOrganizationMemberRequest{UserID: outsiderUserID})
+	require.Nil(t, httpErr)
+	assert.Equal(t, models.OrganizationRoleMember, member.Role)
+
+	// The last owner can neither be demoted nor removed
+	_, httpErr = organizationService.UpdateMember(context.Background(), id, catalogUserID, dto.UpdateOrganizationMemberRequest{Role: models.OrganizationRoleAdmin})
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 400, httpErr.Status())
+	httpErr = organizationService.RemoveMember(context.Background(), id, catalogUserID)
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 400, httpErr.Status())
+
+	member, httpErr = organizationService.UpdateMember(context.Background(), id, thirdMemberUserID, dto.UpdateOrganizationMemberRequest{Role: models.OrganizationRoleOwner})
+	require.Nil(t, httpErr)
+	assert.Equal(t, models.OrganizationRoleOwner, member.Role)
+
+	// An owner who has not accepted yet does not count
+	httpErr = organizationService.RemoveMember(context.Background(), id, catalogUserID)
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 400, httpErr.Status())
+	_, httpErr = organizationService.AcceptMember(context.Background(), id, thirdMemberUserID)
+	require.Nil(t, httpErr)
+	require.Nil(t, organizationService.RemoveMember(context.Background(), id, catalogUserID))
+
+	httpErr = organizationService.RemoveMember(context.Background(), id, catalogUserID)
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 404, httpErr.Status())
+
+	reloaded, httpErr := organizationService.GetOrganization(context.Background(), id)
+	require.Nil(t, httpErr)
+	var userIDs []string
+	for _, member := range reloaded.Members {
+		userIDs = append(userIDs, member.UserID)
+	}
+	assert.ElementsMatch(t, []string{otherBudgetUserID, thirdMemberUserID, outsiderUserID}, userIDs)
+}
+
+func TestSubscriptionsScopedByOrganization(t *testing.T) {
+	organizationService, subscriptionService := setupOrganizations(t)
+	organization := createCompany(t, organizationService)
+	_, httpErr := organizationService.AcceptMember(context.Background(), int(organization.ID), otherBudgetUserID)
+	require.Nil(t, httpErr)
+
+	requests := []dto.CreateSubscriptionRequest{
+		{ServiceName: "Slack", Price: 700, UserID: catalogUserID},
+		{ServiceName: "GitHub", Price: 500, UserID: otherBudgetUserID},
+		{ServiceName: "Netflix", Price: 600, UserID: outsiderUserID},
+		// Shared with the organization owner, who pays 250
+		{ServiceName: "Figma", Price: 1000, UserID: outsiderUserID, SplitRule: models.SplitFixed,
+			Members: []dto.SubscriptionMemberRequest{{UserID: catalogUserID, Share: 250}}},
+	}
+	for _, req := range requests {
+		req.StartDate = "01-2025"
+		req.EndDate = "12-2025"
+		_, httpErr := subscriptionService.CreateSubscription(context.Background(), req)
+		require.Nil(t, httpErr)
+	}
+
+	list, httpErr := subscriptionService.ListSubscriptions(context.Background(), dto.ListSubscriptionsQuery{OrganizationID: &organization.ID})
+	require.Nil(t, httpErr)
+	assert.Equal(t, 2, list.Pagination.Total)
+
+	list, httpErr = subscriptionService.ListSubscriptions(context.Background(), dto.ListSubscriptionsQuery{OrganizationID: &organization.ID, IncludeShared: true})
+	require.Nil(t, httpErr)
+	assert.Equal(t, 3, list.Pagination.Total)
+
+	// Combined with a user the filters intersect
+	list, httpErr = subscriptionService.ListSubscriptions(context.Background(), dto.ListSubscriptionsQuery{OrganizationID: &organization.ID, UserID: strPtr(outsiderUserID)})
+	require.Nil(t, httpErr)
+	assert.Zero(t, list.Pagination.Total)
+
+	total, httpErr := subscriptionService.CalculateTotalCost(context.Background(), dto.TotalCostQuery{
+		OrganizationID: &organization.ID,
+		StartDate:      strPtr("01-2025"),
+		EndDate:        strPtr("12-2025"),
+	})
+	require.Nil(t, httpErr)
+	assert.Equal(t, int64(700+500+250), total.TotalCost)
+
+	missing := uint(9999)
+	_, httpErr = subscriptionService.CalculateTotalCost(context.Background(), dto.TotalCostQuery{
+		OrganizationID: &missing,
+		StartDate:      strPtr("01-2025"),
+		EndDate:        strPtr("12-2025"),
+	})
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 404, httpErr.Status())
+}
+
+func TestOrganizationReadsSkipInvitedUsers(t *testing.T) {
+	organizationService, subscriptionService := setupOrganizations(t)
+	ownerCtx := principalCtx(catalogUserID, false)
+
+	// An owner listing someone who never agreed cannot read their subscriptions
+	organization, httpErr := organizationService.CreateOrganization(ownerCtx, dto.CreateOrganizationRequest{
+		Name: "Snoop", OwnerID: catalogUserID, Members: []dto.AddOrganizationMemberRequest{{UserID: otherBudgetUserID}},
+	})
+	require.Nil(t, httpErr)
+	_, httpErr = subscriptionService.CreateSubscription(context.Background(), dto.CreateSubscriptionRequest{
+		ServiceName: "Spotify", Price: 300, UserID: otherBudgetUserID, StartDate: "01-2025", EndDate: "12-2025",
+	})
+	require.Nil(t, httpErr)
+
+	list, httpErr := subscriptionService.ListSubscriptions(ownerCtx, dto.ListSubscriptionsQuery{OrganizationID: &organization.ID})
+	require.Nil(t, httpErr)
+	assert.Zero(t, list.Pagination.Total)
+	list, httpErr = subscriptionService.ListSubscriptions(ownerCtx, dto.ListSubscriptionsQuery{
+		OrganizationID: &organization.ID, UserID: strPtr(otherBudgetUserID),
+	})
+	require.Nil(t, httpErr)
+	assert.Zero(t, list.Pagination.Total)
+
+	search, httpErr := subscriptionService.SearchSubscriptions(ownerCtx, dto.SearchSubscriptionsQuery{
+		Q: "spotify", OrganizationID: &organization.ID, Limit: 20,
+	})
+	require.Nil(t, httpErr)
+	assert.Empty(t, search.Data)
+
+	totalCostQuery := dto.TotalCostQuery{OrganizationID: &organization.ID, StartDate: strPtr("01-2025"), EndDate: strPtr("12-2025")}
+	total, httpErr := subscriptionService.CalculateTotalCost(ownerCtx, totalCostQuery)
+	require.Nil(t, httpErr)
+	assert.Zero(t, total.TotalCost)
+
+	// Once the user accepts, their subscriptions are part of the organization
+	_, httpErr = organizationService.AcceptMember(principalCtx(otherBudgetUserID, false), int(organization.ID), otherBudgetUserID)
+	require.Nil(t, httpErr)
+	total, httpErr = subscriptionService.CalculateTotalCost(ownerCtx, totalCostQuery)
+	require.Nil(t, httpErr)
+	assert.Equal(t, int64(300), total.TotalCost)
+}
+
+func TestHouseholdMembers(t *testing.T) {
+	organizationService, _ := setupOrganizations(t)
+	createCompany(t, organizationService)
+
+	members, err := organizationService.HouseholdMembers(context.Background(), catalogUserID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{catalogUserID}, members)
+
+	_, httpErr := organizationService.CreateOrganization(context.Background(), dto.CreateOrganizationRequest{
+		Name:    "Home",
+		Kind:    models.OrganizationKindHousehold,
+		OwnerID: catalogUserID,
+		Members: []dto.AddOrganizationMemberRequest{{UserID: outsiderUserID}},
+	})
+	require.Nil(t, httpErr)
+
+	household, httpErr := organizationService.ListOrganizations(context.Background(), dto.ListOrganizationsQuery{Kind: strPtr(models.OrganizationKindHousehold)})
+	require.Nil(t, httpErr)
+	require.Len(t, household.Data, 1)
+
+	// Until the invitation is accepted the households are not shared
+	for _, userID := range []string{catalogUserID, outsiderUserID} {
+		members, err = organizationService.HouseholdMembers(context.Background(), userID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{userID}, members)
+	}
+
+	_, httpErr = organizationService.AcceptMember(context.Background(), int(household.Data[0].ID), outsiderUserID)
+	require.Nil(t, httpErr)
+	members, err = organizationService.HouseholdMembers(context.Background(), outsiderUserID)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{catalogUserID, outsiderUserID}, members)
+}
+
+func TestOrganizationInvitations(t *testing.T) {
+	organizationService, _ := setupOrganizations(t)
+	organization := createCompany(t, organizationService)
+	id := int(organization.ID)
+
+	require.Len(t, organization.Members, 3)
+	assert.NotNil(t, organization.Members[0].AcceptedAt, "the owner creating the organization is a member right away")
+	assert.Nil(t, organization.Members[1].AcceptedAt)
+	assert.Nil(t, organization.Members[2].AcceptedAt)
+
+	// An invited admin can see the organization but not manage it
+	adminCtx := principalCtx(thirdMemberUserID, false)
+	_, httpErr := organizationService.GetOrganization(adminCtx, id)
+	require.Nil(t, httpErr)
+	_, httpErr = organizationService.AddMember(adminCtx, id, dto.AddOrganizationMemberRequest{UserID: outsiderUserID})
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 403, httpErr.Status())
+
+	// Only the invited user can accept
+	_, httpErr = organizationService.AcceptMember(principalCtx(catalogUserID, false), id, thirdMemberUserID)
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 403, httpErr.Status())
+	_, httpErr = organizationService.AcceptMember(principalCtx(outsiderUserID, false), id, outsiderUserID)
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 404, httpErr.Status())
+
+	member, httpErr := organizationService.AcceptMember(adminCtx, id, thirdMemberUserID)
+	require.Nil(t, httpErr)
+	assert.NotNil(t, member.AcceptedAt)
+	_, httpErr = organizationService.AddMember(adminCtx, id, dto.AddOrganizationMemberRequest{UserID: outsiderUserID})
+	require.Nil(t, httpErr)
+
+	// Declining is leaving
+	require.Nil(t, organizationService.RemoveMember(principalCtx(otherBudgetUserID, false), id, otherBudgetUserID))
+	reloaded, httpErr := organizationService.GetOrganization(context.Background(), id)
+	require.Nil(t, httpErr)
+	assert.Len(t, reloaded.Members, 3)
+}
+
+func TestOrganizationAdminsCannotAssignOwners(t *testing.T) {
+	organizationService, _ := setupOrganizations(t)
+	organization := createCompany(t, organizationService)
+	id := int(organization.ID)
+	adminCtx := principalCtx(thirdMemberUserID, false)
+	_, httpErr := organizationService.AcceptMember(adminCtx, id, thirdMemberUserID)
+	require.Nil(t, httpErr)
+
+	// An admin can neither promote themselves or others nor invite an owner
+	_, httpErr = organizationService.UpdateMember(adminCtx, id, thirdMemberUserID, dto.UpdateOrganizationMemberRequest{Role: models.OrganizationRoleOwner})
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 403, httpErr.Status())
+	_, httpErr = organizationService.UpdateMember(adminCtx, id, otherBudgetUserID, dto.UpdateOrganizationMemberRequest{Role: models.OrganizationRoleOwner})
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 403, httpErr.Status())
+	_, httpErr = organizationService.AddMember(adminCtx, id, dto.AddOrganizationMemberRequest{UserID: outsiderUserID, Role: models.OrganizationRoleOwner})
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 403, httpErr.Status())
+
+	// nor demote or remove the owner
+	_, httpErr = organizationService.UpdateMember(adminCtx, id, catalogUserID, dto.UpdateOrganizationMemberRequest{Role: models.OrganizationRoleMember})
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 403, httpErr.Status())
+	httpErr = organizationService.RemoveMember(adminCtx, id, catalogUserID)
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 403, httpErr.Status())
+
+	// Admins still manage other members
+	_, httpErr = organizationService.UpdateMember(adminCtx, id, otherBudgetUserID, dto.UpdateOrganizationMemberRequest{Role: models.OrganizationRoleAdmin})
+	require.Nil(t, httpErr)
+
+	// The owner can hand the role on
+	ownerCtx := principalCtx(catalogUserID, false)
+	promoted, httpErr := organizationService.UpdateMember(ownerCtx, id, thirdMemberUserID, dto.UpdateOrganizationMemberRequest{Role: models.OrganizationRoleOwner})
+	require.Nil(t, httpErr)
+	assert.Equal(t, models.OrganizationRoleOwner, promoted.Role)
+	_, httpErr = organizationService.UpdateMember(adminCtx, id, catalogUserID, dto.UpdateOrganizationMemberRequest{Role: models.OrganizationRoleAdmin})
+	require.Nil(t, httpErr)
+
+	reloaded, httpErr := organizationService.GetOrganization(context.Background(), id)
+	require.Nil(t, httpErr)
+	roles := map[string]string{}
+	for _, member := range reloaded.Members {
+		roles[member.UserID] = member.Role
+	}
+	assert.Equal(t, models.OrganizationRoleAdmin, roles[catalogUserID])
+	assert.Equal(t, models.OrganizationRoleOwner, roles[thirdMemberUserID])
+}
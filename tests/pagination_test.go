@@ -0,0 +1,172 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/handlers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seedPagination creates subscriptions with the prices, in order.
+func seedPagination(t *testing.T, prices ...int64) []uint {
+	SetupRepo(t)
+	ids := make([]uint, 0, len(prices))
+	for _, price := range prices {
+		created, httpErr := testService.CreateSubscription(context.Background(), dto.CreateSubscriptionRequest{
+			ServiceName: "Netflix",
+			Price:       price,
+			UserID:      catalogUserID,
+			StartDate:   "01-2025",
+		})
+		require.Nil(t, httpErr)
+		ids = append(ids, created.ID)
+	}
+	return ids
+}
+
+func listIDs(list *dto.ListSubscriptionsResponse) []uint {
+	ids := make([]uint, 0, len(list.Data))
+	for _, subscription := range list.Data {
+		ids = append(ids, subscription.ID)
+	}
+	return ids
+}
+
+func TestCursorPagination_WalksBothWays(t *testing.T) {
+	ids := seedPagination(t, 300, 100, 200, 100, 300)
+	// Prices ascending, equal prices by id
+	expected := []uint{ids[1], ids[3], ids[2], ids[0], ids[4]}
+	query := dto.ListSubscriptionsQuery{Limit: 2, SortBy: strPtr("price"), SortOrder: strPtr("asc"), After: strPtr("")}
+
+	var forward [][]uint
+	var pages []*dto.ListSubscriptionsResponse
+	for {
+		list, httpErr := testService.ListSubscriptions(context.Background(), query)
+		require.Nil(t, httpErr)
+		require.Nil(t, list.Pagination)
+		require.NotNil(t, list.Cursors.Total)
+		assert.Equal(t, 5, *list.Cursors.Total)
+		forward = append(forward, listIDs(list))
+		pages = append(pages, list)
+		if list.Cursors.NextCursor == "" {
+			break
+		}
+		query.After = &list.Cursors.NextCursor
+	}
+	assert.Equal(t, [][]uint{expected[0:2], expected[2:4], expected[4:5]}, forward)
+	assert.Empty(t, pages[0].Cursors.PrevCursor, "the first page has no previous page")
+
+	// Going back from the last page returns the same pages
+	query.After = nil
+	query.Before = &pages[2].Cursors.PrevCursor
+	list, httpErr := testService.ListSubscriptions(context.Background(), query)
+	require.Nil(t, httpErr)
+	assert.Equal(t, expected[2:4], listIDs(list))
+	require.NotEmpty(t, list.Cursors.PrevCursor)
+	assert.NotEmpty(t, list.Cursors.NextCursor)
+
+	query.Before = &list.Cursors.PrevCursor
+	list, httpErr = testService.ListSubscriptions(context.Background(), query)
+	require.Nil(t, httpErr)
+	assert.Equal(t, expected[0:2], listIDs(list))
+	assert.Empty(t, list.Cursors.PrevCursor)
+}
+
+func TestCursorPagination_StableWhileRowsAreInserted(t *testing.T) {
+	ids := seedPagination(t, 100, 200, 300, 400)
+	query := dto.ListSubscriptionsQuery{Limit: 2, After: strPtr("")}
+
+	first, httpErr := testService.ListSubscriptions(context.Background(), query)
+	require.Nil(t, httpErr)
+	assert.Equal(t, []uint{ids[3], ids[2]}, listIDs(first))
+
+	// A new subscription sorts before the cursor and does not shift the next page
+	seeded := seedPaginationRow(t, 500)
+	query.After = &first.Cursors.NextCursor
+	second, httpErr := testService.ListSubscriptions(context.Background(), query)
+	require.Nil(t, httpErr)
+	assert.Equal(t, []uint{ids[1], ids[0]}, listIDs(second))
+	assert.NotContains(t, listIDs(second), seeded)
+}
+
+func seedPaginationRow(t *testing.T, price int64) uint {
+	created, httpErr := testService.CreateSubscription(context.Background(), dto.CreateSubscriptionRequest{
+		ServiceName: "Hulu",
+		Price:       price,
+		UserID:      catalogUserID,
+		StartDate:   "02-2025",
+	})
+	require.Nil(t, httpErr)
+	return created.ID
+}
+
+func TestCursorPagination_SkipTotal(t *testing.T) {
+	seedPagination(t, 100, 200)
+
+	list, httpErr := testService.ListSubscriptions(context.Background(), dto.ListSubscriptionsQuery{Limit: 10, After: strPtr(""), SkipTotal: true})
+	require.Nil(t, httpErr)
+	assert.Len(t, list.Data, 2)
+	assert.Nil(t, list.Cursors.Total)
+	assert.Empty(t, list.Cursors.NextCursor)
+}
+
+func TestCursorPagination_InvalidRequests(t *testing.T) {
+	seedPagination(t, 100, 200, 300)
+
+	first, httpErr := testService.ListSubscriptions(context.Background(), dto.ListSubscriptionsQuery{Limit: 1, After: strPtr("")})
+	require.Nil(t, httpErr)
+	require.NotEmpty(t, first.Cursors.NextCursor)
+
+	for name, query := range map[string]dto.ListSubscriptionsQuery{
+		"garbage cursor":        {Limit: 1, After: strPtr("not a cursor")},
+		"other sort order":      {Limit: 1, After: &first.Cursors.NextCursor, SortBy: strPtr("price")},
+		"nullable sort field":   {Limit: 1, After: strPtr(""), SortBy: strPtr("end_date")},
+		"after and before":      {Limit: 1, After: &first.Cursors.NextCursor, Before: &first.Cursors.NextCursor},
+		"before without cursor": {Limit: 1, Before: strPtr("")},
+	} {
+		_, httpErr := testService.ListSubscriptions(context.Background(), query)
+		require.NotNil(t, httpErr, name)
+		assert.Equal(t, http.StatusBadRequest, httpErr.Status(), name)
+	}
+}
+
+func TestCursorPagination_Handler(t *testing.T) {
+	seedPagination(t, 100, 200, 300)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/subscriptions", handlers.NewSubscriptionHandler(testService, slog.New(slog.NewTextHandler(io.Discard, nil))).ListSubscriptions)
+
+	get := func(target string) map[string]json.RawMessage {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+		require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+		var body map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+		return body
+	}
+
+	// Page numbers stay the default
+	assert.Contains(t, get("/subscriptions?limit=2"), "pagination")
+
+	body := get("/subscriptions?limit=2&after=")
+	assert.NotContains(t, body, "pagination")
+	var cursors dto.CursorPagination
+	require.NoError(t, json.Unmarshal(body["cursors"], &cursors))
+	assert.NotEmpty(t, cursors.NextCursor)
+
+	body = get("/subscriptions?limit=2&skip_total=true&after=" + cursors.NextCursor)
+	var last dto.CursorPagination
+	require.NoError(t, json.Unmarshal(body["cursors"], &last))
+	assert.Nil(t, last.Total)
+	assert.Empty(t, last.NextCursor)
+	assert.NotEmpty(t, last.PrevCursor)
+}
@@ -0,0 +1,218 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasadov/subscription-manager/internal/middleware"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/pkg/jwt"
+	"github.com/rasadov/subscription-manager/pkg/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimit_ParseLimit(t *testing.T) {
+	limit, err := ratelimit.ParseLimit("100/1m")
+	require.NoError(t, err)
+	assert.Equal(t, ratelimit.Limit{Requests: 100, Per: time.Minute}, limit)
+
+	for _, value := range []string{"", "100", "0/1m", "x/1m", "10/soon", "10/-1s"} {
+		_, err := ratelimit.ParseLimit(value)
+		assert.Error(t, err, value)
+	}
+}
+
+// testStores runs a test against every store implementation.
+func testStores(t *testing.T, test func(t *testing.T, store ratelimit.Store)) {
+	t.Run("memory", func(t *testing.T) { test(t, ratelimit.NewMemoryStore()) })
+	t.Run("database", func(t *testing.T) {
+		SetupRepo(t)
+		test(t, repository.NewRateLimitRepository(db))
+	})
+}
+
+func TestRateLimit_TokenBucket(t *testing.T) {
+	testStores(t, func(t *testing.T, store ratelimit.Store) {
+		ctx := context.Background()
+		limit := ratelimit.Limit{Requests: 3, Per: time.Minute}
+		now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+		for i := range 3 {
+			result, err := store.Take(ctx, "client", limit, now)
+			require.NoError(t, err)
+			assert.True(t, result.Allowed)
+			assert.Equal(t, 2-i, result.Remaining)
+		}
+
+		result, err := store.Take(ctx, "client", limit, now)
+		require.NoError(t, err)
+		assert.False(t, result.Allowed)
+		assert.Equal(t, 20*time.Second, result.RetryAfter)
+		assert.Equal(t, time.Minute, result.Reset)
+
+		// Other clients have buckets of their own
+		result, err = store.Take(ctx, "other", limit, now)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+
+		// One token is refilled every 20 seconds
+		result, err = store.Take(ctx, "client", limit, now.Add(20*time.Second))
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 0, result.Remaining)
+
+		result, err = store.Take(ctx, "client", limit, now.Add(21*time.Second))
+		require.NoError(t, err)
+		assert.False(t, result.Allowed)
+
+		// The bucket never holds more than the limit
+		result, err = store.Take(ctx, "client", limit, now.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.Remaining)
+
+		// A replica with a lagging clock does not refill the bucket again
+		result, err = store.Take(ctx, "client", limit, now.Add(time.Hour-time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Remaining)
+	})
+}
+
+func TestRateLimit_DeleteIdleBuckets(t *testing.T) {
+	SetupRepo(t)
+	store := repository.NewRateLimitRepository(db)
+	limit := ratelimit.Limit{Requests: 1, Per: time.Minute}
+	now := time.Now()
+
+	_, err := store.Take(context.Background(), "idle", limit, now.Add(-time.Hour))
+	require.NoError(t, err)
+	_, err = store.Take(context.Background(), "active", limit, now)
+	require.NoError(t, err)
+
+	removed, err := store.DeleteIdleBuckets(context.Background(), now.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+
+	result, err := store.Take(context.Background(), "active", limit, now)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed, "active buckets are kept")
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, ratelimit.Limit, time.Time) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("store unavailable")
+}
+
+func newRateLimitRouter(store ratelimit.Store, authenticate bool) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	router := gin.New()
+	if authenticate {
+		verifier := &jwt.Verifier{Keys: []jwt.KeySet{jwt.Secret(testSecret)}}
+		router.Use(middleware.Authenticate(logger, middleware.NewJWTAuthenticator(verifier, "admin", "tenant")))
+	}
+	router.Use(middleware.RateLimit(store, middleware.RateLimitPolicy{
+		Default: ratelimit.Limit{Requests: 2, Per: time.Minute},
+		Routes:  map[string]ratelimit.Limit{"/subscriptions/export": {Requests: 1, Per: time.Minute}},
+	}, logger))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/subscriptions", ok)
+	router.GET("/subscriptions/:id", ok)
+	router.GET("/subscriptions/export", ok)
+	return router
+}
+
+func TestRateLimit_Middleware(t *testing.T) {
+	router := newRateLimitRouter(ratelimit.NewMemoryStore(), true)
+	call := func(path, subject string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+signToken(t, jwt.HS256, "", []byte(testSecret), userClaims(subject, nil)))
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	first := call("/subscriptions", catalogUserID)
+	require.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, "2", first.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", first.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", first.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, "2;w=60", first.Header().Get("RateLimit-Policy"))
+
+	// Routes without their own limit share a bucket
+	require.Equal(t, http.StatusOK, call("/subscriptions/1", catalogUserID).Code)
+	limited := call("/subscriptions", catalogUserID)
+	assert.Equal(t, http.StatusTooManyRequests, limited.Code)
+	assert.Equal(t, "0", limited.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", limited.Header().Get("Retry-After"))
+
+	// Routes with their own limit have their own bucket
+	export := call("/subscriptions/export", catalogUserID)
+	require.Equal(t, http.StatusOK, export.Code)
+	assert.Equal(t, "1", export.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, http.StatusTooManyRequests, call("/subscriptions/export", catalogUserID).Code)
+
+	// Every user has buckets of their own
+	assert.Equal(t, http.StatusOK, call("/subscriptions", otherBudgetUserID).Code)
+}
+
+func TestRateLimit_ClientIP(t *testing.T) {
+	router := newRateLimitRouter(ratelimit.NewMemoryStore(), false)
+	require.NoError(t, router.SetTrustedProxies(nil))
+	call := func(remoteAddr, forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodGet, "/subscriptions/export", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusOK, call("192.0.2.1:1234", "198.51.100.1"))
+	// Forwarded headers of untrusted clients are ignored
+	assert.Equal(t, http.StatusTooManyRequests, call("192.0.2.1:1234", "198.51.100.2"))
+	assert.Equal(t, http.StatusOK, call("192.0.2.2:1234", "198.51.100.1"))
+}
+
+func TestRateLimit_StoreFailureLetsRequestsThrough(t *testing.T) {
+	router := newRateLimitRouter(failingStore{}, false)
+	for range 3 {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/subscriptions", nil))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Empty(t, recorder.Header().Get("RateLimit-Limit"))
+	}
+}
+
+func TestRateLimit_IPBeforeAuthentication(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	verifier := &jwt.Verifier{Keys: []jwt.KeySet{jwt.Secret(testSecret)}}
+	router := gin.New()
+	router.Use(middleware.RateLimitIP(ratelimit.NewMemoryStore(), ratelimit.Limit{Requests: 2, Per: time.Minute}, logger))
+	router.Use(middleware.Authenticate(logger, middleware.NewJWTAuthenticator(verifier, "admin", "tenant")))
+	router.GET("/subscriptions", func(c *gin.Context) { c.Status(http.StatusOK) })
+	call := func(remoteAddr, token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/subscriptions", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	// Guessed credentials are rejected and count against the client IP
+	assert.Equal(t, http.StatusUnauthorized, call("192.0.2.1:1234", "guess-1"))
+	assert.Equal(t, http.StatusUnauthorized, call("192.0.2.1:1234", "guess-2"))
+	assert.Equal(t, http.StatusTooManyRequests, call("192.0.2.1:1234", "guess-3"))
+	assert.Equal(t, http.StatusTooManyRequests, call("192.0.2.1:1234", signToken(t, jwt.HS256, "", []byte(testSecret), userClaims(catalogUserID, nil))))
+	assert.Equal(t, http.StatusOK, call("192.0.2.2:1234", signToken(t, jwt.HS256, "", []byte(testSecret), userClaims(catalogUserID, nil))))
+}
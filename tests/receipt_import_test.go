@@ -0,0 +1,177 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/internal/service"
+	"github.com/rasadov/subscription-manager/pkg/receipt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const netflixReceipt = "From: Netflix <info@mailer.netflix.com>\r\n" +
+	"To: user@example.com\r\n" +
+	"Subject: =?UTF-8?Q?Your_Netflix_receipt?=\r\n" +
+	"Date: Mon, 03 Feb 2025 10:00:00 +0000\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/alternative; boundary=\"b1\"\r\n" +
+	"\r\n" +
+	"--b1\r\n" +
+	"Content-Type: text/html; charset=UTF-8\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"<html><head><style>td{color:red}</style></head><body><table>" +
+	"<tr><td>Plan</td><td>Standard</td></tr>=\r\n" +
+	"<tr><td>Subtotal</td><td>$13.00</td></tr>" +
+	"<tr><td>Total</td><td>$15.49</td></tr></table></body></html>\r\n" +
+	"--b1--\r\n"
+
+const spotifyReceipt = "From: Spotify Billing <no-reply@spotify.com>\r\n" +
+	"Subject: Receipt for Premium Family\r\n" +
+	"Date: Wed, 15 Jan 2025 08:30:00 +0000\r\n" +
+	"Content-Type: text/plain; charset=UTF-8\r\n" +
+	"\r\n" +
+	"Order number 12345\r\n" +
+	"Charged on 15/01/2025: EUR 17,99 (incl. VAT)\r\n"
+
+func TestParseEML_ReadsHTMLReceipt(t *testing.T) {
+	msg, err := receipt.ParseEML(strings.NewReader(netflixReceipt))
+
+	require.NoError(t, err)
+	assert.Equal(t, "Netflix", msg.FromName)
+	assert.Equal(t, "mailer.netflix.com", msg.Domain)
+	assert.Equal(t, "Your Netflix receipt", msg.Subject)
+	assert.Equal(t, time.Date(2025, time.February, 3, 10, 0, 0, 0, time.UTC), msg.Date.UTC())
+	assert.Contains(t, msg.Text, "Total $15.49")
+	assert.NotContains(t, msg.Text, "color")
+}
+
+func TestParseMbox_SplitsMessages(t *testing.T) {
+	mbox := "From MAILER-DAEMON Mon Feb  3 10:00:00 2025\n" + strings.ReplaceAll(netflixReceipt, "\r\n", "\n") +
+		"\nFrom MAILER-DAEMON Wed Jan 15 08:30:00 2025\n" + strings.ReplaceAll(spotifyReceipt, "\r\n", "\n") +
+		">From the team\n"
+
+	messages, err := receipt.ParseMbox(strings.NewReader(mbox))
+
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	assert.Equal(t, "spotify.com", messages[1].Domain)
+	assert.Contains(t, messages[1].Text, "\nFrom the team")
+}
+
+func TestGenericExtractor_PrefersTotalLine(t *testing.T) {
+	registry := receipt.NewRegistry()
+	registry.RegisterFallback(receipt.GenericExtractor{})
+	msg, err := receipt.ParseEML(strings.NewReader(netflixReceipt))
+	require.NoError(t, err)
+
+	extracted, err := registry.Extract(msg)
+
+	require.NoError(t, err)
+	assert.Equal(t, "Netflix", extracted.ServiceName)
+	assert.Equal(t, int64(1549), extracted.Amount)
+	assert.Equal(t, "USD", extracted.Currency)
+	assert.Equal(t, receipt.CycleMonthly, extracted.BillingCycle)
+	assert.True(t, extracted.Fallback)
+}
+
+func TestRegistry_DomainRuleTakesPrecedence(t *testing.T) {
+	rule, err := receipt.NewRuleExtractor(receipt.Rule{
+		ServiceName:  "Spotify Premium Family",
+		PricePattern: `Charged on [0-9/]+: (?P<currency>[A-Z]{3}) (?P<amount>[0-9.,]+)`,
+		DatePattern:  `Charged on ([0-9/]+)`,
+		DateLayout:   "02/01/2006",
+		BillingCycle: receipt.CycleMonthly,
+	})
+	require.NoError(t, err)
+
+	registry := receipt.NewRegistry()
+	registry.Register("spotify.com", rule)
+	registry.RegisterFallback(receipt.GenericExtractor{})
+	msg, err := receipt.ParseEML(strings.NewReader(spotifyReceipt))
+	require.NoError(t, err)
+
+	extracted, err := registry.Extract(msg)
+
+	require.NoError(t, err)
+	assert.False(t, extracted.Fallback)
+	assert.Equal(t, "Spotify Premium Family", extracted.ServiceName)
+	assert.Equal(t, int64(1799), extracted.Amount)
+	assert.Equal(t, "EUR", extracted.Currency)
+	assert.Equal(t, time.Date(2025, time.January, 15, 0, 0, 0, 0, time.UTC), extracted.BillingDate)
+}
+
+func TestNewRuleExtractor_RejectsPatternWithoutGroup(t *testing.T) {
+	_, err := receipt.NewRuleExtractor(receipt.Rule{PricePattern: `Total \$[0-9.]+`})
+	assert.Error(t, err)
+
+	_, err = receipt.NewRuleExtractor(receipt.Rule{PricePattern: `Total (`})
+	assert.Error(t, err)
+}
+
+func TestImportReceipts_CreatesDraftsFromMbox(t *testing.T) {
+	SetupRepo(t)
+	rules := repository.NewReceiptRuleRepository(db)
+	drafts := repository.NewDraftRepository(db)
+	receiptService := service.NewReceiptService(rules, drafts, testRepository)
+
+	_, httpErr := receiptService.CreateRule(context.Background(), dto.CreateReceiptRuleRequest{
+		SenderDomain: "spotify.com",
+		ServiceName:  "Spotify",
+		PricePattern: `(?P<currency>[A-Z]{3}) (?P<amount>[0-9.,]+)`,
+	})
+	require.Nil(t, httpErr)
+
+	_, httpErr = receiptService.CreateRule(context.Background(), dto.CreateReceiptRuleRequest{
+		SenderDomain: "example.com",
+		PricePattern: `[unclosed`,
+	})
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 400, httpErr.Status())
+
+	unreadable := "From: Friend <friend@example.org>\r\nSubject: Hello\r\nDate: Mon, 03 Feb 2025 10:00:00 +0000\r\n\r\nSee you soon\r\n"
+	mbox := "From a\n" + netflixReceipt + "From b\n" + spotifyReceipt + "From c\n" + unreadable
+
+	response, httpErr := receiptService.ImportReceipts(context.Background(), statementUserID, "inbox.mbox", strings.NewReader(mbox), dto.ImportReceiptsQuery{})
+
+	require.Nil(t, httpErr)
+	assert.Equal(t, 3, response.Messages)
+	require.Len(t, response.Drafts, 2)
+	require.Len(t, response.Skipped, 1)
+	assert.Equal(t, "friend@example.org", response.Skipped[0].From)
+
+	byName := map[string]*dto.DraftResponse{}
+	for _, draft := range response.Drafts {
+		byName[draft.ServiceName] = draft
+		assert.Equal(t, models.DraftSourceReceipt, draft.Source)
+	}
+	require.Contains(t, byName, "Netflix")
+	require.Contains(t, byName, "Spotify")
+	assert.Equal(t, int64(15), byName["Netflix"].Price)
+	assert.Equal(t, "USD", byName["Netflix"].Currency)
+	assert.Equal(t, "02-2025", time.Time(byName["Netflix"].StartDate).Format("01-2006"))
+	assert.Equal(t, int64(18), byName["Spotify"].Price)
+	assert.Equal(t, "EUR", byName["Spotify"].Currency)
+	assert.Greater(t, byName["Spotify"].Confidence, byName["Netflix"].Confidence)
+}
+
+func TestImportReceipts_SkipsTrackedServices(t *testing.T) {
+	SetupRepo(t)
+	receiptService := service.NewReceiptService(repository.NewReceiptRuleRepository(db), repository.NewDraftRepository(db), testRepository)
+
+	tracked := createTestSubscription("Netflix", statementUserID, "01-2025", "12-2025", 15)
+	require.NoError(t, testRepository.CreateSubscription(context.Background(), tracked))
+
+	response, httpErr := receiptService.ImportReceipts(context.Background(), statementUserID, "receipt.eml", strings.NewReader(netflixReceipt), dto.ImportReceiptsQuery{})
+
+	require.Nil(t, httpErr)
+	assert.Empty(t, response.Drafts)
+	require.Len(t, response.Skipped, 1)
+	assert.Contains(t, response.Skipped[0].Reason, "already tracked")
+}
@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticHouseholds map[string][]string
+
+func (h staticHouseholds) HouseholdMembers(ctx context.Context, userID string) ([]string, error) {
+	if members, ok := h[userID]; ok {
+		return members, nil
+	}
+	return []string{userID}, nil
+}
+
+func setupRecommendations(t *testing.T) (service.RecommendationService, map[string]uint) {
+	catalogService, subscriptionService, netflix := setupCatalog(t)
+
+	for _, plan := range []dto.CreatePlanRequest{
+		{Name: "Basic Annual", Price: 5990, BillingCycle: models.BillingCycleYearly},
+		{Name: "Family", Price: 999, Seats: 4},
+	} {
+		_, httpErr := catalogService.CreatePlan(context.Background(), int(netflix.ID), plan)
+		require.Nil(t, httpErr)
+	}
+
+	basic := netflix.Plans[0].ID
+	requests := []dto.CreateSubscriptionRequest{
+		{PlanID: &basic, UserID: catalogUserID},
+		{PlanID: &basic, UserID: otherBudgetUserID},
+		{ServiceName: "Gym", Price: 3000, UserID: catalogUserID},
+		{ServiceName: "Spotify", Price: 300, UserID: catalogUserID},
+		{ServiceName: "Spotify", Price: 300, UserID: catalogUserID, StartDate: "06-2025"},
+	}
+	ids := make(map[string]uint)
+	for _, req := range requests {
+		if req.StartDate == "" {
+			req.StartDate = "01-2025"
+		}
+		created, httpErr := subscriptionService.CreateSubscription(context.Background(), req)
+		require.Nil(t, httpErr)
+		ids[created.ServiceName+":"+created.UserID] = created.ID
+	}
+
+	recommendationService := service.NewRecommendationService(testRepository, repository.NewCatalogRepository(db),
+		repository.NewRecommendationRepository(db),
+		service.WithHouseholds(staticHouseholds{catalogUserID: {catalogUserID, otherBudgetUserID}}))
+
+	_, httpErr := recommendationService.ReportUsage(context.Background(), int(ids["Gym:"+catalogUserID]), dto.ReportUsageRequest{Usage: models.UsageNever})
+	require.Nil(t, httpErr)
+
+	return recommendationService, ids
+}
+
+func recommendationSavings(response *dto.ListRecommendationsResponse) map[string]int64 {
+	savings := make(map[string]int64)
+	for _, recommendation := range response.Data {
+		savings[recommendation.Type] = recommendation.EstimatedSavings
+	}
+	return savings
+}
+
+func TestListRecommendations(t *testing.T) {
+	recommendationService, ids := setupRecommendations(t)
+
+	response, httpErr := recommendationService.ListRecommendations(context.Background(), catalogUserID, dto.ListRecommendationsQuery{})
+	require.Nil(t, httpErr)
+	require.Len(t, response.Data, 4)
+
+	assert.Equal(t, map[string]int64{
+		models.RecommendationCancelUnused:  36000,
+		models.RecommendationConsolidate:   3600,
+		models.RecommendationFamilyPlan:    2388,
+		models.RecommendationAnnualBilling: 1198,
+	}, recommendationSavings(response))
+
+	// Ordered by savings
+	assert.Equal(t, models.RecommendationCancelUnused, response.Data[0].Type)
+	assert.Equal(t, []uint{ids["Gym:"+catalogUserID]}, response.Data[0].SubscriptionIDs)
+
+	family := response.Data[2]
+	assert.Equal(t, models.RecommendationFamilyPlan, family.Type)
+	assert.ElementsMatch(t, []uint{ids["Netflix:"+catalogUserID], ids["Netflix:"+otherBudgetUserID]}, family.SubscriptionIDs)
+
+	// Without a household there is no family plan
+	response, httpErr = recommendationService.ListRecommendations(context.Background(), otherBudgetUserID, dto.ListRecommendationsQuery{})
+	require.Nil(t, httpErr)
+	assert.Equal(t, map[string]int64{models.RecommendationAnnualBilling: 1198}, recommendationSavings(response))
+}
+
+func TestDismissAndSnoozeRecommendations(t *testing.T) {
+	recommendationService, ids := setupRecommendations(t)
+
+	response, httpErr := recommendationService.ListRecommendations(context.Background(), catalogUserID, dto.ListRecommendationsQuery{})
+	require.Nil(t, httpErr)
+	cancelKey, consolidateKey := response.Data[0].Key, response.Data[1].Key
+	assert.Equal(t, "cancel_unused:"+itoa(ids["Gym:"+catalogUserID]), cancelKey)
+
+	require.Nil(t, recommendationService.DismissRecommendation(context.Background(), catalogUserID, cancelKey))
+	require.Nil(t, recommendationService.SnoozeRecommendation(context.Background(), catalogUserID, consolidateKey, dto.SnoozeRecommendationRequest{Days: 7}))
+	// Snoozing again replaces the stored state
+	require.Nil(t, recommendationService.SnoozeRecommendation(context.Background(), catalogUserID, consolidateKey, dto.SnoozeRecommendationRequest{}))
+
+	response, httpErr = recommendationService.ListRecommendations(context.Background(), catalogUserID, dto.ListRecommendationsQuery{})
+	require.Nil(t, httpErr)
+	assert.Len(t, response.Data, 2)
+
+	response, httpErr = recommendationService.ListRecommendations(context.Background(), catalogUserID, dto.ListRecommendationsQuery{IncludeHidden: true})
+	require.Nil(t, httpErr)
+	require.Len(t, response.Data, 4)
+	assert.Equal(t, models.RecommendationStatusDismissed, response.Data[0].Status)
+	assert.Equal(t, models.RecommendationStatusSnoozed, response.Data[1].Status)
+	require.NotNil(t, response.Data[1].SnoozedUntil)
+
+	httpErr = recommendationService.DismissRecommendation(context.Background(), catalogUserID, "cancel_unused:9999")
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 404, httpErr.Status())
+
+	// Other users do not see the state
+	httpErr = recommendationService.DismissRecommendation(context.Background(), otherBudgetUserID, cancelKey)
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 404, httpErr.Status())
+}
+
+func itoa(value uint) string {
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/middleware"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/internal/service"
+	"github.com/rasadov/subscription-manager/pkg/auth"
+	"github.com/rasadov/subscription-manager/pkg/jwt"
+	"github.com/rasadov/subscription-manager/pkg/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// supportUserID is a support agent holding the auditor role.
+const supportUserID = "523e4567-e89b-12d3-a456-426614174000"
+
+func setupRoles(t *testing.T) service.RoleService {
+	SetupRepo(t)
+	return service.NewRoleService(repository.NewRoleRepository(db), auth.RoleEditor)
+}
+
+func TestRoles_SetRoles(t *testing.T) {
+	principal := &auth.Principal{}
+	principal.SetRoles([]string{auth.RoleViewer, "unknown", auth.RoleViewer})
+	assert.Equal(t, []string{auth.RoleViewer}, principal.Roles)
+	assert.True(t, principal.Allows(auth.PermissionSubscriptionsRead))
+	assert.True(t, principal.Allows(auth.PermissionCostsRead))
+	assert.False(t, principal.Allows(auth.PermissionSubscriptionsWrite))
+	assert.False(t, principal.AllUsers)
+
+	principal.SetRoles([]string{auth.RoleAuditor})
+	assert.True(t, principal.AllUsers)
+	assert.False(t, principal.Allows(auth.PermissionSubscriptionsWrite))
+	assert.False(t, principal.Allows(auth.PermissionAdmin))
+
+	principal.SetRoles([]string{auth.RoleAdmin})
+	assert.True(t, principal.AllUsers)
+	assert.True(t, principal.Allows(auth.PermissionSubscriptionsWrite))
+	assert.True(t, principal.Allows(auth.PermissionAdmin))
+
+	principal.SetRoles(nil)
+	assert.False(t, principal.Allows(auth.PermissionSubscriptionsRead))
+}
+
+func TestRoles_Resolve(t *testing.T) {
+	roles := setupRoles(t)
+	ctx := adminCtx(context.Background())
+
+	user := &auth.Principal{Subject: catalogUserID}
+	require.NoError(t, roles.ResolveRoles(context.Background(), user))
+	assert.Equal(t, []string{auth.RoleEditor}, user.Roles)
+	assert.False(t, user.AllUsers)
+
+	_, httpErr := roles.AssignRole(ctx, dto.CreateRoleAssignmentRequest{UserID: supportUserID, Role: auth.RoleAuditor})
+	require.Nil(t, httpErr)
+
+	support := &auth.Principal{Subject: supportUserID}
+	require.NoError(t, roles.ResolveRoles(context.Background(), support))
+	assert.Equal(t, []string{auth.RoleAuditor}, support.Roles)
+	assert.True(t, support.AllUsers)
+
+	// Token roles are combined with stored ones
+	tokenAdmin := &auth.Principal{Subject: supportUserID, Roles: []string{auth.RoleAdmin}}
+	require.NoError(t, roles.ResolveRoles(context.Background(), tokenAdmin))
+	assert.ElementsMatch(t, []string{auth.RoleAdmin, auth.RoleAuditor}, tokenAdmin.Roles)
+	assert.True(t, tokenAdmin.Allows(auth.PermissionAdmin))
+
+	// Assignments belong to a tenant
+	other := &auth.Principal{Subject: supportUserID}
+	require.NoError(t, roles.ResolveRoles(tenant.WithTenant(context.Background(), "globex"), other))
+	assert.Equal(t, []string{auth.RoleEditor}, other.Roles)
+}
+
+func TestRoles_Assignments(t *testing.T) {
+	roles := setupRoles(t)
+	ctx := adminCtx(context.Background())
+
+	created, httpErr := roles.AssignRole(ctx, dto.CreateRoleAssignmentRequest{UserID: supportUserID, Role: auth.RoleViewer})
+	require.Nil(t, httpErr)
+	assert.Equal(t, catalogUserID, created.CreatedBy)
+
+	_, httpErr = roles.AssignRole(ctx, dto.CreateRoleAssignmentRequest{UserID: supportUserID, Role: auth.RoleViewer})
+	require.NotNil(t, httpErr)
+	assert.Equal(t, http.StatusConflict, httpErr.Status())
+
+	list, httpErr := roles.ListRoleAssignments(ctx, dto.ListRoleAssignmentsQuery{UserID: strPtr(supportUserID)})
+	require.Nil(t, httpErr)
+	require.Len(t, list.Data, 1)
+	assert.Equal(t, auth.RoleViewer, list.Data[0].Role)
+
+	editor := &auth.Principal{Subject: catalogUserID}
+	editor.SetRoles([]string{auth.RoleEditor})
+	_, httpErr = roles.AssignRole(auth.WithPrincipal(context.Background(), editor), dto.CreateRoleAssignmentRequest{UserID: catalogUserID, Role: auth.RoleAdmin})
+	require.NotNil(t, httpErr)
+	assert.Equal(t, http.StatusForbidden, httpErr.Status())
+
+	require.Nil(t, roles.RevokeRole(ctx, int(created.ID)))
+	httpErr = roles.RevokeRole(ctx, int(created.ID))
+	require.NotNil(t, httpErr)
+	assert.Equal(t, http.StatusNotFound, httpErr.Status())
+}
+
+func TestRoles_SupportAgentCanReadButNotDelete(t *testing.T) {
+	roles := setupRoles(t)
+	_, httpErr := roles.AssignRole(adminCtx(context.Background()), dto.CreateRoleAssignmentRequest{UserID: supportUserID, Role: auth.RoleAuditor})
+	require.Nil(t, httpErr)
+
+	created, httpErr := testService.CreateSubscription(context.Background(), dto.CreateSubscriptionRequest{
+		ServiceName: "Netflix", Price: 1000, UserID: catalogUserID, StartDate: "01-2025",
+	})
+	require.Nil(t, httpErr)
+
+	gin.SetMode(gin.TestMode)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	router := gin.New()
+	verifier := &jwt.Verifier{Keys: []jwt.KeySet{jwt.Secret(testSecret)}}
+	router.Use(middleware.Authenticate(logger, middleware.NewJWTAuthenticator(verifier, "admin", "tenant")))
+	router.Use(middleware.ResolveRoles(roles, logger))
+	handler := func(c *gin.Context) {
+		response, httpErr := testService.GetSubscription(c.Request.Context(), int(created.ID))
+		if httpErr != nil {
+			c.JSON(httpErr.Status(), gin.H{"error": httpErr.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, response)
+	}
+	router.GET("/subscriptions/:id", middleware.RequirePermission(auth.PermissionSubscriptionsRead), handler)
+	router.DELETE("/subscriptions/:id", middleware.RequirePermission(auth.PermissionSubscriptionsWrite), handler)
+
+	call := func(method, subject string) int {
+		token := signToken(t, jwt.HS256, "", []byte(testSecret), userClaims(subject, nil))
+		req := httptest.NewRequest(method, "/subscriptions/1", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, supportUserID))
+	assert.Equal(t, http.StatusForbidden, call(http.MethodDelete, supportUserID))
+
+	// Users with the default editor role only see their own subscriptions
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, catalogUserID))
+	assert.Equal(t, http.StatusNotFound, call(http.MethodGet, otherBudgetUserID))
+}
@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/handlers"
+	"github.com/rasadov/subscription-manager/pkg/textmatch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWordSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, textmatch.WordSimilarity("spot", "Spotify Premium"))
+	assert.Equal(t, 1.0, textmatch.WordSimilarity("Apple  music", "apple-music"))
+	assert.InDelta(t, 0.857, textmatch.WordSimilarity("spotfy", "Spotify Premium"), 0.001)
+	// The best run of two words is "premium spotify"
+	assert.InDelta(t, 0.933, textmatch.WordSimilarity("premium spotfy", "Spotify Premium Spotify"), 0.001)
+	assert.Less(t, textmatch.WordSimilarity("netflix", "Spotify"), 0.5)
+	assert.Equal(t, 0.0, textmatch.WordSimilarity("", "Spotify"))
+	assert.Equal(t, 0.0, textmatch.WordSimilarity("spotify", ""))
+}
+
+func searchIDs(t *testing.T, ctx context.Context, query dto.SearchSubscriptionsQuery) []uint {
+	if query.Limit == 0 {
+		query.Limit = 20
+	}
+	response, httpErr := testService.SearchSubscriptions(ctx, query)
+	require.Nil(t, httpErr)
+	ids := make([]uint, 0, len(response.Data))
+	for _, result := range response.Data {
+		ids = append(ids, result.ID)
+	}
+	return ids
+}
+
+func TestSearchSubscriptions_TypoTolerantAndRanked(t *testing.T) {
+	ids := seedSorting(t,
+		dto.CreateSubscriptionRequest{ServiceName: "Spotify", Price: 300},
+		dto.CreateSubscriptionRequest{ServiceName: "Netflix", Price: 600, Tags: []string{"family"}},
+		dto.CreateSubscriptionRequest{ServiceName: "Apple One", Price: 900, Tags: []string{"spotify-replacement"}},
+		dto.CreateSubscriptionRequest{ServiceName: "Yandex Plus", Price: 400, Notes: "Cancel after the Spotfy family trial"},
+		dto.CreateSubscriptionRequest{ServiceName: "YouTube Premium", Price: 500, Notes: "Shared with the family"},
+	)
+
+	// The typo in the service name ranks first, exact notes before a typo in a tag
+	assert.Equal(t, []uint{ids[0], ids[3], ids[2]}, searchIDs(t, context.Background(), dto.SearchSubscriptionsQuery{Q: "spotfy"}))
+	assert.Equal(t, []uint{ids[1], ids[3], ids[4]}, searchIDs(t, context.Background(), dto.SearchSubscriptionsQuery{Q: "Family"}))
+	assert.Equal(t, []uint{ids[1]}, searchIDs(t, context.Background(), dto.SearchSubscriptionsQuery{Q: "netflx"}))
+	assert.Equal(t, []uint{ids[4]}, searchIDs(t, context.Background(), dto.SearchSubscriptionsQuery{Q: "youtube premum"}))
+	assert.Empty(t, searchIDs(t, context.Background(), dto.SearchSubscriptionsQuery{Q: "hulu"}))
+	assert.Equal(t, []uint{ids[0]}, searchIDs(t, context.Background(), dto.SearchSubscriptionsQuery{Q: "spotfy", Limit: 1}))
+
+	response, httpErr := testService.SearchSubscriptions(context.Background(), dto.SearchSubscriptionsQuery{Q: "spotfy", Limit: 20})
+	require.Nil(t, httpErr)
+	require.Len(t, response.Data, 3)
+	assert.InDelta(t, 0.857, response.Data[0].Score, 0.001)
+	assert.Equal(t, 0.8, response.Data[1].Score, "exact matches in the notes rank below the service name")
+	assert.Equal(t, "Cancel after the Spotfy family trial", response.Data[1].Notes)
+	assert.InDelta(t, 0.9*0.857, response.Data[2].Score, 0.001)
+
+	_, httpErr = testService.SearchSubscriptions(context.Background(), dto.SearchSubscriptionsQuery{Q: "   ", Limit: 20})
+	require.NotNil(t, httpErr)
+	assert.Equal(t, http.StatusBadRequest, httpErr.Status())
+}
+
+func TestSearchSubscriptions_NotesCanBeUpdated(t *testing.T) {
+	ids := seedSorting(t, dto.CreateSubscriptionRequest{ServiceName: "Kinopoisk", Price: 300})
+	assert.Empty(t, searchIDs(t, context.Background(), dto.SearchSubscriptionsQuery{Q: "cinema"}))
+
+	updated, httpErr := testService.UpdateSubscription(context.Background(), int(ids[0]), dto.UpdateSubscriptionRequest{Notes: strPtr("Russian cinema")})
+	require.Nil(t, httpErr)
+	assert.Equal(t, "Russian cinema", updated.Notes)
+	assert.Equal(t, ids, searchIDs(t, context.Background(), dto.SearchSubscriptionsQuery{Q: "cinema"}))
+}
+
+func TestSearchSubscriptions_RespectsUserScope(t *testing.T) {
+	ids := seedSorting(t, dto.CreateSubscriptionRequest{ServiceName: "Spotify", Price: 300})
+	_, httpErr := testService.CreateSubscription(context.Background(), dto.CreateSubscriptionRequest{
+		ServiceName: "Spotify", Price: 300, UserID: otherBudgetUserID, StartDate: "01-2025",
+	})
+	require.Nil(t, httpErr)
+
+	ctx := principalCtx(catalogUserID, false)
+	assert.Equal(t, ids, searchIDs(t, ctx, dto.SearchSubscriptionsQuery{Q: "spotfy"}))
+
+	_, httpErr = testService.SearchSubscriptions(ctx, dto.SearchSubscriptionsQuery{Q: "spotfy", UserID: strPtr(otherBudgetUserID), Limit: 20})
+	require.NotNil(t, httpErr)
+	assert.Equal(t, http.StatusForbidden, httpErr.Status())
+
+	// Without a principal every user is searched
+	assert.Len(t, searchIDs(t, context.Background(), dto.SearchSubscriptionsQuery{Q: "spotfy"}), 2)
+}
+
+func TestSearchSubscriptions_Handler(t *testing.T) {
+	seedSorting(t, dto.CreateSubscriptionRequest{ServiceName: "Spotify", Price: 300})
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/subscriptions/search", handlers.NewSubscriptionHandler(testService, slog.New(slog.NewTextHandler(io.Discard, nil))).SearchSubscriptions)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/subscriptions/search?q=spotfy", nil))
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	var body struct {
+		Data []struct {
+			ServiceName string  `json:"service_name"`
+			Score       float64 `json:"score"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	require.Len(t, body.Data, 1)
+	assert.Equal(t, "Spotify", body.Data[0].ServiceName)
+	assert.Greater(t, body.Data[0].Score, 0.8)
+
+	for _, target := range []string{"/subscriptions/search", "/subscriptions/search?q=spotify&limit=500"} {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, target)
+	}
+}
@@ -0,0 +1,247 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasadov/subscription-manager/internal/handlers"
+	"github.com/rasadov/subscription-manager/internal/middleware"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/internal/service"
+	"github.com/rasadov/subscription-manager/pkg/auth"
+	"github.com/rasadov/subscription-manager/pkg/oidc"
+	"github.com/rasadov/subscription-manager/pkg/tenant"
+	"github.com/rasadov/subscription-manager/tests/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const dashboardCallbackURL = "http://dashboard.test/auth/callback"
+
+func setupSessions(t *testing.T) (*gin.Engine, *mocks.OIDCProvider, service.SessionService) {
+	SetupRepo(t)
+	provider := mocks.NewOIDCProvider("dashboard")
+	t.Cleanup(provider.Close)
+	provider.Subject = catalogUserID
+
+	client := oidc.NewClient(oidc.Config{
+		Issuer:      provider.URL,
+		ClientID:    provider.ClientID,
+		RedirectURL: dashboardCallbackURL,
+		Scopes:      []string{"profile"},
+		HTTPClient:  provider.Client(),
+	})
+	sessions := service.NewSessionService(repository.NewSessionRepository(db), client, service.SessionConfig{
+		TTL:         time.Hour,
+		AdminRole:   "admin",
+		TenantClaim: "tenant",
+	})
+	roles := service.NewRoleService(repository.NewRoleRepository(db), auth.RoleEditor)
+
+	gin.SetMode(gin.TestMode)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := handlers.NewSessionHandler(sessions, logger, true)
+	router := gin.New()
+	router.GET("/auth/login", handler.Login)
+	router.GET("/auth/callback", handler.Callback)
+	router.POST("/auth/logout", handler.Logout)
+
+	api := router.Group("/api/v1")
+	api.Use(middleware.Authenticate(logger, middleware.NewSessionAuthenticator(sessions, handlers.SessionCookie)))
+	api.Use(middleware.ResolveRoles(roles, logger))
+	api.GET("/whoami", middleware.RequirePermission(auth.PermissionSubscriptionsRead), func(c *gin.Context) {
+		principal, _ := auth.FromContext(c.Request.Context())
+		c.JSON(http.StatusOK, gin.H{
+			"subject": principal.Subject,
+			"roles":   principal.Roles,
+			"tenant":  tenant.FromContext(c.Request.Context()),
+		})
+	})
+	return router, provider, sessions
+}
+
+func serve(router *gin.Engine, method, target string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func responseCookie(recorder *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, cookie := range recorder.Result().Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+// startLogin begins a login and lets the provider approve it. It returns the
+// login cookie and the callback URL the provider redirected to.
+func startLogin(t *testing.T, router *gin.Engine, provider *mocks.OIDCProvider, returnTo string) (*http.Cookie, *url.URL) {
+	login := serve(router, http.MethodGet, "/auth/login?return_to="+url.QueryEscape(returnTo))
+	require.Equal(t, http.StatusFound, login.Code)
+	location := login.Header().Get("Location")
+	require.True(t, strings.HasPrefix(location, provider.URL+"/authorize?"), location)
+
+	authorizeURL, err := url.Parse(location)
+	require.NoError(t, err)
+	assert.Equal(t, "openid profile", authorizeURL.Query().Get("scope"))
+	assert.Equal(t, "S256", authorizeURL.Query().Get("code_challenge_method"))
+	assert.NotEmpty(t, authorizeURL.Query().Get("code_challenge"))
+
+	loginCookie := responseCookie(login, "sm_login")
+	require.NotNil(t, loginCookie)
+	assert.True(t, loginCookie.HttpOnly)
+
+	client := provider.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	resp, err := client.Get(location)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(callback.String(), dashboardCallbackURL))
+	return loginCookie, callback
+}
+
+func login(t *testing.T, router *gin.Engine, provider *mocks.OIDCProvider, returnTo string) (*http.Cookie, string) {
+	loginCookie, callback := startLogin(t, router, provider, returnTo)
+	recorder := serve(router, http.MethodGet, "/auth/callback?"+callback.RawQuery, loginCookie)
+	require.Equal(t, http.StatusFound, recorder.Code, recorder.Body.String())
+
+	session := responseCookie(recorder, handlers.SessionCookie)
+	require.NotNil(t, session)
+	return session, recorder.Header().Get("Location")
+}
+
+func whoami(t *testing.T, router *gin.Engine, cookie *http.Cookie) (int, map[string]any) {
+	recorder := serve(router, http.MethodGet, "/api/v1/whoami", cookie)
+	var body map[string]any
+	if recorder.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	}
+	return recorder.Code, body
+}
+
+func TestSession_LoginFlow(t *testing.T) {
+	router, provider, _ := setupSessions(t)
+	provider.Claims = map[string]any{"tenant": "acme", "roles": []string{"admin", "unknown"}}
+
+	session, location := login(t, router, provider, "/subscriptions?page=2")
+	assert.Equal(t, "/subscriptions?page=2", location)
+	assert.True(t, session.HttpOnly)
+	assert.True(t, session.Secure)
+	assert.Equal(t, http.SameSiteLaxMode, session.SameSite)
+	assert.Equal(t, "/", session.Path)
+
+	status, body := whoami(t, router, session)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, catalogUserID, body["subject"])
+	assert.Equal(t, "acme", body["tenant"])
+	assert.Equal(t, []any{auth.RoleAdmin}, body["roles"])
+
+	// Only the hash of the session token is stored
+	var stored int64
+	require.NoError(t, db.Table("sessions").Where("token_hash = ?", session.Value).Count(&stored).Error)
+	assert.Zero(t, stored)
+}
+
+func TestSession_DefaultsAndReturnTo(t *testing.T) {
+	router, provider, _ := setupSessions(t)
+
+	for _, returnTo := range []string{"", "https://evil.test/", "//evil.test", "/\\evil.test", "/\t/evil.test"} {
+		session, location := login(t, router, provider, returnTo)
+		assert.Equal(t, "/", location, "return_to %q", returnTo)
+
+		status, body := whoami(t, router, session)
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, tenant.Default, body["tenant"])
+		assert.Equal(t, []any{auth.RoleEditor}, body["roles"])
+	}
+}
+
+func TestSession_CallbackRejected(t *testing.T) {
+	router, provider, _ := setupSessions(t)
+
+	loginCookie, callback := startLogin(t, router, provider, "/")
+	query := callback.Query()
+
+	// Without the cookie of the browser that started the login
+	assert.Equal(t, http.StatusBadRequest, serve(router, http.MethodGet, "/auth/callback?"+callback.RawQuery).Code)
+
+	forged := url.Values{"code": {query.Get("code")}, "state": {"forged"}}
+	recorder := serve(router, http.MethodGet, "/auth/callback?"+forged.Encode(), loginCookie)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Nil(t, responseCookie(recorder, handlers.SessionCookie))
+
+	denied := url.Values{"error": {"access_denied"}, "state": {query.Get("state")}}
+	assert.Equal(t, http.StatusUnauthorized, serve(router, http.MethodGet, "/auth/callback?"+denied.Encode(), loginCookie).Code)
+
+	// Codes are single use
+	require.Equal(t, http.StatusFound, serve(router, http.MethodGet, "/auth/callback?"+callback.RawQuery, loginCookie).Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(router, http.MethodGet, "/auth/callback?"+callback.RawQuery, loginCookie).Code)
+
+	// A code is only redeemed with the PKCE verifier of its login
+	firstCookie, firstCallback := startLogin(t, router, provider, "/")
+	_, secondCallback := startLogin(t, router, provider, "/")
+	mixed := url.Values{"code": {secondCallback.Query().Get("code")}, "state": {firstCallback.Query().Get("state")}}
+	assert.Equal(t, http.StatusUnauthorized, serve(router, http.MethodGet, "/auth/callback?"+mixed.Encode(), firstCookie).Code)
+}
+
+func TestSession_LogoutAndExpiry(t *testing.T) {
+	router, provider, sessions := setupSessions(t)
+
+	session, _ := login(t, router, provider, "/")
+	status, _ := whoami(t, router, session)
+	require.Equal(t, http.StatusOK, status)
+
+	recorder := serve(router, http.MethodPost, "/auth/logout", session)
+	require.Equal(t, http.StatusFound, recorder.Code)
+	assert.True(t, strings.HasPrefix(recorder.Header().Get("Location"), provider.URL+"/logout?"))
+	cleared := responseCookie(recorder, handlers.SessionCookie)
+	require.NotNil(t, cleared)
+	assert.Negative(t, cleared.MaxAge)
+
+	status, _ = whoami(t, router, session)
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	expired, _ := login(t, router, provider, "/")
+	require.NoError(t, db.Exec("UPDATE sessions SET expires_at = ?", time.Now().Add(-time.Minute)).Error)
+	status, _ = whoami(t, router, expired)
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	removed, err := sessions.DeleteExpiredSessions(context.Background(), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+}
+
+func TestSession_AuthorizationHeaderTakesPrecedence(t *testing.T) {
+	router, provider, _ := setupSessions(t)
+	session, _ := login(t, router, provider, "/")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/whoami", nil)
+	req.AddCookie(session)
+	req.Header.Set("Authorization", "Bearer not-a-token")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+	unknown := &http.Cookie{Name: handlers.SessionCookie, Value: "unknown"}
+	status, _ := whoami(t, router, unknown)
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
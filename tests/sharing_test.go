@@ -0,0 +1,208 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/internal/service"
+	"github.com/rasadov/subscription-manager/pkg/exceptions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const thirdMemberUserID = "323e4567-e89b-12d3-a456-426614174000"
+
+func createShared(t *testing.T, subscriptionService service.SubscriptionService, rule string, price int64, members ...dto.SubscriptionMemberRequest) (*dto.SubscriptionResponse, exceptions.HTTPError) {
+	t.Helper()
+	return subscriptionService.CreateSubscription(context.Background(), dto.CreateSubscriptionRequest{
+		ServiceName: "Spotify Family",
+		Price:       price,
+		UserID:      catalogUserID,
+		StartDate:   "01-2025",
+		EndDate:     "12-2025",
+		Category:    "music",
+		SplitRule:   rule,
+		Members:     members,
+	})
+}
+
+func memberAmounts(response *dto.SubscriptionResponse) map[string][2]int64 {
+	amounts := make(map[string][2]int64)
+	for _, member := range response.Members {
+		amounts[member.UserID] = [2]int64{member.Share, member.Amount}
+	}
+	return amounts
+}
+
+func TestCreateSubscription_SplitRules(t *testing.T) {
+	_, subscriptionService, _ := setupCatalog(t)
+
+	equal, err := createShared(t, subscriptionService, "", 1000,
+		dto.SubscriptionMemberRequest{UserID: otherBudgetUserID},
+		dto.SubscriptionMemberRequest{UserID: thirdMemberUserID, Share: 90})
+	require.Nil(t, err)
+	assert.Equal(t, models.SplitEqual, equal.SplitRule)
+	require.Len(t, equal.Members, 3)
+	assert.Equal(t, catalogUserID, equal.Members[0].UserID)
+	// The owner pays the rounding remainder
+	assert.Equal(t, map[string][2]int64{
+		catalogUserID:     {0, 334},
+		otherBudgetUserID: {0, 333},
+		thirdMemberUserID: {0, 333},
+	}, memberAmounts(equal))
+
+	percentage, err := createShared(t, subscriptionService, models.SplitPercentage, 999,
+		dto.SubscriptionMemberRequest{UserID: otherBudgetUserID, Share: 30})
+	require.Nil(t, err)
+	assert.Equal(t, map[string][2]int64{
+		catalogUserID:     {70, 700},
+		otherBudgetUserID: {30, 299},
+	}, memberAmounts(percentage))
+
+	fixed, err := createShared(t, subscriptionService, models.SplitFixed, 1000,
+		dto.SubscriptionMemberRequest{UserID: otherBudgetUserID, Share: 250})
+	require.Nil(t, err)
+	assert.Equal(t, map[string][2]int64{
+		catalogUserID:     {750, 750},
+		otherBudgetUserID: {250, 250},
+	}, memberAmounts(fixed))
+
+	reloaded, httpErr := subscriptionService.GetSubscription(context.Background(), int(fixed.ID))
+	require.Nil(t, httpErr)
+	assert.Equal(t, memberAmounts(fixed), memberAmounts(reloaded))
+
+	unshared, err := createShared(t, subscriptionService, models.SplitFixed, 1000)
+	require.Nil(t, err)
+	assert.Empty(t, unshared.SplitRule)
+	assert.Empty(t, unshared.Members)
+}
+
+func TestCreateSubscription_RejectsInvalidSplits(t *testing.T) {
+	_, subscriptionService, _ := setupCatalog(t)
+
+	cases := map[string]struct {
+		rule    string
+		members []dto.SubscriptionMemberRequest
+	}{
+		"percentages over 100": {models.SplitPercentage, []dto.SubscriptionMemberRequest{
+			{UserID: otherBudgetUserID, Share: 60}, {UserID: thirdMemberUserID, Share: 50}}},
+		"missing percentage": {models.SplitPercentage, []dto.SubscriptionMemberRequest{{UserID: otherBudgetUserID}}},
+		"amounts over price": {models.SplitFixed, []dto.SubscriptionMemberRequest{{UserID: otherBudgetUserID, Share: 1001}}},
+		"owner as member":    {models.SplitEqual, []dto.SubscriptionMemberRequest{{UserID: catalogUserID}}},
+		"repeated member": {models.SplitEqual, []dto.SubscriptionMemberRequest{
+			{UserID: otherBudgetUserID}, {UserID: otherBudgetUserID}}},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := createShared(t, subscriptionService, tc.rule, 1000, tc.members...)
+			require.NotNil(t, err)
+			assert.Equal(t, 400, err.Status())
+		})
+	}
+}
+
+func TestCalculateTotalCost_CountsMemberShares(t *testing.T) {
+	_, subscriptionService, _ := setupCatalog(t)
+
+	_, err := createShared(t, subscriptionService, models.SplitPercentage, 1000,
+		dto.SubscriptionMemberRequest{UserID: otherBudgetUserID, Share: 40})
+	require.Nil(t, err)
+	_, httpErr := subscriptionService.CreateSubscription(context.Background(), dto.CreateSubscriptionRequest{
+		ServiceName: "GitHub", Price: 500, UserID: otherBudgetUserID, StartDate: "01-2025", EndDate: "12-2025", Category: "dev",
+	})
+	require.Nil(t, httpErr)
+
+	totalFor := func(userID string) *dto.TotalCostResponse {
+		response, httpErr := subscriptionService.CalculateTotalCost(context.Background(), dto.TotalCostQuery{
+			UserID:    strPtr(userID),
+			StartDate: strPtr("01-2025"),
+			EndDate:   strPtr("12-2025"),
+			GroupBy:   "category",
+		})
+		require.Nil(t, httpErr)
+		return response
+	}
+
+	owner := totalFor(catalogUserID)
+	assert.Equal(t, int64(600), owner.TotalCost)
+
+	member := totalFor(otherBudgetUserID)
+	assert.Equal(t, int64(900), member.TotalCost)
+	groups := make(map[string]int64)
+	for _, group := range member.Groups {
+		groups[*group.Key] = group.TotalCost
+	}
+	assert.Equal(t, map[string]int64{"music": 400, "dev": 500}, groups)
+
+	// Without a user the full prices are summed
+	all, httpErr := subscriptionService.CalculateTotalCost(context.Background(), dto.TotalCostQuery{
+		StartDate: strPtr("01-2025"),
+		EndDate:   strPtr("12-2025"),
+	})
+	require.Nil(t, httpErr)
+	assert.Equal(t, int64(1500), all.TotalCost)
+
+	list, httpErr := subscriptionService.ListSubscriptions(context.Background(), dto.ListSubscriptionsQuery{UserID: strPtr(otherBudgetUserID)})
+	require.Nil(t, httpErr)
+	assert.Len(t, list.Data, 1)
+
+	list, httpErr = subscriptionService.ListSubscriptions(context.Background(), dto.ListSubscriptionsQuery{UserID: strPtr(otherBudgetUserID), IncludeShared: true})
+	require.Nil(t, httpErr)
+	assert.Len(t, list.Data, 2)
+}
+
+func TestUpdateSubscription_RecomputesShares(t *testing.T) {
+	_, subscriptionService, _ := setupCatalog(t)
+
+	shared, err := createShared(t, subscriptionService, models.SplitPercentage, 1000,
+		dto.SubscriptionMemberRequest{UserID: otherBudgetUserID, Share: 25})
+	require.Nil(t, err)
+
+	updated, httpErr := subscriptionService.UpdateSubscription(context.Background(), int(shared.ID), dto.UpdateSubscriptionRequest{Price: int64Ptr(2000)})
+	require.Nil(t, httpErr)
+	assert.Equal(t, map[string][2]int64{
+		catalogUserID:     {75, 1500},
+		otherBudgetUserID: {25, 500},
+	}, memberAmounts(updated))
+
+	equal := models.SplitEqual
+	updated, httpErr = subscriptionService.UpdateSubscription(context.Background(), int(shared.ID), dto.UpdateSubscriptionRequest{
+		SplitRule: &equal,
+		Members:   &[]dto.SubscriptionMemberRequest{{UserID: otherBudgetUserID}, {UserID: thirdMemberUserID}},
+	})
+	require.Nil(t, httpErr)
+	assert.Equal(t, map[string][2]int64{
+		catalogUserID:     {0, 668},
+		otherBudgetUserID: {0, 666},
+		thirdMemberUserID: {0, 666},
+	}, memberAmounts(updated))
+
+	reloaded, httpErr := subscriptionService.GetSubscription(context.Background(), int(shared.ID))
+	require.Nil(t, httpErr)
+	assert.Equal(t, memberAmounts(updated), memberAmounts(reloaded))
+
+	updated, httpErr = subscriptionService.UpdateSubscription(context.Background(), int(shared.ID), dto.UpdateSubscriptionRequest{
+		Members: &[]dto.SubscriptionMemberRequest{},
+	})
+	require.Nil(t, httpErr)
+	assert.Empty(t, updated.SplitRule)
+	assert.Empty(t, updated.Members)
+
+	var members int64
+	require.NoError(t, db.Model(&models.SubscriptionMember{}).Count(&members).Error)
+	assert.Zero(t, members)
+}
+
+func TestDeleteSubscription_RemovesMembers(t *testing.T) {
+	_, subscriptionService, _ := setupCatalog(t)
+
+	shared, err := createShared(t, subscriptionService, "", 1000, dto.SubscriptionMemberRequest{UserID: otherBudgetUserID})
+	require.Nil(t, err)
+	require.Nil(t, subscriptionService.DeleteSubscription(context.Background(), int(shared.ID)))
+
+	var members int64
+	require.NoError(t, db.Model(&models.SubscriptionMember{}).Count(&members).Error)
+	assert.Zero(t, members)
+}
@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func seedSorting(t *testing.T, requests ...dto.CreateSubscriptionRequest) []uint {
+	SetupRepo(t)
+	ids := make([]uint, 0, len(requests))
+	for _, req := range requests {
+		req.UserID = catalogUserID
+		if req.StartDate == "" {
+			req.StartDate = "01-2025"
+		}
+		created, httpErr := testService.CreateSubscription(context.Background(), req)
+		require.Nil(t, httpErr)
+		ids = append(ids, created.ID)
+	}
+	return ids
+}
+
+func sortedIDs(t *testing.T, query dto.ListSubscriptionsQuery) []uint {
+	list, httpErr := testService.ListSubscriptions(context.Background(), query)
+	require.Nil(t, httpErr)
+	return listIDs(list)
+}
+
+func TestSorting_MultipleFields(t *testing.T) {
+	ids := seedSorting(t,
+		dto.CreateSubscriptionRequest{ServiceName: "Spotify", Price: 500},
+		dto.CreateSubscriptionRequest{ServiceName: "Netflix", Price: 1000},
+		dto.CreateSubscriptionRequest{ServiceName: "Apple Music", Price: 500},
+		dto.CreateSubscriptionRequest{ServiceName: "Netflix", Price: 500},
+	)
+
+	assert.Equal(t, []uint{ids[1], ids[2], ids[3], ids[0]}, sortedIDs(t, dto.ListSubscriptionsQuery{Sort: strPtr("-price,service_name")}))
+	// Equal values are ordered by id in the direction of the last field
+	assert.Equal(t, []uint{ids[3], ids[2], ids[0], ids[1]}, sortedIDs(t, dto.ListSubscriptionsQuery{Sort: strPtr("price,-id")}))
+	assert.Equal(t, []uint{ids[0], ids[2], ids[3], ids[1]}, sortedIDs(t, dto.ListSubscriptionsQuery{Sort: strPtr("price")}))
+	assert.Equal(t, []uint{ids[1], ids[3], ids[2], ids[0]}, sortedIDs(t, dto.ListSubscriptionsQuery{Sort: strPtr("-price")}))
+
+	// sort_by and sort_order keep working
+	assert.Equal(t, []uint{ids[2], ids[1], ids[3], ids[0]},
+		sortedIDs(t, dto.ListSubscriptionsQuery{SortBy: strPtr("service_name"), SortOrder: strPtr("asc")}))
+}
+
+func TestSorting_MonthlyCost(t *testing.T) {
+	ids := seedSorting(t,
+		dto.CreateSubscriptionRequest{ServiceName: "Yearly", Price: 12000, BillingCycle: "yearly"},
+		dto.CreateSubscriptionRequest{ServiceName: "Monthly", Price: 1500},
+		dto.CreateSubscriptionRequest{ServiceName: "Quarterly", Price: 2400, BillingCycle: "quarterly"},
+	)
+
+	// 1500, 1000 and 800 a month
+	assert.Equal(t, []uint{ids[1], ids[0], ids[2]}, sortedIDs(t, dto.ListSubscriptionsQuery{Sort: strPtr("-monthly_cost")}))
+
+	// Computed fields work with cursors as well
+	query := dto.ListSubscriptionsQuery{Limit: 2, Sort: strPtr("monthly_cost"), After: strPtr("")}
+	first, httpErr := testService.ListSubscriptions(context.Background(), query)
+	require.Nil(t, httpErr)
+	assert.Equal(t, []uint{ids[2], ids[0]}, listIDs(first))
+	query.After = &first.Cursors.NextCursor
+	second, httpErr := testService.ListSubscriptions(context.Background(), query)
+	require.Nil(t, httpErr)
+	assert.Equal(t, []uint{ids[1]}, listIDs(second))
+}
+
+func TestSorting_MonthlyCostMatchesTheFilter(t *testing.T) {
+	ids := seedSorting(t,
+		dto.CreateSubscriptionRequest{ServiceName: "Yearly", Price: 1000, BillingCycle: "yearly"},
+		dto.CreateSubscriptionRequest{ServiceName: "Monthly", Price: 84},
+		dto.CreateSubscriptionRequest{ServiceName: "Quarterly", Price: 250, BillingCycle: "quarterly"},
+		dto.CreateSubscriptionRequest{ServiceName: "Cheaper yearly", Price: 999, BillingCycle: "yearly"},
+	)
+
+	// 83.25, then 83.33 twice, then 84 a month; page one row at a time over the tie
+	var paged []uint
+	query := dto.ListSubscriptionsQuery{Limit: 1, Sort: strPtr("monthly_cost"), After: strPtr("")}
+	for {
+		page, httpErr := testService.ListSubscriptions(context.Background(), query)
+		require.Nil(t, httpErr)
+		paged = append(paged, listIDs(page)...)
+		if page.Cursors.NextCursor == "" {
+			break
+		}
+		query.After = &page.Cursors.NextCursor
+	}
+	assert.Equal(t, []uint{ids[3], ids[0], ids[2], ids[1]}, paged)
+
+	assert.Equal(t, []uint{ids[0], ids[2]}, filteredIDs(t, "monthly_cost > 83.3 and monthly_cost < 83.34"))
+	assert.Equal(t, []uint{ids[2], ids[0], ids[3]}, sortedIDs(t, dto.ListSubscriptionsQuery{
+		Sort: strPtr("-monthly_cost"), Filter: strPtr("monthly_cost < 84"),
+	}))
+}
+
+func TestSorting_NullsLast(t *testing.T) {
+	ids := seedSorting(t,
+		dto.CreateSubscriptionRequest{ServiceName: "Open", Price: 100},
+		dto.CreateSubscriptionRequest{ServiceName: "Early", Price: 100, EndDate: "03-2025"},
+		dto.CreateSubscriptionRequest{ServiceName: "Late", Price: 100, EndDate: "09-2025"},
+	)
+
+	assert.Equal(t, []uint{ids[1], ids[2], ids[0]}, sortedIDs(t, dto.ListSubscriptionsQuery{Sort: strPtr("end_date")}))
+	assert.Equal(t, []uint{ids[2], ids[1], ids[0]}, sortedIDs(t, dto.ListSubscriptionsQuery{Sort: strPtr("-end_date")}))
+}
+
+func TestSorting_RejectsInvalidSorts(t *testing.T) {
+	seedSorting(t, dto.CreateSubscriptionRequest{ServiceName: "Netflix", Price: 100})
+
+	for name, query := range map[string]dto.ListSubscriptionsQuery{
+		"unknown field":        {Sort: strPtr("-popularity")},
+		"injection in sort":    {Sort: strPtr("price; DROP TABLE subscriptions")},
+		"injection in sort_by": {SortBy: strPtr("price desc, (SELECT 1)")},
+		"invalid sort_order":   {SortBy: strPtr("price"), SortOrder: strPtr("sideways")},
+		"empty field":          {Sort: strPtr("price,")},
+		"repeated field":       {Sort: strPtr("price,-price")},
+	} {
+		_, httpErr := testService.ListSubscriptions(context.Background(), query)
+		require.NotNil(t, httpErr, name)
+		assert.Equal(t, http.StatusBadRequest, httpErr.Status(), name)
+	}
+
+	_, httpErr := testService.ListSubscriptions(context.Background(), dto.ListSubscriptionsQuery{Sort: strPtr("popularity")})
+	require.NotNil(t, httpErr)
+	assert.Contains(t, httpErr.Error(), "monthly_cost", "the error lists the sortable fields")
+	assert.Equal(t, int64(1), countSubscriptions(t))
+}
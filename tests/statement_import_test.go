@@ -0,0 +1,160 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/internal/service"
+	"github.com/rasadov/subscription-manager/pkg/statement"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const statementUserID = "123e4567-e89b-12d3-a456-426614174000"
+
+func monthlyStatementCSV() string {
+	var sb strings.Builder
+	sb.WriteString("Booking Date,Payee,Amount\n")
+	start := time.Date(2025, time.January, 5, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 6; i++ {
+		date := start.AddDate(0, i, 0)
+		fmt.Fprintf(&sb, "%s,NETFLIX.COM 8445%d,-15.99\n", date.Format("02.01.2006"), i)
+		fmt.Fprintf(&sb, "%s,SPOTIFY P%04d,-9.99\n", date.AddDate(0, 0, 10).Format("02.01.2006"), i*37)
+		fmt.Fprintf(&sb, "%s,GROCERY STORE #%d,-%d.40\n", date.AddDate(0, 0, 3+i).Format("02.01.2006"), i, 20+i*13)
+	}
+	sb.WriteString("15.03.2025,SALARY,2500.00\n")
+	return sb.String()
+}
+
+func TestParseAmount(t *testing.T) {
+	cases := map[string]int64{
+		"-15.99":    -1599,
+		"1 234,56":  123456,
+		"(9.99)":    -999,
+		"$1,234.50": 123450,
+		"12":        1200,
+	}
+	for raw, expected := range cases {
+		amount, err := statement.ParseAmount(raw)
+		require.NoError(t, err, raw)
+		assert.Equal(t, expected, amount, raw)
+	}
+}
+
+func TestParseOFX_SGML(t *testing.T) {
+	ofx := `OFXHEADER:100
+DATA:OFXSGML
+<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS><BANKTRANLIST>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20250105120000[-5:EST]<TRNAMT>-15.99<NAME>NETFLIX.COM<MEMO>Card 1234</STMTTRN>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20250205<TRNAMT>-15.99<NAME>AT&amp;T WIRELESS</STMTTRN>
+</BANKTRANLIST></STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>`
+
+	transactions, err := statement.ParseOFX(strings.NewReader(ofx))
+
+	require.NoError(t, err)
+	require.Len(t, transactions, 2)
+	assert.Equal(t, time.Date(2025, time.January, 5, 0, 0, 0, 0, time.UTC), transactions[0].Date)
+	assert.Equal(t, int64(-1599), transactions[0].Amount)
+	assert.Equal(t, "NETFLIX.COM", transactions[0].Description)
+	assert.Equal(t, "AT&T WIRELESS", transactions[1].Description)
+}
+
+func TestDetectRecurring_FindsMonthlyChargesOnly(t *testing.T) {
+	_, err := statement.ParseCSV(strings.NewReader(monthlyStatementCSV()), statement.CSVOptions{})
+	assert.Error(t, err, "header without mapping should be rejected")
+
+	transactions, err := statement.ParseCSV(strings.NewReader(monthlyStatementCSV()), statement.CSVOptions{
+		Columns: map[string]string{"date": "Booking Date", "description": "Payee", "amount": "Amount"},
+	})
+	require.NoError(t, err)
+
+	detected := statement.DetectRecurring(transactions)
+
+	require.Len(t, detected, 2)
+	keys := []string{detected[0].Key, detected[1].Key}
+	assert.ElementsMatch(t, []string{"netflix", "spotify p"}, keys)
+	for _, charge := range detected {
+		assert.Equal(t, statement.CycleMonthly, charge.Cycle)
+		assert.Equal(t, 6, charge.Occurrences)
+		assert.Greater(t, charge.Confidence, 0.8)
+	}
+}
+
+func TestImportStatement_CreatesDraftsAndConfirms(t *testing.T) {
+	SetupRepo(t)
+	draftRepo := repository.NewDraftRepository(db)
+	statementService := service.NewStatementService(draftRepo, testRepository)
+	draftService := service.NewDraftService(draftRepo, testService)
+
+	// Spotify is already tracked, so only Netflix should be proposed
+	tracked := createTestSubscription("Spotify P", statementUserID, "01-2025", "12-2025", 10)
+	require.NoError(t, testRepository.CreateSubscription(context.Background(), tracked))
+
+	query := dto.ImportStatementQuery{Mapping: "date=Booking Date,description=Payee,amount=Amount", DateFormat: "02.01.2006"}
+
+	response, httpErr := statementService.ImportStatement(context.Background(), statementUserID, "statement.csv",
+		strings.NewReader(monthlyStatementCSV()), query)
+	require.Nil(t, httpErr)
+	assert.Equal(t, 19, response.Transactions)
+	require.Len(t, response.Drafts, 1)
+
+	draft := response.Drafts[0]
+	assert.Equal(t, "Netflix", draft.ServiceName)
+	assert.Equal(t, int64(16), draft.Price)
+	assert.Equal(t, models.BillingCycleMonthly, draft.BillingCycle)
+	assert.Equal(t, models.DraftStatusPending, draft.Status)
+
+	// Importing the same statement again refreshes the pending draft instead of duplicating it
+	response, httpErr = statementService.ImportStatement(context.Background(), statementUserID, "statement.csv",
+		strings.NewReader(monthlyStatementCSV()), query)
+	require.Nil(t, httpErr)
+	require.Len(t, response.Drafts, 1)
+	assert.Equal(t, draft.ID, response.Drafts[0].ID)
+
+	price := int64(15)
+	subscription, httpErr := draftService.ConfirmDraft(context.Background(), statementUserID, int(draft.ID), dto.ConfirmDraftRequest{Price: &price})
+	require.Nil(t, httpErr)
+	assert.Equal(t, "Netflix", subscription.ServiceName)
+	assert.Equal(t, price, subscription.Price)
+	assert.Equal(t, "01-2025", time.Time(subscription.StartDate).Format("01-2006"))
+
+	_, httpErr = draftService.ConfirmDraft(context.Background(), statementUserID, int(draft.ID), dto.ConfirmDraftRequest{})
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 409, httpErr.Status())
+
+	status := models.DraftStatusConfirmed
+	listed, httpErr := draftService.ListDrafts(context.Background(), statementUserID, dto.ListDraftsQuery{Status: &status})
+	require.Nil(t, httpErr)
+	require.Len(t, listed.Data, 1)
+	assert.Equal(t, subscription.ID, *listed.Data[0].SubscriptionID)
+}
+
+func TestDismissDraft_OtherUserIsNotFound(t *testing.T) {
+	SetupRepo(t)
+	draftRepo := repository.NewDraftRepository(db)
+	draftService := service.NewDraftService(draftRepo, testService)
+
+	draft := &models.SubscriptionDraft{
+		UserID:       statementUserID,
+		Source:       models.DraftSourceStatement,
+		ServiceName:  "Netflix",
+		Price:        16,
+		BillingCycle: models.BillingCycleMonthly,
+		StartDate:    time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Status:       models.DraftStatusPending,
+	}
+	require.NoError(t, draftRepo.SaveDraft(context.Background(), draft))
+
+	httpErr := draftService.DismissDraft(context.Background(), "00000000-0000-0000-0000-000000000000", int(draft.ID))
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 404, httpErr.Status())
+
+	httpErr = draftService.DismissDraft(context.Background(), statementUserID, int(draft.ID))
+	assert.Nil(t, httpErr)
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/internal/repository"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gorm.io/gorm"
@@ -152,7 +153,7 @@ func TestListSubscriptions_NoFilters(t *testing.T) {
 	SetupRepo(t)
 	subs := seedTestSubscriptions(t)
 
-	result, total, err := testRepository.ListSubscriptions(context.Background(), 1, 10, nil, nil, nil, nil, nil, nil, nil, nil)
+	result, total, err := testRepository.ListSubscriptions(context.Background(), 1, 10, repository.SubscriptionFilter{}, nil)
 
 	assert.NoError(t, err)
 	assert.Len(t, result, len(subs))
@@ -165,7 +166,7 @@ func TestListSubscriptions_FilterByUserID(t *testing.T) {
 
 	userID := "user-1"
 
-	result, total, err := testRepository.ListSubscriptions(context.Background(), 1, 10, &userID, nil, nil, nil, nil, nil, nil, nil)
+	result, total, err := testRepository.ListSubscriptions(context.Background(), 1, 10, repository.SubscriptionFilter{UserID: &userID}, nil)
 
 	assert.NoError(t, err)
 	assert.Equal(t, int64(3), total)
@@ -180,7 +181,7 @@ func TestListSubscriptions_FilterByServiceName(t *testing.T) {
 
 	serviceName := "Netflix"
 
-	result, total, err := testRepository.ListSubscriptions(context.Background(), 1, 10, nil, &serviceName, nil, nil, nil, nil, nil, nil)
+	result, total, err := testRepository.ListSubscriptions(context.Background(), 1, 10, repository.SubscriptionFilter{ServiceName: &serviceName}, nil)
 
 	assert.NoError(t, err)
 	assert.Equal(t, int64(2), total)
@@ -196,7 +197,7 @@ func TestListSubscriptions_MultipleFilters(t *testing.T) {
 	userID := "user-1"
 	serviceName := "Netflix"
 
-	result, total, err := testRepository.ListSubscriptions(context.Background(), 1, 10, &userID, &serviceName, nil, nil, nil, nil, nil, nil)
+	result, total, err := testRepository.ListSubscriptions(context.Background(), 1, 10, repository.SubscriptionFilter{UserID: &userID, ServiceName: &serviceName}, nil)
 
 	assert.NoError(t, err)
 	assert.Equal(t, int64(1), total)
@@ -212,7 +213,7 @@ func TestListSubscriptions_DateFilters(t *testing.T) {
 	startDateFrom := "02-2025"
 	startDateFromParsed, _ := time.Parse("01-2006", startDateFrom)
 
-	result, total, err := testRepository.ListSubscriptions(context.Background(), 1, 10, nil, nil, &startDateFromParsed, nil, nil, nil, nil, nil)
+	result, total, err := testRepository.ListSubscriptions(context.Background(), 1, 10, repository.SubscriptionFilter{StartDateFrom: &startDateFromParsed}, nil)
 
 	assert.NoError(t, err)
 	assert.True(t, total >= 1)
@@ -225,12 +226,12 @@ func TestListSubscriptions_Pagination(t *testing.T) {
 	SetupRepo(t)
 	seedTestSubscriptions(t)
 
-	result1, total1, err := testRepository.ListSubscriptions(context.Background(), 1, 2, nil, nil, nil, nil, nil, nil, nil, nil)
+	result1, total1, err := testRepository.ListSubscriptions(context.Background(), 1, 2, repository.SubscriptionFilter{}, nil)
 	assert.NoError(t, err)
 	assert.Len(t, result1, 2)
 	assert.Equal(t, int64(5), total1)
 
-	result2, total2, err := testRepository.ListSubscriptions(context.Background(), 2, 2, nil, nil, nil, nil, nil, nil, nil, nil)
+	result2, total2, err := testRepository.ListSubscriptions(context.Background(), 2, 2, repository.SubscriptionFilter{}, nil)
 	assert.NoError(t, err)
 	assert.Len(t, result2, 2)
 	assert.Equal(t, int64(5), total2)
@@ -242,10 +243,9 @@ func TestListSubscriptions_Sorting(t *testing.T) {
 	SetupRepo(t)
 	seedTestSubscriptions(t)
 
-	sortBy := "price"
-	sortOrder := "desc"
+	sort := []repository.SortField{{Field: "price", Desc: true}}
 
-	result, _, err := testRepository.ListSubscriptions(context.Background(), 1, 10, nil, nil, nil, nil, nil, nil, &sortBy, &sortOrder)
+	result, _, err := testRepository.ListSubscriptions(context.Background(), 1, 10, repository.SubscriptionFilter{}, sort)
 
 	assert.NoError(t, err)
 	assert.True(t, len(result) >= 2)
@@ -260,7 +260,7 @@ func TestListSubscriptions_EmptyResult(t *testing.T) {
 
 	nonExistentUser := "non-existent-user"
 
-	result, total, err := testRepository.ListSubscriptions(context.Background(), 1, 10, &nonExistentUser, nil, nil, nil, nil, nil, nil, nil)
+	result, total, err := testRepository.ListSubscriptions(context.Background(), 1, 10, repository.SubscriptionFilter{UserID: &nonExistentUser}, nil)
 
 	assert.NoError(t, err)
 	assert.Empty(t, result)
@@ -282,7 +282,11 @@ func TestCalculateTotalCost_Success(t *testing.T) {
 	startDate, _ := time.Parse("01-2006", "01-2025")
 	endDate, _ := time.Parse("01-2006", "12-2025")
 
-	result, err := testRepository.CalculateTotalCost(context.Background(), userID, "", &startDate, &endDate)
+	result, err := testRepository.CalculateTotalCost(context.Background(), repository.SubscriptionFilter{
+		UserID:        &userID,
+		StartDateFrom: &startDate,
+		EndDateTo:     &endDate,
+	})
 
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
@@ -305,7 +309,12 @@ func TestCalculateTotalCost_FilterByService(t *testing.T) {
 	startDate, _ := time.Parse("01-2006", "01-2025")
 	endDate, _ := time.Parse("01-2006", "12-2025")
 
-	result, err := testRepository.CalculateTotalCost(context.Background(), userID, serviceName, &startDate, &endDate)
+	result, err := testRepository.CalculateTotalCost(context.Background(), repository.SubscriptionFilter{
+		UserID:        &userID,
+		ServiceName:   &serviceName,
+		StartDateFrom: &startDate,
+		EndDateTo:     &endDate,
+	})
 
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
@@ -316,10 +325,16 @@ func TestCalculateTotalCost_NoMatches(t *testing.T) {
 	SetupRepo(t)
 
 	nonExistentUser := "non-existent-user"
+	serviceName := "Netflix"
 	startDate := time.Now()
 	endDate := time.Now().AddDate(0, 1, 0)
 
-	result, err := testRepository.CalculateTotalCost(context.Background(), nonExistentUser, "Netflix", &startDate, &endDate)
+	result, err := testRepository.CalculateTotalCost(context.Background(), repository.SubscriptionFilter{
+		UserID:        &nonExistentUser,
+		ServiceName:   &serviceName,
+		StartDateFrom: &startDate,
+		EndDateTo:     &endDate,
+	})
 
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
@@ -348,7 +363,7 @@ func TestRepository_ConcurrentAccess(t *testing.T) {
 	<-done
 	<-done
 
-	result, total, err := testRepository.ListSubscriptions(context.Background(), 1, 10, nil, nil, nil, nil, nil, nil, nil, nil)
+	result, total, err := testRepository.ListSubscriptions(context.Background(), 1, 10, repository.SubscriptionFilter{}, nil)
 
 	assert.NoError(t, err)
 	assert.Equal(t, int64(2), total)
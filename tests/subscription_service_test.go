@@ -9,6 +9,7 @@ import (
 	"github.com/rasadov/subscription-manager/internal/dto"
 	"github.com/rasadov/subscription-manager/pkg/exceptions"
 	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/internal/repository"
 	"github.com/rasadov/subscription-manager/internal/service"
 	"github.com/rasadov/subscription-manager/tests/mocks"
 	"github.com/stretchr/testify/assert"
@@ -182,14 +183,8 @@ func TestListSubscriptionsService_Success(t *testing.T) {
 		mock.Anything,           // ctx
 		query.Page,                // page
 		query.Limit,              // elements
-		(*string)(nil),          // userID
-		(*string)(nil),          // serviceName
-		(*time.Time)(nil),       // startDateFrom
-		(*time.Time)(nil),       // startDateTo
-		(*time.Time)(nil),       // endDateFrom
-		(*time.Time)(nil),       // endDateTo
-		mock.AnythingOfType("*string"), // sortBy
-		mock.AnythingOfType("*string"), // sortOrder
+		repository.SubscriptionFilter{}, // filter
+		[]repository.SortField{{Field: "created_at", Desc: true}}, // sort
 	).Return(expectedSubs, int64(2), nil)
 
 	result, err := service.ListSubscriptions(context.Background(), query)
@@ -220,14 +215,8 @@ func TestListSubscriptionsService_DefaultPagination(t *testing.T) {
 		mock.Anything,           // ctx
 		expectedQuery.Page,         // page
 		expectedQuery.Limit,        // elements
-		(*string)(nil),          // userID
-		(*string)(nil),          // serviceName
-		(*time.Time)(nil),       // startDateFrom
-		(*time.Time)(nil),       // startDateTo
-		(*time.Time)(nil),       // endDateFrom
-		(*time.Time)(nil),       // endDateTo
-		mock.AnythingOfType("*string"), // sortBy
-		mock.AnythingOfType("*string"), // sortOrder
+		repository.SubscriptionFilter{}, // filter
+		[]repository.SortField{{Field: "created_at", Desc: true}}, // sort
 	).Return([]*models.Subscription{}, int64(0), nil)
 
 	result, err := service.ListSubscriptions(context.Background(), query)
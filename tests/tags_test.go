@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func seedTaggedSubscriptions(t *testing.T, subscriptionService service.SubscriptionService) []*dto.SubscriptionResponse {
+	requests := []dto.CreateSubscriptionRequest{
+		{ServiceName: "Spotify", Price: 300, Category: "Music", Tags: []string{"Family", "personal", "family "}},
+		{ServiceName: "YouTube Premium", Price: 400, Category: "video", Tags: []string{"family"}},
+		{ServiceName: "GitHub", Price: 500, Category: "dev", Tags: []string{"work"}},
+		{ServiceName: "Slack", Price: 700, Tags: []string{"work", "personal"}},
+		{ServiceName: "Gym", Price: 1000},
+	}
+
+	var created []*dto.SubscriptionResponse
+	for _, req := range requests {
+		req.UserID = catalogUserID
+		req.StartDate = "01-2025"
+		req.EndDate = "12-2025"
+		response, httpErr := subscriptionService.CreateSubscription(context.Background(), req)
+		require.Nil(t, httpErr)
+		created = append(created, response)
+	}
+	return created
+}
+
+func TestCreateSubscription_NormalizesCategoryAndTags(t *testing.T) {
+	_, subscriptionService, _ := setupCatalog(t)
+	created := seedTaggedSubscriptions(t, subscriptionService)
+
+	assert.Equal(t, "music", created[0].Category)
+	assert.Equal(t, []string{"family", "personal"}, created[0].Tags)
+	assert.Empty(t, created[4].Tags)
+
+	// Catalog services provide the default category
+	netflix, httpErr := subscriptionService.CreateSubscription(context.Background(), dto.CreateSubscriptionRequest{
+		ServiceName: "Netflix", Price: 600, UserID: catalogUserID, StartDate: "01-2025",
+	})
+	require.Nil(t, httpErr)
+	assert.Equal(t, "video", netflix.Category)
+
+	var tagCount int64
+	require.NoError(t, db.Table("tags").Count(&tagCount).Error)
+	assert.Equal(t, int64(3), tagCount)
+
+	reloaded, httpErr := subscriptionService.GetSubscription(context.Background(), int(created[0].ID))
+	require.Nil(t, httpErr)
+	assert.ElementsMatch(t, []string{"family", "personal"}, reloaded.Tags)
+}
+
+func TestUpdateSubscription_ReplacesTags(t *testing.T) {
+	_, subscriptionService, _ := setupCatalog(t)
+	created := seedTaggedSubscriptions(t, subscriptionService)
+
+	tags := []string{"Work"}
+	updated, httpErr := subscriptionService.UpdateSubscription(context.Background(), int(created[0].ID), dto.UpdateSubscriptionRequest{Tags: &tags})
+	require.Nil(t, httpErr)
+	assert.Equal(t, []string{"work"}, updated.Tags)
+
+	reloaded, httpErr := subscriptionService.GetSubscription(context.Background(), int(created[0].ID))
+	require.Nil(t, httpErr)
+	assert.Equal(t, []string{"work"}, reloaded.Tags)
+	assert.Equal(t, "music", reloaded.Category)
+
+	require.Nil(t, subscriptionService.DeleteSubscription(context.Background(), int(created[0].ID)))
+	var links int64
+	require.NoError(t, db.Table("subscription_tags").Where("subscription_id = ?", created[0].ID).Count(&links).Error)
+	assert.Zero(t, links)
+}
+
+func TestListSubscriptions_FiltersByCategoryAndTags(t *testing.T) {
+	_, subscriptionService, _ := setupCatalog(t)
+	seedTaggedSubscriptions(t, subscriptionService)
+
+	tests := []struct {
+		name     string
+		query    dto.ListSubscriptionsQuery
+		expected []string
+	}{
+		{"category", dto.ListSubscriptionsQuery{Category: strPtr("MUSIC")}, []string{"Spotify"}},
+		{"any tag", dto.ListSubscriptionsQuery{Tags: strPtr("family, work")}, []string{"Spotify", "YouTube Premium", "GitHub", "Slack"}},
+		{"all tags", dto.ListSubscriptionsQuery{Tags: strPtr("work,personal"), TagMatch: "all"}, []string{"Slack"}},
+		{"tag and category", dto.ListSubscriptionsQuery{Tags: strPtr("family"), Category: strPtr("video")}, []string{"YouTube Premium"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.query.Limit = 100
+			list, httpErr := subscriptionService.ListSubscriptions(context.Background(), tt.query)
+			require.Nil(t, httpErr)
+
+			var names []string
+			for _, subscription := range list.Data {
+				names = append(names, subscription.ServiceName)
+			}
+			assert.ElementsMatch(t, tt.expected, names)
+			assert.Equal(t, len(tt.expected), list.Pagination.Total)
+		})
+	}
+}
+
+func TestCalculateTotalCost_GroupBy(t *testing.T) {
+	_, subscriptionService, _ := setupCatalog(t)
+	seedTaggedSubscriptions(t, subscriptionService)
+
+	startDate, endDate := "01-2025", "12-2025"
+	total, httpErr := subscriptionService.CalculateTotalCost(context.Background(), dto.TotalCostQuery{
+		StartDate: &startDate, EndDate: &endDate, GroupBy: "tag",
+	})
+	require.Nil(t, httpErr)
+	assert.Equal(t, int64(2900), total.TotalCost)
+	assert.Equal(t, map[string]int64{"work": 1200, "personal": 1000, "family": 700, "": 1000}, costGroups(total.Groups))
+
+	total, httpErr = subscriptionService.CalculateTotalCost(context.Background(), dto.TotalCostQuery{
+		StartDate: &startDate, EndDate: &endDate, GroupBy: "category", Tags: strPtr("work"),
+	})
+	require.Nil(t, httpErr)
+	assert.Equal(t, int64(1200), total.TotalCost)
+	assert.Equal(t, map[string]int64{"dev": 500, "": 700}, costGroups(total.Groups))
+}
+
+func costGroups(groups []*dto.CostGroup) map[string]int64 {
+	costs := make(map[string]int64)
+	for _, group := range groups {
+		key := ""
+		if group.Key != nil {
+			key = *group.Key
+		}
+		costs[key] = group.TotalCost
+	}
+	return costs
+}
+
+func strPtr(value string) *string {
+	return &value
+}
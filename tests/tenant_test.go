@@ -0,0 +1,285 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rasadov/subscription-manager/internal/dto"
+	"github.com/rasadov/subscription-manager/internal/models"
+	"github.com/rasadov/subscription-manager/internal/repository"
+	"github.com/rasadov/subscription-manager/internal/service"
+	"github.com/rasadov/subscription-manager/pkg/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+var (
+	acmeCtx   = tenant.WithTenant(context.Background(), "acme")
+	globexCtx = tenant.WithTenant(context.Background(), "globex")
+)
+
+func createTenantSubscription(t *testing.T, ctx context.Context, serviceName string, price int64) *dto.SubscriptionResponse {
+	created, httpErr := testService.CreateSubscription(ctx, dto.CreateSubscriptionRequest{
+		ServiceName: serviceName,
+		Price:       price,
+		UserID:      catalogUserID,
+		StartDate:   "01-2025",
+		EndDate:     "12-2025",
+		Tags:        []string{"work"},
+	})
+	require.Nil(t, httpErr)
+	return created
+}
+
+func TestTenantIsolation_Reads(t *testing.T) {
+	SetupRepo(t)
+	acme := createTenantSubscription(t, acmeCtx, "Slack", 700)
+	createTenantSubscription(t, globexCtx, "GitHub", 500)
+
+	_, httpErr := testService.GetSubscription(globexCtx, int(acme.ID))
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 404, httpErr.Status())
+
+	_, httpErr = testService.GetSubscription(context.Background(), int(acme.ID))
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 404, httpErr.Status())
+
+	list, httpErr := testService.ListSubscriptions(globexCtx, dto.ListSubscriptionsQuery{UserID: strPtr(catalogUserID), Tags: strPtr("work")})
+	require.Nil(t, httpErr)
+	require.Len(t, list.Data, 1)
+	assert.Equal(t, "GitHub", list.Data[0].ServiceName)
+	assert.Equal(t, 1, list.Pagination.Total)
+
+	total, httpErr := testService.CalculateTotalCost(acmeCtx, dto.TotalCostQuery{
+		UserID:    strPtr(catalogUserID),
+		StartDate: strPtr("01-2025"),
+		EndDate:   strPtr("12-2025"),
+		GroupBy:   "tag",
+	})
+	require.Nil(t, httpErr)
+	assert.Equal(t, int64(700), total.TotalCost)
+	require.Len(t, total.Groups, 1)
+	assert.Equal(t, int64(700), total.Groups[0].TotalCost)
+
+	var streamed []string
+	err := testRepository.StreamSubscriptions(acmeCtx, repository.SubscriptionFilter{}, func(subscription *models.Subscription) error {
+		streamed = append(streamed, subscription.ServiceName)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Slack"}, streamed)
+
+	// Background jobs may read across tenants explicitly
+	streamed = nil
+	err = testRepository.StreamSubscriptions(tenant.WithAllTenants(context.Background()), repository.SubscriptionFilter{}, func(subscription *models.Subscription) error {
+		streamed = append(streamed, subscription.ServiceName)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"Slack", "GitHub"}, streamed)
+}
+
+func TestTenantIsolation_Writes(t *testing.T) {
+	SetupRepo(t)
+	acme := createTenantSubscription(t, acmeCtx, "Slack", 700)
+	createTenantSubscription(t, globexCtx, "GitHub", 500)
+
+	price := int64(1)
+	_, httpErr := testService.UpdateSubscription(globexCtx, int(acme.ID), dto.UpdateSubscriptionRequest{Price: &price})
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 404, httpErr.Status())
+
+	// Saving a record of another tenant directly is rejected
+	stolen, err := testRepository.GetSubscription(acmeCtx, int(acme.ID))
+	require.NoError(t, err)
+	stolen.Price = 1
+	stolen.TenantID = "globex"
+	stolen.Tags = nil
+	require.ErrorIs(t, testRepository.UpdateSubscription(globexCtx, int(acme.ID), stolen), gorm.ErrRecordNotFound)
+
+	// Deleting it neither removes the record nor its tag links
+	require.Nil(t, testService.DeleteSubscription(globexCtx, int(acme.ID)))
+
+	reloaded, httpErr := testService.GetSubscription(acmeCtx, int(acme.ID))
+	require.Nil(t, httpErr)
+	assert.Equal(t, int64(700), reloaded.Price)
+	assert.Equal(t, []string{"work"}, reloaded.Tags)
+
+	// Created records always belong to the tenant of the context
+	planted := &models.Subscription{ServiceName: "Zoom", Price: 100, Currency: "RUB", UserID: catalogUserID,
+		BillingCycle: models.BillingCycleMonthly, StartDate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), TenantID: "acme"}
+	require.NoError(t, testRepository.CreateSubscription(globexCtx, planted))
+	assert.Equal(t, "globex", planted.TenantID)
+	_, err = testRepository.GetSubscription(acmeCtx, int(planted.ID))
+	require.Error(t, err)
+
+	// Tag names are unique per tenant, so both tenants have their own "work" tag
+	var tags []models.Tag
+	require.NoError(t, db.WithContext(tenant.WithAllTenants(context.Background())).Find(&tags).Error)
+	tenants := []string{}
+	for _, tag := range tags {
+		tenants = append(tenants, tag.TenantID)
+	}
+	assert.ElementsMatch(t, []string{"acme", "globex"}, tenants)
+}
+
+func TestTenantIsolation_BackgroundJobs(t *testing.T) {
+	budgetService, subscriptionService := setupBudgets(t)
+
+	_, httpErr := budgetService.CreateBudget(globexCtx, catalogUserID, dto.CreateBudgetRequest{Scope: models.BudgetScopeAll, MonthlyLimit: 1000})
+	require.Nil(t, httpErr)
+
+	// The same user ID in another tenant is a different user
+	for _, ctx := range []context.Context{acmeCtx, globexCtx} {
+		_, httpErr = subscriptionService.CreateSubscription(ctx, dto.CreateSubscriptionRequest{
+			ServiceName: "Slack", Price: 600, UserID: catalogUserID, StartDate: "01-2025",
+		})
+		require.Nil(t, httpErr)
+	}
+	require.NoError(t, budgetService.EvaluateAll(context.Background(), time.Now()))
+
+	alerts, httpErr := budgetService.ListAlerts(globexCtx, catalogUserID)
+	require.Nil(t, httpErr)
+	assert.Empty(t, alerts.Data)
+
+	_, httpErr = subscriptionService.CreateSubscription(globexCtx, dto.CreateSubscriptionRequest{
+		ServiceName: "GitHub", Price: 300, UserID: catalogUserID, StartDate: "01-2025",
+	})
+	require.Nil(t, httpErr)
+	require.NoError(t, budgetService.EvaluateAll(context.Background(), time.Now()))
+
+	alerts, httpErr = budgetService.ListAlerts(globexCtx, catalogUserID)
+	require.Nil(t, httpErr)
+	require.Len(t, alerts.Data, 1)
+	assert.Equal(t, int64(900), alerts.Data[0].Spend)
+
+	alerts, httpErr = budgetService.ListAlerts(acmeCtx, catalogUserID)
+	require.Nil(t, httpErr)
+	assert.Empty(t, alerts.Data)
+
+	// Calendar feeds are fetched without a tenant and served from the token's tenant
+	calendarService := service.NewCalendarService(repository.NewCalendarTokenRepository(db), testRepository)
+	issued, httpErr := calendarService.IssueToken(globexCtx, catalogUserID)
+	require.Nil(t, httpErr)
+
+	var feed bytes.Buffer
+	require.Nil(t, calendarService.WriteFeed(context.Background(), &feed, catalogUserID, issued.Token))
+	assert.Contains(t, feed.String(), "GitHub")
+}
+
+func TestTenantIsolation_CalendarTokensOfTheSameUser(t *testing.T) {
+	SetupRepo(t)
+	for ctx, serviceName := range map[context.Context]string{acmeCtx: "Slack", globexCtx: "GitHub"} {
+		_, httpErr := testService.CreateSubscription(ctx, dto.CreateSubscriptionRequest{
+			ServiceName: serviceName, Price: 500, UserID: catalogUserID, StartDate: time.Now().Format("01-2006"),
+		})
+		require.Nil(t, httpErr)
+	}
+
+	// The user has a token in each tenant; each one serves its own tenant
+	calendarService := service.NewCalendarService(repository.NewCalendarTokenRepository(db), testRepository)
+	acmeToken, httpErr := calendarService.IssueToken(acmeCtx, catalogUserID)
+	require.Nil(t, httpErr)
+	globexToken, httpErr := calendarService.IssueToken(globexCtx, catalogUserID)
+	require.Nil(t, httpErr)
+
+	for token, expected := range map[string]string{acmeToken.Token: "Slack", globexToken.Token: "GitHub"} {
+		var feed bytes.Buffer
+		require.Nil(t, calendarService.WriteFeed(context.Background(), &feed, catalogUserID, token))
+		assert.Contains(t, feed.String(), expected)
+	}
+
+	// A token only opens the feed of its own user
+	var feed bytes.Buffer
+	httpErr = calendarService.WriteFeed(context.Background(), &feed, otherBudgetUserID, globexToken.Token)
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 404, httpErr.Status())
+	httpErr = calendarService.WriteFeed(context.Background(), &feed, catalogUserID, "")
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 404, httpErr.Status())
+	assert.Zero(t, feed.Len())
+}
+
+func TestTenantIsolation_Catalog(t *testing.T) {
+	catalogService, subscriptionService, _ := setupCatalog(t)
+
+	// Both tenants may have their own entry of the same name
+	services := map[context.Context]*dto.ServiceResponse{}
+	subscriptions := map[context.Context]*dto.SubscriptionResponse{}
+	for _, ctx := range []context.Context{acmeCtx, globexCtx} {
+		created, httpErr := catalogService.CreateService(ctx, dto.CreateServiceRequest{
+			Name: "Slack", Plans: []dto.CreatePlanRequest{{Name: "Pro", Price: 700}},
+		})
+		require.Nil(t, httpErr)
+		services[ctx] = created
+
+		subscription, httpErr := subscriptionService.CreateSubscription(ctx, dto.CreateSubscriptionRequest{
+			ServiceName: "Slack Pro", UserID: catalogUserID, StartDate: "01-2025",
+		})
+		require.Nil(t, httpErr)
+		require.NotNil(t, subscription.PlanID)
+		assert.Equal(t, created.Plans[0].ID, *subscription.PlanID)
+		subscriptions[ctx] = subscription
+	}
+
+	listed, httpErr := catalogService.ListServices(globexCtx, dto.ListServicesQuery{})
+	require.Nil(t, httpErr)
+	require.Len(t, listed.Data, 1)
+	assert.Equal(t, services[globexCtx].ID, listed.Data[0].ID)
+
+	// Another tenant's entries can be neither changed nor deleted
+	httpErr = catalogService.DeletePlan(acmeCtx, int(services[globexCtx].ID), int(services[globexCtx].Plans[0].ID))
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 404, httpErr.Status())
+	httpErr = catalogService.DeleteService(acmeCtx, int(services[globexCtx].ID))
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 404, httpErr.Status())
+
+	// A subscription of globex left linked to the acme catalog, as the shared
+	// catalog allowed, stays linked when acme deletes the entry
+	legacy := createTenantSubscription(t, globexCtx, "Legacy Slack", 700)
+	require.NoError(t, db.Exec("UPDATE subscriptions SET service_id = ? WHERE id = ?", services[acmeCtx].ID, legacy.ID).Error)
+
+	require.Nil(t, catalogService.DeleteService(acmeCtx, int(services[acmeCtx].ID)))
+
+	unlinked, httpErr := subscriptionService.GetSubscription(acmeCtx, int(subscriptions[acmeCtx].ID))
+	require.Nil(t, httpErr)
+	assert.Nil(t, unlinked.ServiceID)
+	assert.Nil(t, unlinked.PlanID)
+
+	linked, httpErr := subscriptionService.GetSubscription(globexCtx, int(subscriptions[globexCtx].ID))
+	require.Nil(t, httpErr)
+	require.NotNil(t, linked.ServiceID)
+	assert.Equal(t, services[globexCtx].ID, *linked.ServiceID)
+	assert.Equal(t, services[globexCtx].Plans[0].ID, *linked.PlanID)
+
+	reloaded, httpErr := subscriptionService.GetSubscription(globexCtx, int(legacy.ID))
+	require.Nil(t, httpErr)
+	require.NotNil(t, reloaded.ServiceID)
+	assert.Equal(t, services[acmeCtx].ID, *reloaded.ServiceID)
+}
+
+func TestTenantIsolation_ReceiptRules(t *testing.T) {
+	SetupRepo(t)
+	receiptService := service.NewReceiptService(repository.NewReceiptRuleRepository(db), repository.NewDraftRepository(db), testRepository)
+
+	rule, httpErr := receiptService.CreateRule(acmeCtx, dto.CreateReceiptRuleRequest{
+		SenderDomain: "slack.com", ServiceName: "Slack", PricePattern: `(?P<amount>[0-9.,]+)`,
+	})
+	require.Nil(t, httpErr)
+
+	rules, httpErr := receiptService.ListRules(globexCtx)
+	require.Nil(t, httpErr)
+	assert.Empty(t, rules.Data)
+
+	httpErr = receiptService.DeleteRule(globexCtx, int(rule.ID))
+	require.NotNil(t, httpErr)
+	assert.Equal(t, 404, httpErr.Status())
+	rules, httpErr = receiptService.ListRules(acmeCtx)
+	require.Nil(t, httpErr)
+	require.Len(t, rules.Data, 1)
+	assert.Equal(t, rule.ID, rules.Data[0].ID)
+}